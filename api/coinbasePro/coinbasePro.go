@@ -17,6 +17,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -31,7 +32,7 @@ type CoinbaseProError struct {
 }
 
 func (e *CoinbaseProError) Error() string {
-	return fmt.Sprintf("Error using the Coinbase Pro API.")
+	return fmt.Sprintf("Error using the Coinbase Pro API: %s", e.Msg)
 }
 
 /*
@@ -103,7 +104,7 @@ func (c *CoinbaseProClient) GetPrices() ([]bookkeeper.PriceRecord, *CoinbaseProE
 			Exchange:               "Coinbase",
 			ArbitrageRecordUuid:    uuid.Nil,
 			IsArbitrageOpportunity: false,
-			Timestamp:              time.Now().Format(time.RFC3339),
+			Timestamp:              time.Now().UTC(),
 		},
 		{
 			Uuid:                   uuid.New(),
@@ -113,7 +114,7 @@ func (c *CoinbaseProClient) GetPrices() ([]bookkeeper.PriceRecord, *CoinbaseProE
 			Exchange:               "Coinbase",
 			ArbitrageRecordUuid:    uuid.Nil,
 			IsArbitrageOpportunity: false,
-			Timestamp:              time.Now().Format(time.RFC3339),
+			Timestamp:              time.Now().UTC(),
 		},
 		{
 			Uuid:                   uuid.New(),
@@ -123,7 +124,7 @@ func (c *CoinbaseProClient) GetPrices() ([]bookkeeper.PriceRecord, *CoinbaseProE
 			Exchange:               "Coinbase",
 			ArbitrageRecordUuid:    uuid.Nil,
 			IsArbitrageOpportunity: false,
-			Timestamp:              time.Now().Format(time.RFC3339),
+			Timestamp:              time.Now().UTC(),
 		},
 		{
 			Uuid:                   uuid.New(),
@@ -133,7 +134,7 @@ func (c *CoinbaseProClient) GetPrices() ([]bookkeeper.PriceRecord, *CoinbaseProE
 			Exchange:               "Coinbase",
 			ArbitrageRecordUuid:    uuid.Nil,
 			IsArbitrageOpportunity: false,
-			Timestamp:              time.Now().Format(time.RFC3339),
+			Timestamp:              time.Now().UTC(),
 		},
 		{
 			Uuid:                   uuid.New(),
@@ -143,14 +144,13 @@ func (c *CoinbaseProClient) GetPrices() ([]bookkeeper.PriceRecord, *CoinbaseProE
 			Exchange:               "Coinbase",
 			ArbitrageRecordUuid:    uuid.Nil,
 			IsArbitrageOpportunity: false,
-			Timestamp:              time.Now().Format(time.RFC3339),
+			Timestamp:              time.Now().UTC(),
 		},
 	}
 	utils.Logger.Debug("Retrieved coinbase prices...", zap.String("prices", strconv.Itoa(len(prices))))
 	if len(prices) == 0 {
 		return nil, &CoinbaseProError{Msg: "No prices returned from Coinbase Pro API."}
 	}
-	bookkeeper.RecordPriceRecord(prices...)
 	return prices, nil
 }
 
@@ -172,7 +172,7 @@ func requestBuilder(now string, method string, path string, body string) *http.R
 	u.Path = path
 	urlString := u.String()
 
-	request, err := http.NewRequest(method, urlString, nil)
+	request, err := http.NewRequest(method, urlString, strings.NewReader(body))
 	if err != nil {
 		utils.Logger.Error(err.Error())
 	}
@@ -3,13 +3,13 @@ package coinbasePro
 import "time"
 
 type Account struct {
-	id             string `json:"id"`
-	currency       string `json:"currency"`
-	balance        string `json:"balance"`
-	available      string `json:"available"`
-	hold           string `json:"hold"`
-	profileId      string `json:"profile_id"`
-	tradingEnabled string `json:"trading_enabled"`
+	Id             string `json:"id"`
+	Currency       string `json:"currency"`
+	Balance        string `json:"balance"`
+	Available      string `json:"available"`
+	Hold           string `json:"hold"`
+	ProfileId      string `json:"profile_id"`
+	TradingEnabled bool   `json:"trading_enabled"`
 }
 
 type errorCoinbasePro struct {
@@ -36,3 +36,69 @@ type SignedPrices struct {
 		Ltc string `json:"LTC"`
 	} `json:"prices"`
 }
+
+type Order struct {
+	Id            string    `json:"id"`
+	ClientOid     string    `json:"client_oid"`
+	Price         string    `json:"price"`
+	Size          string    `json:"size"`
+	ProductId     string    `json:"product_id"`
+	Side          string    `json:"side"`
+	Type          string    `json:"type"`
+	TimeInForce   string    `json:"time_in_force"`
+	CreatedAt     time.Time `json:"created_at"`
+	DoneReason    string    `json:"done_reason"`
+	FillFees      string    `json:"fill_fees"`
+	FilledSize    string    `json:"filled_size"`
+	ExecutedValue string    `json:"executed_value"`
+	Status        string    `json:"status"`
+	Settled       bool      `json:"settled"`
+}
+
+type newOrder struct {
+	Type        string `json:"type"`
+	Side        string `json:"side"`
+	ProductId   string `json:"product_id"`
+	Price       string `json:"price,omitempty"`
+	Size        string `json:"size"`
+	TimeInForce string `json:"time_in_force,omitempty"`
+	ClientOid   string `json:"client_oid,omitempty"`
+}
+
+type CoinbaseAccount struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Balance  string `json:"balance"`
+	Currency string `json:"currency"`
+	Type     string `json:"type"`
+	Active   bool   `json:"active"`
+}
+
+type CryptoAddress struct {
+	Id      string `json:"id"`
+	Address string `json:"address"`
+	Network string `json:"network"`
+}
+
+type cryptoWithdrawal struct {
+	Amount        string `json:"amount"`
+	Currency      string `json:"currency"`
+	CryptoAddress string `json:"crypto_address"`
+}
+
+type Withdrawal struct {
+	Id       string `json:"id"`
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+	Fee      string `json:"fee"`
+}
+
+type Transfer struct {
+	Id          string `json:"id"`
+	Type        string `json:"type"`
+	CreatedAt   string `json:"created_at"`
+	CompletedAt string `json:"completed_at"`
+	CanceledAt  string `json:"canceled_at"`
+	ProcessedAt string `json:"processed_at"`
+	Amount      string `json:"amount"`
+}
@@ -0,0 +1,259 @@
+package coinbasePro
+
+import (
+	"cryptoArbitrageBot/executor"
+	"cryptoArbitrageBot/internal/utils"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const exchangeName = "Coinbase"
+
+/*
+Maps the currency notation used in price records to Coinbase Pro product ids.
+*/
+var coinbaseProducts = map[string]string{
+	"BTCUSD": "BTC-USD",
+	"ETHUSD": "ETH-USD",
+	"LTCUSD": "LTC-USD",
+	"ETHBTC": "ETH-BTC",
+	"LTCBTC": "LTC-BTC",
+}
+
+/*
+Number of decimals of the quote increment of each Coinbase Pro product. Prices of other products are sent with as many
+decimals as they need.
+*/
+var priceDecimals = map[string]int{
+	"BTC-USD": 2,
+	"ETH-USD": 2,
+	"LTC-USD": 2,
+	"ETH-BTC": 5,
+	"LTC-BTC": 6,
+}
+
+/*
+Returns the exchange name of the Coinbase Pro venue.
+*/
+func (c *CoinbaseProClient) Name() string {
+	return exchangeName
+}
+
+/*
+Place an order on Coinbase Pro and look up how much of it was filled. Limit orders are placed as immediate-or-cancel so
+that they never rest on the book.
+*/
+func (c *CoinbaseProClient) PlaceOrder(order executor.OrderRequest) (executor.OrderResult, error) {
+	result := executor.OrderResult{
+		Exchange:      exchangeName,
+		Currency:      order.Currency,
+		Side:          order.Side,
+		Status:        executor.Rejected,
+		RequestedSize: order.Size,
+	}
+
+	productId, ok := coinbaseProducts[order.Currency]
+	if !ok {
+		return result, &CoinbaseProError{Msg: fmt.Sprintf("currency %s is not traded on Coinbase Pro", order.Currency)}
+	}
+
+	body := newOrder{
+		Type:      string(executor.Market),
+		Side:      string(order.Side),
+		ProductId: productId,
+		Size:      strconv.FormatFloat(order.Size, 'f', 8, 64),
+		ClientOid: order.ClientOrderId,
+	}
+	if order.Type == executor.Limit {
+		body.Type = string(executor.Limit)
+		body.Price = formatPrice(productId, order.Price)
+		body.TimeInForce = "IOC"
+	}
+	encodedBody, err := json.Marshal(body)
+	if err != nil {
+		return result, &CoinbaseProError{Msg: err.Error()}
+	}
+
+	var placedOrder Order
+	if err := doRequest(c.client, "POST", "/orders", string(encodedBody), &placedOrder); err != nil {
+		return result, err
+	}
+	result.OrderId = placedOrder.Id
+
+	var filledOrder Order
+	if err := doRequest(c.client, "GET", fmt.Sprintf("/orders/%s", placedOrder.Id), "", &filledOrder); err != nil {
+		return result, err
+	}
+	return toOrderResult(filledOrder, result), nil
+}
+
+/*
+Cancel all open orders on Coinbase Pro and return how many were cancelled.
+*/
+func (c *CoinbaseProClient) CancelAllOrders() (int, error) {
+	var cancelledOrderIds []string
+	if err := doRequest(c.client, "DELETE", "/orders", "", &cancelledOrderIds); err != nil {
+		return 0, err
+	}
+	return len(cancelledOrderIds), nil
+}
+
+/*
+Returns all open orders on Coinbase Pro.
+*/
+func (c *CoinbaseProClient) ListOpenOrders() ([]executor.OrderResult, error) {
+	var orders []Order
+	if err := doRequest(c.client, "GET", "/orders?status=open&status=pending&status=active", "", &orders); err != nil {
+		return nil, err
+	}
+	var results []executor.OrderResult
+	for _, order := range orders {
+		results = append(results, toOrderResult(order, newOrderResult(order)))
+	}
+	return results, nil
+}
+
+/*
+Look up an order on Coinbase Pro by id.
+*/
+func (c *CoinbaseProClient) GetOrder(orderId string, currency string) (executor.OrderResult, error) {
+	var order Order
+	if err := doRequest(c.client, "GET", fmt.Sprintf("/orders/%s", orderId), "", &order); err != nil {
+		if err.Msg == "NotFound" {
+			return executor.OrderResult{}, executor.ErrOrderNotFound
+		}
+		return executor.OrderResult{}, err
+	}
+	return toOrderResult(order, newOrderResult(order)), nil
+}
+
+/*
+Look up an order on Coinbase Pro by its client order id.
+*/
+func (c *CoinbaseProClient) FindOrder(clientOrderId string, currency string) (executor.OrderResult, error) {
+	var order Order
+	if err := doRequest(c.client, "GET", fmt.Sprintf("/orders/client:%s", clientOrderId), "", &order); err != nil {
+		if err.Msg == "NotFound" {
+			return executor.OrderResult{}, executor.ErrOrderNotFound
+		}
+		return executor.OrderResult{}, err
+	}
+	return toOrderResult(order, newOrderResult(order)), nil
+}
+
+/*
+Returns the available balance of every Coinbase Pro account, keyed by currency.
+*/
+func (c *CoinbaseProClient) GetBalances() (map[string]float64, error) {
+	var accounts []Account
+	if err := doRequest(c.client, "GET", "/accounts", "", &accounts); err != nil {
+		return nil, err
+	}
+	balances := make(map[string]float64)
+	for _, account := range accounts {
+		available, err := strconv.ParseFloat(account.Available, 64)
+		if err != nil {
+			return nil, &CoinbaseProError{Msg: fmt.Sprintf("invalid available balance %q for %s", account.Available, account.Currency)}
+		}
+		balances[account.Currency] += available
+	}
+	return balances, nil
+}
+
+/*
+Format a price with the number of decimals of the quote increment of the product.
+*/
+func formatPrice(productId string, price float64) string {
+	decimals, ok := priceDecimals[productId]
+	if !ok {
+		decimals = -1
+	}
+	return strconv.FormatFloat(price, 'f', decimals, 64)
+}
+
+/*
+Create an OrderResult for an order that was looked up on Coinbase Pro rather than placed.
+*/
+func newOrderResult(order Order) executor.OrderResult {
+	requestedSize, _ := strconv.ParseFloat(order.Size, 64)
+	currency := order.ProductId
+	for aCurrency, productId := range coinbaseProducts {
+		if productId == order.ProductId {
+			currency = aCurrency
+		}
+	}
+	return executor.OrderResult{
+		ClientOrderId: order.ClientOid,
+		Exchange:      exchangeName,
+		Currency:      currency,
+		Side:          executor.Side(order.Side),
+		RequestedSize: requestedSize,
+	}
+}
+
+/*
+Fill in the OrderResult from a Coinbase Pro order.
+*/
+func toOrderResult(order Order, result executor.OrderResult) executor.OrderResult {
+	filledSize, _ := strconv.ParseFloat(order.FilledSize, 64)
+	executedValue, _ := strconv.ParseFloat(order.ExecutedValue, 64)
+	fee, _ := strconv.ParseFloat(order.FillFees, 64)
+
+	result.OrderId = order.Id
+	result.FilledSize = filledSize
+	result.Fee = fee
+	if filledSize > 0 {
+		result.AveragePrice = executedValue / filledSize
+	}
+	switch {
+	case filledSize > 0 && filledSize >= result.RequestedSize:
+		result.Status = executor.Filled
+	case order.Status == "open" || order.Status == "pending" || order.Status == "active":
+		result.Status = executor.Open
+	case filledSize > 0:
+		result.Status = executor.PartiallyFilled
+	case order.DoneReason == "canceled":
+		result.Status = executor.Cancelled
+	default:
+		result.Status = executor.Rejected
+	}
+	return result
+}
+
+/*
+Send a signed request to the Coinbase Pro API and unmarshal the response into v.
+*/
+func doRequest(client *http.Client, method string, path string, body string, v interface{}) *CoinbaseProError {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	errorCoinbasePro := errorCoinbasePro{}
+
+	resp, err := client.Do(requestBuilder(now, method, path, body))
+	if err != nil {
+		return &CoinbaseProError{Msg: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &CoinbaseProError{Msg: err.Error()}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		err = json.Unmarshal(responseBody, &errorCoinbasePro)
+		if err != nil || errorCoinbasePro.Message == "" {
+			return &CoinbaseProError{Msg: fmt.Sprintf("unexpected status %d: %s", resp.StatusCode, string(responseBody))}
+		}
+		return &CoinbaseProError{Msg: errorCoinbasePro.Message}
+	}
+
+	err = json.Unmarshal(responseBody, v)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("ERROR UNMARSHALLING %s response --> %v\n\n Response body: \n\n%v", path, err, string(responseBody)))
+		return &CoinbaseProError{Msg: err.Error()}
+	}
+	return nil
+}
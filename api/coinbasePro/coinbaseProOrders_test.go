@@ -0,0 +1,52 @@
+package coinbasePro
+
+import (
+	"cryptoArbitrageBot/executor"
+	"cryptoArbitrageBot/internal/utils"
+	"encoding/json"
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_PlaceOrderPrice(t *testing.T) {
+	utils.InitializeLogger()
+
+	var sentOrder newOrder
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			json.NewDecoder(r.Body).Decode(&sentOrder)
+		}
+		w.Write([]byte(`{"id": "1", "filled_size": "1", "executed_value": "1", "status": "done"}`))
+	}))
+	defer server.Close()
+
+	viper.Set("COINBASE_PRO.URL", server.URL)
+	viper.Set("COINBASE_PRO.TEST.KEY", "key")
+	viper.Set("COINBASE_PRO.TEST.SECRET", "c2VjcmV0")
+	viper.Set("COINBASE_PRO.TEST.PASSPHRASE", "passphrase")
+
+	tests := []struct {
+		name     string
+		currency string
+		price    float64
+		want     string
+	}{
+		{"Sub-cent price", "ETHBTC", 0.05312, "0.05312"},
+		{"Rounded to quote increment", "LTCBTC", 0.00234567, "0.002346"},
+		{"Dollar price", "BTCUSD", 26123.456, "26123.46"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := CoinbaseProClient{server.Client()}
+			order := executor.OrderRequest{Currency: tt.currency, Side: executor.Buy, Type: executor.Limit, Size: 1, Price: tt.price}
+
+			_, err := client.PlaceOrder(order)
+
+			assert.Equal(t, err, nil)
+			assert.Equal(t, sentOrder.Price, tt.want)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package coinbasePro
+
+import (
+	"cryptoArbitrageBot/rebalancer"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+/*
+Returns a deposit address for the asset on Coinbase Pro.
+*/
+func (c *CoinbaseProClient) DepositAddress(asset string) (string, error) {
+	var accounts []CoinbaseAccount
+	if err := doRequest(c.client, "GET", "/coinbase-accounts", "", &accounts); err != nil {
+		return "", err
+	}
+	for _, account := range accounts {
+		if account.Currency != asset {
+			continue
+		}
+		var address CryptoAddress
+		if err := doRequest(c.client, "POST", fmt.Sprintf("/coinbase-accounts/%s/addresses", account.Id), "", &address); err != nil {
+			return "", err
+		}
+		return address.Address, nil
+	}
+	return "", &CoinbaseProError{Msg: fmt.Sprintf("no Coinbase account for %s", asset)}
+}
+
+/*
+Withdraw the asset to the address of another exchange. Returns the id of the withdrawal.
+*/
+func (c *CoinbaseProClient) WithdrawTo(asset string, amount float64, destination rebalancer.Destination) (string, error) {
+	body, err := json.Marshal(cryptoWithdrawal{
+		Amount:        strconv.FormatFloat(amount, 'f', 8, 64),
+		Currency:      asset,
+		CryptoAddress: destination.Address,
+	})
+	if err != nil {
+		return "", &CoinbaseProError{Msg: err.Error()}
+	}
+
+	var withdrawal Withdrawal
+	if err := doRequest(c.client, "POST", "/withdrawals/crypto", string(body), &withdrawal); err != nil {
+		return "", err
+	}
+	return withdrawal.Id, nil
+}
+
+/*
+Returns the status of a withdrawal.
+*/
+func (c *CoinbaseProClient) TransferStatus(asset string, referenceId string) (rebalancer.TransferStatus, error) {
+	var transfer Transfer
+	if err := doRequest(c.client, "GET", fmt.Sprintf("/transfers/%s", referenceId), "", &transfer); err != nil {
+		return "", err
+	}
+	switch {
+	case transfer.CompletedAt != "":
+		return rebalancer.Completed, nil
+	case transfer.CanceledAt != "":
+		return rebalancer.Failed, nil
+	case transfer.ProcessedAt != "":
+		return rebalancer.Pending, nil
+	default:
+		return rebalancer.Submitted, nil
+	}
+}
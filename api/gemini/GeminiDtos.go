@@ -12,7 +12,74 @@ type PriceRecordGemini struct {
 }
 
 type errorGemini struct {
-	result  string `json:"result"`
-	reason  string `json:"reason"`
-	message string `json:"message"`
+	Result  string `json:"result"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+type OrderStatusGemini struct {
+	OrderId           string `json:"order_id"`
+	ClientOrderId     string `json:"client_order_id"`
+	Symbol            string `json:"symbol"`
+	Side              string `json:"side"`
+	Type              string `json:"type"`
+	Price             string `json:"price"`
+	AvgExecutionPrice string `json:"avg_execution_price"`
+	ExecutedAmount    string `json:"executed_amount"`
+	RemainingAmount   string `json:"remaining_amount"`
+	OriginalAmount    string `json:"original_amount"`
+	IsLive            bool   `json:"is_live"`
+	IsCancelled       bool   `json:"is_cancelled"`
+	Timestampms       int64  `json:"timestampms"`
+}
+
+type CancelAllResponseGemini struct {
+	Result  string `json:"result"`
+	Details struct {
+		CancelledOrders []int64 `json:"cancelledOrders"`
+		CancelRejects   []int64 `json:"cancelRejects"`
+	} `json:"details"`
+}
+
+type BalanceGemini struct {
+	Type                   string `json:"type"`
+	Currency               string `json:"currency"`
+	Amount                 string `json:"amount"`
+	Available              string `json:"available"`
+	AvailableForWithdrawal string `json:"availableForWithdrawal"`
+}
+
+type DepositAddressGemini struct {
+	Network string `json:"network"`
+	Address string `json:"address"`
+	Label   string `json:"label"`
+}
+
+type WithdrawalGemini struct {
+	Address      string `json:"address"`
+	Amount       string `json:"amount"`
+	WithdrawalId string `json:"withdrawalId"`
+	TxHash       string `json:"txHash"`
+	Message      string `json:"message"`
+}
+
+type TransferGemini struct {
+	Type         string `json:"type"`
+	Status       string `json:"status"`
+	Timestampms  int64  `json:"timestampms"`
+	Eid          int64  `json:"eid"`
+	WithdrawalId string `json:"withdrawalId"`
+	Currency     string `json:"currency"`
+	Amount       string `json:"amount"`
+	TxHash       string `json:"txHash"`
+}
+
+type BookEntryGemini struct {
+	Price  string `json:"price"`
+	Amount string `json:"amount"`
+}
+
+type BookGemini struct {
+	Bids []BookEntryGemini `json:"bids"`
+	Asks []BookEntryGemini `json:"asks"`
 }
@@ -62,14 +62,13 @@ func (c *GeminiClient) GetPrices() []bookkeeper.PriceRecord {
 				Exchange:               "Gemini",
 				ArbitrageRecordUuid:    uuid.Nil,
 				IsArbitrageOpportunity: false,
-				Timestamp:              time.Now().Format(time.RFC3339),
+				Timestamp:              time.Now().UTC(),
 			}
 			priceRecords = append(priceRecords, priceRecord)
 		}
 	}
 
 	utils.Logger.Debug("Retrieved Gemini prices...", zap.String("numberOfPriceRecords", strconv.Itoa(len(priceRecords))))
-	bookkeeper.RecordPriceRecord(priceRecords...)
 	return priceRecords
 }
 
@@ -160,8 +159,8 @@ func getPriceFeed(client *http.Client) ([]PriceRecordGemini, error) {
 		}
 	}
 
-	if errorGemini.message != "" {
-		return priceRecordGemini, errors.New(errorGemini.message)
+	if errorGemini.Message != "" {
+		return priceRecordGemini, errors.New(errorGemini.Message)
 	}
 	return priceRecordGemini, nil
 }
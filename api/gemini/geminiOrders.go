@@ -0,0 +1,363 @@
+package gemini
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"cryptoArbitrageBot/executor"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const exchangeName = "Gemini"
+
+/*
+Gemini only supports limit orders. Market orders are emulated with an immediate-or-cancel limit order priced this far
+through the current price.
+*/
+const marketOrderPriceBuffer = .01
+
+/*
+Number of decimals of the price tick size of each symbol on Gemini. Prices of other symbols are sent with as many
+decimals as they need.
+*/
+var priceDecimals = map[string]int{
+	"BTCUSD": 2,
+	"ETHUSD": 2,
+	"LTCUSD": 2,
+	"ETHBTC": 5,
+	"LTCBTC": 5,
+}
+
+/*
+Returns the exchange name of the Gemini venue.
+*/
+func (c *GeminiClient) Name() string {
+	return exchangeName
+}
+
+/*
+Place an immediate-or-cancel order on Gemini and report how much of it was filled.
+*/
+func (c *GeminiClient) PlaceOrder(order executor.OrderRequest) (executor.OrderResult, error) {
+	result := executor.OrderResult{
+		Exchange:      exchangeName,
+		Currency:      order.Currency,
+		Side:          order.Side,
+		Status:        executor.Rejected,
+		RequestedSize: order.Size,
+	}
+
+	price := order.Price
+	if order.Type == executor.Market {
+		marketPrice, err := c.currentPrice(order.Currency)
+		if err != nil {
+			return result, err
+		}
+		price = marketPrice * (1 + marketOrderPriceBuffer)
+		if order.Side == executor.Sell {
+			price = marketPrice * (1 - marketOrderPriceBuffer)
+		}
+	}
+
+	params := map[string]interface{}{
+		"symbol":  strings.ToLower(order.Currency),
+		"amount":  strconv.FormatFloat(order.Size, 'f', 8, 64),
+		"price":   formatPrice(order.Currency, price),
+		"side":    string(order.Side),
+		"type":    "exchange limit",
+		"options": []string{"immediate-or-cancel"},
+	}
+	if order.ClientOrderId != "" {
+		params["client_order_id"] = order.ClientOrderId
+	}
+	var orderStatus OrderStatusGemini
+	if err := doPrivateRequest(c.client, "/v1/order/new", params, &orderStatus); err != nil {
+		return result, err
+	}
+	return toOrderResult(orderStatus, result), nil
+}
+
+/*
+Cancel all open orders on Gemini and return how many were cancelled.
+*/
+func (c *GeminiClient) CancelAllOrders() (int, error) {
+	var cancelAllResponse CancelAllResponseGemini
+	if err := doPrivateRequest(c.client, "/v1/order/cancel/all", map[string]interface{}{}, &cancelAllResponse); err != nil {
+		return 0, err
+	}
+	if len(cancelAllResponse.Details.CancelRejects) > 0 {
+		return len(cancelAllResponse.Details.CancelledOrders), fmt.Errorf("Gemini rejected the cancellation of %d orders", len(cancelAllResponse.Details.CancelRejects))
+	}
+	return len(cancelAllResponse.Details.CancelledOrders), nil
+}
+
+/*
+Returns all open orders on Gemini.
+*/
+func (c *GeminiClient) ListOpenOrders() ([]executor.OrderResult, error) {
+	var orders []OrderStatusGemini
+	if err := doPrivateRequest(c.client, "/v1/orders", map[string]interface{}{}, &orders); err != nil {
+		return nil, err
+	}
+	var results []executor.OrderResult
+	for _, order := range orders {
+		results = append(results, toOrderResult(order, newOrderResult(order)))
+	}
+	return results, nil
+}
+
+/*
+Look up an order on Gemini by id.
+*/
+func (c *GeminiClient) GetOrder(orderId string, currency string) (executor.OrderResult, error) {
+	id, err := strconv.ParseInt(orderId, 10, 64)
+	if err != nil {
+		return executor.OrderResult{}, fmt.Errorf("invalid Gemini order id %q", orderId)
+	}
+	var order OrderStatusGemini
+	if err := doPrivateRequest(c.client, "/v1/order/status", map[string]interface{}{"order_id": id}, &order); err != nil {
+		if strings.HasPrefix(err.Error(), "OrderNotFound") {
+			return executor.OrderResult{}, executor.ErrOrderNotFound
+		}
+		return executor.OrderResult{}, err
+	}
+	return toOrderResult(order, newOrderResult(order)), nil
+}
+
+/*
+Look up an order on Gemini by its client order id. Gemini returns every order with the client order id, the most
+recent one is used.
+*/
+func (c *GeminiClient) FindOrder(clientOrderId string, currency string) (executor.OrderResult, error) {
+	var orders []OrderStatusGemini
+	if err := doPrivateRequest(c.client, "/v1/order/status", map[string]interface{}{"client_order_id": clientOrderId}, &orders); err != nil {
+		if strings.HasPrefix(err.Error(), "OrderNotFound") {
+			return executor.OrderResult{}, executor.ErrOrderNotFound
+		}
+		return executor.OrderResult{}, err
+	}
+	if len(orders) == 0 {
+		return executor.OrderResult{}, executor.ErrOrderNotFound
+	}
+	latest := orders[0]
+	for _, order := range orders {
+		if order.Timestampms > latest.Timestampms {
+			latest = order
+		}
+	}
+	return toOrderResult(latest, newOrderResult(latest)), nil
+}
+
+/*
+Returns the available balance of every currency in the Gemini exchange account.
+*/
+func (c *GeminiClient) GetBalances() (map[string]float64, error) {
+	var balancesGemini []BalanceGemini
+	if err := doPrivateRequest(c.client, "/v1/balances", map[string]interface{}{}, &balancesGemini); err != nil {
+		return nil, err
+	}
+	balances := make(map[string]float64)
+	for _, balance := range balancesGemini {
+		available, err := strconv.ParseFloat(balance.Available, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid available balance %q for %s", balance.Available, balance.Currency)
+		}
+		balances[strings.ToUpper(balance.Currency)] += available
+	}
+	return balances, nil
+}
+
+/*
+Returns the best bid and ask of a currency and the amount available at them.
+*/
+func (c *GeminiClient) TopOfBook(currency string) (executor.BookLevel, executor.BookLevel, error) {
+	u, err := url.ParseRequestURI(viper.Get("GEMINI.URL").(string))
+	if err != nil {
+		return executor.BookLevel{}, executor.BookLevel{}, err
+	}
+	u.Path = fmt.Sprintf("/v1/book/%s", strings.ToLower(currency))
+	u.RawQuery = "limit_bids=1&limit_asks=1"
+
+	resp, err := c.client.Get(u.String())
+	if err != nil {
+		return executor.BookLevel{}, executor.BookLevel{}, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return executor.BookLevel{}, executor.BookLevel{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return executor.BookLevel{}, executor.BookLevel{}, fmt.Errorf("unexpected status %d from Gemini: %s", resp.StatusCode, string(body))
+	}
+
+	var book BookGemini
+	if err := json.Unmarshal(body, &book); err != nil {
+		return executor.BookLevel{}, executor.BookLevel{}, err
+	}
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return executor.BookLevel{}, executor.BookLevel{}, fmt.Errorf("the %s order book on Gemini is empty", currency)
+	}
+	bid, err := toBookLevel(book.Bids[0])
+	if err != nil {
+		return executor.BookLevel{}, executor.BookLevel{}, err
+	}
+	ask, err := toBookLevel(book.Asks[0])
+	if err != nil {
+		return executor.BookLevel{}, executor.BookLevel{}, err
+	}
+	return bid, ask, nil
+}
+
+/*
+Convert an entry of a Gemini order book into an executor.BookLevel.
+*/
+func toBookLevel(entry BookEntryGemini) (executor.BookLevel, error) {
+	price, err := strconv.ParseFloat(entry.Price, 64)
+	if err != nil {
+		return executor.BookLevel{}, fmt.Errorf("invalid order book price %q", entry.Price)
+	}
+	size, err := strconv.ParseFloat(entry.Amount, 64)
+	if err != nil {
+		return executor.BookLevel{}, fmt.Errorf("invalid order book amount %q", entry.Amount)
+	}
+	return executor.BookLevel{Price: price, Size: size}, nil
+}
+
+/*
+Look up the current price of a currency in the Gemini price feed.
+*/
+func (c *GeminiClient) currentPrice(currency string) (float64, error) {
+	priceFeed, err := getPriceFeed(c.client)
+	if err != nil {
+		return 0, err
+	}
+	for _, aPrice := range priceFeed {
+		if aPrice.Pair == currency {
+			return strconv.ParseFloat(aPrice.Price, 64)
+		}
+	}
+	return 0, fmt.Errorf("currency %s is not in the Gemini price feed", currency)
+}
+
+/*
+Format a price with the number of decimals of the tick size of the currency.
+*/
+func formatPrice(currency string, price float64) string {
+	decimals, ok := priceDecimals[currency]
+	if !ok {
+		decimals = -1
+	}
+	return strconv.FormatFloat(price, 'f', decimals, 64)
+}
+
+/*
+Create an OrderResult for an order that was looked up on Gemini rather than placed.
+*/
+func newOrderResult(order OrderStatusGemini) executor.OrderResult {
+	requestedSize, _ := strconv.ParseFloat(order.OriginalAmount, 64)
+	return executor.OrderResult{
+		ClientOrderId: order.ClientOrderId,
+		Exchange:      exchangeName,
+		Currency:      strings.ToUpper(order.Symbol),
+		Side:          executor.Side(order.Side),
+		RequestedSize: requestedSize,
+	}
+}
+
+/*
+Fill in the OrderResult from a Gemini order status.
+*/
+func toOrderResult(orderStatus OrderStatusGemini, result executor.OrderResult) executor.OrderResult {
+	executedAmount, _ := strconv.ParseFloat(orderStatus.ExecutedAmount, 64)
+	averagePrice, _ := strconv.ParseFloat(orderStatus.AvgExecutionPrice, 64)
+
+	result.OrderId = orderStatus.OrderId
+	result.FilledSize = executedAmount
+	result.AveragePrice = averagePrice
+	result.Fee = executedAmount * averagePrice * takerFeeGemini
+	switch {
+	case executedAmount > 0 && executedAmount >= result.RequestedSize:
+		result.Status = executor.Filled
+	case orderStatus.IsLive:
+		result.Status = executor.Open
+	case executedAmount > 0:
+		result.Status = executor.PartiallyFilled
+	case orderStatus.IsCancelled:
+		result.Status = executor.Cancelled
+	default:
+		result.Status = executor.Rejected
+	}
+	return result
+}
+
+/*
+Send a signed request to a private Gemini endpoint and unmarshal the response into v. The request path and nonce are
+added to the params before they are signed.
+*/
+func doPrivateRequest(client *http.Client, path string, params map[string]interface{}, v interface{}) error {
+	key, ok := viper.Get("GEMINI.TEST.KEY").(string)
+	if !ok {
+		return errors.New("invalid type assertion for GEMINI.TEST.KEY")
+	}
+	secret, ok := viper.Get("GEMINI.TEST.SECRET").(string)
+	if !ok {
+		return errors.New("invalid type assertion for GEMINI.TEST.SECRET")
+	}
+
+	params["request"] = path
+	params["nonce"] = strconv.FormatInt(time.Now().UnixNano(), 10)
+	encodedPayload, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	signature := hmac.New(sha512.New384, []byte(secret))
+	signature.Write(encodedPayload)
+
+	u, err := url.ParseRequestURI(viper.Get("GEMINI.URL").(string))
+	if err != nil {
+		return err
+	}
+	u.Path = path
+
+	request, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Length", "0")
+	request.Header.Set("Content-Type", "text/plain")
+	request.Header.Set("X-GEMINI-APIKEY", key)
+	request.Header.Set("X-GEMINI-PAYLOAD", base64.StdEncoding.EncodeToString(encodedPayload))
+	request.Header.Set("X-GEMINI-SIGNATURE", hex.EncodeToString(signature.Sum(nil)))
+	request.Header.Set("Cache-Control", "no-cache")
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errorGemini := errorGemini{}
+		if err := json.Unmarshal(body, &errorGemini); err != nil || errorGemini.Message == "" {
+			return fmt.Errorf("unexpected status %d from Gemini: %s", resp.StatusCode, string(body))
+		}
+		return fmt.Errorf("%s: %s", errorGemini.Reason, errorGemini.Message)
+	}
+	return json.Unmarshal(body, v)
+}
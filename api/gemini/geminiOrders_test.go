@@ -0,0 +1,54 @@
+package gemini
+
+import (
+	"cryptoArbitrageBot/executor"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPlaceOrderPrice(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	var sentPrice string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodedPayload, _ := base64.StdEncoding.DecodeString(r.Header.Get("X-GEMINI-PAYLOAD"))
+		var params map[string]interface{}
+		json.Unmarshal(encodedPayload, &params)
+		sentPrice, _ = params["price"].(string)
+		w.Write([]byte(`{"order_id": "1", "executed_amount": "1", "avg_execution_price": "1"}`))
+	}))
+	defer server.Close()
+
+	geminiUrl := viper.Get("GEMINI.URL")
+	viper.Set("GEMINI.URL", server.URL)
+	defer viper.Set("GEMINI.URL", geminiUrl)
+
+	tests := []struct {
+		name     string
+		currency string
+		price    float64
+		want     string
+	}{
+		{"Sub-cent price", "ETHBTC", 0.05312, "0.05312"},
+		{"Rounded to tick size", "LTCBTC", 0.0023456, "0.00235"},
+		{"Dollar price", "BTCUSD", 26123.456, "26123.46"},
+		{"Unknown currency", "LTCETH", 0.0512345, "0.0512345"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := GeminiClient{client: server.Client()}
+			order := executor.OrderRequest{Currency: tt.currency, Side: executor.Buy, Type: executor.Limit, Size: 1, Price: tt.price}
+
+			_, err := client.PlaceOrder(order)
+
+			assert.Equal(t, err, nil)
+			assert.Equal(t, sentPrice, tt.want)
+		})
+	}
+}
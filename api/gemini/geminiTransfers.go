@@ -0,0 +1,73 @@
+package gemini
+
+import (
+	"cryptoArbitrageBot/rebalancer"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+Maps assets to the network names Gemini uses for deposit addresses.
+*/
+var geminiNetworks = map[string]string{
+	"BTC": "bitcoin",
+	"ETH": "ethereum",
+	"LTC": "litecoin",
+}
+
+/*
+Returns a new deposit address for the asset on Gemini.
+*/
+func (c *GeminiClient) DepositAddress(asset string) (string, error) {
+	network, ok := geminiNetworks[asset]
+	if !ok {
+		return "", fmt.Errorf("asset %s can not be deposited to Gemini", asset)
+	}
+	var depositAddress DepositAddressGemini
+	if err := doPrivateRequest(c.client, fmt.Sprintf("/v1/deposit/%s/newAddress", network), map[string]interface{}{}, &depositAddress); err != nil {
+		return "", err
+	}
+	return depositAddress.Address, nil
+}
+
+/*
+Withdraw the asset to the address of another exchange. Returns the id of the withdrawal.
+*/
+func (c *GeminiClient) WithdrawTo(asset string, amount float64, destination rebalancer.Destination) (string, error) {
+	params := map[string]interface{}{
+		"address": destination.Address,
+		"amount":  strconv.FormatFloat(amount, 'f', 8, 64),
+	}
+	var withdrawal WithdrawalGemini
+	if err := doPrivateRequest(c.client, fmt.Sprintf("/v1/withdraw/%s", strings.ToLower(asset)), params, &withdrawal); err != nil {
+		return "", err
+	}
+	if withdrawal.WithdrawalId != "" {
+		return withdrawal.WithdrawalId, nil
+	}
+	if withdrawal.TxHash != "" {
+		return withdrawal.TxHash, nil
+	}
+	return "", fmt.Errorf("Gemini returned no withdrawal id: %s", withdrawal.Message)
+}
+
+/*
+Returns the status of a withdrawal.
+*/
+func (c *GeminiClient) TransferStatus(asset string, referenceId string) (rebalancer.TransferStatus, error) {
+	var transfers []TransferGemini
+	if err := doPrivateRequest(c.client, "/v1/transfers", map[string]interface{}{"currency": asset}, &transfers); err != nil {
+		return "", err
+	}
+	for _, transfer := range transfers {
+		if transfer.Type != "Withdrawal" || (transfer.WithdrawalId != referenceId && transfer.TxHash != referenceId) {
+			continue
+		}
+		if transfer.Status == "Complete" {
+			return rebalancer.Completed, nil
+		}
+		return rebalancer.Pending, nil
+	}
+	return rebalancer.Submitted, nil
+}
@@ -519,6 +519,26 @@ type WithdrawInfoResponse struct {
 	Fee    big.Float `json:"fee"`
 }
 
+// FundingStatus represents the status of a deposit or withdrawal
+type FundingStatus struct {
+	Method string `json:"method"`
+	Aclass string `json:"aclass"`
+	Asset  string `json:"asset"`
+	RefID  string `json:"refid"`
+	TxID   string `json:"txid"`
+	Info   string `json:"info"`
+	Amount string `json:"amount"`
+	Fee    string `json:"fee"`
+	Time   int64  `json:"time"`
+	// Status is one of Initial, Pending, Settled, Success or Failure
+	Status string `json:"status"`
+	// StatusProp holds additional properties like cancel-pending, canceled, onhold or return
+	StatusProp string `json:"status-prop"`
+}
+
+// WithdrawStatusResponse is the response type of a WithdrawStatus query to the Kraken API.
+type WithdrawStatusResponse []FundingStatus
+
 // GetPairTickerInfo is a helper method that returns given `pair`'s `PairTickerInfo`
 func (v *TickerResponse) GetPairTickerInfo(pair string) PairTickerInfo {
 	r := reflect.ValueOf(v)
@@ -641,6 +661,7 @@ type Order struct {
 	TransactionID  string           `json:"-"`
 	ReferenceID    string           `json:"refid"`
 	UserRef        int              `json:"userref"`
+	ClientOrderID  string           `json:"cl_ord_id"`
 	Status         string           `json:"status"`
 	OpenTime       float64          `json:"opentm"`
 	StartTime      float64          `json:"starttm"`
@@ -724,6 +745,11 @@ type CancelOrderResponse struct {
 	Pending bool `json:"pending"`
 }
 
+// CancelAllResponse response when cancelling all open orders
+type CancelAllResponse struct {
+	Count int `json:"count"`
+}
+
 // QueryOrdersResponse response when checking all orders
 type QueryOrdersResponse map[string]Order
 
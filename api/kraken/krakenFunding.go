@@ -0,0 +1,91 @@
+package kraken
+
+import (
+	"cryptoArbitrageBot/rebalancer"
+	"fmt"
+	"github.com/spf13/viper"
+	"strconv"
+)
+
+// krakenAssets maps asset names to the names Kraken uses for funding
+var krakenAssets = map[string]string{
+	"BTC": "XBT",
+	"ETH": "ETH",
+	"LTC": "LTC",
+}
+
+// DepositAddress returns a deposit address for the asset. The deposit method (e.g. "Bitcoin") is configured in
+// REBALANCER.KRAKEN.DEPOSIT_METHODS.
+func (api *KrakenAPI) DepositAddress(asset string) (string, error) {
+	krakenAsset, ok := krakenAssets[asset]
+	if !ok {
+		return "", fmt.Errorf("asset %s can not be deposited to Kraken", asset)
+	}
+	addresses, err := api.DepositAddresses(krakenAsset, viper.GetString("REBALANCER.KRAKEN.DEPOSIT_METHODS."+asset), false)
+	if err != nil {
+		return "", err
+	}
+	if len(*addresses) == 0 {
+		// No address was generated for this method yet
+		addresses, err = api.DepositAddresses(krakenAsset, viper.GetString("REBALANCER.KRAKEN.DEPOSIT_METHODS."+asset), true)
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(*addresses) == 0 || (*addresses)[0].Address == "" {
+		return "", fmt.Errorf("Kraken returned no %s deposit address", asset)
+	}
+	return (*addresses)[0].Address, nil
+}
+
+// WithdrawTo withdraws the asset to another exchange. Kraken only withdraws to addresses that were added to the
+// account beforehand, so the destination address is ignored and the withdrawal key configured in
+// REBALANCER.KRAKEN.WITHDRAW_KEYS.<asset>.<exchange> is used instead.
+func (api *KrakenAPI) WithdrawTo(asset string, amount float64, destination rebalancer.Destination) (string, error) {
+	krakenAsset, ok := krakenAssets[asset]
+	if !ok {
+		return "", fmt.Errorf("asset %s can not be withdrawn from Kraken", asset)
+	}
+	key := viper.GetString("REBALANCER.KRAKEN.WITHDRAW_KEYS." + asset + "." + destination.Exchange)
+	if key == "" {
+		return "", fmt.Errorf("no Kraken withdrawal key configured for %s to %s", asset, destination.Exchange)
+	}
+
+	withdrawal, err := api.Withdraw(krakenAsset, key, strconv.FormatFloat(amount, 'f', 8, 64))
+	if err != nil {
+		return "", err
+	}
+	if withdrawal.RefID == "" {
+		return "", fmt.Errorf("Kraken returned no reference id for the withdrawal")
+	}
+	return withdrawal.RefID, nil
+}
+
+// TransferStatus returns the status of a withdrawal
+func (api *KrakenAPI) TransferStatus(asset string, referenceId string) (rebalancer.TransferStatus, error) {
+	withdrawal, err := api.FindWithdrawal(krakenAssets[asset], referenceId)
+	if err != nil {
+		return "", err
+	}
+	if withdrawal == nil {
+		return rebalancer.Submitted, nil
+	}
+	return transferStatus(*withdrawal), nil
+}
+
+// transferStatus converts a Kraken funding status into a rebalancer.TransferStatus
+func transferStatus(status FundingStatus) rebalancer.TransferStatus {
+	if status.StatusProp == "canceled" || status.StatusProp == "return" {
+		return rebalancer.Failed
+	}
+	switch status.Status {
+	case "Success":
+		return rebalancer.Completed
+	case "Failure":
+		return rebalancer.Failed
+	case "Initial":
+		return rebalancer.Submitted
+	default:
+		return rebalancer.Pending
+	}
+}
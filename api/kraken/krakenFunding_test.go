@@ -0,0 +1,200 @@
+package kraken
+
+import (
+	"cryptoArbitrageBot/internal/utils"
+	"cryptoArbitrageBot/rebalancer"
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupTest(tb testing.TB) func(tb testing.TB) {
+	utils.InitializeLogger()
+
+	return func(tb testing.TB) {
+		APIURL = "https://api.kraken.com"
+	}
+}
+
+// fundingServerForTest starts a Kraken API that answers each private method with its responses in turn and points the
+// client to it. Requests are counted per method.
+func fundingServerForTest(tb testing.TB, responses map[string][]string, requests map[string]int) *KrakenAPI {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.URL.Path[len("/0/private/"):]
+		body := `{"error": ["EGeneral:Unknown method"]}`
+		if bodies := responses[method]; len(bodies) > 0 {
+			body = bodies[requests[method]%len(bodies)]
+		}
+		requests[method]++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	tb.Cleanup(server.Close)
+	APIURL = server.URL
+	return NewWithClient("key", "c2VjcmV0", server.Client())
+}
+
+func Test_TransferStatus(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name       string
+		response   string
+		wantStatus rebalancer.TransferStatus
+		wantErr    bool
+	}{
+		{
+			name:       "Withdrawal is not listed yet.",
+			response:   `{"error": [], "result": []}`,
+			wantStatus: rebalancer.Submitted,
+		},
+		{
+			name:       "Initial withdrawal is submitted.",
+			response:   `{"error": [], "result": [{"refid": "ref-1", "asset": "XXBT", "amount": "0.5", "time": 1688000000, "status": "Initial"}]}`,
+			wantStatus: rebalancer.Submitted,
+		},
+		{
+			name:       "Settled withdrawal is pending.",
+			response:   `{"error": [], "result": [{"refid": "ref-1", "txid": "tx-1", "status": "Settled"}]}`,
+			wantStatus: rebalancer.Pending,
+		},
+		{
+			name:       "Successful withdrawal is completed.",
+			response:   `{"error": [], "result": [{"refid": "ref-2", "status": "Failure"}, {"refid": "ref-1", "status": "Success"}]}`,
+			wantStatus: rebalancer.Completed,
+		},
+		{
+			name:       "Failed withdrawal failed.",
+			response:   `{"error": [], "result": [{"refid": "ref-1", "status": "Failure"}]}`,
+			wantStatus: rebalancer.Failed,
+		},
+		{
+			name:       "Cancelled withdrawal failed.",
+			response:   `{"error": [], "result": [{"refid": "ref-1", "status": "Pending", "status-prop": "canceled"}]}`,
+			wantStatus: rebalancer.Failed,
+		},
+		{
+			name:       "Returned withdrawal failed.",
+			response:   `{"error": [], "result": [{"refid": "ref-1", "status": "Success", "status-prop": "return"}]}`,
+			wantStatus: rebalancer.Failed,
+		},
+		{
+			name:     "Kraken error is returned.",
+			response: `{"error": ["EFunding:Unknown reference id"]}`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := make(map[string]int)
+			api := fundingServerForTest(t, map[string][]string{"WithdrawStatus": {tt.response}}, requests)
+
+			status, err := api.TransferStatus("BTC", "ref-1")
+			assert.Equal(t, err != nil, tt.wantErr)
+			assert.Equal(t, status, tt.wantStatus)
+			assert.Equal(t, requests["WithdrawStatus"], 1)
+		})
+	}
+}
+
+func Test_WithdrawTo(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+	viper.Set("REBALANCER.KRAKEN.WITHDRAW_KEYS.BTC.Gemini", "gemini-btc")
+
+	tests := []struct {
+		name            string
+		asset           string
+		destination     string
+		response        string
+		wantReferenceId string
+		wantErr         bool
+		wantRequests    int
+	}{
+		{
+			name:            "Reference id of the withdrawal is returned.",
+			asset:           "BTC",
+			destination:     "Gemini",
+			response:        `{"error": [], "result": {"refid": "ref-1"}}`,
+			wantReferenceId: "ref-1",
+			wantRequests:    1,
+		},
+		{
+			name:         "Withdrawal without a reference id fails.",
+			asset:        "BTC",
+			destination:  "Gemini",
+			response:     `{"error": [], "result": {}}`,
+			wantErr:      true,
+			wantRequests: 1,
+		},
+		{
+			name:        "Withdrawal without a configured key is not sent.",
+			asset:       "BTC",
+			destination: "Coinbase",
+			wantErr:     true,
+		},
+		{
+			name:        "Unknown asset is not sent.",
+			asset:       "DOGE",
+			destination: "Gemini",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := make(map[string]int)
+			api := fundingServerForTest(t, map[string][]string{"Withdraw": {tt.response}}, requests)
+
+			referenceId, err := api.WithdrawTo(tt.asset, .5, rebalancer.Destination{Exchange: tt.destination})
+			assert.Equal(t, err != nil, tt.wantErr)
+			assert.Equal(t, referenceId, tt.wantReferenceId)
+			assert.Equal(t, requests["Withdraw"], tt.wantRequests)
+		})
+	}
+}
+
+func Test_DepositAddress(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name         string
+		responses    []string
+		wantAddress  string
+		wantErr      bool
+		wantRequests int
+	}{
+		{
+			name:         "Existing address is returned.",
+			responses:    []string{`{"error": [], "result": [{"address": "bc1-existing", "expiretm": "0"}]}`},
+			wantAddress:  "bc1-existing",
+			wantRequests: 1,
+		},
+		{
+			name:         "New address is generated if there is none.",
+			responses:    []string{`{"error": [], "result": []}`, `{"error": [], "result": [{"address": "bc1-new", "expiretm": "0", "new": true}]}`},
+			wantAddress:  "bc1-new",
+			wantRequests: 2,
+		},
+		{
+			name:         "No address is generated.",
+			responses:    []string{`{"error": [], "result": []}`},
+			wantErr:      true,
+			wantRequests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := make(map[string]int)
+			api := fundingServerForTest(t, map[string][]string{"DepositAddresses": tt.responses}, requests)
+
+			address, err := api.DepositAddress("BTC")
+			assert.Equal(t, err != nil, tt.wantErr)
+			assert.Equal(t, address, tt.wantAddress)
+			assert.Equal(t, requests["DepositAddresses"], tt.wantRequests)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package kraken
+
+import (
+	"cryptoArbitrageBot/executor"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// exchangeName is the name used for Kraken in price records and orders
+const exchangeName = "Kraken"
+
+// krakenPairs maps the currency notation used in price records to Kraken's asset pairs
+var krakenPairs = map[string]string{
+	"BTCUSD": "XBTUSD",
+	"ETHUSD": "ETHUSD",
+	"LTCUSD": "LTCUSD",
+	"ETHBTC": "ETHXBT",
+	"LTCBTC": "LTCXBT",
+}
+
+// Name returns the exchange name of the Kraken venue
+func (api *KrakenAPI) Name() string {
+	return exchangeName
+}
+
+// PlaceOrder places an order on Kraken and looks up how much of it was filled.
+// Limit orders are placed as immediate-or-cancel so that they never rest on the book. The client order id is sent as
+// cl_ord_id, Kraken rejects a second open order with the same id.
+func (api *KrakenAPI) PlaceOrder(order executor.OrderRequest) (executor.OrderResult, error) {
+	result := executor.OrderResult{
+		Exchange:      exchangeName,
+		Currency:      order.Currency,
+		Side:          order.Side,
+		Status:        executor.Rejected,
+		RequestedSize: order.Size,
+	}
+
+	pair, ok := krakenPairs[order.Currency]
+	if !ok {
+		return result, fmt.Errorf("currency %s is not traded on Kraken", order.Currency)
+	}
+
+	args := map[string]string{}
+	if order.ClientOrderId != "" {
+		args["cl_ord_id"] = order.ClientOrderId
+	}
+	orderType := OTMarket
+	if order.Type == executor.Limit {
+		orderType = OTLimit
+		args["price"] = strconv.FormatFloat(order.Price, 'f', -1, 64)
+		args["timeinforce"] = "IOC"
+	}
+
+	addOrderResponse, err := api.AddOrder(pair, string(order.Side), orderType, strconv.FormatFloat(order.Size, 'f', 8, 64), args)
+	if err != nil {
+		return result, err
+	}
+	if len(addOrderResponse.TransactionIds) == 0 {
+		return result, fmt.Errorf("Kraken did not return a transaction id for the order")
+	}
+	result.OrderId = addOrderResponse.TransactionIds[0]
+
+	orders, err := api.QueryOrders(result.OrderId)
+	if err != nil {
+		return result, err
+	}
+	krakenOrder, ok := (*orders)[result.OrderId]
+	if !ok {
+		return result, fmt.Errorf("order %s not found on Kraken", result.OrderId)
+	}
+	result.FilledSize = krakenOrder.VolumeExecuted
+	result.AveragePrice = krakenOrder.Price
+	result.Fee = krakenOrder.Fee
+	result.Status = orderStatus(krakenOrder.Status, krakenOrder.VolumeExecuted, order.Size)
+
+	return result, nil
+}
+
+// CancelAllOrders cancels all open orders on Kraken and returns how many were cancelled
+func (api *KrakenAPI) CancelAllOrders() (int, error) {
+	resp, err := api.CancelAll()
+	if err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// ListOpenOrders returns all open orders on Kraken
+func (api *KrakenAPI) ListOpenOrders() ([]executor.OrderResult, error) {
+	resp, err := api.OpenOrders(nil)
+	if err != nil {
+		return nil, err
+	}
+	var results []executor.OrderResult
+	for txid, krakenOrder := range resp.Open {
+		results = append(results, toOrderResult(txid, krakenOrder))
+	}
+	return results, nil
+}
+
+// GetOrder looks up an order on Kraken by transaction id
+func (api *KrakenAPI) GetOrder(orderId string, currency string) (executor.OrderResult, error) {
+	orders, err := api.QueryOrders(orderId)
+	if err != nil {
+		if strings.Contains(err.Error(), "EOrder:Invalid order") {
+			return executor.OrderResult{}, executor.ErrOrderNotFound
+		}
+		return executor.OrderResult{}, err
+	}
+	krakenOrder, ok := (*orders)[orderId]
+	if !ok {
+		return executor.OrderResult{}, executor.ErrOrderNotFound
+	}
+	return toOrderResult(orderId, krakenOrder), nil
+}
+
+// FindOrder looks up an order on Kraken by its client order id. Open orders are searched first, then the most recent
+// closed orders.
+func (api *KrakenAPI) FindOrder(clientOrderId string, currency string) (executor.OrderResult, error) {
+	args := map[string]string{"cl_ord_id": clientOrderId}
+	openOrders, err := api.OpenOrders(args)
+	if err != nil {
+		return executor.OrderResult{}, err
+	}
+	for txid, krakenOrder := range openOrders.Open {
+		return toOrderResult(txid, krakenOrder), nil
+	}
+
+	closedOrders, err := api.ClosedOrders(args)
+	if err != nil {
+		return executor.OrderResult{}, err
+	}
+	for txid, krakenOrder := range closedOrders.Closed {
+		return toOrderResult(txid, krakenOrder), nil
+	}
+	return executor.OrderResult{}, executor.ErrOrderNotFound
+}
+
+// GetBalances returns the balance of every asset the bot trades on Kraken
+func (api *KrakenAPI) GetBalances() (map[string]float64, error) {
+	resp, err := api.Balance()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]float64{
+		"BTC": resp.XXBT,
+		"ETH": resp.XETH,
+		"LTC": resp.XLTC,
+		"USD": resp.ZUSD,
+	}, nil
+}
+
+// toOrderResult converts a Kraken order into an executor.OrderResult
+func toOrderResult(txid string, krakenOrder Order) executor.OrderResult {
+	requestedSize, _ := strconv.ParseFloat(krakenOrder.Volume, 64)
+	currency := krakenOrder.Description.AssetPair
+	for aCurrency, pair := range krakenPairs {
+		if pair == krakenOrder.Description.AssetPair {
+			currency = aCurrency
+		}
+	}
+	return executor.OrderResult{
+		OrderId:       txid,
+		ClientOrderId: krakenOrder.ClientOrderID,
+		Exchange:      exchangeName,
+		Currency:      currency,
+		Side:          executor.Side(krakenOrder.Description.Type),
+		Status:        orderStatus(krakenOrder.Status, krakenOrder.VolumeExecuted, requestedSize),
+		RequestedSize: requestedSize,
+		FilledSize:    krakenOrder.VolumeExecuted,
+		AveragePrice:  krakenOrder.Price,
+		Fee:           krakenOrder.Fee,
+	}
+}
+
+// orderStatus converts a Kraken order status into an executor.OrderStatus
+func orderStatus(status string, filledSize float64, requestedSize float64) executor.OrderStatus {
+	switch {
+	case filledSize >= requestedSize:
+		return executor.Filled
+	case status == "pending" || status == "open":
+		return executor.Open
+	case filledSize > 0:
+		return executor.PartiallyFilled
+	case status == "canceled" || status == "expired":
+		return executor.Cancelled
+	default:
+		return executor.Rejected
+	}
+}
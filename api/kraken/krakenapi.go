@@ -37,6 +37,7 @@ func init() {
 var publicMethods = []string{
 	"Assets",
 	"AssetPairs",
+	"OHLC",
 	"Time",
 	"Trades",
 }
@@ -46,6 +47,7 @@ var privateMethods = []string{
 	"AddExport",
 	"AddOrder",
 	"Balance",
+	"CancelAll",
 	"CancelOrder",
 	"ClosedOrders",
 	"DepositAddresses",
@@ -177,7 +179,7 @@ func (api *KrakenAPI) GetPrices() []bookkeeper.PriceRecord {
 			Exchange:               "Kraken",
 			ArbitrageRecordUuid:    uuid.Nil,
 			IsArbitrageOpportunity: false,
-			Timestamp:              time.Now().Format(time.RFC3339),
+			Timestamp:              time.Now().UTC(),
 		},
 		{
 			Uuid:                   uuid.New(),
@@ -187,7 +189,7 @@ func (api *KrakenAPI) GetPrices() []bookkeeper.PriceRecord {
 			Exchange:               "Kraken",
 			ArbitrageRecordUuid:    uuid.Nil,
 			IsArbitrageOpportunity: false,
-			Timestamp:              time.Now().Format(time.RFC3339),
+			Timestamp:              time.Now().UTC(),
 		},
 		{
 			Uuid:                   uuid.New(),
@@ -197,10 +199,9 @@ func (api *KrakenAPI) GetPrices() []bookkeeper.PriceRecord {
 			Exchange:               "Kraken",
 			ArbitrageRecordUuid:    uuid.Nil,
 			IsArbitrageOpportunity: false,
-			Timestamp:              time.Now().Format(time.RFC3339),
+			Timestamp:              time.Now().UTC(),
 		},
 	}
-	bookkeeper.RecordPriceRecord(priceRecords...)
 	return priceRecords
 }
 
@@ -227,7 +228,15 @@ func (api *KrakenAPI) Trades(pair string, since int64) (*TradesResponse, error)
 		Trades: make([]TradeInfo, 0),
 	}
 
-	trades := v[pair].([]interface{})
+	trades, ok := v[pair].([]interface{})
+	if !ok {
+		// Kraken answers with its own name of the pair (e.g. XXBTZUSD for XBTUSD)
+		for key, value := range v {
+			if key != "last" {
+				trades, _ = value.([]interface{})
+			}
+		}
+	}
 	for _, v := range trades {
 		trade := v.([]interface{})
 
@@ -256,6 +265,212 @@ func (api *KrakenAPI) Trades(pair string, since int64) (*TradesResponse, error)
 	return result, nil
 }
 
+// OHLC returns the candles of a pair with the given interval in minutes, starting after since (unix seconds). Kraken
+// returns at most the 720 most recent candles, older ones can not be requested. Last is the since to request the
+// following candles with.
+func (api *KrakenAPI) OHLC(pair string, interval int, since int64) (*OHLCResponse, error) {
+	values := url.Values{"pair": {pair}, "interval": {strconv.Itoa(interval)}}
+	if since > 0 {
+		values.Set("since", strconv.FormatInt(since, 10))
+	}
+	resp, err := api.queryPublic("OHLC", values, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := resp.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected OHLC response for %s", pair)
+	}
+	last, _ := v["last"].(float64)
+	result := &OHLCResponse{
+		Pair: pair,
+		OHLC: make([]*OHLC, 0),
+		Last: last,
+	}
+
+	for key, value := range v {
+		if key == "last" {
+			continue
+		}
+		candles, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected OHLC data for %s", key)
+		}
+		for _, candle := range candles {
+			ohlc, err := NewOHLC(candle.([]interface{}))
+			if err != nil {
+				return nil, err
+			}
+			result.OHLC = append(result.OHLC, ohlc)
+		}
+	}
+
+	return result, nil
+}
+
+// Balance returns all account asset balances
+func (api *KrakenAPI) Balance() (*BalanceResponse, error) {
+	resp, err := api.queryPrivate("Balance", url.Values{}, &BalanceResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*BalanceResponse), nil
+}
+
+// TradeBalance returns trade balance info. Optional arguments (e.g. asset) are passed in args.
+func (api *KrakenAPI) TradeBalance(args map[string]string) (*TradeBalanceResponse, error) {
+	params := url.Values{}
+	for key, value := range args {
+		params.Set(key, value)
+	}
+
+	resp, err := api.queryPrivate("TradeBalance", params, &TradeBalanceResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*TradeBalanceResponse), nil
+}
+
+// AddOrder adds a new order. Optional arguments (e.g. price, timeinforce, userref) are passed in args.
+func (api *KrakenAPI) AddOrder(pair string, direction string, orderType string, volume string, args map[string]string) (*AddOrderResponse, error) {
+	params := url.Values{
+		"pair":      {pair},
+		"type":      {direction},
+		"ordertype": {orderType},
+		"volume":    {volume},
+	}
+	for key, value := range args {
+		params.Set(key, value)
+	}
+
+	resp, err := api.queryPrivate("AddOrder", params, &AddOrderResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*AddOrderResponse), nil
+}
+
+// CancelOrder cancels an open order by transaction id
+func (api *KrakenAPI) CancelOrder(txid string) (*CancelOrderResponse, error) {
+	params := url.Values{"txid": {txid}}
+	resp, err := api.queryPrivate("CancelOrder", params, &CancelOrderResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*CancelOrderResponse), nil
+}
+
+// CancelAll cancels all open orders
+func (api *KrakenAPI) CancelAll() (*CancelAllResponse, error) {
+	resp, err := api.queryPrivate("CancelAll", url.Values{}, &CancelAllResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*CancelAllResponse), nil
+}
+
+// OpenOrders returns all open orders. Optional arguments (e.g. trades, userref) are passed in args.
+func (api *KrakenAPI) OpenOrders(args map[string]string) (*OpenOrdersResponse, error) {
+	params := url.Values{}
+	for key, value := range args {
+		params.Set(key, value)
+	}
+
+	resp, err := api.queryPrivate("OpenOrders", params, &OpenOrdersResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*OpenOrdersResponse), nil
+}
+
+// ClosedOrders returns the most recent closed orders. Optional arguments (e.g. start, end, ofs) are passed in args.
+func (api *KrakenAPI) ClosedOrders(args map[string]string) (*ClosedOrdersResponse, error) {
+	params := url.Values{}
+	for key, value := range args {
+		params.Set(key, value)
+	}
+
+	resp, err := api.queryPrivate("ClosedOrders", params, &ClosedOrdersResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*ClosedOrdersResponse), nil
+}
+
+// QueryOrders returns the information of the given orders
+func (api *KrakenAPI) QueryOrders(txids ...string) (*QueryOrdersResponse, error) {
+	params := url.Values{"txid": {strings.Join(txids, ",")}}
+	resp, err := api.queryPrivate("QueryOrders", params, &QueryOrdersResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*QueryOrdersResponse), nil
+}
+
+// DepositAddresses returns the deposit addresses of the asset for a deposit method. A new address is generated if
+// newAddress is true.
+func (api *KrakenAPI) DepositAddresses(asset string, method string, newAddress bool) (*DepositAddressesResponse, error) {
+	params := url.Values{"asset": {asset}, "method": {method}}
+	if newAddress {
+		params.Set("new", "true")
+	}
+	resp, err := api.queryPrivate("DepositAddresses", params, &DepositAddressesResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*DepositAddressesResponse), nil
+}
+
+// Withdraw withdraws amount of the asset to the withdrawal key (the name of an address added to the account)
+func (api *KrakenAPI) Withdraw(asset string, key string, amount string) (*WithdrawResponse, error) {
+	params := url.Values{"asset": {asset}, "key": {key}, "amount": {amount}}
+	resp, err := api.queryPrivate("Withdraw", params, &WithdrawResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*WithdrawResponse), nil
+}
+
+// WithdrawStatus returns the status of recent withdrawals of the asset. The withdrawal method is optional.
+func (api *KrakenAPI) WithdrawStatus(asset string, method string) (*WithdrawStatusResponse, error) {
+	params := url.Values{"asset": {asset}}
+	if method != "" {
+		params.Set("method", method)
+	}
+	resp, err := api.queryPrivate("WithdrawStatus", params, &WithdrawStatusResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*WithdrawStatusResponse), nil
+}
+
+// FindWithdrawal returns the status of the withdrawal with the reference id, or nil if it is not listed yet
+func (api *KrakenAPI) FindWithdrawal(asset string, refID string) (*FundingStatus, error) {
+	withdrawals, err := api.WithdrawStatus(asset, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, withdrawal := range *withdrawals {
+		if withdrawal.RefID == refID {
+			return &withdrawal, nil
+		}
+	}
+
+	return nil, nil
+}
+
 // Query sends a query to Kraken api for given method and parameters
 func (api *KrakenAPI) Query(method string, data map[string]string) (interface{}, error) {
 	values := url.Values{}
@@ -307,12 +522,13 @@ func (api *KrakenAPI) doRequest(reqURL string, values url.Values, headers map[st
 
 	var req *http.Request
 	var err error
-	if values.Get("pair") != "" {
+	if len(values) > 0 {
 		// Create request
 		req, err = http.NewRequest("POST", reqURL, strings.NewReader(values.Encode()))
 		if err != nil {
 			return nil, fmt.Errorf("Could not execute request! #1 (%s)", err.Error())
 		}
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	} else {
 		// Create request
 		req, err = http.NewRequest("POST", reqURL, nil)
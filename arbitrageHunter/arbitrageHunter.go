@@ -5,13 +5,18 @@ import (
 	"cryptoArbitrageBot/api/gemini"
 	"cryptoArbitrageBot/api/kraken"
 	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/executor"
 	"cryptoArbitrageBot/internal"
 	"cryptoArbitrageBot/internal/utils"
+	"cryptoArbitrageBot/inventory"
+	"cryptoArbitrageBot/rebalancer"
+	"cryptoArbitrageBot/retention"
 	"fmt"
 	_ "github.com/doug-martin/goqu/v9/dialect/mysql"
 	"github.com/go-co-op/gocron"
 	"github.com/google/uuid"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 	"math"
 	"sort"
 	"strconv"
@@ -42,17 +47,117 @@ func Start() *ArbitrageHunterError {
 	geminiClient := gemini.NewClient()
 	krakenCLient := kraken.NewWithClient(viper.Get("KRAKEN.TEST.KEY").(string), viper.Get("KRAKEN.TEST.KEY").(string), internal.GetClient())
 
+	var tradeExecutor *executor.Executor
+	var orderStore *executor.OrderStore
+	var ledger *bookkeeper.Ledger
+	if viper.GetBool("EXECUTION.ENABLED") {
+		var err error
+		ledger, err = bookkeeper.LoadLedger(viper.GetString("LEDGER.BASE_CURRENCY"))
+		if err != nil {
+			utils.Logger.Fatal("Unable to load the ledger.", zap.Error(err))
+		}
+		orderStore, err = executor.LoadOrderStore()
+		if err != nil {
+			utils.Logger.Fatal("Unable to load open orders.", zap.Error(err))
+		}
+		orderStore.WithLedger(ledger)
+		tradeExecutor = executor.NewExecutor(executor.LoadLegRiskPolicy(), &coinbaseProClient, &geminiClient, krakenCLient).WithRiskManager(executor.NewRiskManager(executor.LoadRiskLimits())).WithOrderStore(orderStore).WithTriangularPolicy(executor.LoadTriangularPolicy()).WithRetries(viper.GetInt("EXECUTION.SUBMIT_ATTEMPTS"), time.Duration(viper.GetInt("EXECUTION.RETRY_DELAY_MILLISECONDS"))*time.Millisecond)
+		listenForKillSwitch(tradeExecutor)
+		utils.Logger.Info("Order execution is enabled.")
+	}
+
+	fetchQuotes := func(cycleUuid uuid.UUID) []bookkeeper.PriceRecord {
+		coinbasePriceRecords, _ := coinbaseProClient.GetPrices()
+		quotes := flatten([][]bookkeeper.PriceRecord{coinbasePriceRecords, geminiClient.GetPrices(), krakenCLient.GetPrices()})
+		recordQuotes(cycleUuid, quotes)
+		return quotes
+	}
+
 	scheduler := gocron.NewScheduler(time.UTC)
+
+	if tradeExecutor != nil {
+		reconciler := executor.NewReconciler(orderStore, &coinbaseProClient, &geminiClient, krakenCLient)
+		_, err := scheduler.Every(viper.GetInt("EXECUTION.RECONCILE_INTERVAL_SECONDS")).Seconds().Do(reconciler.Reconcile)
+		if err != nil {
+			utils.Logger.Error(fmt.Sprintf("Error scheduling the order reconciliation: %v", err))
+		}
+		_, err = scheduler.Every(viper.GetInt("LEDGER.SUMMARY_INTERVAL_SECONDS")).Seconds().Do(ledger.RecordSummaries)
+		if err != nil {
+			utils.Logger.Error(fmt.Sprintf("Error scheduling the PnL summaries: %v", err))
+		}
+	}
+
+	var balances *inventory.Inventory
+	if viper.GetBool("INVENTORY.ENABLED") {
+		balances = inventory.NewInventory(&coinbaseProClient, &geminiClient, krakenCLient)
+		_, err := scheduler.Every(viper.GetInt("INVENTORY.REFRESH_INTERVAL_SECONDS")).Seconds().Do(balances.Refresh)
+		if err != nil {
+			utils.Logger.Error(fmt.Sprintf("Error scheduling the inventory refresh: %v", err))
+		}
+		utils.Logger.Info("Inventory-aware opportunity filtering is enabled.")
+	}
+
+	if viper.GetBool("REBALANCER.ENABLED") {
+		if balances == nil {
+			utils.Logger.Error("The rebalancer requires INVENTORY.ENABLED. Rebalancing is disabled.")
+		} else {
+			inventoryRebalancer := rebalancer.NewRebalancer(rebalancer.LoadConfig(), balances, &coinbaseProClient, &geminiClient, krakenCLient)
+			if ledger != nil {
+				inventoryRebalancer.WithLedger(ledger)
+			}
+			_, err := scheduler.Every(viper.GetInt("REBALANCER.INTERVAL_MINUTES")).Minutes().Do(inventoryRebalancer.Run)
+			if err != nil {
+				utils.Logger.Error(fmt.Sprintf("Error scheduling the rebalancer: %v", err))
+			}
+			utils.Logger.Info("Inventory rebalancing is enabled.")
+		}
+	}
+
+	if viper.GetBool("RETENTION.ENABLED") {
+		priceHistoryRetention := retention.NewRetention(retention.DatabaseStore{}, retention.LoadConfig())
+		_, err := scheduler.Every(viper.GetInt("RETENTION.INTERVAL_MINUTES")).Minutes().SingletonMode().Do(priceHistoryRetention.Run)
+		if err != nil {
+			utils.Logger.Error(fmt.Sprintf("Error scheduling the price history retention: %v", err))
+		}
+		utils.Logger.Info("Price history retention is enabled.")
+	}
+
+	strategies, err := LoadStrategies(StrategyContext{Inventory: balances})
+	if err != nil {
+		utils.Logger.Fatal("Unable to load the strategies.", zap.Error(err))
+	}
+
 	job, err := scheduler.Every(5).Seconds().Do(
 		func() {
 			coinbasePriceRecords, coinbaseProErr := coinbaseProClient.GetPrices()
 			if coinbaseProErr != nil {
 				utils.Logger.Error(fmt.Sprintf("Error fetching coinbase pro prices: %v", coinbaseProErr))
 			}
-			geminiPriceRecords := geminiClient.GetPrices()
-			krakenPriceRecords := krakenCLient.GetPrices()
-			bookkeeper.RecordArbitrageRecords(isArbitrageOpportunity(coinbasePriceRecords, geminiPriceRecords, krakenPriceRecords)) // TODO need to handle what happens when one of these records is nil. I believe this is the source of the main bug causing the program to crash.
-			bookkeeper.RecordTriangularArbitrageRecord(isTrangularArbitrage1Exchange(geminiPriceRecords))
+			snapshot := NewMarketSnapshot(time.Now(), coinbasePriceRecords, geminiClient.GetPrices(), krakenCLient.GetPrices())
+			cycle := bookkeeper.NewCycle(snapshot.Timestamp)
+			quotes := snapshot.All()
+			if tradeExecutor != nil {
+				ledger.MarkPrices(quotes)
+				tradeExecutor.MarkPrices(quotes)
+			}
+			signalsByStrategy := make([][]Signal, len(strategies))
+			for i, strategy := range strategies {
+				signalsByStrategy[i] = strategy.Evaluate(snapshot)
+				linkQuotes(quotes, signalsByStrategy[i])
+			}
+			cycle.AddPriceRecords(quotes...)
+			for _, signals := range signalsByStrategy {
+				addSignals(cycle, signals)
+			}
+			recordCycle(*cycle)
+			fetchCycleQuotes := func() []bookkeeper.PriceRecord {
+				return fetchQuotes(cycle.Snapshot.Uuid)
+			}
+			for _, signals := range signalsByStrategy {
+				if tradeExecutor != nil {
+					executeSignals(tradeExecutor, signals, quotes, fetchCycleQuotes)
+				}
+			}
 			utils.Logger.Info("Ran arbitrage hunter job.")
 		})
 	if err != nil {
@@ -65,6 +170,113 @@ func Start() *ArbitrageHunterError {
 	return nil
 }
 
+/*
+Mark the quotes that an arbitrage or triangular arbitrage opportunity was detected with. A quote that was used by several
+opportunities references the first of them, the opportunities reference all of their quotes.
+*/
+func linkQuotes(quotes []bookkeeper.PriceRecord, signals []Signal) {
+	byUuid := make(map[uuid.UUID]*bookkeeper.PriceRecord, len(quotes))
+	for i := range quotes {
+		if quotes[i].Uuid != uuid.Nil {
+			byUuid[quotes[i].Uuid] = &quotes[i]
+		}
+	}
+	for _, signal := range signals {
+		var eventUuid uuid.UUID
+		var quoteUuids []uuid.UUID
+		switch signal := signal.(type) {
+		case ArbitrageSignal:
+			if !signal.Event.IsArbitrageOpportunity {
+				continue
+			}
+			eventUuid, quoteUuids = signal.Event.Uuid, []uuid.UUID{signal.Event.PriceRecordUuidA, signal.Event.PriceRecordUuidB}
+		case TriangularSignal:
+			if !signal.Event.IsTriangularArbitrageOpportunity {
+				continue
+			}
+			eventUuid, quoteUuids = signal.Event.Uuid, []uuid.UUID{signal.Event.TradePair1PriceRecordUuid, signal.Event.TradePair2PriceRecordUuid, signal.Event.TradePair3PriceRecordUuid}
+		default:
+			continue
+		}
+		for _, quoteUuid := range quoteUuids {
+			if quote, ok := byUuid[quoteUuid]; ok && !quote.IsArbitrageOpportunity {
+				quote.IsArbitrageOpportunity = true
+				quote.ArbitrageRecordUuid = eventUuid
+			}
+		}
+	}
+}
+
+/*
+Record the quotes fetched while the opportunities of a cycle are executed. They are written after the cycle and
+reference the cycle that triggered the execution.
+*/
+func recordQuotes(cycleUuid uuid.UUID, quotes []bookkeeper.PriceRecord) {
+	for i := range quotes {
+		quotes[i].CycleUuid = cycleUuid
+	}
+	if err := bookkeeper.RecordPriceRecord(quotes...); err != nil {
+		utils.Logger.Error(fmt.Sprintf("Failed to record %d quotes of cycle %s: %v", len(quotes), cycleUuid, err))
+	}
+}
+
+/*
+Record one run of the Arbitrage Hunter job with its quotes and signals.
+*/
+func recordCycle(cycle bookkeeper.Cycle) {
+	if err := bookkeeper.RecordCycle(cycle); err != nil {
+		utils.Logger.Error(fmt.Sprintf("Failed to record cycle %s with %d quotes: %v", cycle.Snapshot.Uuid, cycle.Snapshot.Quotes, err))
+	}
+}
+
+/*
+Execute the opportunities among the signals of a strategy.
+*/
+func executeSignals(tradeExecutor *executor.Executor, signals []Signal, quotes []bookkeeper.PriceRecord, fetchQuotes func() []bookkeeper.PriceRecord) {
+	var arbitrageRecords []bookkeeper.ArbitrageEventRecord
+	for _, signal := range signals {
+		if !signal.IsOpportunity() {
+			continue
+		}
+		switch signal := signal.(type) {
+		case ArbitrageSignal:
+			arbitrageRecords = append(arbitrageRecords, signal.Event)
+		case TriangularSignal:
+			if viper.GetBool("EXECUTION.TRIANGULAR.ENABLED") {
+				executeTriangularOpportunity(tradeExecutor, signal.Event, signal.Fee)
+			}
+		}
+	}
+	executeOpportunities(tradeExecutor, arbitrageRecords, quotes, fetchQuotes)
+}
+
+/*
+Execute every arbitrage opportunity that is not blocked by inventory with its trade size and record the corrective
+actions that were needed. The quotes are fetched again while the orders are placed, so that the analysis of every
+execution can tell how much the market moved since the opportunity was detected.
+*/
+func executeOpportunities(tradeExecutor *executor.Executor, arbitrageRecords []bookkeeper.ArbitrageEventRecord, quotes []bookkeeper.PriceRecord, fetchQuotes func() []bookkeeper.PriceRecord) {
+	for _, record := range arbitrageRecords {
+		if !record.IsArbitrageOpportunity || record.IsBlockedByInventory {
+			continue
+		}
+		marketQuotes := make(chan []bookkeeper.PriceRecord, 1)
+		go func() {
+			marketQuotes <- fetchQuotes()
+		}()
+		report, err := tradeExecutor.ExecuteArbitrage(record, record.TradeSize, quotes)
+		if err != nil {
+			utils.Logger.Error(fmt.Sprintf("Failed to execute arbitrage opportunity %s.", record.Uuid), zap.Error(err))
+			continue
+		}
+		bookkeeper.RecordTradeAnalysisRecord(executor.AnalyzeExecution(record, record.TradeSize, report, <-marketQuotes))
+		utils.Logger.Info(fmt.Sprintf("Executed arbitrage opportunity %s. Bought %v on %s, sold %v on %s.", record.Uuid, report.BuyLeg.FilledSize, report.BuyLeg.Exchange, report.SellLeg.FilledSize, report.SellLeg.Exchange))
+		if len(report.Corrections) > 0 {
+			bookkeeper.RecordLegCorrectionRecords(report.Corrections)
+		}
+	}
+}
+
 /*
 Prompt the user to start the Arbitrage Hunter.
 */
@@ -113,7 +325,7 @@ func isArbitrageOpportunity(exchangePrices ...[]bookkeeper.PriceRecord) []bookke
 			projectedProfit := ((math.Abs(priceRecords[i].Price-priceRecords[j].Price) - ((priceRecords[i].Price * priceRecords[i].Fee) + (priceRecords[j].Price * priceRecords[j].Fee))) / ((priceRecords[i].Price + priceRecords[j].Price) / 2)) * 100
 			record := bookkeeper.ArbitrageEventRecord{
 				Uuid:                   uuid.New(),
-				Timestamp:              time.Now().Format(time.RFC3339),
+				Timestamp:              time.Now().UTC(),
 				Currency:               priceRecords[i].Currency,
 				PriceA:                 priceRecords[i].Price,
 				ExchangeA:              priceRecords[i].Exchange,
@@ -121,6 +333,8 @@ func isArbitrageOpportunity(exchangePrices ...[]bookkeeper.PriceRecord) []bookke
 				ExchangeB:              priceRecords[j].Exchange,
 				ProjectedProfit:        projectedProfit,
 				IsArbitrageOpportunity: false,
+				PriceRecordUuidA:       priceRecords[i].Uuid,
+				PriceRecordUuidB:       priceRecords[j].Uuid,
 			}
 			if projectedProfit > 0 {
 				record.ProjectedProfit = projectedProfit
@@ -133,6 +347,69 @@ func isArbitrageOpportunity(exchangePrices ...[]bookkeeper.PriceRecord) []bookke
 	return arbitrageRecords
 }
 
+/*
+Set the trade size of every arbitrage opportunity. The size is capped by what can be bought with the quote currency on
+the cheaper exchange and by what can be sold from the base currency on the more expensive exchange. Opportunities that
+can not be traded with at least the minimum trade size are marked as blocked by inventory. Without an inventory every
+opportunity gets the maximum trade size.
+*/
+func capByInventory(arbitrageRecords []bookkeeper.ArbitrageEventRecord, quotes []bookkeeper.PriceRecord, balances *inventory.Inventory, maxTradeSize float64, minTradeSize float64) []bookkeeper.ArbitrageEventRecord {
+	for i := range arbitrageRecords {
+		record := &arbitrageRecords[i]
+		if !record.IsArbitrageOpportunity {
+			continue
+		}
+		record.TradeSize = maxTradeSize
+		if balances == nil {
+			continue
+		}
+
+		buyExchange, buyPrice, sellExchange := record.ExchangeA, record.PriceA, record.ExchangeB
+		if record.PriceA > record.PriceB {
+			buyExchange, buyPrice, sellExchange = record.ExchangeB, record.PriceB, record.ExchangeA
+		}
+		baseAsset, quoteAsset := bookkeeper.SplitCurrency(record.Currency)
+		quoteAvailable, buyKnown := balances.Available(buyExchange, quoteAsset)
+		baseAvailable, sellKnown := balances.Available(sellExchange, baseAsset)
+		if !buyKnown || !sellKnown {
+			record.TradeSize = 0
+			record.IsBlockedByInventory = true
+			utils.Logger.Warn(fmt.Sprintf("Balances of %s or %s are unknown. Arbitrage opportunity %s is blocked.", buyExchange, sellExchange, record.Uuid))
+			continue
+		}
+
+		fee := 0.0
+		for _, quote := range quotes {
+			if quote.Exchange == buyExchange && quote.Currency == record.Currency {
+				fee = quote.Fee
+			}
+		}
+		affordableSize := quoteAvailable / (buyPrice * (1 + fee))
+		record.TradeSize = math.Min(maxTradeSize, math.Min(affordableSize, baseAvailable))
+		if record.TradeSize < minTradeSize || record.TradeSize <= 0 {
+			record.IsBlockedByInventory = true
+			utils.Logger.Info(fmt.Sprintf("Arbitrage opportunity %s is blocked by inventory. %v %s available on %s, %v %s available on %s.", record.Uuid, quoteAvailable, quoteAsset, buyExchange, baseAvailable, baseAsset, sellExchange))
+		}
+	}
+	return arbitrageRecords
+}
+
+/*
+Execute a triangular arbitrage opportunity and record the per-leg fills and the orders that unwound an aborted
+execution.
+*/
+func executeTriangularOpportunity(tradeExecutor *executor.Executor, record bookkeeper.TriangularArbitrageEventRecord, fee float64) {
+	report, err := tradeExecutor.ExecuteTriangular(record, fee)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Failed to execute triangular arbitrage opportunity %s.", record.Uuid), zap.Error(err))
+		return
+	}
+	bookkeeper.RecordTriangularExecutionRecord(report.Record)
+	if len(report.Unwinds) > 0 {
+		bookkeeper.RecordLegCorrectionRecords(report.Unwinds)
+	}
+}
+
 /*
 Take in a slice of price records and determine if there is a triangular arbitrage opportunity. The triangular arbitrage opportunity is only possible if there is one exchange that has all three currencies.
 */
@@ -140,14 +417,18 @@ func isTrangularArbitrage1Exchange(exchangePrices []bookkeeper.PriceRecord) book
 	var ethBtcExchangeRate float64
 	var ethLtcExchangeRate float64 //NOTE this is inverted (i.e. ETHLTC = 1 / LTCETH)
 	var ltcbtcExchangeRate float64
+	var ethBtcUuid, ltcEthUuid, ltcBtcUuid uuid.UUID
 
 	for _, priceRecord := range exchangePrices {
 		if priceRecord.Currency == "ETHBTC" {
 			ethBtcExchangeRate = priceRecord.Price
+			ethBtcUuid = priceRecord.Uuid
 		} else if priceRecord.Currency == "LTCETH" {
 			ethLtcExchangeRate = 1 / priceRecord.Price //NOTE this is inverted (i.e. ETHLTC = 1 / LTCETH)
+			ltcEthUuid = priceRecord.Uuid
 		} else if priceRecord.Currency == "LTCBTC" {
 			ltcbtcExchangeRate = priceRecord.Price
+			ltcBtcUuid = priceRecord.Uuid
 		}
 	}
 	crossExchangeRate := ethLtcExchangeRate * ltcbtcExchangeRate
@@ -163,7 +444,7 @@ func isTrangularArbitrage1Exchange(exchangePrices []bookkeeper.PriceRecord) book
 
 	record := bookkeeper.TriangularArbitrageEventRecord{
 		Uuid:                             uuid.New(),
-		Timestamp:                        time.Now().Format(time.RFC3339),
+		Timestamp:                        time.Now().UTC(),
 		Exchange:                         exchangePrices[0].Exchange,
 		TradePair1:                       "ETHBTC",
 		TradePair1ExchangeRate:           ethBtcExchangeRate,
@@ -173,6 +454,9 @@ func isTrangularArbitrage1Exchange(exchangePrices []bookkeeper.PriceRecord) book
 		TradePair3ExchangeRate:           ltcbtcExchangeRate,
 		CrossExchangeRateDiff:            crossExchangeRateDiff,
 		IsTriangularArbitrageOpportunity: isTriangularArbitrageOpportunity(),
+		TradePair1PriceRecordUuid:        ethBtcUuid,
+		TradePair2PriceRecordUuid:        ltcEthUuid,
+		TradePair3PriceRecordUuid:        ltcBtcUuid,
 	}
 
 	return record
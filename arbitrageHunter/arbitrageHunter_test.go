@@ -3,12 +3,14 @@ package arbitrageHunter
 import (
 	"cryptoArbitrageBot/bookkeeper"
 	"cryptoArbitrageBot/internal/utils"
+	"cryptoArbitrageBot/inventory"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/magiconair/properties/assert"
 	"github.com/spf13/viper"
 	"log"
 	"testing"
+	"time"
 )
 
 func setupTest(tb testing.TB) func(tb testing.TB) {
@@ -53,7 +55,7 @@ func Test_isArbitrageOpportunity(t *testing.T) {
 							Price:     25000,
 							Fee:       .002,
 							Exchange:  "Coinbase",
-							Timestamp: "123",
+							Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 						},
 					},
 					{
@@ -63,7 +65,7 @@ func Test_isArbitrageOpportunity(t *testing.T) {
 							Price:     26000,
 							Fee:       .004,
 							Exchange:  "Gemini",
-							Timestamp: "123",
+							Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 						},
 					},
 				},
@@ -91,7 +93,7 @@ func Test_isArbitrageOpportunity(t *testing.T) {
 							Price:     23000,
 							Fee:       .002,
 							Exchange:  "Coinbase",
-							Timestamp: "123",
+							Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 						},
 					},
 					{
@@ -101,7 +103,7 @@ func Test_isArbitrageOpportunity(t *testing.T) {
 							Price:     24500,
 							Fee:       .004,
 							Exchange:  "Gemini",
-							Timestamp: "123",
+							Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 						},
 					},
 					{
@@ -111,7 +113,7 @@ func Test_isArbitrageOpportunity(t *testing.T) {
 							Price:     23050,
 							Fee:       .003,
 							Exchange:  "Kraken",
-							Timestamp: "123",
+							Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 						},
 					},
 				},
@@ -157,7 +159,7 @@ func Test_isArbitrageOpportunity(t *testing.T) {
 							Price:     25000,
 							Fee:       .002,
 							Exchange:  "Coinbase",
-							Timestamp: "123",
+							Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 						},
 						bookkeeper.PriceRecord{
 							Uuid:      uuid.New(),
@@ -165,7 +167,7 @@ func Test_isArbitrageOpportunity(t *testing.T) {
 							Price:     1500,
 							Fee:       .002,
 							Exchange:  "Coinbase",
-							Timestamp: "123",
+							Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 						},
 					},
 					{
@@ -175,7 +177,7 @@ func Test_isArbitrageOpportunity(t *testing.T) {
 							Price:     23900,
 							Fee:       .004,
 							Exchange:  "Gemini",
-							Timestamp: "123",
+							Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 						},
 						bookkeeper.PriceRecord{
 							Uuid:      uuid.New(),
@@ -183,7 +185,7 @@ func Test_isArbitrageOpportunity(t *testing.T) {
 							Price:     1650,
 							Fee:       .004,
 							Exchange:  "Gemini",
-							Timestamp: "123",
+							Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 						},
 					},
 					{
@@ -193,7 +195,7 @@ func Test_isArbitrageOpportunity(t *testing.T) {
 							Price:     25980,
 							Fee:       .003,
 							Exchange:  "Kraken",
-							Timestamp: "123",
+							Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 						},
 						bookkeeper.PriceRecord{
 							Uuid:      uuid.New(),
@@ -201,7 +203,7 @@ func Test_isArbitrageOpportunity(t *testing.T) {
 							Price:     1645,
 							Fee:       .003,
 							Exchange:  "Kraken",
-							Timestamp: "123",
+							Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 						},
 					},
 				},
@@ -301,7 +303,7 @@ func Test_isTrangularArbitrage1Exchange(t *testing.T) {
 						Price:     .06,
 						Fee:       0,
 						Exchange:  "Gemini",
-						Timestamp: "123",
+						Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 					},
 					bookkeeper.PriceRecord{
 						Uuid:      uuid.New(),
@@ -309,7 +311,7 @@ func Test_isTrangularArbitrage1Exchange(t *testing.T) {
 						Price:     .04776,
 						Fee:       0,
 						Exchange:  "Gemini",
-						Timestamp: "123",
+						Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 					},
 					bookkeeper.PriceRecord{
 						Uuid:      uuid.New(),
@@ -317,13 +319,13 @@ func Test_isTrangularArbitrage1Exchange(t *testing.T) {
 						Price:     0.003144,
 						Fee:       0,
 						Exchange:  "Gemini",
-						Timestamp: "123",
+						Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 					},
 				},
 			},
 			want: bookkeeper.TriangularArbitrageEventRecord{
 				Uuid:                             uuid.UUID{},
-				Timestamp:                        "2023-07-26T18:00:00-00:00",
+				Timestamp:                        time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 				Exchange:                         "Gemini",
 				TradePair1:                       "ETHBTC",
 				TradePair1ExchangeRate:           .06,
@@ -345,7 +347,7 @@ func Test_isTrangularArbitrage1Exchange(t *testing.T) {
 						Price:     .06,
 						Fee:       .002,
 						Exchange:  "Gemini",
-						Timestamp: "123",
+						Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 					},
 					bookkeeper.PriceRecord{
 						Uuid:      uuid.New(),
@@ -353,7 +355,7 @@ func Test_isTrangularArbitrage1Exchange(t *testing.T) {
 						Price:     .07,
 						Fee:       .002,
 						Exchange:  "Gemini",
-						Timestamp: "123",
+						Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 					},
 					bookkeeper.PriceRecord{
 						Uuid:      uuid.New(),
@@ -361,13 +363,13 @@ func Test_isTrangularArbitrage1Exchange(t *testing.T) {
 						Price:     0.003,
 						Fee:       .002,
 						Exchange:  "Gemini",
-						Timestamp: "123",
+						Timestamp: time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 					},
 				},
 			},
 			want: bookkeeper.TriangularArbitrageEventRecord{
 				Uuid:                             uuid.UUID{},
-				Timestamp:                        "2023-07-26T18:00:00-00:00",
+				Timestamp:                        time.Date(2023, 7, 26, 18, 0, 0, 0, time.UTC),
 				Exchange:                         "Gemini",
 				TradePair1:                       "ETHBTC",
 				TradePair1ExchangeRate:           .06,
@@ -395,3 +397,144 @@ func Test_isTrangularArbitrage1Exchange(t *testing.T) {
 		})
 	}
 }
+
+func Test_capByInventory(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	quotes := []bookkeeper.PriceRecord{
+		{Currency: "BTCUSD", Exchange: "Coinbase", Price: 25000, Fee: .006},
+		{Currency: "BTCUSD", Exchange: "Gemini", Price: 26000, Fee: .004},
+	}
+	opportunity := bookkeeper.ArbitrageEventRecord{
+		Uuid:                   uuid.New(),
+		Currency:               "BTCUSD",
+		PriceA:                 26000,
+		ExchangeA:              "Gemini",
+		PriceB:                 25000,
+		ExchangeB:              "Coinbase",
+		IsArbitrageOpportunity: true,
+	}
+
+	tests := []struct {
+		name          string
+		balances      map[string]map[string]float64
+		wantTradeSize float64
+		wantBlocked   bool
+	}{
+		{
+			name: "Enough inventory on both exchanges.",
+			balances: map[string]map[string]float64{
+				"Coinbase": {"USD": 10000},
+				"Gemini":   {"BTC": 1},
+			},
+			wantTradeSize: .01,
+		},
+		{
+			name: "Capped by the quote currency on the buy exchange.",
+			balances: map[string]map[string]float64{
+				"Coinbase": {"USD": 125.75},
+				"Gemini":   {"BTC": 1},
+			},
+			wantTradeSize: .005,
+		},
+		{
+			name: "Capped by the base currency on the sell exchange.",
+			balances: map[string]map[string]float64{
+				"Coinbase": {"USD": 10000},
+				"Gemini":   {"BTC": .002},
+			},
+			wantTradeSize: .002,
+		},
+		{
+			name: "No base currency on the sell exchange.",
+			balances: map[string]map[string]float64{
+				"Coinbase": {"USD": 10000},
+				"Gemini":   {"USD": 10000},
+			},
+			wantTradeSize: 0,
+			wantBlocked:   true,
+		},
+		{
+			name: "Balances of the sell exchange are unknown.",
+			balances: map[string]map[string]float64{
+				"Coinbase": {"USD": 10000},
+			},
+			wantTradeSize: 0,
+			wantBlocked:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balances := inventory.NewInventory()
+			for exchange, exchangeBalances := range tt.balances {
+				balances.SetBalances(exchange, exchangeBalances)
+			}
+			got := capByInventory([]bookkeeper.ArbitrageEventRecord{opportunity}, quotes, balances, .01, .0001)
+			assert.Equal(t, got[0].TradeSize, tt.wantTradeSize)
+			assert.Equal(t, got[0].IsBlockedByInventory, tt.wantBlocked)
+		})
+	}
+}
+
+func Test_linkQuotes(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	newQuotes := func() []bookkeeper.PriceRecord {
+		return []bookkeeper.PriceRecord{
+			{Uuid: uuid.New(), Currency: "BTCUSD", Exchange: "Coinbase", Price: 25000, Fee: .001},
+			{Uuid: uuid.New(), Currency: "BTCUSD", Exchange: "Gemini", Price: 26000, Fee: .001},
+			{Uuid: uuid.New(), Currency: "BTCUSD", Exchange: "Kraken", Price: 25010, Fee: .001},
+			{Uuid: uuid.New(), Currency: "ETHBTC", Exchange: "Gemini", Price: .06},
+			{Uuid: uuid.New(), Currency: "LTCETH", Exchange: "Gemini", Price: .04776},
+			{Uuid: uuid.New(), Currency: "LTCBTC", Exchange: "Gemini", Price: .003144},
+		}
+	}
+
+	t.Run("Spatial opportunities reference the quotes they compared and the quotes reference the first opportunity.", func(t *testing.T) {
+		quotes := newQuotes()
+		var signals []Signal
+		for _, record := range isArbitrageOpportunity(quotes[:3]) {
+			signals = append(signals, ArbitrageSignal{Event: record})
+		}
+		linkQuotes(quotes, signals)
+
+		byUuid := make(map[uuid.UUID]bookkeeper.PriceRecord)
+		for _, quote := range quotes {
+			byUuid[quote.Uuid] = quote
+		}
+		for _, signal := range signals {
+			event := signal.(ArbitrageSignal).Event
+			quoteA, quoteB := byUuid[event.PriceRecordUuidA], byUuid[event.PriceRecordUuidB]
+			assert.Equal(t, quoteA.Exchange, event.ExchangeA)
+			assert.Equal(t, quoteB.Exchange, event.ExchangeB)
+			assert.Equal(t, quoteA.Price, event.PriceA)
+			if event.IsArbitrageOpportunity {
+				assert.Equal(t, quoteA.IsArbitrageOpportunity, true)
+				assert.Equal(t, quoteA.ArbitrageRecordUuid != uuid.Nil, true)
+			}
+		}
+		// Gemini is an opportunity against Coinbase and against Kraken, Kraken is too close to Coinbase
+		assert.Equal(t, quotes[0].ArbitrageRecordUuid, signals[0].(ArbitrageSignal).Event.Uuid)
+		assert.Equal(t, quotes[1].ArbitrageRecordUuid, signals[0].(ArbitrageSignal).Event.Uuid)
+		assert.Equal(t, quotes[2].ArbitrageRecordUuid, signals[2].(ArbitrageSignal).Event.Uuid)
+		assert.Equal(t, quotes[3].IsArbitrageOpportunity, false)
+		assert.Equal(t, quotes[3].ArbitrageRecordUuid, uuid.Nil)
+	})
+
+	t.Run("Triangular opportunities reference the quote of every trade pair.", func(t *testing.T) {
+		quotes := newQuotes()
+		event := isTrangularArbitrage1Exchange(quotes[3:])
+		linkQuotes(quotes, []Signal{TriangularSignal{Event: event}})
+
+		assert.Equal(t, event.IsTriangularArbitrageOpportunity, true)
+		assert.Equal(t, event.TradePair1PriceRecordUuid, quotes[3].Uuid)
+		assert.Equal(t, event.TradePair2PriceRecordUuid, quotes[4].Uuid)
+		assert.Equal(t, event.TradePair3PriceRecordUuid, quotes[5].Uuid)
+		for _, quote := range quotes[3:] {
+			assert.Equal(t, quote.ArbitrageRecordUuid, event.Uuid)
+		}
+		assert.Equal(t, quotes[0].ArbitrageRecordUuid, uuid.Nil)
+	})
+}
@@ -0,0 +1,604 @@
+package arbitrageHunter
+
+import (
+	"bufio"
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/utils"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+/*
+Configuration of a backtest.
+  - Fees: the fee of every exchange (keyed by the upper case exchange name). Exchanges without a fee keep the fee
+    stored with each quote.
+  - MinProjectedProfit: the projected profit in percent an opportunity needs to be executed.
+  - Latency: the time between detecting an opportunity and its orders reaching the exchanges.
+  - OrderSize: the size of both legs of every execution.
+  - MaxQuoteAge: quotes older than this are not used to detect opportunities. 0 keeps quotes forever.
+  - ReferenceCurrency: the currency the PnL of every pair is converted to, so that pairs with different quote currencies
+    can be added up. Defaults to USD.
+*/
+type BacktestConfig struct {
+	From               time.Time
+	To                 time.Time
+	Fees               map[string]float64
+	MinProjectedProfit float64
+	Latency            time.Duration
+	OrderSize          float64
+	MaxQuoteAge        time.Duration
+	ReferenceCurrency  string
+}
+
+/*
+Load the backtest configuration from the configuration file (BACKTEST).
+*/
+func LoadBacktestConfig() BacktestConfig {
+	fees := make(map[string]float64)
+	for exchange := range viper.GetStringMap("BACKTEST.FEES") {
+		fees[strings.ToUpper(exchange)] = viper.GetFloat64("BACKTEST.FEES." + exchange)
+	}
+	return BacktestConfig{
+		From:               configTimestamp("BACKTEST.FROM"),
+		To:                 configTimestamp("BACKTEST.TO"),
+		Fees:               fees,
+		MinProjectedProfit: viper.GetFloat64("BACKTEST.MIN_PROJECTED_PROFIT"),
+		Latency:            time.Duration(viper.GetInt("BACKTEST.LATENCY_MILLISECONDS")) * time.Millisecond,
+		OrderSize:          viper.GetFloat64("BACKTEST.ORDER_SIZE"),
+		MaxQuoteAge:        time.Duration(viper.GetInt("BACKTEST.MAX_QUOTE_AGE_SECONDS")) * time.Second,
+		ReferenceCurrency:  viper.GetString("BACKTEST.REFERENCE_CURRENCY"),
+	}
+}
+
+/*
+Returns a time of the configuration file, zero if it is not set.
+*/
+func configTimestamp(key string) time.Time {
+	if viper.GetString(key) == "" {
+		return time.Time{}
+	}
+	timestamp, err := parseTimestamp(viper.GetString(key))
+	if err != nil {
+		utils.Logger.Fatal(fmt.Sprintf("%s: %v", key, err))
+	}
+	return timestamp
+}
+
+/*
+The backtest results of one currency bought on one exchange and sold on another. Executed opportunities filled both
+legs, missed opportunities filled at most one leg because the market moved during the latency. The simulated PnL is in
+the quote currency of the pair.
+*/
+type PairStats struct {
+	Currency        string
+	BuyExchange     string
+	SellExchange    string
+	Opportunities   int
+	Executed        int
+	Missed          int
+	ProjectedProfit float64
+	SimulatedPnl    float64
+}
+
+/*
+The signals of one strategy during a backtest and how many of them were opportunities.
+*/
+type StrategyStats struct {
+	Name          string
+	Signals       int
+	Opportunities int
+}
+
+/*
+The results of a backtest. SimulatedPnl and MaxDrawdown are in the reference currency, every execution is converted at
+the quotes it was settled at. PnL of quote currencies that could not be converted because no quotes lead to the
+reference currency is kept apart in UnconvertedPnl, keyed by quote currency. Projected profits are in percent.
+Profitable counts the executions with a positive simulated PnL, MaxDrawdown is the largest drop of the cumulative
+simulated PnL from its previous high. SpreadReversion is the sum of the spread changes in percent of the spread
+positions that were exited.
+*/
+type BacktestReport struct {
+	From                    string
+	To                      string
+	Quotes                  int
+	Ticks                   int
+	Opportunities           int
+	Executed                int
+	Missed                  int
+	Profitable              int
+	ReferenceCurrency       string
+	SimulatedPnl            float64
+	MaxDrawdown             float64
+	UnconvertedPnl          map[string]float64
+	TriangularOpportunities int
+	SpreadEntries           int
+	SpreadExits             int
+	SpreadReversion         float64
+	Strategies              []StrategyStats
+	Pairs                   []PairStats
+}
+
+/*
+An opportunity that was detected and is executed once the latency has passed.
+*/
+type pendingExecution struct {
+	due          time.Time
+	currency     string
+	buyExchange  string
+	buyPrice     float64
+	sellExchange string
+	sellPrice    float64
+}
+
+/*
+Replays historical quotes through the strategies of the live bot. Quotes are collected into ticks of the same second;
+after each tick the latest quote of every exchange and currency is passed to the strategies, like the quotes of one run
+of the Arbitrage Hunter job.
+
+Executions of arbitrage signals are simulated with immediate-or-cancel limit orders at the detected prices, filled at
+the quotes of the first tick after the latency if the market did not move past the limit. If only one leg fills, it is
+unwound at the same price, which costs the fee twice. Triangular and spread signals are counted but, like in the live
+bot without execution, not traded.
+*/
+type Backtester struct {
+	config     BacktestConfig
+	strategies []Strategy
+	book       map[string]map[string]bookkeeper.PriceRecord
+	quotedAt   map[string]map[string]time.Time
+	tick       time.Time
+	pending    []pendingExecution
+	pairs      map[string]*PairStats
+	report     BacktestReport
+	peakPnl    float64
+	marks      map[string]float64
+}
+
+/*
+Create a new Backtester with the given configuration that replays the quotes through the given strategies. Strategies
+keep state between evaluations, so every Backtester needs strategies of its own.
+*/
+func NewBacktester(config BacktestConfig, strategies ...Strategy) *Backtester {
+	if config.ReferenceCurrency == "" {
+		config.ReferenceCurrency = "USD"
+	}
+	backtester := &Backtester{
+		config:     config,
+		strategies: strategies,
+		book:       make(map[string]map[string]bookkeeper.PriceRecord),
+		quotedAt:   make(map[string]map[string]time.Time),
+		pairs:      make(map[string]*PairStats),
+		marks:      map[string]float64{config.ReferenceCurrency: 1},
+	}
+	backtester.report.ReferenceCurrency = config.ReferenceCurrency
+	backtester.report.UnconvertedPnl = make(map[string]float64)
+	for _, strategy := range strategies {
+		backtester.report.Strategies = append(backtester.report.Strategies, StrategyStats{Name: strategy.Name()})
+	}
+	return backtester
+}
+
+/*
+Replay the next quote. Quotes must be replayed in timestamp order.
+*/
+func (b *Backtester) Replay(quote bookkeeper.PriceRecord) error {
+	timestamp := quote.Timestamp.Truncate(time.Second)
+	if timestamp.Before(b.tick) {
+		return fmt.Errorf("quote of %s on %s at %s is out of order", quote.Currency, quote.Exchange, quote.Timestamp.Format(time.RFC3339Nano))
+	}
+	if timestamp.After(b.tick) && !b.tick.IsZero() {
+		b.closeTick()
+	}
+	if b.report.From == "" {
+		b.report.From = timestamp.Format(time.RFC3339)
+	}
+	b.tick = timestamp
+	b.report.To = timestamp.Format(time.RFC3339)
+	b.report.Quotes++
+
+	if fee, ok := b.config.Fees[strings.ToUpper(quote.Exchange)]; ok {
+		quote.Fee = fee
+	}
+	if b.book[quote.Exchange] == nil {
+		b.book[quote.Exchange] = make(map[string]bookkeeper.PriceRecord)
+		b.quotedAt[quote.Exchange] = make(map[string]time.Time)
+	}
+	b.book[quote.Exchange][quote.Currency] = quote
+	b.quotedAt[quote.Exchange][quote.Currency] = timestamp
+	return nil
+}
+
+/*
+Close the last tick, settle the executions that are still pending with the last quotes and return the results.
+*/
+func (b *Backtester) Finish() BacktestReport {
+	if !b.tick.IsZero() {
+		b.closeTick()
+	}
+	for _, execution := range b.pending {
+		b.settle(execution)
+	}
+	b.pending = nil
+
+	b.report.Pairs = nil
+	for _, pair := range b.pairs {
+		b.report.Pairs = append(b.report.Pairs, *pair)
+	}
+	sort.Slice(b.report.Pairs, func(i, j int) bool {
+		return pairKey(b.report.Pairs[i].Currency, b.report.Pairs[i].BuyExchange, b.report.Pairs[i].SellExchange) < pairKey(b.report.Pairs[j].Currency, b.report.Pairs[j].BuyExchange, b.report.Pairs[j].SellExchange)
+	})
+	return b.report
+}
+
+/*
+Evaluate the strategies on the quotes of the current tick, then settle the executions that are due.
+*/
+func (b *Backtester) closeTick() {
+	b.report.Ticks++
+	snapshot := NewMarketSnapshot(b.tick, b.freshQuotes()...)
+	b.markPrices(snapshot.All())
+
+	for i, strategy := range b.strategies {
+		for _, signal := range strategy.Evaluate(snapshot) {
+			b.report.Strategies[i].Signals++
+			if signal.IsOpportunity() {
+				b.report.Strategies[i].Opportunities++
+			}
+			switch signal := signal.(type) {
+			case ArbitrageSignal:
+				if signal.IsOpportunity() && signal.Event.ProjectedProfit >= b.config.MinProjectedProfit {
+					b.schedule(signal.Event)
+				}
+			case TriangularSignal:
+				if signal.IsOpportunity() {
+					b.report.TriangularOpportunities++
+				}
+			case SpreadSignal:
+				if signal.Event.Action == spreadExit {
+					b.report.SpreadExits++
+					b.report.SpreadReversion += signal.Event.SpreadChange
+				} else {
+					b.report.SpreadEntries++
+				}
+			}
+		}
+	}
+
+	var stillPending []pendingExecution
+	for _, execution := range b.pending {
+		if execution.due.After(b.tick) {
+			stillPending = append(stillPending, execution)
+			continue
+		}
+		b.settle(execution)
+	}
+	b.pending = stillPending
+}
+
+/*
+Execute an arbitrage opportunity once the latency has passed.
+*/
+func (b *Backtester) schedule(record bookkeeper.ArbitrageEventRecord) {
+	execution := pendingExecution{due: b.tick.Add(b.config.Latency), currency: record.Currency, buyExchange: record.ExchangeA, buyPrice: record.PriceA, sellExchange: record.ExchangeB, sellPrice: record.PriceB}
+	if execution.buyPrice > execution.sellPrice {
+		execution.buyExchange, execution.buyPrice, execution.sellExchange, execution.sellPrice = execution.sellExchange, execution.sellPrice, execution.buyExchange, execution.buyPrice
+	}
+	pair := b.pair(execution.currency, execution.buyExchange, execution.sellExchange)
+	pair.Opportunities++
+	pair.ProjectedProfit += record.ProjectedProfit
+	b.report.Opportunities++
+	b.pending = append(b.pending, execution)
+}
+
+/*
+Simulate the execution of an opportunity at the current quotes.
+*/
+func (b *Backtester) settle(execution pendingExecution) {
+	pair := b.pair(execution.currency, execution.buyExchange, execution.sellExchange)
+	buyQuote, buyOk := b.book[execution.buyExchange][execution.currency]
+	sellQuote, sellOk := b.book[execution.sellExchange][execution.currency]
+	buyFilled := buyOk && buyQuote.Price <= execution.buyPrice
+	sellFilled := sellOk && sellQuote.Price >= execution.sellPrice
+	size := b.config.OrderSize
+
+	var pnl float64
+	switch {
+	case buyFilled && sellFilled:
+		pnl = size*(sellQuote.Price-buyQuote.Price) - size*(buyQuote.Price*buyQuote.Fee+sellQuote.Price*sellQuote.Fee)
+		pair.Executed++
+		b.report.Executed++
+	case buyFilled:
+		pnl = -2 * size * buyQuote.Price * buyQuote.Fee
+		pair.Missed++
+		b.report.Missed++
+	case sellFilled:
+		pnl = -2 * size * sellQuote.Price * sellQuote.Fee
+		pair.Missed++
+		b.report.Missed++
+	default:
+		pair.Missed++
+		b.report.Missed++
+	}
+	if pnl > 0 {
+		b.report.Profitable++
+	}
+	pair.SimulatedPnl += pnl
+
+	_, quoteCurrency := bookkeeper.SplitCurrency(execution.currency)
+	mark, ok := b.marks[quoteCurrency]
+	if !ok {
+		b.report.UnconvertedPnl[quoteCurrency] += pnl
+		return
+	}
+	b.report.SimulatedPnl += pnl * mark
+	b.peakPnl = math.Max(b.peakPnl, b.report.SimulatedPnl)
+	b.report.MaxDrawdown = math.Max(b.report.MaxDrawdown, b.peakPnl-b.report.SimulatedPnl)
+}
+
+/*
+Update the marks of the assets from the quotes of a tick. Assets quoted in the reference currency are marked to the
+average price of all exchanges, other assets through the pair they are quoted in (e.g. LTC through LTCETH and ETHUSD).
+Assets without quotes in the tick keep their previous mark.
+*/
+func (b *Backtester) markPrices(quotes []bookkeeper.PriceRecord) {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for _, quote := range quotes {
+		if quote.Price > 0 {
+			sums[quote.Currency] += quote.Price
+			counts[quote.Currency]++
+		}
+	}
+
+	marks := map[string]float64{b.config.ReferenceCurrency: 1}
+	for changed := true; changed; {
+		changed = false
+		for currency, sum := range sums {
+			base, quote := bookkeeper.SplitCurrency(currency)
+			if _, ok := marks[base]; ok {
+				continue
+			}
+			if quoteMark, ok := marks[quote]; ok {
+				marks[base] = sum / float64(counts[currency]) * quoteMark
+				changed = true
+			}
+		}
+	}
+	for asset, mark := range marks {
+		b.marks[asset] = mark
+	}
+}
+
+/*
+Returns the quotes of every exchange that are not older than the maximum quote age, sorted by currency.
+*/
+func (b *Backtester) freshQuotes() [][]bookkeeper.PriceRecord {
+	exchanges := make([]string, 0, len(b.book))
+	for exchange := range b.book {
+		exchanges = append(exchanges, exchange)
+	}
+	sort.Strings(exchanges)
+
+	var quotes [][]bookkeeper.PriceRecord
+	for _, exchange := range exchanges {
+		var exchangeQuotes []bookkeeper.PriceRecord
+		for currency, quote := range b.book[exchange] {
+			if b.config.MaxQuoteAge > 0 && b.tick.Sub(b.quotedAt[exchange][currency]) > b.config.MaxQuoteAge {
+				continue
+			}
+			exchangeQuotes = append(exchangeQuotes, quote)
+		}
+		sort.Slice(exchangeQuotes, func(i, j int) bool {
+			return exchangeQuotes[i].Currency < exchangeQuotes[j].Currency
+		})
+		quotes = append(quotes, exchangeQuotes)
+	}
+	return quotes
+}
+
+func (b *Backtester) pair(currency string, buyExchange string, sellExchange string) *PairStats {
+	key := pairKey(currency, buyExchange, sellExchange)
+	if b.pairs[key] == nil {
+		b.pairs[key] = &PairStats{Currency: currency, BuyExchange: buyExchange, SellExchange: sellExchange}
+	}
+	return b.pairs[key]
+}
+
+func pairKey(currency string, buyExchange string, sellExchange string) string {
+	return currency + " " + buyExchange + " " + sellExchange
+}
+
+/*
+Returns true if the quotes contain the three trade pairs of a triangular arbitrage cycle.
+*/
+func hasTriangularPairs(quotes []bookkeeper.PriceRecord) bool {
+	found := make(map[string]bool)
+	for _, quote := range quotes {
+		found[quote.Currency] = quote.Price > 0
+	}
+	return found["ETHBTC"] && found["LTCETH"] && found["LTCBTC"]
+}
+
+/*
+Parse a timestamp of a quote file or of the configuration, either in RFC3339 or in the MySQL notation (UTC).
+*/
+func parseTimestamp(timestamp string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+		return parsed.UTC(), nil
+	}
+	parsed, err := time.Parse("2006-01-02 15:04:05.999999999", timestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid quote timestamp %q", timestamp)
+	}
+	return parsed, nil
+}
+
+/*
+Run a backtest of the enabled strategies over the price history in the database, or over the quotes in a file if a path
+is given, and print the results.
+*/
+func Backtest(path string) error {
+	strategies, err := LoadStrategies(StrategyContext{})
+	if err != nil {
+		return err
+	}
+	backtester := NewBacktester(LoadBacktestConfig(), strategies...)
+	if err := streamQuotes(path, backtester.config, backtester.Replay); err != nil {
+		return err
+	}
+	report := backtester.Finish()
+	utils.Logger.Info(fmt.Sprintf("Backtest replayed %d quotes from %s to %s.", report.Quotes, report.From, report.To))
+	printBacktestReport(os.Stdout, report)
+	return nil
+}
+
+/*
+Pass the quotes of a file, or of the configured source in the database (BACKTEST.SOURCE) if no path is given, to handle
+in timestamp order.
+*/
+func streamQuotes(path string, config BacktestConfig, handle func(bookkeeper.PriceRecord) error) error {
+	if path != "" {
+		return ReadQuoteFile(path, handle)
+	}
+	if databaseErr := internal.ConnectToDatabaseReadOnly(); databaseErr != nil {
+		return databaseErr
+	}
+	switch source := viper.GetString("BACKTEST.SOURCE"); source {
+	case "", "price_records":
+		return bookkeeper.StreamPriceRecords(config.From, config.To, handle)
+	default:
+		return bookkeeper.StreamMarketQuotes(source, config.From, config.To, handle)
+	}
+}
+
+/*
+Replay the quotes of a CSV file or of a JSON lines file, see ReadQuoteFile.
+*/
+func ReplayFile(path string, backtester *Backtester) error {
+	return ReadQuoteFile(path, backtester.Replay)
+}
+
+/*
+Read the quotes of a CSV file with a header (timestamp, currency, price, fee and exchange columns), of a JSON lines
+file with one price record per line or of an event log directory (DATABASE.EVENT_LOG) and pass them to handle. The
+quotes must be sorted by timestamp.
+*/
+func ReadQuoteFile(path string, handle func(bookkeeper.PriceRecord) error) error {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return bookkeeper.ReadEventLogPriceRecords(path, time.Time{}, time.Time{}, handle)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return readCsv(file, handle)
+	case ".jsonl", ".json":
+		return readJsonLines(file, handle)
+	default:
+		return fmt.Errorf("unsupported quote file %s, expected .csv or .jsonl", path)
+	}
+}
+
+func readCsv(reader io.Reader, handle func(bookkeeper.PriceRecord) error) error {
+	csvReader := csv.NewReader(reader)
+	header, err := csvReader.Read()
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"timestamp", "currency", "price", "exchange"} {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("quote file has no %s column", name)
+		}
+	}
+
+	for line := 2; ; line++ {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		quote := bookkeeper.PriceRecord{Currency: row[columns["currency"]], Exchange: row[columns["exchange"]]}
+		if quote.Timestamp, err = parseTimestamp(row[columns["timestamp"]]); err != nil {
+			return fmt.Errorf("invalid timestamp on line %d: %v", line, err)
+		}
+		if quote.Price, err = strconv.ParseFloat(row[columns["price"]], 64); err != nil {
+			return fmt.Errorf("invalid price on line %d: %v", line, err)
+		}
+		if i, ok := columns["fee"]; ok {
+			if quote.Fee, err = strconv.ParseFloat(row[i], 64); err != nil {
+				return fmt.Errorf("invalid fee on line %d: %v", line, err)
+			}
+		}
+		if err := handle(quote); err != nil {
+			return err
+		}
+	}
+}
+
+func readJsonLines(reader io.Reader, handle func(bookkeeper.PriceRecord) error) error {
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var quote bookkeeper.PriceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &quote); err != nil {
+			return fmt.Errorf("invalid quote on line %d: %v", line, err)
+		}
+		if err := handle(quote); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func printBacktestReport(writer io.Writer, report BacktestReport) {
+	fmt.Fprintf(writer, "Backtest from %s to %s: %d quotes, %d ticks\n", report.From, report.To, report.Quotes, report.Ticks)
+	fmt.Fprintf(writer, "Opportunities: %d, executed: %d, missed: %d, simulated PnL: %.2f %s, max drawdown: %.2f %s\n", report.Opportunities, report.Executed, report.Missed, report.SimulatedPnl, report.ReferenceCurrency, report.MaxDrawdown, report.ReferenceCurrency)
+	quoteCurrencies := make([]string, 0, len(report.UnconvertedPnl))
+	for quoteCurrency := range report.UnconvertedPnl {
+		quoteCurrencies = append(quoteCurrencies, quoteCurrency)
+	}
+	sort.Strings(quoteCurrencies)
+	for _, quoteCurrency := range quoteCurrencies {
+		fmt.Fprintf(writer, "Simulated PnL not converted to %s: %v %s\n", report.ReferenceCurrency, report.UnconvertedPnl[quoteCurrency], quoteCurrency)
+	}
+	fmt.Fprintf(writer, "Triangular opportunities: %d\n", report.TriangularOpportunities)
+	fmt.Fprintf(writer, "Spread entries: %d, exits: %d, reversion: %.4f%%\n", report.SpreadEntries, report.SpreadExits, report.SpreadReversion)
+	for _, strategy := range report.Strategies {
+		fmt.Fprintf(writer, "Strategy %s: %d signals, %d opportunities\n", strategy.Name, strategy.Signals, strategy.Opportunities)
+	}
+	fmt.Fprintln(writer)
+
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CURRENCY\tBUY\tSELL\tOPPORTUNITIES\tEXECUTED\tMISSED\tAVG PROJECTED %\tSIMULATED PNL (QUOTE CURRENCY)")
+	for _, pair := range report.Pairs {
+		averageProjectedProfit := 0.0
+		if pair.Opportunities > 0 {
+			averageProjectedProfit = pair.ProjectedProfit / float64(pair.Opportunities)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%d\t%d\t%.4f\t%v\n", pair.Currency, pair.BuyExchange, pair.SellExchange, pair.Opportunities, pair.Executed, pair.Missed, averageProjectedProfit, pair.SimulatedPnl)
+	}
+	table.Flush()
+}
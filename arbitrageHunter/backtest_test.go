@@ -0,0 +1,195 @@
+package arbitrageHunter
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"github.com/magiconair/properties/assert"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/*
+A BTCUSD spread of 1000 between Coinbase and Gemini for two seconds that closes in the third.
+*/
+var quotesForTest = `timestamp,currency,price,fee,exchange
+2023-05-01 10:00:00,BTCUSD,25000,0.001,Coinbase
+2023-05-01 10:00:00,BTCUSD,26000,0.001,Gemini
+2023-05-01 10:00:01,BTCUSD,25000,0.001,Coinbase
+2023-05-01 10:00:01,BTCUSD,26000,0.001,Gemini
+2023-05-01 10:00:02,BTCUSD,25000,0.001,Coinbase
+2023-05-01 10:00:02,BTCUSD,25000,0.001,Gemini
+`
+
+func timeForTest(value string) time.Time {
+	timestamp, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		panic(err)
+	}
+	return timestamp
+}
+
+func Test_Backtest(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	path := filepath.Join(t.TempDir(), "quotes.csv")
+	if err := os.WriteFile(path, []byte(quotesForTest), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name              string
+		config            BacktestConfig
+		wantOpportunities int
+		wantExecuted      int
+		wantMissed        int
+		wantPnl           float64
+	}{
+		{
+			name:              "Opportunities are executed at the detected prices without latency.",
+			config:            BacktestConfig{OrderSize: .01},
+			wantOpportunities: 2,
+			wantExecuted:      2,
+			wantPnl:           18.98,
+		},
+		{
+			name:              "Spread closed during the latency, the filled leg is unwound.",
+			config:            BacktestConfig{OrderSize: .01, Latency: time.Second},
+			wantOpportunities: 2,
+			wantExecuted:      1,
+			wantMissed:        1,
+			wantPnl:           8.99,
+		},
+		{
+			name:   "Opportunities below the minimum projected profit are ignored.",
+			config: BacktestConfig{OrderSize: .01, MinProjectedProfit: 5},
+		},
+		{
+			name:              "Configured fees replace the fees of the quotes.",
+			config:            BacktestConfig{OrderSize: .01, Fees: map[string]float64{"COINBASE": .02}},
+			wantOpportunities: 2,
+			wantExecuted:      2,
+			wantPnl:           9.48,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backtester := NewBacktester(tt.config, &spatialStrategy{})
+			assert.Equal(t, ReplayFile(path, backtester), nil)
+			report := backtester.Finish()
+
+			assert.Equal(t, report.Quotes, 6)
+			assert.Equal(t, report.Ticks, 3)
+			assert.Equal(t, report.Opportunities, tt.wantOpportunities)
+			assert.Equal(t, report.Executed, tt.wantExecuted)
+			assert.Equal(t, report.Missed, tt.wantMissed)
+			assert.Equal(t, math.Round(report.SimulatedPnl*100)/100, tt.wantPnl)
+			if tt.wantOpportunities > 0 {
+				assert.Equal(t, len(report.Pairs), 1)
+				assert.Equal(t, report.Pairs[0].BuyExchange, "Coinbase")
+				assert.Equal(t, report.Pairs[0].SellExchange, "Gemini")
+				assert.Equal(t, report.Pairs[0].Opportunities, tt.wantOpportunities)
+			}
+		})
+	}
+}
+
+func Test_BacktestIgnoresStaleQuotes(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name              string
+		maxQuoteAge       time.Duration
+		wantOpportunities int
+	}{
+		{name: "Gemini quote is compared while it is fresh.", maxQuoteAge: 10 * time.Second, wantOpportunities: 1},
+		{name: "Quotes are kept forever without a maximum age.", wantOpportunities: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backtester := NewBacktester(BacktestConfig{OrderSize: .01, MaxQuoteAge: tt.maxQuoteAge}, &spatialStrategy{})
+			backtester.Replay(bookkeeper.PriceRecord{Timestamp: timeForTest("2023-05-01T10:00:00Z"), Currency: "BTCUSD", Exchange: "Coinbase", Price: 25000, Fee: .001})
+			backtester.Replay(bookkeeper.PriceRecord{Timestamp: timeForTest("2023-05-01T10:00:00Z"), Currency: "BTCUSD", Exchange: "Gemini", Price: 26000, Fee: .001})
+			backtester.Replay(bookkeeper.PriceRecord{Timestamp: timeForTest("2023-05-01T10:00:30Z"), Currency: "BTCUSD", Exchange: "Coinbase", Price: 25000, Fee: .001})
+			assert.Equal(t, backtester.Finish().Opportunities, tt.wantOpportunities)
+		})
+	}
+}
+
+func Test_BacktestReferenceCurrency(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name            string
+		btcPrice        float64
+		wantPnl         float64
+		wantUnconverted map[string]float64
+	}{
+		{
+			name:            "PnL in BTC is converted to USD at the BTCUSD quotes.",
+			btcPrice:        25000,
+			wantPnl:         5,
+			wantUnconverted: map[string]float64{},
+		},
+		{
+			name:            "PnL in BTC is kept apart without BTCUSD quotes.",
+			wantUnconverted: map[string]float64{"BTC": .0002},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backtester := NewBacktester(BacktestConfig{OrderSize: .01}, &spatialStrategy{})
+			for _, timestamp := range []string{"2023-05-01T10:00:00Z", "2023-05-01T10:00:01Z"} {
+				if tt.btcPrice > 0 {
+					backtester.Replay(bookkeeper.PriceRecord{Timestamp: timeForTest(timestamp), Currency: "BTCUSD", Exchange: "Coinbase", Price: tt.btcPrice})
+				}
+				backtester.Replay(bookkeeper.PriceRecord{Timestamp: timeForTest(timestamp), Currency: "ETHBTC", Exchange: "Coinbase", Price: .06})
+				backtester.Replay(bookkeeper.PriceRecord{Timestamp: timeForTest(timestamp), Currency: "ETHBTC", Exchange: "Gemini", Price: .07})
+			}
+			report := backtester.Finish()
+
+			assert.Equal(t, report.Executed, 2)
+			assert.Equal(t, report.ReferenceCurrency, "USD")
+			assert.Equal(t, math.Round(report.SimulatedPnl*100)/100, tt.wantPnl)
+			for quoteCurrency, pnl := range report.UnconvertedPnl {
+				report.UnconvertedPnl[quoteCurrency] = math.Round(pnl*1e8) / 1e8
+			}
+			assert.Equal(t, report.UnconvertedPnl, tt.wantUnconverted)
+		})
+	}
+}
+
+func Test_BacktestStrategies(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	meanReversion := &meanReversionStrategy{window: 5, minSamples: 2, entryZScore: 2, exitZScore: .5, spreads: make(map[string][]float64), positions: make(map[string]bookkeeper.SpreadSignalRecord)}
+	backtester := NewBacktester(BacktestConfig{OrderSize: .01}, meanReversion)
+	start := timeForTest("2023-05-01T10:00:00Z")
+	// The spread jumps to 11% in the fifth second and reverts in the sixth
+	for i, geminiPrice := range []float64{100, 101, 100, 101, 90, 100} {
+		timestamp := start.Add(time.Duration(i) * time.Second)
+		assert.Equal(t, backtester.Replay(bookkeeper.PriceRecord{Timestamp: timestamp, Currency: "BTCUSD", Exchange: "Coinbase", Price: 100}), nil)
+		assert.Equal(t, backtester.Replay(bookkeeper.PriceRecord{Timestamp: timestamp, Currency: "BTCUSD", Exchange: "Gemini", Price: geminiPrice}), nil)
+	}
+	report := backtester.Finish()
+
+	assert.Equal(t, report.SpreadEntries, 1)
+	assert.Equal(t, report.SpreadExits, 1)
+	assert.Equal(t, math.Round(report.SpreadReversion*100)/100, 11.11)
+	assert.Equal(t, report.Strategies, []StrategyStats{{Name: "mean_reversion", Signals: 2, Opportunities: 1}})
+	assert.Equal(t, report.Opportunities, 0)
+}
+
+func Test_BacktestRejectsQuotesOutOfOrder(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	backtester := NewBacktester(BacktestConfig{OrderSize: .01}, &spatialStrategy{})
+	assert.Equal(t, backtester.Replay(bookkeeper.PriceRecord{Timestamp: timeForTest("2023-05-01T10:00:01Z"), Currency: "BTCUSD", Exchange: "Coinbase", Price: 25000}), nil)
+	assert.Equal(t, backtester.Replay(bookkeeper.PriceRecord{Timestamp: timeForTest("2023-05-01T10:00:00Z"), Currency: "BTCUSD", Exchange: "Gemini", Price: 26000}) != nil, true)
+}
@@ -0,0 +1,155 @@
+package arbitrageHunter
+
+import (
+	"crypto/subtle"
+	"cryptoArbitrageBot/api/coinbasePro"
+	"cryptoArbitrageBot/api/gemini"
+	"cryptoArbitrageBot/api/kraken"
+	"cryptoArbitrageBot/executor"
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/utils"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+/*
+The header that carries EXECUTION.KILL_SWITCH.TOKEN in requests to the kill switch endpoints.
+*/
+const killSwitchTokenHeader = "X-Kill-Switch-Token"
+
+/*
+Listen for the kill switch. It can be triggered by sending SIGUSR1 to the process or with a POST request to
+/killswitch on EXECUTION.KILL_SWITCH.ADDRESS. A POST request to /killswitch/reset allows orders again. The endpoints
+only listen on a loopback address unless EXECUTION.KILL_SWITCH.TOKEN is set, which every request then has to carry in
+the X-Kill-Switch-Token header.
+*/
+func listenForKillSwitch(tradeExecutor *executor.Executor) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for range signals {
+			tradeExecutor.KillSwitch("received SIGUSR1")
+		}
+	}()
+
+	address := viper.GetString("EXECUTION.KILL_SWITCH.ADDRESS")
+	if address == "" {
+		return
+	}
+	token := viper.GetString("EXECUTION.KILL_SWITCH.TOKEN")
+	if err := checkKillSwitchAddress(address, token); err != nil {
+		utils.Logger.Error(fmt.Sprintf("Kill switch endpoint is disabled: %s.", err.msg))
+		return
+	}
+	go func() {
+		utils.Logger.Info(fmt.Sprintf("Kill switch is listening on http://%s/killswitch", address))
+		if err := http.ListenAndServe(address, killSwitchHandler(tradeExecutor, token)); err != nil {
+			utils.Logger.Error("Kill switch endpoint stopped.", zap.Error(err))
+		}
+	}()
+}
+
+/*
+Returns an error if the kill switch endpoints would be reachable from other hosts without a token. An address without a
+host listens on every interface.
+*/
+func checkKillSwitchAddress(address string, token string) *ArbitrageHunterError {
+	if token != "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return &ArbitrageHunterError{msg: fmt.Sprintf("invalid kill switch address %s: %v", address, err)}
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return &ArbitrageHunterError{msg: fmt.Sprintf("kill switch address %s is not a loopback address and EXECUTION.KILL_SWITCH.TOKEN is not set", address)}
+}
+
+/*
+Serve the kill switch endpoints. Both only accept POST requests and, if a token is given, only requests that carry it.
+*/
+func killSwitchHandler(tradeExecutor *executor.Executor, token string) http.Handler {
+	protect := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(killSwitchTokenHeader)), []byte(token)) != 1 {
+				utils.Logger.Warn(fmt.Sprintf("Refused kill switch request from %s without a valid token.", r.RemoteAddr))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			handler(w, r)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/killswitch", protect(func(w http.ResponseWriter, r *http.Request) {
+		cancelled, err := tradeExecutor.KillSwitch("HTTP request from " + r.RemoteAddr)
+		response := map[string]interface{}{"cancelledOrders": cancelled}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			response["error"] = err.Error()
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	mux.HandleFunc("/killswitch/reset", protect(func(w http.ResponseWriter, r *http.Request) {
+		tradeExecutor.ResetKillSwitch()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	return mux
+}
+
+/*
+Trigger the kill switch from the command line. Notifies a running Arbitrage Hunter so that it stops placing orders and
+cancels all open orders on every exchange directly, so that it also works when the bot is not running.
+*/
+func KillSwitch() error {
+	address := viper.GetString("EXECUTION.KILL_SWITCH.ADDRESS")
+	if address != "" {
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/killswitch", address), nil)
+		if err != nil {
+			return err
+		}
+		if token := viper.GetString("EXECUTION.KILL_SWITCH.TOKEN"); token != "" {
+			req.Header.Set(killSwitchTokenHeader, token)
+		}
+		resp, err := internal.GetClient().Do(req)
+		if err != nil {
+			utils.Logger.Warn("Unable to reach a running Arbitrage Hunter. Cancelling orders directly.", zap.Error(err))
+		} else {
+			resp.Body.Close()
+			utils.Logger.Info(fmt.Sprintf("Notified the running Arbitrage Hunter (%s).", resp.Status))
+		}
+	}
+
+	cancelled, err := executor.CancelAllOrders(newVenues()...)
+	utils.Logger.Info(fmt.Sprintf("Kill switch cancelled %d open orders.", cancelled))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+/*
+Create a venue for every supported exchange.
+*/
+func newVenues() []executor.Venue {
+	coinbaseProClient := coinbasePro.NewClient()
+	geminiClient := gemini.NewClient()
+	krakenClient := kraken.NewWithClient(viper.GetString("KRAKEN.TEST.KEY"), viper.GetString("KRAKEN.TEST.SECRET"), internal.GetClient())
+	return []executor.Venue{&coinbaseProClient, &geminiClient, krakenClient}
+}
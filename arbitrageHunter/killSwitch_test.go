@@ -0,0 +1,70 @@
+package arbitrageHunter
+
+import (
+	"cryptoArbitrageBot/executor"
+	"github.com/magiconair/properties/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_checkKillSwitchAddress(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name    string
+		address string
+		token   string
+		wantErr bool
+	}{
+		{name: "localhost is allowed without a token.", address: "localhost:8081"},
+		{name: "An IPv4 loopback address is allowed without a token.", address: "127.0.0.1:8081"},
+		{name: "An IPv6 loopback address is allowed without a token.", address: "[::1]:8081"},
+		{name: "Every interface is refused without a token.", address: ":8081", wantErr: true},
+		{name: "A public address is refused without a token.", address: "0.0.0.0:8081", wantErr: true},
+		{name: "A public address is allowed with a token.", address: "0.0.0.0:8081", token: "secret"},
+		{name: "An address without a port is refused.", address: "localhost", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkKillSwitchAddress(tt.address, tt.token)
+			assert.Equal(t, err != nil, tt.wantErr)
+		})
+	}
+}
+
+func Test_killSwitchHandler(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name       string
+		token      string
+		method     string
+		path       string
+		header     string
+		wantStatus int
+	}{
+		{name: "Trigger without a configured token.", method: http.MethodPost, path: "/killswitch", wantStatus: http.StatusOK},
+		{name: "Trigger only accepts POST.", method: http.MethodGet, path: "/killswitch", wantStatus: http.StatusMethodNotAllowed},
+		{name: "Reset only accepts POST.", method: http.MethodGet, path: "/killswitch/reset", wantStatus: http.StatusMethodNotAllowed},
+		{name: "Trigger with the token.", token: "secret", method: http.MethodPost, path: "/killswitch", header: "secret", wantStatus: http.StatusOK},
+		{name: "Trigger without the token is refused.", token: "secret", method: http.MethodPost, path: "/killswitch", wantStatus: http.StatusUnauthorized},
+		{name: "Trigger with a wrong token is refused.", token: "secret", method: http.MethodPost, path: "/killswitch", header: "guess", wantStatus: http.StatusUnauthorized},
+		{name: "Reset with the token.", token: "secret", method: http.MethodPost, path: "/killswitch/reset", header: "secret", wantStatus: http.StatusNoContent},
+		{name: "Reset without the token is refused.", token: "secret", method: http.MethodPost, path: "/killswitch/reset", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := killSwitchHandler(executor.NewExecutor(executor.LegRiskPolicy{}), tt.token)
+			request := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				request.Header.Set(killSwitchTokenHeader, tt.header)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, recorder.Code, tt.wantStatus)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package arbitrageHunter
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	spreadEntry = "entry"
+	spreadExit  = "exit"
+	shortSpread = "short_spread"
+	longSpread  = "long_spread"
+)
+
+/*
+An entry or exit of the spread mean-reversion strategy. Spread signals are recorded but not executed.
+*/
+type SpreadSignal struct {
+	Event bookkeeper.SpreadSignalRecord
+}
+
+func (s SpreadSignal) IsOpportunity() bool {
+	return s.Event.Action == spreadEntry
+}
+
+func (s SpreadSignal) AddTo(cycle *bookkeeper.Cycle) {
+	cycle.AddSpreadSignalRecords(s.Event)
+}
+
+/*
+Tracks the spread of every currency between every two exchanges and bets on it returning to its rolling mean.
+  - window: the number of previous spreads the mean and standard deviation are calculated over.
+  - minSamples: no signals are emitted until the window holds this many spreads.
+  - entryZScore: a position is entered when the z-score of the spread is at least this far from 0.
+  - exitZScore: the position is exited when the z-score is back within this distance of 0.
+*/
+type meanReversionStrategy struct {
+	mu          sync.Mutex
+	window      int
+	minSamples  int
+	entryZScore float64
+	exitZScore  float64
+	spreads     map[string][]float64
+	positions   map[string]bookkeeper.SpreadSignalRecord
+}
+
+func newMeanReversionStrategy(configKey string, context StrategyContext) (Strategy, error) {
+	strategy := &meanReversionStrategy{
+		window:      viper.GetInt(configKey + ".WINDOW"),
+		minSamples:  viper.GetInt(configKey + ".MIN_SAMPLES"),
+		entryZScore: viper.GetFloat64(configKey + ".ENTRY_Z_SCORE"),
+		exitZScore:  viper.GetFloat64(configKey + ".EXIT_Z_SCORE"),
+		spreads:     make(map[string][]float64),
+		positions:   make(map[string]bookkeeper.SpreadSignalRecord),
+	}
+	if strategy.minSamples < 2 || strategy.window < strategy.minSamples {
+		return nil, fmt.Errorf("%s.WINDOW must be at least %s.MIN_SAMPLES, which must be at least 2", configKey, configKey)
+	}
+	if strategy.exitZScore < 0 || strategy.entryZScore <= strategy.exitZScore {
+		return nil, fmt.Errorf("%s.ENTRY_Z_SCORE must be larger than %s.EXIT_Z_SCORE", configKey, configKey)
+	}
+	return strategy, nil
+}
+
+func (s *meanReversionStrategy) Name() string {
+	return "mean_reversion"
+}
+
+/*
+Add the spread of every currency quoted by two exchanges to its window and return the entries and exits.
+*/
+func (s *meanReversionStrategy) Evaluate(snapshot MarketSnapshot) []Signal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotes := snapshot.All()
+	sort.Slice(quotes, func(i, j int) bool {
+		if quotes[i].Currency != quotes[j].Currency {
+			return quotes[i].Currency < quotes[j].Currency
+		}
+		return quotes[i].Exchange < quotes[j].Exchange
+	})
+
+	var signals []Signal
+	for i := 0; i < len(quotes); i++ {
+		for j := i + 1; j < len(quotes) && quotes[j].Currency == quotes[i].Currency; j++ {
+			if quotes[i].Price <= 0 || quotes[j].Price <= 0 || quotes[i].Exchange == quotes[j].Exchange {
+				continue
+			}
+			if signal, ok := s.evaluatePair(snapshot.Timestamp, quotes[i], quotes[j]); ok {
+				signals = append(signals, signal)
+			}
+		}
+	}
+	return signals
+}
+
+func (s *meanReversionStrategy) evaluatePair(timestamp time.Time, quoteA bookkeeper.PriceRecord, quoteB bookkeeper.PriceRecord) (Signal, bool) {
+	key := pairKey(quoteA.Currency, quoteA.Exchange, quoteB.Exchange)
+	spread := (quoteA.Price - quoteB.Price) / quoteB.Price * 100
+	history := s.spreads[key]
+	s.spreads[key] = append(history, spread)
+	if len(s.spreads[key]) > s.window {
+		s.spreads[key] = s.spreads[key][len(s.spreads[key])-s.window:]
+	}
+	if len(history) < s.minSamples {
+		return nil, false
+	}
+
+	mean, standardDeviation := meanAndStandardDeviation(history)
+	if standardDeviation == 0 {
+		return nil, false
+	}
+	zScore := (spread - mean) / standardDeviation
+	record := bookkeeper.SpreadSignalRecord{
+		Uuid:              uuid.New(),
+		Timestamp:         timestamp.UTC(),
+		Currency:          quoteA.Currency,
+		ExchangeA:         quoteA.Exchange,
+		PriceA:            quoteA.Price,
+		ExchangeB:         quoteB.Exchange,
+		PriceB:            quoteB.Price,
+		Spread:            spread,
+		Mean:              mean,
+		StandardDeviation: standardDeviation,
+		ZScore:            zScore,
+	}
+
+	entry, open := s.positions[key]
+	switch {
+	case !open && math.Abs(zScore) >= s.entryZScore:
+		record.Action = spreadEntry
+		record.Direction = longSpread
+		if zScore > 0 {
+			record.Direction = shortSpread
+		}
+		s.positions[key] = record
+		utils.Logger.Info(fmt.Sprintf("Spread of %s between %s and %s is %.2f standard deviations from its mean. Entering %s.", record.Currency, record.ExchangeA, record.ExchangeB, zScore, record.Direction))
+	case open && entry.Direction == shortSpread && zScore <= s.exitZScore, open && entry.Direction == longSpread && zScore >= -s.exitZScore:
+		record.Action = spreadExit
+		record.Direction = entry.Direction
+		record.EntryUuid = entry.Uuid
+		record.SpreadChange = spread - entry.Spread
+		if entry.Direction == shortSpread {
+			record.SpreadChange = -record.SpreadChange
+		}
+		delete(s.positions, key)
+		utils.Logger.Info(fmt.Sprintf("Spread of %s between %s and %s reverted by %.4f%%. Exiting %s.", record.Currency, record.ExchangeA, record.ExchangeB, record.SpreadChange, record.Direction))
+	default:
+		return nil, false
+	}
+	return SpreadSignal{Event: record}, true
+}
+
+func meanAndStandardDeviation(values []float64) (float64, float64) {
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+
+	squares := 0.0
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)-1))
+}
@@ -0,0 +1,44 @@
+package arbitrageHunter
+
+import (
+	"cryptoArbitrageBot/inventory"
+	"github.com/spf13/viper"
+)
+
+/*
+Buys a currency on the exchange where it is cheaper and sells it on the exchange where it is more expensive. The trade
+size of every opportunity is capped by the inventory when it is available.
+  - OrderSize: the largest trade size (EXECUTION.ORDER_SIZE).
+  - MinTradeSize: opportunities the inventory allows less than this for are blocked (INVENTORY.MIN_TRADE_SIZE).
+*/
+type spatialStrategy struct {
+	orderSize    float64
+	minTradeSize float64
+	balances     *inventory.Inventory
+}
+
+func newSpatialStrategy(configKey string, context StrategyContext) (Strategy, error) {
+	return &spatialStrategy{
+		orderSize:    viper.GetFloat64("EXECUTION.ORDER_SIZE"),
+		minTradeSize: viper.GetFloat64("INVENTORY.MIN_TRADE_SIZE"),
+		balances:     context.Inventory,
+	}, nil
+}
+
+func (s *spatialStrategy) Name() string {
+	return "spatial"
+}
+
+/*
+Compare the price of every currency across all exchanges. Every compared pair of exchanges is a signal.
+*/
+func (s *spatialStrategy) Evaluate(snapshot MarketSnapshot) []Signal {
+	quotes := snapshot.All()
+	arbitrageRecords := capByInventory(isArbitrageOpportunity(snapshot.ByExchange()...), quotes, s.balances, s.orderSize, s.minTradeSize)
+
+	signals := make([]Signal, 0, len(arbitrageRecords))
+	for _, record := range arbitrageRecords {
+		signals = append(signals, ArbitrageSignal{Event: record})
+	}
+	return signals
+}
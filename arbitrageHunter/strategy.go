@@ -0,0 +1,180 @@
+package arbitrageHunter
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"cryptoArbitrageBot/inventory"
+	"fmt"
+	"github.com/spf13/viper"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+The latest quotes of every exchange at the time of one run of the Arbitrage Hunter job, keyed by exchange.
+*/
+type MarketSnapshot struct {
+	Timestamp time.Time
+	Quotes    map[string][]bookkeeper.PriceRecord
+}
+
+/*
+Create a snapshot of the quotes of several exchanges. Quotes are grouped by the exchange they belong to.
+*/
+func NewMarketSnapshot(timestamp time.Time, exchangeQuotes ...[]bookkeeper.PriceRecord) MarketSnapshot {
+	snapshot := MarketSnapshot{Timestamp: timestamp, Quotes: make(map[string][]bookkeeper.PriceRecord)}
+	for _, quotes := range exchangeQuotes {
+		for _, quote := range quotes {
+			snapshot.Quotes[quote.Exchange] = append(snapshot.Quotes[quote.Exchange], quote)
+		}
+	}
+	return snapshot
+}
+
+/*
+Returns the quotes of every exchange, ordered by exchange name.
+*/
+func (s MarketSnapshot) ByExchange() [][]bookkeeper.PriceRecord {
+	exchanges := make([]string, 0, len(s.Quotes))
+	for exchange := range s.Quotes {
+		exchanges = append(exchanges, exchange)
+	}
+	sort.Strings(exchanges)
+
+	byExchange := make([][]bookkeeper.PriceRecord, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		byExchange = append(byExchange, s.Quotes[exchange])
+	}
+	return byExchange
+}
+
+/*
+Returns the quotes of all exchanges.
+*/
+func (s MarketSnapshot) All() []bookkeeper.PriceRecord {
+	return flatten(s.ByExchange())
+}
+
+/*
+The result of evaluating a strategy. A signal is recorded whether or not it is an opportunity, so that the evaluations
+can be analysed later; only opportunities are executed. The concrete type of a signal tells how it is executed. AddTo
+adds the event of the signal to the cycle it was detected in, which is recorded as a whole.
+*/
+type Signal interface {
+	IsOpportunity() bool
+	AddTo(cycle *bookkeeper.Cycle)
+}
+
+/*
+A currency that can be bought on one exchange and sold on another.
+*/
+type ArbitrageSignal struct {
+	Event bookkeeper.ArbitrageEventRecord
+}
+
+func (s ArbitrageSignal) IsOpportunity() bool {
+	return s.Event.IsArbitrageOpportunity && !s.Event.IsBlockedByInventory
+}
+
+func (s ArbitrageSignal) AddTo(cycle *bookkeeper.Cycle) {
+	cycle.AddArbitrageEventRecords(s.Event)
+}
+
+/*
+A cycle of three trade pairs on one exchange. Fee is the taker fee of the exchange.
+*/
+type TriangularSignal struct {
+	Event bookkeeper.TriangularArbitrageEventRecord
+	Fee   float64
+}
+
+func (s TriangularSignal) IsOpportunity() bool {
+	return s.Event.IsTriangularArbitrageOpportunity
+}
+
+func (s TriangularSignal) AddTo(cycle *bookkeeper.Cycle) {
+	cycle.AddTriangularArbitrageEventRecords(s.Event)
+}
+
+/*
+A trading strategy. Evaluate is called with the quotes of every run of the Arbitrage Hunter job and returns the signals
+of the strategy.
+*/
+type Strategy interface {
+	Name() string
+	Evaluate(snapshot MarketSnapshot) []Signal
+}
+
+/*
+What strategies can use besides their configuration. Inventory is nil unless INVENTORY.ENABLED.
+*/
+type StrategyContext struct {
+	Inventory *inventory.Inventory
+}
+
+/*
+Creates a strategy from its configuration, which is found under the given key (e.g. STRATEGIES.SPATIAL).
+*/
+type StrategyFactory func(configKey string, context StrategyContext) (Strategy, error)
+
+var (
+	strategyRegistryMu sync.Mutex
+	strategyRegistry   = map[string]StrategyFactory{
+		"MEAN_REVERSION": newMeanReversionStrategy,
+		"SPATIAL":        newSpatialStrategy,
+		"TRIANGULAR":     newTriangularStrategy,
+	}
+)
+
+/*
+Register a strategy under the name of its configuration (STRATEGIES.<NAME>).
+*/
+func RegisterStrategy(name string, factory StrategyFactory) {
+	strategyRegistryMu.Lock()
+	defer strategyRegistryMu.Unlock()
+	strategyRegistry[strings.ToUpper(name)] = factory
+}
+
+/*
+Create every registered strategy that is enabled in the configuration file (STRATEGIES.<NAME>.ENABLED), ordered by
+name.
+*/
+func LoadStrategies(context StrategyContext) ([]Strategy, error) {
+	strategyRegistryMu.Lock()
+	defer strategyRegistryMu.Unlock()
+
+	names := make([]string, 0, len(strategyRegistry))
+	for name := range strategyRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var strategies []Strategy
+	for _, name := range names {
+		configKey := "STRATEGIES." + name
+		if !viper.GetBool(configKey + ".ENABLED") {
+			continue
+		}
+		strategy, err := strategyRegistry[name](configKey, context)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create strategy %s: %v", name, err)
+		}
+		strategies = append(strategies, strategy)
+		utils.Logger.Info(fmt.Sprintf("Strategy %s is enabled.", strategy.Name()))
+	}
+	return strategies, nil
+}
+
+/*
+Add the signals of a strategy to the cycle they were detected in and count its opportunities.
+*/
+func addSignals(cycle *bookkeeper.Cycle, signals []Signal) {
+	for _, signal := range signals {
+		signal.AddTo(cycle)
+		if signal.IsOpportunity() {
+			cycle.Snapshot.Opportunities++
+		}
+	}
+}
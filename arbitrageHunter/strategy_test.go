@@ -0,0 +1,239 @@
+package arbitrageHunter
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/inventory"
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+	"math"
+	"testing"
+	"time"
+)
+
+var snapshotForTest = NewMarketSnapshot(time.Now(),
+	[]bookkeeper.PriceRecord{{Currency: "BTCUSD", Exchange: "Coinbase", Price: 25000, Fee: .001}},
+	[]bookkeeper.PriceRecord{
+		{Currency: "BTCUSD", Exchange: "Gemini", Price: 26000, Fee: .001},
+		{Currency: "ETHBTC", Exchange: "Gemini", Price: .06},
+		{Currency: "LTCETH", Exchange: "Gemini", Price: .04776},
+		{Currency: "LTCBTC", Exchange: "Gemini", Price: .003144},
+	},
+	[]bookkeeper.PriceRecord{{Currency: "BTCUSD", Exchange: "Kraken", Price: 25990, Fee: .001}},
+)
+
+type fakeStrategy struct {
+	name string
+}
+
+func (f *fakeStrategy) Name() string {
+	return f.name
+}
+
+func (f *fakeStrategy) Evaluate(snapshot MarketSnapshot) []Signal {
+	return nil
+}
+
+func Test_LoadStrategies(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	RegisterStrategy("fake", func(configKey string, context StrategyContext) (Strategy, error) {
+		return &fakeStrategy{name: configKey}, nil
+	})
+
+	tests := []struct {
+		name      string
+		enabled   map[string]bool
+		wantNames []string
+	}{
+		{
+			name:      "Only enabled strategies are created, ordered by name.",
+			enabled:   map[string]bool{"FAKE": true, "SPATIAL": true, "TRIANGULAR": false},
+			wantNames: []string{"STRATEGIES.FAKE", "spatial"},
+		},
+		{
+			name:    "Every strategy can be disabled.",
+			enabled: map[string]bool{"FAKE": false, "SPATIAL": false, "TRIANGULAR": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, enabled := range tt.enabled {
+				viper.Set("STRATEGIES."+name+".ENABLED", enabled)
+			}
+			strategies, err := LoadStrategies(StrategyContext{})
+			assert.Equal(t, err, nil)
+
+			var names []string
+			for _, strategy := range strategies {
+				names = append(names, strategy.Name())
+			}
+			assert.Equal(t, names, tt.wantNames)
+		})
+	}
+	viper.Set("STRATEGIES.FAKE.ENABLED", false)
+}
+
+func Test_SpatialStrategy(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name              string
+		balances          map[string]map[string]float64
+		wantSignals       int
+		wantOpportunities int
+	}{
+		{
+			name:              "Every pair of exchanges quoting the same currency is a signal.",
+			wantSignals:       3,
+			wantOpportunities: 2,
+		},
+		{
+			name:              "Opportunities blocked by inventory are not executed.",
+			balances:          map[string]map[string]float64{"Coinbase": {"USD": 0}, "Gemini": {"BTC": 1}, "Kraken": {"USD": 100000, "BTC": 1}},
+			wantSignals:       3,
+			wantOpportunities: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var balances *inventory.Inventory
+			if tt.balances != nil {
+				balances = inventory.NewInventory()
+				for exchange, exchangeBalances := range tt.balances {
+					balances.SetBalances(exchange, exchangeBalances)
+				}
+			}
+			strategy := &spatialStrategy{orderSize: .01, minTradeSize: .001, balances: balances}
+
+			signals := strategy.Evaluate(snapshotForTest)
+			assert.Equal(t, len(signals), tt.wantSignals)
+			opportunities := 0
+			for _, signal := range signals {
+				if signal.IsOpportunity() {
+					opportunities++
+					assert.Equal(t, signal.(ArbitrageSignal).Event.TradeSize, .01)
+				}
+			}
+			assert.Equal(t, opportunities, tt.wantOpportunities)
+		})
+	}
+}
+
+func Test_TriangularStrategy(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name            string
+		exchange        string
+		wantSignals     int
+		wantOpportunity bool
+	}{
+		{
+			name:            "Exchange quoting all three trade pairs.",
+			exchange:        "Gemini",
+			wantSignals:     1,
+			wantOpportunity: true,
+		},
+		{
+			name:     "Exchange without the trade pairs of the cycle.",
+			exchange: "Kraken",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signals := (&triangularStrategy{exchange: tt.exchange}).Evaluate(snapshotForTest)
+			assert.Equal(t, len(signals), tt.wantSignals)
+			if len(signals) > 0 {
+				assert.Equal(t, signals[0].IsOpportunity(), tt.wantOpportunity)
+				assert.Equal(t, signals[0].(TriangularSignal).Event.Exchange, tt.exchange)
+			}
+		})
+	}
+}
+
+func Test_MeanReversionStrategy(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	// Coinbase quotes BTCUSD 10 above or below Kraken, alternating, before the spread moves
+	spreadsForTest := func(spreads ...float64) []float64 {
+		var prices []float64
+		for i := 0; i < 20; i++ {
+			prices = append(prices, 10*float64(1-2*(i%2)))
+		}
+		return append(prices, spreads...)
+	}
+
+	tests := []struct {
+		name        string
+		spreads     []float64
+		wantActions []string
+		wantChange  float64
+	}{
+		{
+			name:        "Steady spread emits no signals.",
+			spreads:     spreadsForTest(10, -10),
+			wantActions: nil,
+		},
+		{
+			name:        "Spread above its mean is shorted and exited once it reverts.",
+			spreads:     spreadsForTest(100, 60, 0),
+			wantActions: []string{"entry short_spread", "exit short_spread"},
+			wantChange:  .4,
+		},
+		{
+			name:        "Spread below its mean is bought and exited once it reverts.",
+			spreads:     spreadsForTest(-100, 0),
+			wantActions: []string{"entry long_spread", "exit long_spread"},
+			wantChange:  .4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := &meanReversionStrategy{window: 20, minSamples: 10, entryZScore: 2, exitZScore: .5, spreads: make(map[string][]float64), positions: make(map[string]bookkeeper.SpreadSignalRecord)}
+
+			var actions []string
+			var change float64
+			start := time.Now()
+			for i, spread := range tt.spreads {
+				snapshot := NewMarketSnapshot(start.Add(time.Duration(i)*5*time.Second),
+					[]bookkeeper.PriceRecord{{Currency: "BTCUSD", Exchange: "Coinbase", Price: 25000 + spread}},
+					[]bookkeeper.PriceRecord{{Currency: "BTCUSD", Exchange: "Kraken", Price: 25000}},
+				)
+				for _, signal := range strategy.Evaluate(snapshot) {
+					event := signal.(SpreadSignal).Event
+					assert.Equal(t, event.ExchangeA, "Coinbase")
+					actions = append(actions, event.Action+" "+event.Direction)
+					change = event.SpreadChange
+				}
+			}
+			assert.Equal(t, actions, tt.wantActions)
+			assert.Equal(t, math.Round(change*1000)/1000, tt.wantChange)
+		})
+	}
+}
+
+func Test_addSignals(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	t.Run("Signals are added to their cycle and its opportunities are counted.", func(t *testing.T) {
+		cycle := bookkeeper.NewCycle(snapshotForTest.Timestamp)
+		addSignals(cycle, []Signal{
+			ArbitrageSignal{Event: bookkeeper.ArbitrageEventRecord{Currency: "BTCUSD", IsArbitrageOpportunity: true}},
+			ArbitrageSignal{Event: bookkeeper.ArbitrageEventRecord{Currency: "BTCUSD", IsArbitrageOpportunity: true, IsBlockedByInventory: true}},
+			TriangularSignal{Event: bookkeeper.TriangularArbitrageEventRecord{Exchange: "Gemini"}},
+			SpreadSignal{Event: bookkeeper.SpreadSignalRecord{Currency: "BTCUSD", Action: spreadEntry}},
+		})
+
+		assert.Equal(t, cycle.Snapshot.ArbitrageEvents, 2)
+		assert.Equal(t, cycle.Snapshot.TriangularArbitrageEvents, 1)
+		assert.Equal(t, cycle.Snapshot.SpreadSignals, 1)
+		assert.Equal(t, cycle.Snapshot.Opportunities, 2)
+		assert.Equal(t, cycle.ArbitrageEventRecords[1].CycleUuid, cycle.Snapshot.Uuid)
+		assert.Equal(t, cycle.SpreadSignalRecords[0].CycleUuid, cycle.Snapshot.Uuid)
+	})
+}
@@ -0,0 +1,303 @@
+package arbitrageHunter
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+/*
+The backtest parameters a sweep can vary, keyed by their name in the configuration file (SWEEP.PARAMETERS).
+*/
+var sweepParameters = map[string]func(config *BacktestConfig, value float64){
+	"MIN_PROJECTED_PROFIT": func(config *BacktestConfig, value float64) {
+		config.MinProjectedProfit = value
+	},
+	"MAX_QUOTE_AGE_SECONDS": func(config *BacktestConfig, value float64) {
+		config.MaxQuoteAge = time.Duration(value * float64(time.Second))
+	},
+	"ORDER_SIZE": func(config *BacktestConfig, value float64) {
+		config.OrderSize = value
+	},
+	"LATENCY_MILLISECONDS": func(config *BacktestConfig, value float64) {
+		config.Latency = time.Duration(value * float64(time.Millisecond))
+	},
+}
+
+/*
+Configuration of a parameter sweep.
+  - Parameters: the values of every varied parameter, keyed by the names in sweepParameters. Parameters that are not
+    varied keep their value from the backtest configuration.
+  - Random: instead of every combination of the values (grid search), evaluate Samples combinations drawn uniformly
+    between the smallest and the largest value of every parameter.
+  - Workers: the number of backtests run in parallel, 0 uses every CPU core.
+  - RankBy: "pnl" (highest first), "hit_rate" (highest first) or "drawdown" (lowest first). PnL and drawdown are in the
+    reference currency of the backtest.
+  - Top: the number of results printed, 0 prints all.
+*/
+type SweepConfig struct {
+	Parameters map[string][]float64
+	Random     bool
+	Samples    int
+	Seed       int64
+	Workers    int
+	RankBy     string
+	Top        int
+}
+
+/*
+Load the sweep configuration from the configuration file (SWEEP).
+*/
+func LoadSweepConfig() (SweepConfig, error) {
+	config := SweepConfig{
+		Random:  strings.ToLower(viper.GetString("SWEEP.MODE")) == "random",
+		Samples: viper.GetInt("SWEEP.SAMPLES"),
+		Seed:    viper.GetInt64("SWEEP.SEED"),
+		Workers: viper.GetInt("SWEEP.WORKERS"),
+		RankBy:  strings.ToLower(viper.GetString("SWEEP.RANK_BY")),
+		Top:     viper.GetInt("SWEEP.TOP"),
+	}
+	var parameters map[string][]float64
+	if err := viper.UnmarshalKey("SWEEP.PARAMETERS", &parameters); err != nil {
+		return config, fmt.Errorf("invalid SWEEP.PARAMETERS: %v", err)
+	}
+	config.Parameters = make(map[string][]float64)
+	for name, values := range parameters {
+		config.Parameters[strings.ToUpper(name)] = values
+	}
+	return config, nil
+}
+
+/*
+The parameters of one backtest of a sweep and its results. HitRate is the share of the opportunities that were executed
+with a profit.
+*/
+type SweepResult struct {
+	Parameters map[string]float64
+	Report     BacktestReport
+	HitRate    float64
+}
+
+/*
+Returns the combinations of parameter values a sweep evaluates.
+*/
+func sweepCombinations(config SweepConfig) ([]map[string]float64, error) {
+	names := make([]string, 0, len(config.Parameters))
+	for name, values := range config.Parameters {
+		if _, ok := sweepParameters[name]; !ok {
+			return nil, fmt.Errorf("unknown sweep parameter %s", name)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("sweep parameter %s has no values", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if config.Random {
+		random := rand.New(rand.NewSource(config.Seed))
+		combinations := make([]map[string]float64, 0, config.Samples)
+		for i := 0; i < config.Samples; i++ {
+			combination := make(map[string]float64)
+			for _, name := range names {
+				low, high := config.Parameters[name][0], config.Parameters[name][0]
+				for _, value := range config.Parameters[name] {
+					low, high = minFloat(low, value), maxFloat(high, value)
+				}
+				combination[name] = low + random.Float64()*(high-low)
+			}
+			combinations = append(combinations, combination)
+		}
+		return combinations, nil
+	}
+
+	combinations := []map[string]float64{{}}
+	for _, name := range names {
+		var extended []map[string]float64
+		for _, combination := range combinations {
+			for _, value := range config.Parameters[name] {
+				next := map[string]float64{name: value}
+				for otherName, otherValue := range combination {
+					next[otherName] = otherValue
+				}
+				extended = append(extended, next)
+			}
+		}
+		combinations = extended
+	}
+	return combinations, nil
+}
+
+func minFloat(a float64, b float64) float64 {
+	if b < a {
+		return b
+	}
+	return a
+}
+
+func maxFloat(a float64, b float64) float64 {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+/*
+Backtest every combination of parameters of the sweep on the same quotes in parallel and return the results ranked by
+the configured metric. Every backtest replays the quotes through the strategies returned by a call of newStrategies.
+*/
+func RunSweep(quotes []bookkeeper.PriceRecord, base BacktestConfig, config SweepConfig, newStrategies func() ([]Strategy, error)) ([]SweepResult, error) {
+	combinations, err := sweepCombinations(config)
+	if err != nil {
+		return nil, err
+	}
+	workers := config.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([]SweepResult, len(combinations))
+	errs := make([]error, len(combinations))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = backtestCombination(quotes, base, combinations[i], newStrategies)
+			}
+		}()
+	}
+	for i := range combinations {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := rankSweepResults(results, config.RankBy); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func backtestCombination(quotes []bookkeeper.PriceRecord, base BacktestConfig, parameters map[string]float64, newStrategies func() ([]Strategy, error)) (SweepResult, error) {
+	config := base
+	for name, value := range parameters {
+		sweepParameters[name](&config, value)
+	}
+	strategies, err := newStrategies()
+	if err != nil {
+		return SweepResult{}, err
+	}
+	backtester := NewBacktester(config, strategies...)
+	for _, quote := range quotes {
+		if err := backtester.Replay(quote); err != nil {
+			return SweepResult{}, err
+		}
+	}
+
+	result := SweepResult{Parameters: parameters, Report: backtester.Finish()}
+	if result.Report.Opportunities > 0 {
+		result.HitRate = float64(result.Report.Profitable) / float64(result.Report.Opportunities)
+	}
+	return result, nil
+}
+
+/*
+Sort the results by the metric, ties are ranked by PnL. PnL and drawdown are compared in the reference currency, PnL
+that could not be converted to it is not ranked.
+*/
+func rankSweepResults(results []SweepResult, rankBy string) error {
+	var better func(a SweepResult, b SweepResult) bool
+	switch rankBy {
+	case "", "pnl":
+		better = func(a SweepResult, b SweepResult) bool { return a.Report.SimulatedPnl > b.Report.SimulatedPnl }
+	case "hit_rate":
+		better = func(a SweepResult, b SweepResult) bool { return a.HitRate > b.HitRate }
+	case "drawdown":
+		better = func(a SweepResult, b SweepResult) bool { return a.Report.MaxDrawdown < b.Report.MaxDrawdown }
+	default:
+		return fmt.Errorf("unknown sweep ranking %s, expected pnl, hit_rate or drawdown", rankBy)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if better(results[i], results[j]) != better(results[j], results[i]) {
+			return better(results[i], results[j])
+		}
+		return results[i].Report.SimulatedPnl > results[j].Report.SimulatedPnl
+	})
+	return nil
+}
+
+/*
+Run a parameter sweep of the enabled strategies over the price history in the database, or over the quotes in a file if a path is given, and print
+the ranked results.
+*/
+func Sweep(path string) error {
+	base := LoadBacktestConfig()
+	config, err := LoadSweepConfig()
+	if err != nil {
+		return err
+	}
+
+	// Every backtest replays the same quotes, they are loaded once
+	var quotes []bookkeeper.PriceRecord
+	err = streamQuotes(path, base, func(quote bookkeeper.PriceRecord) error {
+		quotes = append(quotes, quote)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	results, err := RunSweep(quotes, base, config, func() ([]Strategy, error) {
+		return LoadStrategies(StrategyContext{})
+	})
+	if err != nil {
+		return err
+	}
+	utils.Logger.Info(fmt.Sprintf("Sweep backtested %d parameter combinations on %d quotes.", len(results), len(quotes)))
+	printSweepResults(os.Stdout, results, config.Top)
+	return nil
+}
+
+func printSweepResults(writer io.Writer, results []SweepResult, top int) {
+	if len(results) == 0 {
+		fmt.Fprintln(writer, "No parameter combinations to sweep.")
+		return
+	}
+	if top > 0 && top < len(results) {
+		results = results[:top]
+	}
+	names := make([]string, 0, len(results[0].Parameters))
+	for name := range results[0].Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "RANK\t%s\tOPPORTUNITIES\tEXECUTED\tHIT RATE\tMAX DRAWDOWN\tSIMULATED PNL\n", strings.Join(names, "\t"))
+	for i, result := range results {
+		values := make([]string, 0, len(names))
+		for _, name := range names {
+			values = append(values, fmt.Sprintf("%g", result.Parameters[name]))
+		}
+		fmt.Fprintf(table, "%d\t%s\t%d\t%d\t%.2f\t%.2f\t%.2f\n", i+1, strings.Join(values, "\t"), result.Report.Opportunities, result.Report.Executed, result.HitRate, result.Report.MaxDrawdown, result.Report.SimulatedPnl)
+	}
+	table.Flush()
+}
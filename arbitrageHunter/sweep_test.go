@@ -0,0 +1,111 @@
+package arbitrageHunter
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"github.com/magiconair/properties/assert"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_SweepCombinations(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name             string
+		config           SweepConfig
+		wantCombinations int
+		wantErr          bool
+	}{
+		{
+			name:             "Grid search evaluates every combination.",
+			config:           SweepConfig{Parameters: map[string][]float64{"MIN_PROJECTED_PROFIT": {0, .1, .5}, "LATENCY_MILLISECONDS": {250, 500}}},
+			wantCombinations: 6,
+		},
+		{
+			name:             "Random search draws the configured number of samples.",
+			config:           SweepConfig{Parameters: map[string][]float64{"MIN_PROJECTED_PROFIT": {0, .5}, "ORDER_SIZE": {.001, .01}}, Random: true, Samples: 25},
+			wantCombinations: 25,
+		},
+		{
+			name:             "Without parameters the backtest configuration is evaluated once.",
+			config:           SweepConfig{},
+			wantCombinations: 1,
+		},
+		{
+			name:    "Unknown parameters are rejected.",
+			config:  SweepConfig{Parameters: map[string][]float64{"FEE": {.001}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			combinations, err := sweepCombinations(tt.config)
+			assert.Equal(t, err != nil, tt.wantErr)
+			assert.Equal(t, len(combinations), tt.wantCombinations)
+			for _, combination := range combinations {
+				for name, value := range combination {
+					low, high := math.Inf(1), math.Inf(-1)
+					for _, configured := range tt.config.Parameters[name] {
+						low, high = math.Min(low, configured), math.Max(high, configured)
+					}
+					assert.Equal(t, value >= low && value <= high, true)
+				}
+			}
+		})
+	}
+}
+
+func Test_RunSweep(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	path := filepath.Join(t.TempDir(), "quotes.csv")
+	if err := os.WriteFile(path, []byte(quotesForTest), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var quotes []bookkeeper.PriceRecord
+	err := ReadQuoteFile(path, func(quote bookkeeper.PriceRecord) error {
+		quotes = append(quotes, quote)
+		return nil
+	})
+	assert.Equal(t, err, nil)
+
+	parameters := map[string][]float64{"LATENCY_MILLISECONDS": {0, 1000}, "MIN_PROJECTED_PROFIT": {0, 5}}
+	tests := []struct {
+		name           string
+		rankBy         string
+		wantParameters map[string]float64
+		wantPnl        float64
+		wantHitRate    float64
+	}{
+		{
+			name:           "Ranked by PnL the parameters without latency win.",
+			rankBy:         "pnl",
+			wantParameters: map[string]float64{"LATENCY_MILLISECONDS": 0, "MIN_PROJECTED_PROFIT": 0},
+			wantPnl:        18.98,
+			wantHitRate:    1,
+		},
+		{
+			name:           "Ranked by drawdown the most profitable parameters without drawdown win.",
+			rankBy:         "drawdown",
+			wantParameters: map[string]float64{"LATENCY_MILLISECONDS": 0, "MIN_PROJECTED_PROFIT": 0},
+			wantPnl:        18.98,
+			wantHitRate:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := RunSweep(quotes, BacktestConfig{OrderSize: .01}, SweepConfig{Parameters: parameters, Workers: 2, RankBy: tt.rankBy}, func() ([]Strategy, error) {
+				return []Strategy{&spatialStrategy{}}, nil
+			})
+			assert.Equal(t, err, nil)
+			assert.Equal(t, len(results), 4)
+			assert.Equal(t, results[0].Parameters, tt.wantParameters)
+			assert.Equal(t, math.Round(results[0].Report.SimulatedPnl*100)/100, tt.wantPnl)
+			assert.Equal(t, results[0].HitRate, tt.wantHitRate)
+		})
+	}
+}
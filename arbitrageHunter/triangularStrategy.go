@@ -0,0 +1,37 @@
+package arbitrageHunter
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+)
+
+/*
+Trades the ETHBTC -> LTCETH -> LTCBTC cycle on one exchange when the cross exchange rate differs from the direct one by
+more than the fees of three trades.
+*/
+type triangularStrategy struct {
+	exchange string
+}
+
+func newTriangularStrategy(configKey string, context StrategyContext) (Strategy, error) {
+	exchange := viper.GetString(configKey + ".EXCHANGE")
+	if exchange == "" {
+		return nil, fmt.Errorf("%s.EXCHANGE is not configured", configKey)
+	}
+	return &triangularStrategy{exchange: exchange}, nil
+}
+
+func (s *triangularStrategy) Name() string {
+	return "triangular"
+}
+
+/*
+Returns a signal if the exchange quoted all three trade pairs of the cycle.
+*/
+func (s *triangularStrategy) Evaluate(snapshot MarketSnapshot) []Signal {
+	quotes := snapshot.Quotes[s.exchange]
+	if !hasTriangularPairs(quotes) {
+		return nil
+	}
+	return []Signal{TriangularSignal{Event: isTrangularArbitrage1Exchange(quotes), Fee: quotes[0].Fee}}
+}
@@ -0,0 +1,287 @@
+package bookkeeper
+
+import (
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/spf13/viper"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+Configuration of the BatchWriter.
+  - BatchSize: a table is written as soon as this many of its records are pending.
+  - FlushInterval: pending records are written at least this often.
+  - QueueSize: the number of writes that can wait for the BatchWriter before it falls behind.
+  - DropWhenFull: when the BatchWriter has fallen behind, drop new records instead of blocking the caller until the
+    database catches up.
+*/
+type BatchWriterConfig struct {
+	BatchSize     int
+	FlushInterval time.Duration
+	QueueSize     int
+	DropWhenFull  bool
+}
+
+/*
+Load the BatchWriter configuration from the configuration file (DATABASE.BATCH_WRITER).
+*/
+func LoadBatchWriterConfig() BatchWriterConfig {
+	return BatchWriterConfig{
+		BatchSize:     viper.GetInt("DATABASE.BATCH_WRITER.BATCH_SIZE"),
+		FlushInterval: time.Duration(viper.GetInt("DATABASE.BATCH_WRITER.FLUSH_INTERVAL_MILLISECONDS")) * time.Millisecond,
+		QueueSize:     viper.GetInt("DATABASE.BATCH_WRITER.QUEUE_SIZE"),
+		DropWhenFull:  viper.GetBool("DATABASE.BATCH_WRITER.DROP_WHEN_FULL"),
+	}
+}
+
+/*
+Counters of the records that went through a BatchWriter.
+*/
+type BatchWriterStats struct {
+	Written uint64
+	Dropped uint64
+	Failed  uint64
+	Flushes uint64
+}
+
+/*
+A write waiting for the BatchWriter. A write without records asks for the pending records to be written.
+*/
+type batchWrite struct {
+	priceRecords                    []PriceRecord
+	arbitrageEventRecords           []ArbitrageEventRecord
+	triangularArbitrageEventRecords []TriangularArbitrageEventRecord
+	cycles                          []Cycle
+	flushed                         chan struct{}
+}
+
+func (b batchWrite) size() int {
+	return len(b.priceRecords) + len(b.arbitrageEventRecords) + len(b.triangularArbitrageEventRecords) + cyclesSize(b.cycles)
+}
+
+func cyclesSize(cycles []Cycle) int {
+	size := 0
+	for _, cycle := range cycles {
+		size += cycle.size()
+	}
+	return size
+}
+
+/*
+Writes quotes and arbitrage events to another storage in the background, in one multi-row insert per table, so that the
+Arbitrage Hunter job does not wait for the database. Pending cycles are written together, each in its own transaction.
+Orders are passed through immediately, they are read back while they are executed.
+*/
+type BatchWriter struct {
+	storage Storage
+	config  BatchWriterConfig
+	queue   chan batchWrite
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+	written uint64
+	dropped uint64
+	failed  uint64
+	flushes uint64
+}
+
+/*
+Create a BatchWriter for the given storage and start writing in the background.
+*/
+func NewBatchWriter(storage Storage, config BatchWriterConfig) *BatchWriter {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1000
+	}
+	w := &BatchWriter{
+		storage: storage,
+		config:  config,
+		queue:   make(chan batchWrite, config.QueueSize),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+/*
+Write all records of the bookkeeper through a BatchWriter for the current storage. Close the returned BatchWriter on
+shutdown, so that the pending records are written.
+*/
+func StartBatchWriter(config BatchWriterConfig) *BatchWriter {
+	w := NewBatchWriter(currentStorage(), config)
+	UseStorage(w)
+	utils.Logger.Info(fmt.Sprintf("Writing to the database in batches of %d records at least every %v.", w.config.BatchSize, w.config.FlushInterval))
+	return w
+}
+
+func (w *BatchWriter) RecordPriceRecords(priceRecords []PriceRecord) error {
+	return w.enqueue(batchWrite{priceRecords: priceRecords})
+}
+
+func (w *BatchWriter) StreamPriceRecords(from time.Time, to time.Time, handle func(PriceRecord) error) error {
+	return w.storage.StreamPriceRecords(from, to, handle)
+}
+
+func (w *BatchWriter) RecordArbitrageRecords(arbitrageEventRecords []ArbitrageEventRecord) error {
+	return w.enqueue(batchWrite{arbitrageEventRecords: arbitrageEventRecords})
+}
+
+func (w *BatchWriter) RecordTriangularArbitrageRecords(triangularArbitrageEventRecords []TriangularArbitrageEventRecord) error {
+	return w.enqueue(batchWrite{triangularArbitrageEventRecords: triangularArbitrageEventRecords})
+}
+
+func (w *BatchWriter) RecordCycles(cycles []Cycle) error {
+	return w.enqueue(batchWrite{cycles: cycles})
+}
+
+func (w *BatchWriter) RecordOrderRecord(orderRecord OrderRecord) error {
+	return w.storage.RecordOrderRecord(orderRecord)
+}
+
+func (w *BatchWriter) UpdateOrderRecord(orderRecord OrderRecord) error {
+	return w.storage.UpdateOrderRecord(orderRecord)
+}
+
+func (w *BatchWriter) GetOrderRecords(states ...string) ([]OrderRecord, error) {
+	return w.storage.GetOrderRecords(states...)
+}
+
+/*
+Hand a write to the background writer. When the queue is full the caller waits, or the records are dropped if
+DropWhenFull.
+*/
+func (w *BatchWriter) enqueue(write batchWrite) error {
+	if write.size() == 0 {
+		return nil
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return fmt.Errorf("the batch writer is closed, %d records were not written", write.size())
+	}
+	if !w.config.DropWhenFull {
+		w.queue <- write
+		return nil
+	}
+	select {
+	case w.queue <- write:
+		return nil
+	default:
+		dropped := atomic.AddUint64(&w.dropped, uint64(write.size()))
+		utils.Logger.Warn(fmt.Sprintf("The database is falling behind. Dropped %d records, %d in total.", write.size(), dropped))
+		return nil
+	}
+}
+
+/*
+Write all pending records and wait until they are written.
+*/
+func (w *BatchWriter) Flush() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return
+	}
+	flushed := make(chan struct{})
+	w.queue <- batchWrite{flushed: flushed}
+	<-flushed
+}
+
+/*
+Stop accepting records, write the pending ones and wait until they are written.
+*/
+func (w *BatchWriter) Close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	<-w.done
+
+	stats := w.Stats()
+	utils.Logger.Info(fmt.Sprintf("Batch writer stopped. Written %d records in %d flushes, %d failed, %d dropped.", stats.Written, stats.Flushes, stats.Failed, stats.Dropped))
+}
+
+/*
+Returns the counters of the records that went through the BatchWriter.
+*/
+func (w *BatchWriter) Stats() BatchWriterStats {
+	return BatchWriterStats{
+		Written: atomic.LoadUint64(&w.written),
+		Dropped: atomic.LoadUint64(&w.dropped),
+		Failed:  atomic.LoadUint64(&w.failed),
+		Flushes: atomic.LoadUint64(&w.flushes),
+	}
+}
+
+func (w *BatchWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	var pending batchWrite
+	for {
+		select {
+		case write, ok := <-w.queue:
+			if !ok {
+				w.flush(&pending, 0)
+				return
+			}
+			if write.flushed != nil {
+				w.flush(&pending, 0)
+				close(write.flushed)
+				continue
+			}
+			pending.priceRecords = append(pending.priceRecords, write.priceRecords...)
+			pending.arbitrageEventRecords = append(pending.arbitrageEventRecords, write.arbitrageEventRecords...)
+			pending.triangularArbitrageEventRecords = append(pending.triangularArbitrageEventRecords, write.triangularArbitrageEventRecords...)
+			pending.cycles = append(pending.cycles, write.cycles...)
+			w.flush(&pending, w.config.BatchSize)
+		case <-ticker.C:
+			w.flush(&pending, 0)
+		}
+	}
+}
+
+/*
+Write the pending records of every table that has at least minRecords of them, and the pending cycles once they have
+at least minRecords rows. Records that fail to be written are
+counted and dropped, so that a database outage does not grow the memory of the bot.
+*/
+func (w *BatchWriter) flush(pending *batchWrite, minRecords int) {
+	if len(pending.priceRecords) > 0 && len(pending.priceRecords) >= minRecords {
+		w.write("price_records", len(pending.priceRecords), w.storage.RecordPriceRecords(pending.priceRecords))
+		pending.priceRecords = nil
+	}
+	if len(pending.arbitrageEventRecords) > 0 && len(pending.arbitrageEventRecords) >= minRecords {
+		w.write("arbitrage_records", len(pending.arbitrageEventRecords), w.storage.RecordArbitrageRecords(pending.arbitrageEventRecords))
+		pending.arbitrageEventRecords = nil
+	}
+	if len(pending.triangularArbitrageEventRecords) > 0 && len(pending.triangularArbitrageEventRecords) >= minRecords {
+		w.write("triangular_arbitrage_1_exchange", len(pending.triangularArbitrageEventRecords), w.storage.RecordTriangularArbitrageRecords(pending.triangularArbitrageEventRecords))
+		pending.triangularArbitrageEventRecords = nil
+	}
+	if size := cyclesSize(pending.cycles); size > 0 && size >= minRecords {
+		w.write("cycle_snapshots", size, w.storage.RecordCycles(pending.cycles))
+		pending.cycles = nil
+	}
+}
+
+func (w *BatchWriter) write(table string, records int, err error) {
+	atomic.AddUint64(&w.flushes, 1)
+	if err != nil {
+		failed := atomic.AddUint64(&w.failed, uint64(records))
+		utils.Logger.Error(fmt.Sprintf("Failed to write %d records to %s, %d failed in total: %v", records, table, failed, err))
+		return
+	}
+	atomic.AddUint64(&w.written, uint64(records))
+	utils.Logger.Debug(fmt.Sprintf("Wrote %d records to %s.", records, table))
+}
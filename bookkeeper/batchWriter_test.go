@@ -0,0 +1,198 @@
+package bookkeeper
+
+import (
+	"fmt"
+	"github.com/magiconair/properties/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+Counts the inserts of every table. Writes wait until release is closed, if it is set.
+*/
+type fakeStorage struct {
+	mu      sync.Mutex
+	release chan struct{}
+	fail    bool
+	inserts map[string][]int
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{inserts: make(map[string][]int)}
+}
+
+func (f *fakeStorage) insert(table string, records int) error {
+	if f.release != nil {
+		<-f.release
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return fmt.Errorf("database is down")
+	}
+	f.inserts[table] = append(f.inserts[table], records)
+	return nil
+}
+
+func (f *fakeStorage) insertsOf(table string) []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.inserts[table]
+}
+
+func (f *fakeStorage) RecordPriceRecords(priceRecords []PriceRecord) error {
+	return f.insert("price_records", len(priceRecords))
+}
+
+func (f *fakeStorage) StreamPriceRecords(from time.Time, to time.Time, handle func(PriceRecord) error) error {
+	return nil
+}
+
+func (f *fakeStorage) RecordArbitrageRecords(arbitrageEventRecords []ArbitrageEventRecord) error {
+	return f.insert("arbitrage_records", len(arbitrageEventRecords))
+}
+
+func (f *fakeStorage) RecordTriangularArbitrageRecords(triangularArbitrageEventRecords []TriangularArbitrageEventRecord) error {
+	return f.insert("triangular_arbitrage_1_exchange", len(triangularArbitrageEventRecords))
+}
+
+func (f *fakeStorage) RecordCycles(cycles []Cycle) error {
+	return f.insert("cycle_snapshots", len(cycles))
+}
+
+func (f *fakeStorage) RecordOrderRecord(orderRecord OrderRecord) error {
+	return f.insert("order_records", 1)
+}
+
+func (f *fakeStorage) UpdateOrderRecord(orderRecord OrderRecord) error {
+	return nil
+}
+
+func (f *fakeStorage) GetOrderRecords(states ...string) ([]OrderRecord, error) {
+	return nil, nil
+}
+
+func Test_BatchWriter(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name             string
+		config           BatchWriterConfig
+		writes           int
+		arbitrageRecords int
+		flush            bool
+		wantPriceWrites  []int
+		wantArbitrage    []int
+	}{
+		{
+			name:            "Records are written in batches of the batch size.",
+			config:          BatchWriterConfig{BatchSize: 6, FlushInterval: time.Hour},
+			writes:          4,
+			wantPriceWrites: []int{6, 6},
+		},
+		{
+			name:             "A flush writes the pending records of every table.",
+			config:           BatchWriterConfig{BatchSize: 100, FlushInterval: time.Hour},
+			writes:           2,
+			arbitrageRecords: 1,
+			flush:            true,
+			wantPriceWrites:  []int{6},
+			wantArbitrage:    []int{2},
+		},
+		{
+			name:             "Pending records are written after the flush interval.",
+			config:           BatchWriterConfig{BatchSize: 100, FlushInterval: 10 * time.Millisecond},
+			writes:           1,
+			arbitrageRecords: 1,
+			wantPriceWrites:  []int{3},
+			wantArbitrage:    []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newFakeStorage()
+			writer := NewBatchWriter(storage, tt.config)
+			defer writer.Close()
+			for i := 0; i < tt.writes; i++ {
+				assert.Equal(t, writer.RecordPriceRecords(make([]PriceRecord, 3)), nil)
+				assert.Equal(t, writer.RecordArbitrageRecords(make([]ArbitrageEventRecord, tt.arbitrageRecords)), nil)
+			}
+			if tt.flush {
+				writer.Flush()
+			}
+			time.Sleep(50 * time.Millisecond)
+			assert.Equal(t, storage.insertsOf("price_records"), tt.wantPriceWrites)
+			assert.Equal(t, storage.insertsOf("arbitrage_records"), tt.wantArbitrage)
+		})
+	}
+}
+
+func Test_BatchWriterBackpressure(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	t.Run("A slow database drops records when the queue is full.", func(t *testing.T) {
+		storage := newFakeStorage()
+		storage.release = make(chan struct{})
+		writer := NewBatchWriter(storage, BatchWriterConfig{BatchSize: 1, FlushInterval: time.Hour, QueueSize: 1, DropWhenFull: true})
+
+		// The first write blocks the writer in the database, the second waits in the queue, the others are dropped
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, writer.RecordPriceRecords(make([]PriceRecord, 2)), nil)
+			time.Sleep(5 * time.Millisecond)
+		}
+		close(storage.release)
+		writer.Close()
+
+		stats := writer.Stats()
+		assert.Equal(t, stats.Written, uint64(4))
+		assert.Equal(t, stats.Dropped, uint64(6))
+		assert.Equal(t, writer.RecordPriceRecords(make([]PriceRecord, 1)) != nil, true)
+	})
+
+	t.Run("Failed writes are counted and do not stop the writer.", func(t *testing.T) {
+		storage := newFakeStorage()
+		storage.fail = true
+		writer := NewBatchWriter(storage, BatchWriterConfig{BatchSize: 2, FlushInterval: time.Hour})
+		assert.Equal(t, writer.RecordPriceRecords(make([]PriceRecord, 2)), nil)
+		writer.Flush()
+		storage.mu.Lock()
+		storage.fail = false
+		storage.mu.Unlock()
+		assert.Equal(t, writer.RecordPriceRecords(make([]PriceRecord, 2)), nil)
+		writer.Close()
+
+		stats := writer.Stats()
+		assert.Equal(t, stats.Failed, uint64(2))
+		assert.Equal(t, stats.Written, uint64(2))
+	})
+
+	t.Run("Orders are written immediately.", func(t *testing.T) {
+		storage := newFakeStorage()
+		writer := NewBatchWriter(storage, BatchWriterConfig{})
+		assert.Equal(t, writer.RecordOrderRecord(OrderRecord{}), nil)
+		assert.Equal(t, storage.insertsOf("order_records"), []int{1})
+		writer.Close()
+	})
+}
+
+func Test_BatchWriterCycles(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	t.Run("Pending cycles are written together once they have a batch of rows.", func(t *testing.T) {
+		storage := newFakeStorage()
+		writer := NewBatchWriter(storage, BatchWriterConfig{BatchSize: 10, FlushInterval: time.Hour})
+		for i := 0; i < 4; i++ {
+			cycle := NewCycle(time.Now())
+			cycle.AddPriceRecords(make([]PriceRecord, 3)...)
+			assert.Equal(t, writer.RecordCycles([]Cycle{*cycle}), nil)
+		}
+		writer.Close()
+
+		assert.Equal(t, storage.insertsOf("cycle_snapshots"), []int{3, 1})
+		assert.Equal(t, writer.Stats().Written, uint64(16))
+	})
+}
@@ -8,22 +8,33 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"time"
 )
 
+/*
+A quote of an exchange. ArbitrageRecordUuid references the arbitrage or triangular arbitrage opportunity that was
+detected with the quote, uuid.Nil if there was none. CycleUuid references the detection cycle of the quote, uuid.Nil if
+it was recorded outside of one.
+*/
 type PriceRecord struct {
 	Uuid                   uuid.UUID `db:"uuid"`
-	Timestamp              string    `db:"timestamp"`
+	Timestamp              time.Time `db:"timestamp"`
 	Currency               string    `db:"currency"`
 	Price                  float64   `db:"price"`
 	Fee                    float64   `db:"fee"`
 	Exchange               string    `db:"exchange"`
 	ArbitrageRecordUuid    uuid.UUID `db:"arbitrage_record_uuid"`
 	IsArbitrageOpportunity bool      `db:"is_arbitrage_opportunity"`
+	CycleUuid              uuid.UUID `db:"cycle_uuid"`
 }
 
+/*
+A currency compared between two exchanges. PriceRecordUuidA and PriceRecordUuidB reference the quotes that were
+compared, CycleUuid the detection cycle.
+*/
 type ArbitrageEventRecord struct {
 	Uuid                   uuid.UUID `db:"uuid"`
-	Timestamp              string    `db:"timestamp"`
+	Timestamp              time.Time `db:"timestamp"`
 	Currency               string    `db:"currency"`
 	PriceA                 float64   `db:"price_a"`
 	ExchangeA              string    `db:"exchange_a"`
@@ -31,11 +42,20 @@ type ArbitrageEventRecord struct {
 	ExchangeB              string    `db:"exchange_b"`
 	ProjectedProfit        float64   `db:"projected_profit"`
 	IsArbitrageOpportunity bool      `db:"is_arbitrage_opportunity"`
+	TradeSize              float64   `db:"trade_size"`
+	IsBlockedByInventory   bool      `db:"is_blocked_by_inventory"`
+	PriceRecordUuidA       uuid.UUID `db:"price_record_uuid_a"`
+	PriceRecordUuidB       uuid.UUID `db:"price_record_uuid_b"`
+	CycleUuid              uuid.UUID `db:"cycle_uuid"`
 }
 
+/*
+A cycle of three trade pairs on one exchange. The price record uuids reference the quote of every trade pair, CycleUuid
+the detection cycle.
+*/
 type TriangularArbitrageEventRecord struct {
 	Uuid                             uuid.UUID `db:"uuid"`
-	Timestamp                        string    `db:"timestamp"`
+	Timestamp                        time.Time `db:"timestamp"`
 	Exchange                         string    `db:"exchange"`
 	TradePair1                       string    `db:"trade_pair_1"`
 	TradePair1ExchangeRate           float64   `db:"trade_pair_1_exchange_rate"`
@@ -45,85 +65,104 @@ type TriangularArbitrageEventRecord struct {
 	TradePair3ExchangeRate           float64   `db:"trade_pair_3_exchange_rate"`
 	CrossExchangeRateDiff            float64   `db:"cross_exchange_rate_difference"`
 	IsTriangularArbitrageOpportunity bool      `db:"is_triangular_arbitrage_opportunity"`
+	TradePair1PriceRecordUuid        uuid.UUID `db:"trade_pair_1_price_record_uuid"`
+	TradePair2PriceRecordUuid        uuid.UUID `db:"trade_pair_2_price_record_uuid"`
+	TradePair3PriceRecordUuid        uuid.UUID `db:"trade_pair_3_price_record_uuid"`
+	CycleUuid                        uuid.UUID `db:"cycle_uuid"`
+}
+
+type LegCorrectionRecord struct {
+	Uuid                uuid.UUID `db:"uuid"`
+	Timestamp           time.Time `db:"timestamp"`
+	ArbitrageRecordUuid uuid.UUID `db:"arbitrage_record_uuid"`
+	Action              string    `db:"action"`
+	Exchange            string    `db:"exchange"`
+	Currency            string    `db:"currency"`
+	Side                string    `db:"side"`
+	RequestedSize       float64   `db:"requested_size"`
+	FilledSize          float64   `db:"filled_size"`
+	AveragePrice        float64   `db:"average_price"`
+	Status              string    `db:"status"`
+	Reason              string    `db:"reason"`
 }
 
 /*
 Insert PriceRecord into the database.
 */
 func RecordPriceRecord(priceRecords ...PriceRecord) *internal.DatabaseError {
-	database := goqu.New("mysql", internal.DbPool)
-	for _, priceRecord := range priceRecords {
-		aPriceRecord := goqu.Record{"uuid": priceRecord.Uuid.String(), "timestamp": priceRecord.Timestamp, "currency": priceRecord.Currency, "price": priceRecord.Price, "fee": priceRecord.Fee, "exchange": priceRecord.Exchange, "arbitrage_record_uuid": priceRecord.ArbitrageRecordUuid, "is_arbitrage_opportunity": priceRecord.IsArbitrageOpportunity}
-
-		insertPriceRecordSQL, _, _ := database.Insert("price_records").Rows(aPriceRecord).ToSQL()
-
-		_, err := internal.DbPool.Exec(insertPriceRecordSQL)
-		if err != nil {
-			utils.Logger.Error(fmt.Sprintf("Database query failed: %s", fmt.Sprintf(insertPriceRecordSQL)), zap.String("queryError,", err.Error()))
-			return &internal.DatabaseError{
-				Msg: err.Error(),
-			}
+	if err := currentStorage().RecordPriceRecords(priceRecords); err != nil {
+		return &internal.DatabaseError{
+			Msg: err.Error(),
 		}
-		utils.Logger.Debug("Inserted new `priceRecord` into the database.", zap.Object("priceRecord", &priceRecord))
 	}
-
 	return nil
 }
 
+/*
+Read the PriceRecords between from and to (zero for no limit) in timestamp order and pass them to handle one
+by one, so that the price history does not have to fit into memory. Stops at the first error returned by handle.
+*/
+func StreamPriceRecords(from time.Time, to time.Time, handle func(PriceRecord) error) error {
+	return currentStorage().StreamPriceRecords(from, to, handle)
+}
+
 /*
 Insert ArbitrageEventRecord into the database.
 */
 func RecordArbitrageRecords(arbitrageEventRecords []ArbitrageEventRecord) error {
-	database := goqu.New("mysql", internal.DbPool)
+	return currentStorage().RecordArbitrageRecords(arbitrageEventRecords)
+}
+
+/*
+Insert TriangularArbitrageEventRecord into the database.
+*/
+func RecordTriangularArbitrageRecord(triangularArbitrageEventRecord TriangularArbitrageEventRecord) error {
+	return currentStorage().RecordTriangularArbitrageRecords([]TriangularArbitrageEventRecord{triangularArbitrageEventRecord})
+}
+
+/*
+Insert LegCorrectionRecord into the database.
+*/
+func RecordLegCorrectionRecords(legCorrectionRecords []LegCorrectionRecord) error {
+	database := database()
 
-	for _, arbitrageEventRecord := range arbitrageEventRecords {
-		arbitrageRecord := goqu.Record{"uuid": arbitrageEventRecord.Uuid.String(), "timestamp": arbitrageEventRecord.Timestamp, "currency": arbitrageEventRecord.Currency, "price_a": arbitrageEventRecord.PriceA, "exchange_a": arbitrageEventRecord.ExchangeA, "price_b": arbitrageEventRecord.PriceB, "exchange_b": arbitrageEventRecord.ExchangeB, "projected_profit": arbitrageEventRecord.ProjectedProfit, "is_arbitrage_opportunity": arbitrageEventRecord.IsArbitrageOpportunity}
+	for _, legCorrectionRecord := range legCorrectionRecords {
+		correctionRecord := goqu.Record{"uuid": legCorrectionRecord.Uuid.String(), "timestamp": legCorrectionRecord.Timestamp, "arbitrage_record_uuid": legCorrectionRecord.ArbitrageRecordUuid.String(), "action": legCorrectionRecord.Action, "exchange": legCorrectionRecord.Exchange, "currency": legCorrectionRecord.Currency, "side": legCorrectionRecord.Side, "requested_size": legCorrectionRecord.RequestedSize, "filled_size": legCorrectionRecord.FilledSize, "average_price": legCorrectionRecord.AveragePrice, "status": legCorrectionRecord.Status, "reason": legCorrectionRecord.Reason}
 
-		insertArbitrageEventSQL, _, _ := database.Insert("arbitrage_records").Rows(arbitrageRecord).ToSQL()
+		insertLegCorrectionSQL, _, _ := database.Insert("leg_correction_records").Rows(correctionRecord).ToSQL()
 
-		_, err := internal.DbPool.Exec(insertArbitrageEventSQL)
+		_, err := internal.DbPool.Exec(insertLegCorrectionSQL)
 		if err != nil {
-			utils.Logger.Error(fmt.Sprintf("Database query failed: %s", fmt.Sprintf(insertArbitrageEventSQL)), zap.String("queryError,", err.Error()))
+			utils.Logger.Error(fmt.Sprintf("Database query failed: %s", insertLegCorrectionSQL), zap.String("queryError,", err.Error()))
 			return err
 		}
 
-		utils.Logger.Debug("Inserted new arbitrageRecord into the database.", zap.Object("arbitrageEventRecord", &arbitrageEventRecord))
+		utils.Logger.Debug("Inserted new legCorrectionRecord into the database.", zap.Object("legCorrectionRecord", &legCorrectionRecord))
 	}
 
 	return nil
 }
 
 /*
-Insert TriangularArbitrageEventRecord into the database.
+Split a currency pair in the price record notation (e.g. BTCUSD) into its base and quote asset.
 */
-func RecordTriangularArbitrageRecord(triangularArbitrageEventRecord TriangularArbitrageEventRecord) error {
-
-	database := goqu.New("mysql", internal.DbPool)
-
-	triangularArbitrageRecord := goqu.Record{"uuid": triangularArbitrageEventRecord.Uuid.String(), "timestamp": triangularArbitrageEventRecord.Timestamp, "exchange": triangularArbitrageEventRecord.Exchange, "trade_pair_1": triangularArbitrageEventRecord.TradePair1, "trade_pair_1_exchange_rate": triangularArbitrageEventRecord.TradePair1ExchangeRate, "trade_pair_2": triangularArbitrageEventRecord.TradePair2, "trade_pair_2_exchange_rate": triangularArbitrageEventRecord.TradePair2ExchangeRate, "trade_pair_3_exchange_rate": triangularArbitrageEventRecord.TradePair3ExchangeRate, "trade_pair_3": triangularArbitrageEventRecord.TradePair3, "cross_exchange_rate_difference": triangularArbitrageEventRecord.CrossExchangeRateDiff, "is_triangular_arbitrage_opportunity": triangularArbitrageEventRecord.IsTriangularArbitrageOpportunity}
-
-	insertTriangularArbitrageEventSQL, _, _ := database.Insert("triangular_arbitrage_1_exchange").Rows(triangularArbitrageRecord).ToSQL()
-
-	_, err := internal.DbPool.Exec(insertTriangularArbitrageEventSQL)
-	if err != nil {
-		utils.Logger.Error(fmt.Sprintf("Database query failed: %s", fmt.Sprintf(insertTriangularArbitrageEventSQL)), zap.String("queryError,", err.Error()))
-		return err
+func SplitCurrency(currency string) (string, string) {
+	if len(currency) < 6 {
+		return currency, ""
 	}
-
-	utils.Logger.Debug("Inserted new triangularArbitrageRecord into the database.", zap.Object("triangularArbitrageEventRecord", &TriangularArbitrageEventRecord{}))
-
-	return nil
+	return currency[:len(currency)-3], currency[len(currency)-3:]
 }
 
 func (p PriceRecord) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
 	encoder.AddString("uuid", p.Uuid.String())
-	encoder.AddString("time", p.Timestamp)
+	encoder.AddTime("time", p.Timestamp)
 	encoder.AddString("currency", p.Currency)
 	encoder.AddFloat64("price", p.Price)
 	encoder.AddFloat64("fee", p.Fee)
 	encoder.AddString("exchange", p.Exchange)
 	encoder.AddString("arbitrage_record_uuid", p.ArbitrageRecordUuid.String())
 	encoder.AddBool("is_arbitrage_opportunity", p.IsArbitrageOpportunity)
+	encoder.AddString("cycle_uuid", p.CycleUuid.String())
 	return nil
 }
 
@@ -133,12 +172,16 @@ func (arbitrageEventRecord ArbitrageEventRecord) MarshalLogObject(enc zapcore.Ob
 	enc.AddFloat64("priceB", arbitrageEventRecord.PriceB)
 	enc.AddString("exchangeB", arbitrageEventRecord.ExchangeB)
 	enc.AddBool("isArbitrageOpportunity", arbitrageEventRecord.IsArbitrageOpportunity)
+	enc.AddFloat64("tradeSize", arbitrageEventRecord.TradeSize)
+	enc.AddBool("isBlockedByInventory", arbitrageEventRecord.IsBlockedByInventory)
+	enc.AddString("priceRecordUuidA", arbitrageEventRecord.PriceRecordUuidA.String())
+	enc.AddString("priceRecordUuidB", arbitrageEventRecord.PriceRecordUuidB.String())
 	return nil
 }
 
 func (t TriangularArbitrageEventRecord) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
 	encoder.AddString("uuid", t.Uuid.String())
-	encoder.AddString("timestamp", t.Timestamp)
+	encoder.AddTime("timestamp", t.Timestamp)
 	encoder.AddString("exchange", t.Exchange)
 	encoder.AddString("trade_pair_1", t.TradePair1)
 	encoder.AddString("trade_pair_2", t.TradePair2)
@@ -147,3 +190,15 @@ func (t TriangularArbitrageEventRecord) MarshalLogObject(encoder zapcore.ObjectE
 	encoder.AddBool("isTriangularArbitrageOpportunity", t.IsTriangularArbitrageOpportunity)
 	return nil
 }
+
+func (l LegCorrectionRecord) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
+	encoder.AddString("uuid", l.Uuid.String())
+	encoder.AddString("arbitrage_record_uuid", l.ArbitrageRecordUuid.String())
+	encoder.AddString("action", l.Action)
+	encoder.AddString("exchange", l.Exchange)
+	encoder.AddString("side", l.Side)
+	encoder.AddFloat64("requested_size", l.RequestedSize)
+	encoder.AddFloat64("filled_size", l.FilledSize)
+	encoder.AddString("status", l.Status)
+	return nil
+}
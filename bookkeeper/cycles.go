@@ -0,0 +1,85 @@
+package bookkeeper
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+/*
+The snapshot row of a detection cycle, one run of the Arbitrage Hunter job: when it ran and how many quotes, events and
+opportunities it produced.
+*/
+type CycleSnapshot struct {
+	Uuid                      uuid.UUID `db:"uuid"`
+	Timestamp                 time.Time `db:"timestamp"`
+	Quotes                    int       `db:"quotes"`
+	ArbitrageEvents           int       `db:"arbitrage_events"`
+	TriangularArbitrageEvents int       `db:"triangular_arbitrage_events"`
+	SpreadSignals             int       `db:"spread_signals"`
+	Opportunities             int       `db:"opportunities"`
+}
+
+/*
+A detection cycle with its quotes and events. A cycle is stored as a whole, so that the database never holds the quotes
+of a cycle without its events or the other way around. Every record of the cycle references it with its CycleUuid.
+*/
+type Cycle struct {
+	Snapshot                        CycleSnapshot
+	PriceRecords                    []PriceRecord
+	ArbitrageEventRecords           []ArbitrageEventRecord
+	TriangularArbitrageEventRecords []TriangularArbitrageEventRecord
+	SpreadSignalRecords             []SpreadSignalRecord
+}
+
+/*
+Start a cycle that ran at timestamp.
+*/
+func NewCycle(timestamp time.Time) *Cycle {
+	return &Cycle{Snapshot: CycleSnapshot{Uuid: uuid.New(), Timestamp: timestamp}}
+}
+
+func (c *Cycle) AddPriceRecords(priceRecords ...PriceRecord) {
+	for _, priceRecord := range priceRecords {
+		priceRecord.CycleUuid = c.Snapshot.Uuid
+		c.PriceRecords = append(c.PriceRecords, priceRecord)
+	}
+	c.Snapshot.Quotes = len(c.PriceRecords)
+}
+
+func (c *Cycle) AddArbitrageEventRecords(arbitrageEventRecords ...ArbitrageEventRecord) {
+	for _, arbitrageEventRecord := range arbitrageEventRecords {
+		arbitrageEventRecord.CycleUuid = c.Snapshot.Uuid
+		c.ArbitrageEventRecords = append(c.ArbitrageEventRecords, arbitrageEventRecord)
+	}
+	c.Snapshot.ArbitrageEvents = len(c.ArbitrageEventRecords)
+}
+
+func (c *Cycle) AddTriangularArbitrageEventRecords(triangularArbitrageEventRecords ...TriangularArbitrageEventRecord) {
+	for _, triangularArbitrageEventRecord := range triangularArbitrageEventRecords {
+		triangularArbitrageEventRecord.CycleUuid = c.Snapshot.Uuid
+		c.TriangularArbitrageEventRecords = append(c.TriangularArbitrageEventRecords, triangularArbitrageEventRecord)
+	}
+	c.Snapshot.TriangularArbitrageEvents = len(c.TriangularArbitrageEventRecords)
+}
+
+func (c *Cycle) AddSpreadSignalRecords(spreadSignalRecords ...SpreadSignalRecord) {
+	for _, spreadSignalRecord := range spreadSignalRecords {
+		spreadSignalRecord.CycleUuid = c.Snapshot.Uuid
+		c.SpreadSignalRecords = append(c.SpreadSignalRecords, spreadSignalRecord)
+	}
+	c.Snapshot.SpreadSignals = len(c.SpreadSignalRecords)
+}
+
+/*
+Returns the number of rows of the cycle, its snapshot included.
+*/
+func (c Cycle) size() int {
+	return 1 + len(c.PriceRecords) + len(c.ArbitrageEventRecords) + len(c.TriangularArbitrageEventRecords) + len(c.SpreadSignalRecords)
+}
+
+/*
+Insert a Cycle with its snapshot, quotes and events into the database in one transaction.
+*/
+func RecordCycle(cycle Cycle) error {
+	return currentStorage().RecordCycles([]Cycle{cycle})
+}
@@ -0,0 +1,716 @@
+package bookkeeper
+
+import (
+	"bufio"
+	"bytes"
+	"cryptoArbitrageBot/internal/utils"
+	"encoding/json"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Types of the entries of the event log.
+*/
+const (
+	PriceRecordEntry               = "price_record"
+	ArbitrageRecordEntry           = "arbitrage_record"
+	TriangularArbitrageRecordEntry = "triangular_arbitrage_record"
+	OrderRecordEntry               = "order_record"
+	OrderUpdateEntry               = "order_update"
+	CycleEntry                     = "cycle"
+	eventLogSegmentPattern         = "events-*.jsonl"
+	maxEventLogEntrySize           = 1 << 20
+)
+
+/*
+Configuration of the EventLog.
+  - Directory: where the segments are written.
+  - SegmentSize: a new segment is started once the current one is larger than this many bytes.
+  - Sync: fsync every write, so that written records survive a power loss and not only a crash of the bot.
+*/
+type EventLogConfig struct {
+	Directory   string
+	SegmentSize int64
+	Sync        bool
+}
+
+/*
+Load the EventLog configuration from the configuration file (DATABASE.EVENT_LOG).
+*/
+func LoadEventLogConfig() EventLogConfig {
+	return EventLogConfig{
+		Directory:   viper.GetString("DATABASE.EVENT_LOG.DIRECTORY"),
+		SegmentSize: viper.GetInt64("DATABASE.EVENT_LOG.SEGMENT_SIZE_MB") << 20,
+		Sync:        viper.GetBool("DATABASE.EVENT_LOG.SYNC"),
+	}
+}
+
+/*
+An entry of the event log. Checksum is the CRC-32 (IEEE) of Record, the JSON of a PriceRecord, ArbitrageEventRecord,
+TriangularArbitrageEventRecord, OrderRecord or Cycle depending on Type.
+*/
+type EventLogEntry struct {
+	Type     string          `json:"type"`
+	Checksum uint32          `json:"checksum"`
+	Record   json.RawMessage `json:"record"`
+}
+
+/*
+Decode the record of the entry.
+*/
+func (e EventLogEntry) Decode(record interface{}) error {
+	return json.Unmarshal(e.Record, record)
+}
+
+func (e EventLogEntry) valid() bool {
+	return len(e.Record) > 0 && crc32.ChecksumIEEE(e.Record) == e.Checksum
+}
+
+/*
+Appends every quote, arbitrage event, triangular arbitrage event, cycle and order to segmented JSON lines files before
+passing them to another storage, so that they are not lost while the database is down. A cycle is one entry, so that it
+is replayed as a whole. Without a storage the event log is the only sink: price records and orders are then read back
+from the log.
+
+Segments are named events-<sequence>.jsonl and are never modified once a newer segment exists. A line that was only
+partly written when the bot stopped is cut off when the event log is opened again.
+*/
+type EventLog struct {
+	storage  Storage
+	config   EventLogConfig
+	mu       sync.Mutex
+	file     *os.File
+	writer   *bufio.Writer
+	sequence int
+	size     int64
+	closed   bool
+}
+
+/*
+Open the event log in the configured directory and continue its latest segment. Records are passed on to storage, which
+may be nil to use the event log as the only sink.
+*/
+func NewEventLog(storage Storage, config EventLogConfig) (*EventLog, error) {
+	if config.Directory == "" {
+		config.Directory = "event_log"
+	}
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = 64 << 20
+	}
+	if err := os.MkdirAll(config.Directory, 0755); err != nil {
+		return nil, err
+	}
+	l := &EventLog{storage: storage, config: config}
+
+	segments, err := eventLogSegments(config.Directory)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return l, l.openSegment(1)
+	}
+	last := segments[len(segments)-1]
+	fmt.Sscanf(filepath.Base(last), "events-%d.jsonl", &l.sequence)
+	if err := repairSegment(last); err != nil {
+		return nil, err
+	}
+	return l, l.openSegment(l.sequence)
+}
+
+/*
+Write all records of the bookkeeper to an event log in front of the current storage, or only to the event log if
+only. Close the returned EventLog on shutdown.
+*/
+func StartEventLog(config EventLogConfig, only bool) (*EventLog, error) {
+	var next Storage
+	if !only {
+		next = currentStorage()
+	}
+	l, err := NewEventLog(next, config)
+	if err != nil {
+		return nil, err
+	}
+	UseStorage(l)
+	if only {
+		utils.Logger.Info(fmt.Sprintf("Writing quotes, arbitrage events and orders only to the event log in %s.", l.config.Directory))
+	} else {
+		utils.Logger.Info(fmt.Sprintf("Writing quotes, arbitrage events and orders to the event log in %s before the database.", l.config.Directory))
+	}
+	return l, nil
+}
+
+func (l *EventLog) RecordPriceRecords(priceRecords []PriceRecord) error {
+	records := make([]interface{}, len(priceRecords))
+	for i := range priceRecords {
+		records[i] = priceRecords[i]
+	}
+	if err := l.append(PriceRecordEntry, records...); err != nil {
+		return err
+	}
+	if l.storage == nil {
+		return nil
+	}
+	return l.storage.RecordPriceRecords(priceRecords)
+}
+
+func (l *EventLog) StreamPriceRecords(from time.Time, to time.Time, handle func(PriceRecord) error) error {
+	if l.storage != nil {
+		return l.storage.StreamPriceRecords(from, to, handle)
+	}
+	if err := l.flush(); err != nil {
+		return err
+	}
+	return ReadEventLogPriceRecords(l.config.Directory, from, to, handle)
+}
+
+func (l *EventLog) RecordArbitrageRecords(arbitrageEventRecords []ArbitrageEventRecord) error {
+	records := make([]interface{}, len(arbitrageEventRecords))
+	for i := range arbitrageEventRecords {
+		records[i] = arbitrageEventRecords[i]
+	}
+	if err := l.append(ArbitrageRecordEntry, records...); err != nil {
+		return err
+	}
+	if l.storage == nil {
+		return nil
+	}
+	return l.storage.RecordArbitrageRecords(arbitrageEventRecords)
+}
+
+func (l *EventLog) RecordTriangularArbitrageRecords(triangularArbitrageEventRecords []TriangularArbitrageEventRecord) error {
+	records := make([]interface{}, len(triangularArbitrageEventRecords))
+	for i := range triangularArbitrageEventRecords {
+		records[i] = triangularArbitrageEventRecords[i]
+	}
+	if err := l.append(TriangularArbitrageRecordEntry, records...); err != nil {
+		return err
+	}
+	if l.storage == nil {
+		return nil
+	}
+	return l.storage.RecordTriangularArbitrageRecords(triangularArbitrageEventRecords)
+}
+
+func (l *EventLog) RecordCycles(cycles []Cycle) error {
+	records := make([]interface{}, len(cycles))
+	for i := range cycles {
+		records[i] = cycles[i]
+	}
+	if err := l.append(CycleEntry, records...); err != nil {
+		return err
+	}
+	if l.storage == nil {
+		return nil
+	}
+	return l.storage.RecordCycles(cycles)
+}
+
+func (l *EventLog) RecordOrderRecord(orderRecord OrderRecord) error {
+	if err := l.append(OrderRecordEntry, orderRecord); err != nil {
+		return err
+	}
+	if l.storage == nil {
+		return nil
+	}
+	return l.storage.RecordOrderRecord(orderRecord)
+}
+
+func (l *EventLog) UpdateOrderRecord(orderRecord OrderRecord) error {
+	if err := l.append(OrderUpdateEntry, orderRecord); err != nil {
+		return err
+	}
+	if l.storage == nil {
+		return nil
+	}
+	return l.storage.UpdateOrderRecord(orderRecord)
+}
+
+func (l *EventLog) GetOrderRecords(states ...string) ([]OrderRecord, error) {
+	if l.storage != nil {
+		return l.storage.GetOrderRecords(states...)
+	}
+	if err := l.flush(); err != nil {
+		return nil, err
+	}
+	return ReadEventLogOrderRecords(l.config.Directory, states...)
+}
+
+/*
+Write the buffered entries and close the current segment.
+*/
+func (l *EventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	if err := l.writer.Flush(); err != nil {
+		l.file.Close()
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		l.file.Close()
+		return err
+	}
+	utils.Logger.Info(fmt.Sprintf("Event log closed at segment %d.", l.sequence))
+	return l.file.Close()
+}
+
+/*
+Append one entry per record and write them to the segment before returning.
+*/
+func (l *EventLog) append(entryType string, records ...interface{}) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var lines bytes.Buffer
+	for _, record := range records {
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		line, err := json.Marshal(EventLogEntry{Type: entryType, Checksum: crc32.ChecksumIEEE(encoded), Record: encoded})
+		if err != nil {
+			return err
+		}
+		lines.Write(line)
+		lines.WriteByte('\n')
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return fmt.Errorf("the event log is closed, %d %s entries were not written", len(records), entryType)
+	}
+	if l.size > 0 && l.size+int64(lines.Len()) > l.config.SegmentSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := l.writer.Write(lines.Bytes()); err != nil {
+		utils.Logger.Error(fmt.Sprintf("Failed to append %d %s entries to the event log: %v", len(records), entryType, err))
+		return err
+	}
+	l.size += int64(lines.Len())
+	if err := l.writer.Flush(); err != nil {
+		utils.Logger.Error(fmt.Sprintf("Failed to append %d %s entries to the event log: %v", len(records), entryType, err))
+		return err
+	}
+	if l.config.Sync {
+		return l.file.Sync()
+	}
+	return nil
+}
+
+func (l *EventLog) flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	return l.writer.Flush()
+}
+
+/*
+Close the current segment and start the next one.
+*/
+func (l *EventLog) rotate() error {
+	if err := l.writer.Flush(); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	utils.Logger.Info(fmt.Sprintf("Event log segment %d is complete (%d bytes).", l.sequence, l.size))
+	return l.openSegment(l.sequence + 1)
+}
+
+func (l *EventLog) openSegment(sequence int) error {
+	file, err := os.OpenFile(eventLogSegmentPath(l.config.Directory, sequence), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file, l.writer, l.sequence, l.size = file, bufio.NewWriter(file), sequence, info.Size()
+	return nil
+}
+
+func eventLogSegmentPath(directory string, sequence int) string {
+	return filepath.Join(directory, fmt.Sprintf("events-%08d.jsonl", sequence))
+}
+
+/*
+Returns the segments of an event log in the order they were written.
+*/
+func eventLogSegments(directory string) ([]string, error) {
+	segments, err := filepath.Glob(filepath.Join(directory, eventLogSegmentPattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(segments)
+	return segments, nil
+}
+
+/*
+Cut off an incomplete last line of a segment, which is left behind when the bot stops while an entry is written.
+Complete lines are kept even if they are corrupt, ReadEventLog skips those.
+*/
+func repairSegment(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var complete int64
+	reader := bufio.NewReaderSize(file, 64<<10)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		complete += int64(len(line))
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == complete {
+		return nil
+	}
+	utils.Logger.Warn(fmt.Sprintf("Cutting off %d bytes after the last complete line of %s.", info.Size()-complete, path))
+	return file.Truncate(complete)
+}
+
+/*
+Pass every entry of an event log to handle in the order it was written. Entries with an invalid checksum are skipped and
+logged, the number of skipped entries is returned.
+*/
+func ReadEventLog(directory string, handle func(EventLogEntry) error) (int, error) {
+	segments, err := eventLogSegments(directory)
+	if err != nil {
+		return 0, err
+	}
+	if len(segments) == 0 {
+		return 0, fmt.Errorf("no event log in %s", directory)
+	}
+	skipped := 0
+	for _, segment := range segments {
+		file, err := os.Open(segment)
+		if err != nil {
+			return skipped, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64<<10), maxEventLogEntrySize)
+		for line := 1; scanner.Scan(); line++ {
+			var entry EventLogEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || !entry.valid() {
+				skipped++
+				utils.Logger.Warn(fmt.Sprintf("Skipped the corrupt entry on line %d of %s.", line, segment))
+				continue
+			}
+			if err := handle(entry); err != nil {
+				file.Close()
+				return skipped, err
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return skipped, fmt.Errorf("reading %s: %v", segment, err)
+		}
+	}
+	return skipped, nil
+}
+
+/*
+Pass the PriceRecords of an event log between from and to (zero for no limit) to handle in the order they were
+written, e.g. to replay them in the backtester. The quotes of cycles are included.
+*/
+func ReadEventLogPriceRecords(directory string, from time.Time, to time.Time, handle func(PriceRecord) error) error {
+	handleInRange := func(priceRecord PriceRecord) error {
+		if (!from.IsZero() && priceRecord.Timestamp.Before(from)) || (!to.IsZero() && !priceRecord.Timestamp.Before(to)) {
+			return nil
+		}
+		return handle(priceRecord)
+	}
+	_, err := ReadEventLog(directory, func(entry EventLogEntry) error {
+		switch entry.Type {
+		case PriceRecordEntry:
+			var priceRecord PriceRecord
+			if err := entry.Decode(&priceRecord); err != nil {
+				return err
+			}
+			return handleInRange(priceRecord)
+		case CycleEntry:
+			var cycle Cycle
+			if err := entry.Decode(&cycle); err != nil {
+				return err
+			}
+			for _, priceRecord := range cycle.PriceRecords {
+				if err := handleInRange(priceRecord); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+/*
+Returns the latest state of the orders of an event log that are in one of the given states, ordered by timestamp.
+*/
+func ReadEventLogOrderRecords(directory string, states ...string) ([]OrderRecord, error) {
+	orders := make(map[string]*OrderRecord)
+	_, err := ReadEventLog(directory, func(entry EventLogEntry) error {
+		if entry.Type != OrderRecordEntry && entry.Type != OrderUpdateEntry {
+			return nil
+		}
+		var orderRecord OrderRecord
+		if err := entry.Decode(&orderRecord); err != nil {
+			return err
+		}
+		order, ok := orders[orderRecord.Uuid.String()]
+		if entry.Type == OrderRecordEntry || !ok {
+			orders[orderRecord.Uuid.String()] = &orderRecord
+			return nil
+		}
+		order.UpdatedAt, order.OrderId, order.FilledSize, order.AveragePrice, order.Fee, order.State, order.Reason = orderRecord.UpdatedAt, orderRecord.OrderId, orderRecord.FilledSize, orderRecord.AveragePrice, orderRecord.Fee, orderRecord.State, orderRecord.Reason
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, state := range states {
+		wanted[state] = true
+	}
+	var orderRecords []OrderRecord
+	for _, order := range orders {
+		if wanted[order.State] {
+			orderRecords = append(orderRecords, *order)
+		}
+	}
+	sort.Slice(orderRecords, func(i, j int) bool {
+		return orderRecords[i].Timestamp.Before(orderRecords[j].Timestamp)
+	})
+	return orderRecords, nil
+}
+
+/*
+Counters of a replay of an event log into the database. Stored counts the records that were already in the database,
+Corrupt the entries with an invalid checksum.
+*/
+type EventLogReplayStats struct {
+	Replayed int
+	Stored   int
+	Corrupt  int
+}
+
+/*
+Write the records of an event log that are not in the database yet into it, e.g. after the database was down. Records
+are matched by uuid, so an event log can be replayed more than once. A cycle is replayed as a whole if its snapshot is
+missing. Orders are inserted if they are missing and their updates are applied in the order they were logged.
+*/
+func ReplayEventLog(directory string) (EventLogReplayStats, error) {
+	var stats EventLogReplayStats
+	var priceRecords []PriceRecord
+	var arbitrageEventRecords []ArbitrageEventRecord
+	var triangularArbitrageEventRecords []TriangularArbitrageEventRecord
+	var cycles []Cycle
+
+	flush := func(minRecords int) error {
+		if len(priceRecords) > 0 && len(priceRecords) >= minRecords {
+			uuids := make([]string, len(priceRecords))
+			for i, priceRecord := range priceRecords {
+				uuids[i] = priceRecord.Uuid.String()
+			}
+			stored, err := storedUuids("price_records", uuids...)
+			if err != nil {
+				return err
+			}
+			var missing []PriceRecord
+			for _, priceRecord := range priceRecords {
+				if !stored[priceRecord.Uuid.String()] {
+					missing = append(missing, priceRecord)
+				}
+			}
+			if err := currentStorage().RecordPriceRecords(missing); len(missing) > 0 && err != nil {
+				return err
+			}
+			stats.Replayed, stats.Stored = stats.Replayed+len(missing), stats.Stored+len(priceRecords)-len(missing)
+			priceRecords = nil
+		}
+		if len(arbitrageEventRecords) > 0 && len(arbitrageEventRecords) >= minRecords {
+			uuids := make([]string, len(arbitrageEventRecords))
+			for i, arbitrageEventRecord := range arbitrageEventRecords {
+				uuids[i] = arbitrageEventRecord.Uuid.String()
+			}
+			stored, err := storedUuids("arbitrage_records", uuids...)
+			if err != nil {
+				return err
+			}
+			var missing []ArbitrageEventRecord
+			for _, arbitrageEventRecord := range arbitrageEventRecords {
+				if !stored[arbitrageEventRecord.Uuid.String()] {
+					missing = append(missing, arbitrageEventRecord)
+				}
+			}
+			if err := currentStorage().RecordArbitrageRecords(missing); len(missing) > 0 && err != nil {
+				return err
+			}
+			stats.Replayed, stats.Stored = stats.Replayed+len(missing), stats.Stored+len(arbitrageEventRecords)-len(missing)
+			arbitrageEventRecords = nil
+		}
+		if len(triangularArbitrageEventRecords) > 0 && len(triangularArbitrageEventRecords) >= minRecords {
+			uuids := make([]string, len(triangularArbitrageEventRecords))
+			for i, triangularArbitrageEventRecord := range triangularArbitrageEventRecords {
+				uuids[i] = triangularArbitrageEventRecord.Uuid.String()
+			}
+			stored, err := storedUuids("triangular_arbitrage_1_exchange", uuids...)
+			if err != nil {
+				return err
+			}
+			var missing []TriangularArbitrageEventRecord
+			for _, triangularArbitrageEventRecord := range triangularArbitrageEventRecords {
+				if !stored[triangularArbitrageEventRecord.Uuid.String()] {
+					missing = append(missing, triangularArbitrageEventRecord)
+				}
+			}
+			if err := currentStorage().RecordTriangularArbitrageRecords(missing); len(missing) > 0 && err != nil {
+				return err
+			}
+			stats.Replayed, stats.Stored = stats.Replayed+len(missing), stats.Stored+len(triangularArbitrageEventRecords)-len(missing)
+			triangularArbitrageEventRecords = nil
+		}
+		if len(cycles) > 0 && len(cycles) >= minRecords {
+			uuids := make([]string, len(cycles))
+			for i, cycle := range cycles {
+				uuids[i] = cycle.Snapshot.Uuid.String()
+			}
+			stored, err := storedUuids("cycle_snapshots", uuids...)
+			if err != nil {
+				return err
+			}
+			var missing []Cycle
+			for _, cycle := range cycles {
+				if !stored[cycle.Snapshot.Uuid.String()] {
+					missing = append(missing, cycle)
+				}
+			}
+			if err := currentStorage().RecordCycles(missing); len(missing) > 0 && err != nil {
+				return err
+			}
+			stats.Replayed, stats.Stored = stats.Replayed+cyclesSize(missing), stats.Stored+cyclesSize(cycles)-cyclesSize(missing)
+			cycles = nil
+		}
+		return nil
+	}
+
+	entries := 0
+	corrupt, err := ReadEventLog(directory, func(entry EventLogEntry) error {
+		entries++
+		if entries%100000 == 0 {
+			utils.Logger.Info(fmt.Sprintf("Read %d entries of the event log: %d records replayed, %d already stored.", entries, stats.Replayed, stats.Stored))
+		}
+		switch entry.Type {
+		case PriceRecordEntry:
+			var priceRecord PriceRecord
+			if err := entry.Decode(&priceRecord); err != nil {
+				return err
+			}
+			priceRecords = append(priceRecords, priceRecord)
+		case ArbitrageRecordEntry:
+			var arbitrageEventRecord ArbitrageEventRecord
+			if err := entry.Decode(&arbitrageEventRecord); err != nil {
+				return err
+			}
+			arbitrageEventRecords = append(arbitrageEventRecords, arbitrageEventRecord)
+		case TriangularArbitrageRecordEntry:
+			var triangularArbitrageEventRecord TriangularArbitrageEventRecord
+			if err := entry.Decode(&triangularArbitrageEventRecord); err != nil {
+				return err
+			}
+			triangularArbitrageEventRecords = append(triangularArbitrageEventRecords, triangularArbitrageEventRecord)
+		case CycleEntry:
+			var cycle Cycle
+			if err := entry.Decode(&cycle); err != nil {
+				return err
+			}
+			cycles = append(cycles, cycle)
+		case OrderRecordEntry:
+			var orderRecord OrderRecord
+			if err := entry.Decode(&orderRecord); err != nil {
+				return err
+			}
+			stored, err := storedUuids("order_records", orderRecord.Uuid.String())
+			if err != nil {
+				return err
+			}
+			if stored[orderRecord.Uuid.String()] {
+				stats.Stored++
+				return nil
+			}
+			if err := currentStorage().RecordOrderRecord(orderRecord); err != nil {
+				return err
+			}
+			stats.Replayed++
+		case OrderUpdateEntry:
+			var orderRecord OrderRecord
+			if err := entry.Decode(&orderRecord); err != nil {
+				return err
+			}
+			if err := currentStorage().UpdateOrderRecord(orderRecord); err != nil {
+				return err
+			}
+			stats.Replayed++
+		default:
+			utils.Logger.Warn(fmt.Sprintf("Skipped an entry of the unknown type %s.", entry.Type))
+		}
+		return flush(500)
+	})
+	if err == nil {
+		err = flush(0)
+	}
+	stats.Corrupt = corrupt
+	return stats, err
+}
+
+/*
+Returns which of the uuids are in the table.
+*/
+func storedUuids(table string, uuids ...string) (map[string]bool, error) {
+	var stored []string
+	if err := database().From(table).Select("uuid").Where(goqu.C("uuid").In(uuids)).ScanVals(&stored); err != nil {
+		utils.Logger.Error(fmt.Sprintf("Failed to look up the stored records of %s.", table), zap.Error(err))
+		return nil, err
+	}
+	isStored := make(map[string]bool, len(stored))
+	for _, uuid := range stored {
+		isStored[uuid] = true
+	}
+	return isStored, nil
+}
@@ -0,0 +1,246 @@
+package bookkeeper
+
+import (
+	"github.com/google/uuid"
+	"github.com/magiconair/properties/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func priceRecordsForTest(count int) []PriceRecord {
+	priceRecords := make([]PriceRecord, count)
+	for i := range priceRecords {
+		priceRecords[i] = PriceRecord{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T10:00:00Z").Add(time.Duration(i) * time.Second), Currency: "BTCUSD", Price: 27000 + float64(i), Fee: .0026, Exchange: "Kraken"}
+	}
+	return priceRecords
+}
+
+func readPricesForTest(t *testing.T, directory string, from time.Time, to time.Time) []float64 {
+	var prices []float64
+	err := ReadEventLogPriceRecords(directory, from, to, func(priceRecord PriceRecord) error {
+		prices = append(prices, priceRecord.Price)
+		return nil
+	})
+	assert.Equal(t, err, nil)
+	return prices
+}
+
+func Test_EventLog(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	t.Run("Records are read back in the order they were written.", func(t *testing.T) {
+		directory := t.TempDir()
+		eventLog, err := NewEventLog(nil, EventLogConfig{Directory: directory})
+		assert.Equal(t, err, nil)
+		priceRecords := priceRecordsForTest(3)
+		assert.Equal(t, eventLog.RecordPriceRecords(priceRecords), nil)
+		assert.Equal(t, eventLog.RecordArbitrageRecords([]ArbitrageEventRecord{{Uuid: uuid.New(), Timestamp: priceRecords[0].Timestamp, Currency: "BTCUSD"}}), nil)
+
+		var prices []float64
+		err = eventLog.StreamPriceRecords(priceRecords[1].Timestamp, time.Time{}, func(priceRecord PriceRecord) error {
+			prices = append(prices, priceRecord.Price)
+			assert.Equal(t, priceRecord.Timestamp.Equal(priceRecords[int(priceRecord.Price)-27000].Timestamp), true)
+			return nil
+		})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, prices, []float64{27001, 27002})
+
+		var types []string
+		_, err = ReadEventLog(directory, func(entry EventLogEntry) error {
+			types = append(types, entry.Type)
+			return nil
+		})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, types, []string{PriceRecordEntry, PriceRecordEntry, PriceRecordEntry, ArbitrageRecordEntry})
+		assert.Equal(t, eventLog.Close(), nil)
+	})
+
+	t.Run("Orders are read back in their latest state.", func(t *testing.T) {
+		eventLog, err := NewEventLog(nil, EventLogConfig{Directory: t.TempDir()})
+		assert.Equal(t, err, nil)
+		defer eventLog.Close()
+		filled := OrderRecord{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T10:00:00Z"), ClientOrderId: "client-1", State: "new"}
+		open := OrderRecord{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T10:00:05Z"), ClientOrderId: "client-2", State: "new"}
+		assert.Equal(t, eventLog.RecordOrderRecord(filled), nil)
+		assert.Equal(t, eventLog.RecordOrderRecord(open), nil)
+		filled.State, filled.FilledSize = "filled", .01
+		assert.Equal(t, eventLog.UpdateOrderRecord(filled), nil)
+
+		orderRecords, err := eventLog.GetOrderRecords("new", "acknowledged")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(orderRecords), 1)
+		assert.Equal(t, orderRecords[0].ClientOrderId, "client-2")
+		orderRecords, err = eventLog.GetOrderRecords("filled")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, orderRecords[0].FilledSize, .01)
+	})
+
+	t.Run("A new segment is started once a segment is full.", func(t *testing.T) {
+		directory := t.TempDir()
+		eventLog, err := NewEventLog(nil, EventLogConfig{Directory: directory, SegmentSize: 600})
+		assert.Equal(t, err, nil)
+		for _, priceRecord := range priceRecordsForTest(6) {
+			assert.Equal(t, eventLog.RecordPriceRecords([]PriceRecord{priceRecord}), nil)
+		}
+		assert.Equal(t, eventLog.Close(), nil)
+
+		segments, err := eventLogSegments(directory)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(segments) > 1, true)
+		assert.Equal(t, filepath.Base(segments[0]), "events-00000001.jsonl")
+		assert.Equal(t, readPricesForTest(t, directory, time.Time{}, time.Time{}), []float64{27000, 27001, 27002, 27003, 27004, 27005})
+	})
+
+	t.Run("A partly written entry is cut off when the event log is opened again.", func(t *testing.T) {
+		directory := t.TempDir()
+		eventLog, err := NewEventLog(nil, EventLogConfig{Directory: directory})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, eventLog.RecordPriceRecords(priceRecordsForTest(2)), nil)
+		assert.Equal(t, eventLog.Close(), nil)
+
+		segment := eventLogSegmentPath(directory, 1)
+		file, err := os.OpenFile(segment, os.O_WRONLY|os.O_APPEND, 0644)
+		assert.Equal(t, err, nil)
+		file.WriteString(`{"type":"price_record","checksum":12`)
+		file.Close()
+
+		eventLog, err = NewEventLog(nil, EventLogConfig{Directory: directory})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, eventLog.RecordPriceRecords(priceRecordsForTest(1)), nil)
+		assert.Equal(t, eventLog.Close(), nil)
+		assert.Equal(t, readPricesForTest(t, directory, time.Time{}, time.Time{}), []float64{27000, 27001, 27000})
+	})
+
+	t.Run("Entries with an invalid checksum are skipped.", func(t *testing.T) {
+		directory := t.TempDir()
+		eventLog, err := NewEventLog(nil, EventLogConfig{Directory: directory})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, eventLog.RecordPriceRecords(priceRecordsForTest(3)), nil)
+		assert.Equal(t, eventLog.Close(), nil)
+
+		segment := eventLogSegmentPath(directory, 1)
+		content, err := os.ReadFile(segment)
+		assert.Equal(t, err, nil)
+		// Change the price of the first record from 27000 to 27900
+		for i := range content {
+			if string(content[i:i+5]) == "27000" {
+				content[i+2] = '9'
+				break
+			}
+		}
+		assert.Equal(t, os.WriteFile(segment, content, 0644), nil)
+
+		skipped, err := ReadEventLog(directory, func(entry EventLogEntry) error { return nil })
+		assert.Equal(t, err, nil)
+		assert.Equal(t, skipped, 1)
+		assert.Equal(t, readPricesForTest(t, directory, time.Time{}, time.Time{}), []float64{27001, 27002})
+	})
+
+	t.Run("Entries after a corrupt entry are kept when the event log is opened again.", func(t *testing.T) {
+		directory := t.TempDir()
+		eventLog, err := NewEventLog(nil, EventLogConfig{Directory: directory})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, eventLog.RecordPriceRecords(priceRecordsForTest(3)), nil)
+		assert.Equal(t, eventLog.Close(), nil)
+
+		segment := eventLogSegmentPath(directory, 1)
+		content, err := os.ReadFile(segment)
+		assert.Equal(t, err, nil)
+		// Change the price of the second record from 27001 to 27901
+		for i := range content {
+			if string(content[i:i+5]) == "27001" {
+				content[i+2] = '9'
+				break
+			}
+		}
+		assert.Equal(t, os.WriteFile(segment, content, 0644), nil)
+
+		eventLog, err = NewEventLog(nil, EventLogConfig{Directory: directory})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, eventLog.RecordPriceRecords(priceRecordsForTest(1)), nil)
+		assert.Equal(t, eventLog.Close(), nil)
+		assert.Equal(t, readPricesForTest(t, directory, time.Time{}, time.Time{}), []float64{27000, 27002, 27000})
+	})
+
+	t.Run("A cycle is one entry and its quotes are read back.", func(t *testing.T) {
+		directory := t.TempDir()
+		eventLog, err := NewEventLog(nil, EventLogConfig{Directory: directory})
+		assert.Equal(t, err, nil)
+		cycle := NewCycle(timeForTest("2023-06-01T10:00:00Z"))
+		cycle.AddPriceRecords(priceRecordsForTest(2)...)
+		assert.Equal(t, eventLog.RecordCycles([]Cycle{*cycle}), nil)
+		assert.Equal(t, eventLog.RecordPriceRecords(priceRecordsForTest(1)), nil)
+		assert.Equal(t, eventLog.Close(), nil)
+
+		var types []string
+		_, err = ReadEventLog(directory, func(entry EventLogEntry) error {
+			types = append(types, entry.Type)
+			return nil
+		})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, types, []string{CycleEntry, PriceRecordEntry})
+		assert.Equal(t, readPricesForTest(t, directory, time.Time{}, time.Time{}), []float64{27000, 27001, 27000})
+	})
+
+	t.Run("Records are logged even if the database fails.", func(t *testing.T) {
+		directory := t.TempDir()
+		storage := newFakeStorage()
+		storage.fail = true
+		eventLog, err := NewEventLog(storage, EventLogConfig{Directory: directory})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, eventLog.RecordPriceRecords(priceRecordsForTest(2)) != nil, true)
+		assert.Equal(t, eventLog.Close(), nil)
+		assert.Equal(t, readPricesForTest(t, directory, time.Time{}, time.Time{}), []float64{27000, 27001})
+	})
+}
+
+func Test_ReplayEventLog(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+	db := setupSqliteForTest(t)
+
+	directory := t.TempDir()
+	eventLog, err := NewEventLog(nil, EventLogConfig{Directory: directory})
+	assert.Equal(t, err, nil)
+	priceRecords := priceRecordsForTest(4)
+	assert.Equal(t, eventLog.RecordPriceRecords(priceRecords), nil)
+	assert.Equal(t, eventLog.RecordArbitrageRecords([]ArbitrageEventRecord{{Uuid: uuid.New(), Timestamp: priceRecords[0].Timestamp, Currency: "BTCUSD", ExchangeA: "Coinbase", ExchangeB: "Kraken", PriceRecordUuidB: priceRecords[0].Uuid}}), nil)
+	cycle := NewCycle(timeForTest("2023-06-01T10:00:10Z"))
+	cycle.AddPriceRecords(priceRecordsForTest(2)...)
+	assert.Equal(t, eventLog.RecordCycles([]Cycle{*cycle}), nil)
+	orderRecord := OrderRecord{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T10:00:00Z"), UpdatedAt: timeForTest("2023-06-01T10:00:00Z"), ClientOrderId: "client-1", Exchange: "Kraken", Currency: "BTCUSD", State: "new"}
+	assert.Equal(t, eventLog.RecordOrderRecord(orderRecord), nil)
+	orderRecord.State = "filled"
+	assert.Equal(t, eventLog.UpdateOrderRecord(orderRecord), nil)
+	assert.Equal(t, eventLog.Close(), nil)
+
+	// The database received the first quotes before it went down
+	assert.Equal(t, RecordPriceRecord(priceRecords[:2]...) == nil, true)
+
+	t.Run("Only the missing records are written into the database.", func(t *testing.T) {
+		stats, err := ReplayEventLog(directory)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, stats, EventLogReplayStats{Replayed: 8, Stored: 2})
+
+		var count int
+		assert.Equal(t, db.QueryRow("SELECT COUNT(*) FROM price_records").Scan(&count), nil)
+		assert.Equal(t, count, 6)
+		assert.Equal(t, db.QueryRow("SELECT COUNT(*) FROM cycle_snapshots").Scan(&count), nil)
+		assert.Equal(t, count, 1)
+		orderRecords, err := GetOrderRecords("filled")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(orderRecords), 1)
+	})
+
+	t.Run("An event log can be replayed again.", func(t *testing.T) {
+		stats, err := ReplayEventLog(directory)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, stats, EventLogReplayStats{Replayed: 1, Stored: 9})
+		var count int
+		assert.Equal(t, db.QueryRow("SELECT COUNT(*) FROM arbitrage_records").Scan(&count), nil)
+		assert.Equal(t, count, 1)
+	})
+}
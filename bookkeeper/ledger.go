@@ -0,0 +1,520 @@
+package bookkeeper
+
+import (
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"math"
+	"sync"
+	"time"
+)
+
+/*
+The accounts of the ledger. Every transaction balances per asset: the assets account holds the balances on the
+exchanges, the trading account holds the counter-entries of fills and the fees account the fees that were paid.
+*/
+const (
+	AssetsAccount  = "assets"
+	TradingAccount = "trading"
+	FeesAccount    = "fees"
+)
+
+/*
+One entry of a ledger transaction. Amount is in units of the asset, Value is the amount in the base currency of the
+ledger at the time it was posted.
+*/
+type LedgerEntry struct {
+	Uuid            uuid.UUID `db:"uuid"`
+	Timestamp       time.Time `db:"timestamp"`
+	TransactionUuid uuid.UUID `db:"transaction_uuid"`
+	Reference       string    `db:"reference"`
+	Account         string    `db:"account"`
+	Exchange        string    `db:"exchange"`
+	Asset           string    `db:"asset"`
+	Amount          float64   `db:"amount"`
+	Value           float64   `db:"value"`
+}
+
+/*
+The fill of an order as posted to the ledger. The fee is paid in the quote asset.
+*/
+type Fill struct {
+	Timestamp  time.Time
+	Reference  string
+	Exchange   string
+	BaseAsset  string
+	QuoteAsset string
+	Side       string
+	Size       float64
+	Price      float64
+	Fee        float64
+}
+
+/*
+The PnL of a day or a month in the base currency of the ledger. Period is the day, or the first day of the month.
+Unrealized PnL is the mark-to-market value of the open positions when the summary was last updated.
+*/
+type PnlSummary struct {
+	Period        string    `db:"period"`
+	BaseCurrency  string    `db:"base_currency"`
+	RealizedPnl   float64   `db:"realized_pnl"`
+	UnrealizedPnl float64   `db:"unrealized_pnl"`
+	Fees          float64   `db:"fees"`
+	TotalPnl      float64   `db:"total_pnl"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+/*
+An open position in an asset, held across all exchanges. Cost is the base currency paid for the position (negative
+for a short position), so that Cost/Quantity is the average cost of a unit.
+*/
+type position struct {
+	quantity float64
+	cost     float64
+}
+
+/*
+The realized PnL and fees of a period.
+*/
+type periodPnl struct {
+	realized float64
+	fees     float64
+}
+
+/*
+A double-entry ledger of every fill, fee and transfer per asset and exchange. Realized PnL is calculated with the
+average cost of each asset, unrealized PnL by marking the open positions to the latest prices.
+*/
+type Ledger struct {
+	mu           sync.Mutex
+	baseCurrency string
+	balances     map[string]map[string]float64
+	positions    map[string]*position
+	marks        map[string]float64
+	realized     float64
+	fees         float64
+	days         map[string]*periodPnl
+	months       map[string]*periodPnl
+	persistent   bool
+}
+
+/*
+Create a Ledger that only keeps entries in memory.
+*/
+func NewLedger(baseCurrency string) *Ledger {
+	return &Ledger{
+		baseCurrency: baseCurrency,
+		balances:     make(map[string]map[string]float64),
+		positions:    make(map[string]*position),
+		marks:        map[string]float64{baseCurrency: 1},
+		days:         make(map[string]*periodPnl),
+		months:       make(map[string]*periodPnl),
+	}
+}
+
+/*
+Create a Ledger that is backed by the database and replay all entries that were posted before.
+*/
+func LoadLedger(baseCurrency string) (*Ledger, error) {
+	entries, err := GetLedgerEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	ledger := NewLedger(baseCurrency)
+	ledger.persistent = true
+	for start := 0; start < len(entries); {
+		end := start + 1
+		for end < len(entries) && entries[end].TransactionUuid == entries[start].TransactionUuid {
+			end++
+		}
+		ledger.post(entries[start:end])
+		start = end
+	}
+	utils.Logger.Info(fmt.Sprintf("Loaded %d ledger entries. Realized PnL is %v %s.", len(entries), ledger.realized, baseCurrency))
+	return ledger, nil
+}
+
+/*
+Update the prices the ledger values assets with. Assets quoted in the base currency are marked to the average price of
+all exchanges, other assets through the pair they are quoted in (e.g. LTC through LTCETH and ETHUSD).
+*/
+func (l *Ledger) MarkPrices(priceRecords []PriceRecord) {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for _, priceRecord := range priceRecords {
+		if priceRecord.Price > 0 {
+			sums[priceRecord.Currency] += priceRecord.Price
+			counts[priceRecord.Currency]++
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	marks := map[string]float64{l.baseCurrency: 1}
+	for changed := true; changed; {
+		changed = false
+		for currency, sum := range sums {
+			base, quote := SplitCurrency(currency)
+			if _, ok := marks[base]; ok {
+				continue
+			}
+			if quoteMark, ok := marks[quote]; ok {
+				marks[base] = sum / float64(counts[currency]) * quoteMark
+				changed = true
+			}
+		}
+	}
+	for asset, mark := range marks {
+		l.marks[asset] = mark
+	}
+}
+
+/*
+Post the fill of an order: the base asset is bought or sold for the quote asset on the exchange and the fee is paid in
+the quote asset.
+*/
+func (l *Ledger) PostFill(fill Fill) error {
+	if fill.Size <= 0 {
+		return nil
+	}
+	sign := 1.0
+	if fill.Side == "sell" {
+		sign = -1
+	}
+	quoteAmount := fill.Size * fill.Price
+
+	l.mu.Lock()
+	value := l.value(fill.QuoteAsset, quoteAmount)
+	if value == 0 {
+		value = l.value(fill.BaseAsset, fill.Size)
+	}
+	if value == 0 {
+		utils.Logger.Warn(fmt.Sprintf("No price for %s or %s in %s. The fill %s is posted without a value.", fill.BaseAsset, fill.QuoteAsset, l.baseCurrency, fill.Reference))
+	}
+	feeValue := l.value(fill.QuoteAsset, fill.Fee)
+	l.mu.Unlock()
+
+	entries := []LedgerEntry{
+		l.entry(AssetsAccount, fill.Exchange, fill.BaseAsset, sign*fill.Size, sign*value),
+		l.entry(TradingAccount, fill.Exchange, fill.BaseAsset, -sign*fill.Size, -sign*value),
+		l.entry(AssetsAccount, fill.Exchange, fill.QuoteAsset, -sign*quoteAmount, -sign*value),
+		l.entry(TradingAccount, fill.Exchange, fill.QuoteAsset, sign*quoteAmount, sign*value),
+	}
+	if fill.Fee > 0 {
+		entries = append(entries,
+			l.entry(AssetsAccount, fill.Exchange, fill.QuoteAsset, -fill.Fee, -feeValue),
+			l.entry(FeesAccount, fill.Exchange, fill.QuoteAsset, fill.Fee, feeValue),
+		)
+	}
+	return l.record(fill.Timestamp, fill.Reference, entries)
+}
+
+/*
+Post a completed transfer between two exchanges. The destination receives the amount of the transfer, the source also
+pays the withdrawal fee.
+*/
+func (l *Ledger) PostTransfer(transfer TransferRecord) error {
+	l.mu.Lock()
+	value := l.value(transfer.Asset, transfer.Amount)
+	feeValue := l.value(transfer.Asset, transfer.EstimatedFee)
+	l.mu.Unlock()
+
+	entries := []LedgerEntry{
+		l.entry(AssetsAccount, transfer.FromExchange, transfer.Asset, -transfer.Amount, -value),
+		l.entry(AssetsAccount, transfer.ToExchange, transfer.Asset, transfer.Amount, value),
+	}
+	if transfer.EstimatedFee > 0 {
+		entries = append(entries,
+			l.entry(AssetsAccount, transfer.FromExchange, transfer.Asset, -transfer.EstimatedFee, -feeValue),
+			l.entry(FeesAccount, transfer.FromExchange, transfer.Asset, transfer.EstimatedFee, feeValue),
+		)
+	}
+	return l.record(transfer.UpdatedAt, transfer.Uuid.String(), entries)
+}
+
+/*
+Returns the balance of an asset on an exchange as posted to the ledger.
+*/
+func (l *Ledger) Balance(exchange string, asset string) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.balances[exchange][asset]
+}
+
+/*
+Returns the total realized PnL in the base currency, including all fees.
+*/
+func (l *Ledger) RealizedPnl() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.realized
+}
+
+/*
+Returns the PnL of the open positions in the base currency at the latest prices. Positions in assets without a price
+are not included.
+*/
+func (l *Ledger) UnrealizedPnl() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.unrealizedPnl()
+}
+
+/*
+Returns the PnL summaries of the day and the month of the given time (UTC).
+*/
+func (l *Ledger) Summaries(now time.Time) (PnlSummary, PnlSummary) {
+	day, month := periods(now)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	unrealized := l.unrealizedPnl()
+	summary := func(period string, pnl *periodPnl) PnlSummary {
+		if pnl == nil {
+			pnl = &periodPnl{}
+		}
+		return PnlSummary{
+			Period:        period,
+			BaseCurrency:  l.baseCurrency,
+			RealizedPnl:   pnl.realized,
+			UnrealizedPnl: unrealized,
+			Fees:          pnl.fees,
+			TotalPnl:      pnl.realized + unrealized,
+			UpdatedAt:     now.UTC(),
+		}
+	}
+	return summary(day, l.days[day]), summary(month, l.months[month])
+}
+
+/*
+Store the PnL summaries of the current day and month in the pnl_daily and pnl_monthly tables.
+*/
+func (l *Ledger) RecordSummaries() {
+	daily, monthly := l.Summaries(time.Now())
+	if err := RecordPnlSummary("pnl_daily", daily); err != nil {
+		return
+	}
+	RecordPnlSummary("pnl_monthly", monthly)
+}
+
+/*
+Create an entry of a new transaction. The timestamp, reference and transaction uuid are set when it is recorded.
+*/
+func (l *Ledger) entry(account string, exchange string, asset string, amount float64, value float64) LedgerEntry {
+	return LedgerEntry{Uuid: uuid.New(), Account: account, Exchange: exchange, Asset: asset, Amount: amount, Value: value}
+}
+
+/*
+Complete the entries of a transaction, store them if the ledger is backed by the database and post them. Entries are
+posted even if they could not be stored, because the fill or transfer happened regardless.
+*/
+func (l *Ledger) record(timestamp time.Time, reference string, entries []LedgerEntry) error {
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	timestamp = timestamp.UTC()
+	transactionUuid := uuid.New()
+	for i := range entries {
+		entries[i].Timestamp = timestamp
+		entries[i].TransactionUuid = transactionUuid
+		entries[i].Reference = reference
+	}
+
+	var err error
+	if l.persistent {
+		err = RecordLedgerEntries(entries)
+	}
+	l.mu.Lock()
+	l.post(entries)
+	l.mu.Unlock()
+	return err
+}
+
+/*
+Apply the entries of one transaction to the balances, positions and PnL. Fills are applied before fees, so that a fee
+paid in the asset that was just bought reduces the new position. Must be called with the lock held.
+*/
+func (l *Ledger) post(entries []LedgerEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	day, month := periods(entries[0].Timestamp)
+	if l.days[day] == nil {
+		l.days[day] = &periodPnl{}
+	}
+	if l.months[month] == nil {
+		l.months[month] = &periodPnl{}
+	}
+
+	var fees []LedgerEntry
+	for _, entry := range entries {
+		switch entry.Account {
+		case AssetsAccount:
+			if l.balances[entry.Exchange] == nil {
+				l.balances[entry.Exchange] = make(map[string]float64)
+			}
+			l.balances[entry.Exchange][entry.Asset] += entry.Amount
+		case TradingAccount:
+			// The trading account holds the counter-entry, the position moves the other way
+			l.addRealized(day, month, l.trade(entry.Asset, -entry.Amount, math.Abs(entry.Value)))
+		case FeesAccount:
+			fees = append(fees, entry)
+		}
+	}
+	for _, fee := range fees {
+		l.addRealized(day, month, l.trade(fee.Asset, -fee.Amount, 0))
+		l.fees += fee.Value
+		l.days[day].fees += fee.Value
+		l.months[month].fees += fee.Value
+	}
+}
+
+func (l *Ledger) addRealized(day string, month string, realized float64) {
+	l.realized += realized
+	l.days[day].realized += realized
+	l.months[month].realized += realized
+}
+
+/*
+Add a quantity of an asset (negative when it is sold or paid) to its position, for the given value in the base currency.
+Returns the realized PnL of the part of the quantity that reduced the position. The base currency is always valued at
+1, so only paying it without receiving anything (a fee) realizes a loss. Must be called with the lock held.
+*/
+func (l *Ledger) trade(asset string, quantity float64, value float64) float64 {
+	if asset == l.baseCurrency {
+		if quantity < 0 {
+			return value + quantity
+		}
+		return 0
+	}
+	if quantity == 0 {
+		return 0
+	}
+
+	p, ok := l.positions[asset]
+	if !ok {
+		p = &position{}
+		l.positions[asset] = p
+	}
+	unitValue := value / math.Abs(quantity)
+	if p.quantity == 0 || (p.quantity > 0) == (quantity > 0) {
+		p.quantity += quantity
+		p.cost += quantity * unitValue
+		return 0
+	}
+
+	averageCost := p.cost / p.quantity
+	closing := math.Min(math.Abs(quantity), math.Abs(p.quantity))
+	direction := p.quantity / math.Abs(p.quantity)
+	realized := closing * (unitValue - averageCost) * direction
+	p.quantity -= closing * direction
+	p.cost -= closing * direction * averageCost
+
+	// The rest of the quantity opens a position in the other direction
+	if remaining := math.Abs(quantity) - closing; remaining > 0 {
+		p.quantity = remaining * -direction
+		p.cost = p.quantity * unitValue
+	}
+	return realized
+}
+
+/*
+Returns the value of an amount of an asset in the base currency, or 0 if there is no price for the asset. Must be called
+with the lock held.
+*/
+func (l *Ledger) value(asset string, amount float64) float64 {
+	return amount * l.marks[asset]
+}
+
+/*
+Must be called with the lock held.
+*/
+func (l *Ledger) unrealizedPnl() float64 {
+	unrealized := 0.0
+	for asset, p := range l.positions {
+		if mark, ok := l.marks[asset]; ok && p.quantity != 0 {
+			unrealized += p.quantity*mark - p.cost
+		}
+	}
+	return unrealized
+}
+
+/*
+Returns the day and the first day of the month of a time (UTC) in the notation of the PnL summary tables.
+*/
+func periods(timestamp time.Time) (string, string) {
+	timestamp = timestamp.UTC()
+	return timestamp.Format("2006-01-02"), timestamp.Format("2006-01") + "-01"
+}
+
+/*
+Insert the entries of a ledger transaction into the database in a single statement.
+*/
+func RecordLedgerEntries(ledgerEntries []LedgerEntry) error {
+	database := database()
+
+	records := make([]interface{}, len(ledgerEntries))
+	for i, ledgerEntry := range ledgerEntries {
+		records[i] = goqu.Record{"uuid": ledgerEntry.Uuid.String(), "timestamp": ledgerEntry.Timestamp, "transaction_uuid": ledgerEntry.TransactionUuid.String(), "reference": ledgerEntry.Reference, "account": ledgerEntry.Account, "exchange": ledgerEntry.Exchange, "asset": ledgerEntry.Asset, "amount": ledgerEntry.Amount, "value": ledgerEntry.Value}
+	}
+
+	insertLedgerEntriesSQL, _, _ := database.Insert("ledger_entries").Rows(records...).ToSQL()
+
+	_, err := internal.DbPool.Exec(insertLedgerEntriesSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: %s", insertLedgerEntriesSQL), zap.String("queryError,", err.Error()))
+		return err
+	}
+
+	utils.Logger.Debug(fmt.Sprintf("Inserted %d new ledgerEntries into the database.", len(ledgerEntries)))
+	return nil
+}
+
+/*
+Returns all LedgerEntries, grouped by transaction in the order they were posted.
+*/
+func GetLedgerEntries() ([]LedgerEntry, error) {
+	database := database()
+
+	var ledgerEntries []LedgerEntry
+	err := database.From("ledger_entries").Order(goqu.C("timestamp").Asc(), goqu.C("transaction_uuid").Asc()).ScanStructs(&ledgerEntries)
+	if err != nil {
+		utils.Logger.Error("Failed to query ledger entries.", zap.Error(err))
+		return nil, err
+	}
+	return ledgerEntries, nil
+}
+
+/*
+Insert a PnlSummary into the pnl_daily or pnl_monthly table, or update it if the period was recorded before.
+*/
+func RecordPnlSummary(table string, pnlSummary PnlSummary) error {
+	database := database()
+
+	record := goqu.Record{"period": pnlSummary.Period, "base_currency": pnlSummary.BaseCurrency, "realized_pnl": pnlSummary.RealizedPnl, "unrealized_pnl": pnlSummary.UnrealizedPnl, "fees": pnlSummary.Fees, "total_pnl": pnlSummary.TotalPnl, "updated_at": pnlSummary.UpdatedAt}
+
+	upsertPnlSummarySQL, _, _ := database.Insert(table).Rows(record).OnConflict(goqu.DoUpdate("period, base_currency", goqu.Record{"realized_pnl": pnlSummary.RealizedPnl, "unrealized_pnl": pnlSummary.UnrealizedPnl, "fees": pnlSummary.Fees, "total_pnl": pnlSummary.TotalPnl, "updated_at": pnlSummary.UpdatedAt})).ToSQL()
+
+	_, err := internal.DbPool.Exec(upsertPnlSummarySQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: %s", upsertPnlSummarySQL), zap.String("queryError,", err.Error()))
+		return err
+	}
+
+	utils.Logger.Debug(fmt.Sprintf("Recorded pnlSummary in %s.", table), zap.Object("pnlSummary", &pnlSummary))
+	return nil
+}
+
+func (p PnlSummary) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
+	encoder.AddString("period", p.Period)
+	encoder.AddString("base_currency", p.BaseCurrency)
+	encoder.AddFloat64("realized_pnl", p.RealizedPnl)
+	encoder.AddFloat64("unrealized_pnl", p.UnrealizedPnl)
+	encoder.AddFloat64("fees", p.Fees)
+	return nil
+}
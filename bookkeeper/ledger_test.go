@@ -0,0 +1,122 @@
+package bookkeeper
+
+import (
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+	"log"
+	"math"
+	"testing"
+	"time"
+)
+
+func setupTest(tb testing.TB) func(tb testing.TB) {
+	log.Println("Setup tests.")
+
+	viper.SetConfigName("config-DEV")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath("../")
+	err := viper.ReadInConfig() // Find and read the config file
+	if err != nil {
+		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+	}
+
+	utils.InitializeLogger()
+	utils.SetLoggerLevel("DEBUG")
+
+	return func(tb testing.TB) {
+		log.Println("Teardown tests.")
+	}
+}
+
+func roundedForTest(value float64) float64 {
+	return math.Round(value*1e6) / 1e6
+}
+
+func Test_Ledger(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	buyOnCoinbase := Fill{Exchange: "Coinbase", BaseAsset: "BTC", QuoteAsset: "USD", Side: "buy", Size: 1, Price: 25000, Fee: 25}
+	marks := []PriceRecord{
+		{Currency: "BTCUSD", Exchange: "Coinbase", Price: 25900},
+		{Currency: "BTCUSD", Exchange: "Gemini", Price: 26100},
+		{Currency: "ETHBTC", Exchange: "Gemini", Price: .06},
+	}
+
+	tests := []struct {
+		name           string
+		fills          []Fill
+		transfers      []TransferRecord
+		wantRealized   float64
+		wantUnrealized float64
+		wantFees       float64
+		wantBalances   map[string]map[string]float64
+	}{
+		{
+			name:         "Arbitrage across two exchanges realizes the spread minus fees.",
+			fills:        []Fill{buyOnCoinbase, {Exchange: "Gemini", BaseAsset: "BTC", QuoteAsset: "USD", Side: "sell", Size: 1, Price: 26000, Fee: 26}},
+			wantRealized: 949,
+			wantFees:     51,
+			wantBalances: map[string]map[string]float64{
+				"Coinbase": {"BTC": 1, "USD": -25025},
+				"Gemini":   {"BTC": -1, "USD": 25974},
+			},
+		},
+		{
+			name:           "Open position is marked to the average price of the exchanges.",
+			fills:          []Fill{buyOnCoinbase},
+			wantRealized:   -25,
+			wantUnrealized: 1000,
+			wantFees:       25,
+			wantBalances:   map[string]map[string]float64{"Coinbase": {"BTC": 1, "USD": -25025}},
+		},
+		{
+			name:           "Withdrawal fee of a transfer is realized at the average cost.",
+			fills:          []Fill{buyOnCoinbase},
+			transfers:      []TransferRecord{{Uuid: uuid.New(), Asset: "BTC", FromExchange: "Coinbase", ToExchange: "Kraken", Amount: .5, EstimatedFee: .001}},
+			wantRealized:   -50,
+			wantUnrealized: 999,
+			wantFees:       51,
+			wantBalances: map[string]map[string]float64{
+				"Coinbase": {"BTC": .499, "USD": -25025},
+				"Kraken":   {"BTC": .5},
+			},
+		},
+		{
+			name:           "Paying for ETH with BTC realizes the BTC at its price in USD.",
+			fills:          []Fill{buyOnCoinbase, {Exchange: "Coinbase", BaseAsset: "ETH", QuoteAsset: "BTC", Side: "buy", Size: 10, Price: .06}},
+			wantRealized:   575,
+			wantUnrealized: 400,
+			wantFees:       25,
+			wantBalances:   map[string]map[string]float64{"Coinbase": {"BTC": .4, "ETH": 10, "USD": -25025}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := NewLedger("USD")
+			ledger.MarkPrices(marks)
+			for _, fill := range tt.fills {
+				assert.Equal(t, ledger.PostFill(fill), nil)
+			}
+			for _, transfer := range tt.transfers {
+				assert.Equal(t, ledger.PostTransfer(transfer), nil)
+			}
+
+			assert.Equal(t, roundedForTest(ledger.RealizedPnl()), tt.wantRealized)
+			assert.Equal(t, roundedForTest(ledger.UnrealizedPnl()), tt.wantUnrealized)
+			for exchange, balances := range tt.wantBalances {
+				for asset, balance := range balances {
+					assert.Equal(t, roundedForTest(ledger.Balance(exchange, asset)), balance)
+				}
+			}
+
+			daily, monthly := ledger.Summaries(time.Now())
+			assert.Equal(t, roundedForTest(daily.RealizedPnl), tt.wantRealized)
+			assert.Equal(t, roundedForTest(daily.Fees), tt.wantFees)
+			assert.Equal(t, roundedForTest(monthly.TotalPnl), roundedForTest(tt.wantRealized+tt.wantUnrealized))
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package bookkeeper
+
+import (
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"time"
+)
+
+/*
+Kinds of downloaded market data. Candles are stored per interval, their cursors use the kind "ohlc_<interval minutes>".
+*/
+const (
+	MarketTrades  = "market_trades"
+	MarketCandles = "market_candles"
+)
+
+/*
+A public trade downloaded from an exchange. Pair is the name of the trade pair on the exchange (e.g. XBTUSD), Currency
+the name used in price_records (e.g. BTCUSD).
+*/
+type MarketTrade struct {
+	Uuid      uuid.UUID `db:"uuid"`
+	Exchange  string    `db:"exchange"`
+	Pair      string    `db:"pair"`
+	Currency  string    `db:"currency"`
+	Timestamp time.Time `db:"timestamp"`
+	Price     float64   `db:"price"`
+	Volume    float64   `db:"volume"`
+	Side      string    `db:"side"`
+	OrderType string    `db:"order_type"`
+}
+
+/*
+A candle downloaded from an exchange. Timestamp is the time the candle opened.
+*/
+type MarketCandle struct {
+	Exchange        string    `db:"exchange"`
+	Pair            string    `db:"pair"`
+	Currency        string    `db:"currency"`
+	IntervalMinutes int       `db:"interval_minutes"`
+	Timestamp       time.Time `db:"timestamp"`
+	Open            float64   `db:"open"`
+	High            float64   `db:"high"`
+	Low             float64   `db:"low"`
+	Close           float64   `db:"close"`
+	Vwap            float64   `db:"vwap"`
+	Volume          float64   `db:"volume"`
+	Count           int       `db:"count"`
+}
+
+/*
+Insert MarketTrades into the database. Trades that were already stored (same uuid) are skipped, so a page that is
+downloaded again after an interruption does not duplicate trades.
+*/
+func RecordMarketTrades(marketTrades []MarketTrade) error {
+	if len(marketTrades) == 0 {
+		return nil
+	}
+	database := database()
+
+	records := make([]interface{}, 0, len(marketTrades))
+	for _, marketTrade := range marketTrades {
+		records = append(records, goqu.Record{"uuid": marketTrade.Uuid.String(), "exchange": marketTrade.Exchange, "pair": marketTrade.Pair, "currency": marketTrade.Currency, "timestamp": marketTrade.Timestamp, "price": marketTrade.Price, "volume": marketTrade.Volume, "side": marketTrade.Side, "order_type": marketTrade.OrderType})
+	}
+
+	insertMarketTradesSQL, _, _ := database.Insert(MarketTrades).Rows(records...).OnConflict(goqu.DoNothing()).ToSQL()
+
+	_, err := internal.DbPool.Exec(insertMarketTradesSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: inserting %d market trades.", len(marketTrades)), zap.String("queryError,", err.Error()))
+		return err
+	}
+
+	utils.Logger.Debug(fmt.Sprintf("Inserted %d market trades into the database.", len(marketTrades)))
+	return nil
+}
+
+/*
+Insert MarketCandles into the database. A candle that was already stored is updated, the last candle of a download is
+still open and changes until its interval has passed.
+*/
+func RecordMarketCandles(marketCandles []MarketCandle) error {
+	if len(marketCandles) == 0 {
+		return nil
+	}
+	database := database()
+
+	records := make([]interface{}, 0, len(marketCandles))
+	for _, marketCandle := range marketCandles {
+		records = append(records, goqu.Record{"exchange": marketCandle.Exchange, "pair": marketCandle.Pair, "currency": marketCandle.Currency, "interval_minutes": marketCandle.IntervalMinutes, "timestamp": marketCandle.Timestamp, "open": marketCandle.Open, "high": marketCandle.High, "low": marketCandle.Low, "close": marketCandle.Close, "vwap": marketCandle.Vwap, "volume": marketCandle.Volume, "count": marketCandle.Count})
+	}
+
+	upsertMarketCandlesSQL, _, _ := database.Insert(MarketCandles).Rows(records...).OnConflict(goqu.DoUpdate("exchange, pair, interval_minutes, timestamp", upsertColumns(internal.Dialect, "open", "high", "low", "close", "vwap", "volume", "count"))).ToSQL()
+
+	_, err := internal.DbPool.Exec(upsertMarketCandlesSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: inserting %d market candles.", len(marketCandles)), zap.String("queryError,", err.Error()))
+		return err
+	}
+
+	utils.Logger.Debug(fmt.Sprintf("Inserted %d market candles into the database.", len(marketCandles)))
+	return nil
+}
+
+/*
+Returns the cursor a download of market data continues from, or 0 if the download has not started yet.
+*/
+func GetMarketDataCursor(exchange string, pair string, kind string) (int64, error) {
+	database := database()
+
+	var cursor int64
+	found, err := database.From("market_data_cursors").Select("cursor").Where(goqu.Ex{"exchange": exchange, "pair": pair, "kind": kind}).ScanVal(&cursor)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Failed to query the %s cursor of %s on %s.", kind, pair, exchange), zap.Error(err))
+		return 0, err
+	}
+	if !found {
+		return 0, nil
+	}
+	return cursor, nil
+}
+
+/*
+Store the cursor a download of market data continues from.
+*/
+func RecordMarketDataCursor(exchange string, pair string, kind string, cursor int64) error {
+	database := database()
+
+	updatedAt := time.Now().Format(time.RFC3339)
+	record := goqu.Record{"exchange": exchange, "pair": pair, "kind": kind, "cursor": cursor, "updated_at": updatedAt}
+
+	upsertCursorSQL, _, _ := database.Insert("market_data_cursors").Rows(record).OnConflict(goqu.DoUpdate("exchange, pair, kind", goqu.Record{"cursor": cursor, "updated_at": updatedAt})).ToSQL()
+
+	_, err := internal.DbPool.Exec(upsertCursorSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: %s", upsertCursorSQL), zap.String("queryError,", err.Error()))
+		return err
+	}
+	return nil
+}
+
+/*
+A row of StreamMarketQuotes. The close time of a candle is computed by the database, SQLite returns it as text.
+*/
+type marketQuote struct {
+	Timestamp string  `db:"timestamp"`
+	Currency  string  `db:"currency"`
+	Price     float64 `db:"price"`
+	Exchange  string  `db:"exchange"`
+}
+
+/*
+Stream downloaded market data between from and to (inclusive, exclusive, zero for no limit) as price records, ordered by
+time. Trades are replayed at their price, candles at their close price when they closed. Market data has no fee, it is
+0 in the price records.
+*/
+func StreamMarketQuotes(kind string, from time.Time, to time.Time, handle func(PriceRecord) error) error {
+	database := database()
+
+	var query *goqu.SelectDataset
+	var timestamp exp.Comparable
+	switch kind {
+	case MarketTrades:
+		timestamp = goqu.C("timestamp")
+		query = database.From(MarketTrades).Select(goqu.C("timestamp"), goqu.C("currency"), goqu.C("price"), goqu.C("exchange"))
+	case MarketCandles:
+		closedAt := candleCloseTime(internal.Dialect)
+		timestamp = closedAt
+		query = database.From(MarketCandles).Select(closedAt.As("timestamp"), goqu.C("currency"), goqu.C("close").As("price"), goqu.C("exchange"))
+	default:
+		return fmt.Errorf("unknown market data %s", kind)
+	}
+	query = query.Order(goqu.I("timestamp").Asc(), goqu.C("exchange").Asc(), goqu.C("currency").Asc())
+	if !from.IsZero() {
+		query = query.Where(timestamp.Gte(from))
+	}
+	if !to.IsZero() {
+		query = query.Where(timestamp.Lt(to))
+	}
+
+	scanner, err := query.Executor().Scanner()
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Failed to query %s.", kind), zap.Error(err))
+		return err
+	}
+	defer scanner.Close()
+	for scanner.Next() {
+		var quote marketQuote
+		if err := scanner.ScanStruct(&quote); err != nil {
+			return err
+		}
+		timestamp, err := time.Parse(time.RFC3339Nano, quote.Timestamp)
+		if err != nil {
+			return fmt.Errorf("invalid %s timestamp %q", kind, quote.Timestamp)
+		}
+		priceRecord := PriceRecord{Timestamp: timestamp.UTC(), Currency: quote.Currency, Price: quote.Price, Exchange: quote.Exchange}
+		if err := handle(priceRecord); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,63 @@
+package bookkeeper
+
+import (
+	"github.com/google/uuid"
+	"go.uber.org/zap/zapcore"
+	"time"
+)
+
+type OrderRecord struct {
+	Uuid                uuid.UUID `db:"uuid"`
+	Timestamp           time.Time `db:"timestamp"`
+	UpdatedAt           time.Time `db:"updated_at"`
+	ArbitrageRecordUuid uuid.UUID `db:"arbitrage_record_uuid"`
+	Leg                 string    `db:"leg"`
+	ClientOrderId       string    `db:"client_order_id"`
+	Exchange            string    `db:"exchange"`
+	OrderId             string    `db:"order_id"`
+	Currency            string    `db:"currency"`
+	Side                string    `db:"side"`
+	Type                string    `db:"type"`
+	Price               float64   `db:"price"`
+	Size                float64   `db:"size"`
+	FilledSize          float64   `db:"filled_size"`
+	AveragePrice        float64   `db:"average_price"`
+	Fee                 float64   `db:"fee"`
+	State               string    `db:"state"`
+	Reason              string    `db:"reason"`
+}
+
+/*
+Insert OrderRecord into the database.
+*/
+func RecordOrderRecord(orderRecord OrderRecord) error {
+	return currentStorage().RecordOrderRecord(orderRecord)
+}
+
+/*
+Update the exchange order id, fills, state and reason of an OrderRecord in the database.
+*/
+func UpdateOrderRecord(orderRecord OrderRecord) error {
+	return currentStorage().UpdateOrderRecord(orderRecord)
+}
+
+/*
+Returns all OrderRecords in one of the given states.
+*/
+func GetOrderRecords(states ...string) ([]OrderRecord, error) {
+	return currentStorage().GetOrderRecords(states...)
+}
+
+func (o OrderRecord) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
+	encoder.AddString("uuid", o.Uuid.String())
+	encoder.AddString("arbitrage_record_uuid", o.ArbitrageRecordUuid.String())
+	encoder.AddString("exchange", o.Exchange)
+	encoder.AddString("client_order_id", o.ClientOrderId)
+	encoder.AddString("order_id", o.OrderId)
+	encoder.AddString("currency", o.Currency)
+	encoder.AddString("side", o.Side)
+	encoder.AddFloat64("size", o.Size)
+	encoder.AddFloat64("filled_size", o.FilledSize)
+	encoder.AddString("state", o.State)
+	return nil
+}
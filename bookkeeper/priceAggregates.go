@@ -0,0 +1,150 @@
+package bookkeeper
+
+import (
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"go.uber.org/zap"
+	"time"
+)
+
+/*
+The OHLC of the quotes of one currency on one exchange during an interval. Timestamp is the start of the interval.
+*/
+type PriceAggregate struct {
+	Exchange        string    `db:"exchange"`
+	Currency        string    `db:"currency"`
+	IntervalMinutes int       `db:"interval_minutes"`
+	Timestamp       time.Time `db:"timestamp"`
+	Open            float64   `db:"open"`
+	High            float64   `db:"high"`
+	Low             float64   `db:"low"`
+	Close           float64   `db:"close"`
+	Average         float64   `db:"average"`
+	Count           int       `db:"count"`
+}
+
+/*
+The OHLC of the spread of one currency between two exchanges during an interval, in percent of the price on exchange B
+like the spread of spread_signal_records. Timestamp is the start of the interval.
+*/
+type SpreadAggregate struct {
+	Currency        string    `db:"currency"`
+	ExchangeA       string    `db:"exchange_a"`
+	ExchangeB       string    `db:"exchange_b"`
+	IntervalMinutes int       `db:"interval_minutes"`
+	Timestamp       time.Time `db:"timestamp"`
+	Open            float64   `db:"open"`
+	High            float64   `db:"high"`
+	Low             float64   `db:"low"`
+	Close           float64   `db:"close"`
+	Average         float64   `db:"average"`
+	Count           int       `db:"count"`
+}
+
+/*
+Insert the PriceAggregates and SpreadAggregates of an interval into the database in one transaction, so that the
+interval is never stored with only one of them. An aggregate that was already stored is replaced, so an interval can be
+rolled up again.
+*/
+func RecordAggregates(priceAggregates []PriceAggregate, spreadAggregates []SpreadAggregate) error {
+	if len(priceAggregates) == 0 && len(spreadAggregates) == 0 {
+		return nil
+	}
+
+	priceRecords := make([]interface{}, 0, len(priceAggregates))
+	for _, aggregate := range priceAggregates {
+		priceRecords = append(priceRecords, goqu.Record{"exchange": aggregate.Exchange, "currency": aggregate.Currency, "interval_minutes": aggregate.IntervalMinutes, "timestamp": aggregate.Timestamp, "open": aggregate.Open, "high": aggregate.High, "low": aggregate.Low, "close": aggregate.Close, "average": aggregate.Average, "count": aggregate.Count})
+	}
+	spreadRecords := make([]interface{}, 0, len(spreadAggregates))
+	for _, aggregate := range spreadAggregates {
+		spreadRecords = append(spreadRecords, goqu.Record{"currency": aggregate.Currency, "exchange_a": aggregate.ExchangeA, "exchange_b": aggregate.ExchangeB, "interval_minutes": aggregate.IntervalMinutes, "timestamp": aggregate.Timestamp, "open": aggregate.Open, "high": aggregate.High, "low": aggregate.Low, "close": aggregate.Close, "average": aggregate.Average, "count": aggregate.Count})
+	}
+	upserts := []struct {
+		table    string
+		rows     []interface{}
+		conflict string
+	}{
+		{"price_aggregates", priceRecords, "exchange, currency, interval_minutes, timestamp"},
+		{"spread_aggregates", spreadRecords, "currency, exchange_a, exchange_b, interval_minutes, timestamp"},
+	}
+
+	tx, err := database().Begin()
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database transaction failed: inserting %d price and %d spread aggregates.", len(priceAggregates), len(spreadAggregates)), zap.String("queryError,", err.Error()))
+		return err
+	}
+	err = tx.Wrap(func() error {
+		for _, upsert := range upserts {
+			if len(upsert.rows) == 0 {
+				continue
+			}
+			upsertSQL, _, _ := tx.Insert(upsert.table).Rows(upsert.rows...).OnConflict(goqu.DoUpdate(upsert.conflict, upsertColumns(internal.Dialect, "open", "high", "low", "close", "average", "count"))).ToSQL()
+			if _, err := tx.Exec(upsertSQL); err != nil {
+				return fmt.Errorf("inserting %d records into %s: %v", len(upsert.rows), upsert.table, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database transaction failed: inserting %d price and %d spread aggregates.", len(priceAggregates), len(spreadAggregates)), zap.String("queryError,", err.Error()))
+		return err
+	}
+
+	utils.Logger.Debug(fmt.Sprintf("Inserted %d price and %d spread aggregates into the database.", len(priceAggregates), len(spreadAggregates)))
+	return nil
+}
+
+/*
+Returns the start of the latest interval that was rolled up into price aggregates of the given interval, zero if none
+was.
+*/
+func GetLatestPriceAggregateTime(intervalMinutes int) (time.Time, error) {
+	database := database()
+
+	var timestamp time.Time
+	_, err := database.From("price_aggregates").Select("timestamp").Where(goqu.Ex{"interval_minutes": intervalMinutes}).Order(goqu.C("timestamp").Desc()).Limit(1).ScanVal(&timestamp)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Failed to query the latest %d minute price aggregate.", intervalMinutes), zap.Error(err))
+		return time.Time{}, err
+	}
+	return timestamp.UTC(), nil
+}
+
+/*
+Returns the time of the oldest price record, zero if there is none. Unless withOpportunities, price records that an
+arbitrage opportunity was detected with are ignored.
+*/
+func GetOldestPriceRecordTime(withOpportunities bool) (time.Time, error) {
+	database := database()
+
+	query := database.From("price_records").Select("timestamp").Order(goqu.C("timestamp").Asc()).Limit(1)
+	if !withOpportunities {
+		query = query.Where(goqu.C("is_arbitrage_opportunity").IsFalse())
+	}
+	var timestamp time.Time
+	_, err := query.ScanVal(&timestamp)
+	if err != nil {
+		utils.Logger.Error("Failed to query the oldest price record.", zap.Error(err))
+		return time.Time{}, err
+	}
+	return timestamp.UTC(), nil
+}
+
+/*
+Delete the price records between from and to (inclusive, exclusive) and return how many were deleted. Price records that
+an arbitrage opportunity was detected with are kept, the opportunity references them.
+*/
+func DeletePriceRecords(from time.Time, to time.Time) (int64, error) {
+	database := database()
+
+	deletePriceRecordsSQL, _, _ := database.Delete("price_records").Where(goqu.C("timestamp").Gte(from), goqu.C("timestamp").Lt(to), goqu.C("is_arbitrage_opportunity").IsFalse()).ToSQL()
+
+	result, err := internal.DbPool.Exec(deletePriceRecordsSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: %s", deletePriceRecordsSQL), zap.String("queryError,", err.Error()))
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,124 @@
+package bookkeeper
+
+import (
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"time"
+)
+
+/*
+Selects the records of a time range (From inclusive, To exclusive, zero for no limit). Exchange and Currency are ignored
+if empty. An arbitrage event matches an exchange if it is either of its exchanges, a triangular arbitrage event matches
+a currency if it is one of its trade pairs. Cycle selects the records of one detection cycle, every cycle if uuid.Nil.
+*/
+type RecordFilter struct {
+	From              time.Time
+	To                time.Time
+	Exchange          string
+	Currency          string
+	OpportunitiesOnly bool
+	Cycle             uuid.UUID
+}
+
+/*
+Read the PriceRecords that match filter in timestamp order and pass them to handle one by one.
+*/
+func StreamFilteredPriceRecords(filter RecordFilter, handle func(PriceRecord) error) error {
+	query := filteredRecords("price_records", filter, "is_arbitrage_opportunity")
+	if filter.Exchange != "" {
+		query = query.Where(goqu.C("exchange").Eq(filter.Exchange))
+	}
+	if filter.Currency != "" {
+		query = query.Where(goqu.C("currency").Eq(filter.Currency))
+	}
+	return streamRecords(query, "price records", func(scan func(interface{}) error) error {
+		var priceRecord PriceRecord
+		if err := scan(&priceRecord); err != nil {
+			return err
+		}
+		return handle(priceRecord)
+	})
+}
+
+/*
+Read the ArbitrageEventRecords that match filter in timestamp order and pass them to handle one by one.
+*/
+func StreamArbitrageRecords(filter RecordFilter, handle func(ArbitrageEventRecord) error) error {
+	query := filteredRecords("arbitrage_records", filter, "is_arbitrage_opportunity")
+	if filter.Exchange != "" {
+		query = query.Where(goqu.Or(goqu.C("exchange_a").Eq(filter.Exchange), goqu.C("exchange_b").Eq(filter.Exchange)))
+	}
+	if filter.Currency != "" {
+		query = query.Where(goqu.C("currency").Eq(filter.Currency))
+	}
+	return streamRecords(query, "arbitrage records", func(scan func(interface{}) error) error {
+		var arbitrageEventRecord ArbitrageEventRecord
+		if err := scan(&arbitrageEventRecord); err != nil {
+			return err
+		}
+		return handle(arbitrageEventRecord)
+	})
+}
+
+/*
+Read the TriangularArbitrageEventRecords that match filter in timestamp order and pass them to handle one by one.
+*/
+func StreamTriangularArbitrageRecords(filter RecordFilter, handle func(TriangularArbitrageEventRecord) error) error {
+	query := filteredRecords("triangular_arbitrage_1_exchange", filter, "is_triangular_arbitrage_opportunity")
+	if filter.Exchange != "" {
+		query = query.Where(goqu.C("exchange").Eq(filter.Exchange))
+	}
+	if filter.Currency != "" {
+		query = query.Where(goqu.Or(goqu.C("trade_pair_1").Eq(filter.Currency), goqu.C("trade_pair_2").Eq(filter.Currency), goqu.C("trade_pair_3").Eq(filter.Currency)))
+	}
+	return streamRecords(query, "triangular arbitrage records", func(scan func(interface{}) error) error {
+		var triangularArbitrageEventRecord TriangularArbitrageEventRecord
+		if err := scan(&triangularArbitrageEventRecord); err != nil {
+			return err
+		}
+		return handle(triangularArbitrageEventRecord)
+	})
+}
+
+/*
+Select the records of a table in the time range of filter, ordered by timestamp.
+*/
+func filteredRecords(table string, filter RecordFilter, opportunityColumn string) *goqu.SelectDataset {
+	conditions := []exp.Expression{}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, goqu.C("timestamp").Gte(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, goqu.C("timestamp").Lt(filter.To))
+	}
+	if filter.OpportunitiesOnly {
+		conditions = append(conditions, goqu.C(opportunityColumn).IsTrue())
+	}
+	if filter.Cycle != uuid.Nil {
+		conditions = append(conditions, goqu.C("cycle_uuid").Eq(filter.Cycle.String()))
+	}
+	return database().From(table).Where(conditions...).Order(goqu.C("timestamp").Asc(), goqu.C("uuid").Asc())
+}
+
+/*
+Run a query and pass a function that scans the current row into a struct to handle for every row, without loading the
+result into memory.
+*/
+func streamRecords(query *goqu.SelectDataset, name string, handle func(scan func(interface{}) error) error) error {
+	scanner, err := query.Executor().Scanner()
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Failed to query %s.", name), zap.Error(err))
+		return err
+	}
+	defer scanner.Close()
+	for scanner.Next() {
+		if err := handle(scanner.ScanStruct); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
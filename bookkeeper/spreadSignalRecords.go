@@ -0,0 +1,56 @@
+package bookkeeper
+
+import (
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"go.uber.org/zap/zapcore"
+	"time"
+)
+
+/*
+An entry or exit signal of the spread mean-reversion strategy. Spread is (PriceA - PriceB) / PriceB in percent, Mean and
+StandardDeviation are those of the rolling window of spreads before this one.
+
+Direction is "short_spread" (sell on exchange A, buy on exchange B) when the spread is above its mean and "long_spread"
+when it is below. An exit references its entry with EntryUuid; SpreadChange is how much the spread reverted since the
+entry in percent, positive if it moved in favour of the position. CycleUuid references the detection cycle of the
+signal.
+*/
+type SpreadSignalRecord struct {
+	Uuid              uuid.UUID `db:"uuid"`
+	Timestamp         time.Time `db:"timestamp"`
+	Currency          string    `db:"currency"`
+	ExchangeA         string    `db:"exchange_a"`
+	PriceA            float64   `db:"price_a"`
+	ExchangeB         string    `db:"exchange_b"`
+	PriceB            float64   `db:"price_b"`
+	Spread            float64   `db:"spread"`
+	Mean              float64   `db:"mean"`
+	StandardDeviation float64   `db:"standard_deviation"`
+	ZScore            float64   `db:"z_score"`
+	Action            string    `db:"action"`
+	Direction         string    `db:"direction"`
+	EntryUuid         uuid.UUID `db:"entry_uuid"`
+	SpreadChange      float64   `db:"spread_change"`
+	CycleUuid         uuid.UUID `db:"cycle_uuid"`
+}
+
+func spreadSignalRecordRows(spreadSignalRecords []SpreadSignalRecord) []interface{} {
+	records := make([]interface{}, 0, len(spreadSignalRecords))
+	for _, spreadSignalRecord := range spreadSignalRecords {
+		records = append(records, goqu.Record{"uuid": spreadSignalRecord.Uuid.String(), "timestamp": spreadSignalRecord.Timestamp, "currency": spreadSignalRecord.Currency, "exchange_a": spreadSignalRecord.ExchangeA, "price_a": spreadSignalRecord.PriceA, "exchange_b": spreadSignalRecord.ExchangeB, "price_b": spreadSignalRecord.PriceB, "spread": spreadSignalRecord.Spread, "mean": spreadSignalRecord.Mean, "standard_deviation": spreadSignalRecord.StandardDeviation, "z_score": spreadSignalRecord.ZScore, "action": spreadSignalRecord.Action, "direction": spreadSignalRecord.Direction, "entry_uuid": spreadSignalRecord.EntryUuid.String(), "spread_change": spreadSignalRecord.SpreadChange, "cycle_uuid": spreadSignalRecord.CycleUuid.String()})
+	}
+	return records
+}
+
+func (s SpreadSignalRecord) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
+	encoder.AddString("uuid", s.Uuid.String())
+	encoder.AddString("currency", s.Currency)
+	encoder.AddString("exchange_a", s.ExchangeA)
+	encoder.AddString("exchange_b", s.ExchangeB)
+	encoder.AddFloat64("spread", s.Spread)
+	encoder.AddFloat64("z_score", s.ZScore)
+	encoder.AddString("action", s.Action)
+	encoder.AddString("direction", s.Direction)
+	return nil
+}
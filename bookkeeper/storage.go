@@ -0,0 +1,310 @@
+package bookkeeper
+
+import (
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/utils"
+	"database/sql"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"go.uber.org/zap"
+	"time"
+)
+
+/*
+Where the bookkeeper stores quotes, arbitrage events, triangular arbitrage events, detection cycles and the orders
+(trades) of the bot.
+*/
+type Storage interface {
+	RecordPriceRecords(priceRecords []PriceRecord) error
+	StreamPriceRecords(from time.Time, to time.Time, handle func(PriceRecord) error) error
+	RecordArbitrageRecords(arbitrageEventRecords []ArbitrageEventRecord) error
+	RecordTriangularArbitrageRecords(triangularArbitrageEventRecords []TriangularArbitrageEventRecord) error
+	RecordCycles(cycles []Cycle) error
+	RecordOrderRecord(orderRecord OrderRecord) error
+	UpdateOrderRecord(orderRecord OrderRecord) error
+	GetOrderRecords(states ...string) ([]OrderRecord, error)
+}
+
+/*
+Storage in a SQL database. The tables are the same in every database, the dialect decides how queries are written.
+*/
+type sqlStorage struct {
+	db      *sql.DB
+	dialect string
+}
+
+/*
+Storage in a MySQL database (internal/migrations/mysql).
+*/
+func NewMySqlStorage(db *sql.DB) Storage {
+	return &sqlStorage{db: db, dialect: internal.MySql}
+}
+
+/*
+Storage in a PostgreSQL database (internal/migrations/postgres).
+*/
+func NewPostgresStorage(db *sql.DB) Storage {
+	return &sqlStorage{db: db, dialect: internal.Postgres}
+}
+
+/*
+Storage in a SQLite database (internal/migrations/sqlite3).
+*/
+func NewSqliteStorage(db *sql.DB) Storage {
+	return &sqlStorage{db: db, dialect: internal.Sqlite}
+}
+
+/*
+Returns the storage of a database by the name of its dialect (internal.MySql, internal.Postgres or internal.Sqlite).
+*/
+func NewStorage(dialect string, db *sql.DB) (Storage, error) {
+	switch dialect {
+	case internal.MySql:
+		return NewMySqlStorage(db), nil
+	case internal.Postgres:
+		return NewPostgresStorage(db), nil
+	case internal.Sqlite:
+		return NewSqliteStorage(db), nil
+	}
+	return nil, fmt.Errorf("unknown database dialect %s", dialect)
+}
+
+var storage Storage
+
+/*
+Store records in the given storage instead of the database connected by internal.ConnectToDatabase.
+*/
+func UseStorage(s Storage) {
+	storage = s
+}
+
+func currentStorage() Storage {
+	if storage != nil {
+		return storage
+	}
+	return &sqlStorage{db: internal.DbPool, dialect: internal.Dialect}
+}
+
+/*
+Returns the query builder of the database connected by internal.ConnectToDatabase.
+*/
+func database() *goqu.Database {
+	return goqu.New(internal.Dialect, internal.DbPool)
+}
+
+/*
+Returns the update of an upsert that overwrites the given columns with the values of the inserted row.
+*/
+func upsertColumns(dialect string, columns ...string) goqu.Record {
+	record := goqu.Record{}
+	for _, column := range columns {
+		if dialect == internal.MySql {
+			record[column] = goqu.L(fmt.Sprintf("VALUES(`%s`)", column))
+		} else {
+			record[column] = goqu.I("excluded." + column)
+		}
+	}
+	return record
+}
+
+/*
+Returns the time a candle closed, its open time plus its interval.
+*/
+func candleCloseTime(dialect string) exp.LiteralExpression {
+	switch dialect {
+	case internal.Postgres:
+		return goqu.L(`("timestamp" + "interval_minutes" * INTERVAL '1 minute')`)
+	case internal.Sqlite:
+		return goqu.L("strftime('%Y-%m-%dT%H:%M:%f', `timestamp`, '+' || `interval_minutes` || ' minutes') || '000000Z'")
+	}
+	return goqu.L("DATE_ADD(`timestamp`, INTERVAL `interval_minutes` MINUTE)")
+}
+
+func priceRecordRows(priceRecords []PriceRecord) []interface{} {
+	records := make([]interface{}, 0, len(priceRecords))
+	for _, priceRecord := range priceRecords {
+		records = append(records, goqu.Record{"uuid": priceRecord.Uuid.String(), "timestamp": priceRecord.Timestamp, "currency": priceRecord.Currency, "price": priceRecord.Price, "fee": priceRecord.Fee, "exchange": priceRecord.Exchange, "arbitrage_record_uuid": priceRecord.ArbitrageRecordUuid.String(), "is_arbitrage_opportunity": priceRecord.IsArbitrageOpportunity, "cycle_uuid": priceRecord.CycleUuid.String()})
+	}
+	return records
+}
+
+func arbitrageRecordRows(arbitrageEventRecords []ArbitrageEventRecord) []interface{} {
+	records := make([]interface{}, 0, len(arbitrageEventRecords))
+	for _, arbitrageEventRecord := range arbitrageEventRecords {
+		records = append(records, goqu.Record{"uuid": arbitrageEventRecord.Uuid.String(), "timestamp": arbitrageEventRecord.Timestamp, "currency": arbitrageEventRecord.Currency, "price_a": arbitrageEventRecord.PriceA, "exchange_a": arbitrageEventRecord.ExchangeA, "price_b": arbitrageEventRecord.PriceB, "exchange_b": arbitrageEventRecord.ExchangeB, "projected_profit": arbitrageEventRecord.ProjectedProfit, "is_arbitrage_opportunity": arbitrageEventRecord.IsArbitrageOpportunity, "trade_size": arbitrageEventRecord.TradeSize, "is_blocked_by_inventory": arbitrageEventRecord.IsBlockedByInventory, "price_record_uuid_a": arbitrageEventRecord.PriceRecordUuidA.String(), "price_record_uuid_b": arbitrageEventRecord.PriceRecordUuidB.String(), "cycle_uuid": arbitrageEventRecord.CycleUuid.String()})
+	}
+	return records
+}
+
+func triangularArbitrageRecordRows(triangularArbitrageEventRecords []TriangularArbitrageEventRecord) []interface{} {
+	records := make([]interface{}, 0, len(triangularArbitrageEventRecords))
+	for _, triangularArbitrageEventRecord := range triangularArbitrageEventRecords {
+		records = append(records, goqu.Record{"uuid": triangularArbitrageEventRecord.Uuid.String(), "timestamp": triangularArbitrageEventRecord.Timestamp, "exchange": triangularArbitrageEventRecord.Exchange, "trade_pair_1": triangularArbitrageEventRecord.TradePair1, "trade_pair_1_exchange_rate": triangularArbitrageEventRecord.TradePair1ExchangeRate, "trade_pair_2": triangularArbitrageEventRecord.TradePair2, "trade_pair_2_exchange_rate": triangularArbitrageEventRecord.TradePair2ExchangeRate, "trade_pair_3_exchange_rate": triangularArbitrageEventRecord.TradePair3ExchangeRate, "trade_pair_3": triangularArbitrageEventRecord.TradePair3, "cross_exchange_rate_difference": triangularArbitrageEventRecord.CrossExchangeRateDiff, "is_triangular_arbitrage_opportunity": triangularArbitrageEventRecord.IsTriangularArbitrageOpportunity, "trade_pair_1_price_record_uuid": triangularArbitrageEventRecord.TradePair1PriceRecordUuid.String(), "trade_pair_2_price_record_uuid": triangularArbitrageEventRecord.TradePair2PriceRecordUuid.String(), "trade_pair_3_price_record_uuid": triangularArbitrageEventRecord.TradePair3PriceRecordUuid.String(), "cycle_uuid": triangularArbitrageEventRecord.CycleUuid.String()})
+	}
+	return records
+}
+
+func (s *sqlStorage) database() *goqu.Database {
+	return goqu.New(s.dialect, s.db)
+}
+
+func (s *sqlStorage) RecordPriceRecords(priceRecords []PriceRecord) error {
+	if len(priceRecords) == 0 {
+		return nil
+	}
+	insertPriceRecordsSQL, _, _ := s.database().Insert("price_records").Rows(priceRecordRows(priceRecords)...).ToSQL()
+
+	_, err := s.db.Exec(insertPriceRecordsSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: inserting %d price records.", len(priceRecords)), zap.String("queryError,", err.Error()))
+		return err
+	}
+	utils.Logger.Debug(fmt.Sprintf("Inserted %d new priceRecords into the database.", len(priceRecords)))
+
+	return nil
+}
+
+func (s *sqlStorage) StreamPriceRecords(from time.Time, to time.Time, handle func(PriceRecord) error) error {
+	query := s.database().From("price_records").Select("timestamp", "currency", "price", "fee", "exchange").Order(goqu.C("timestamp").Asc(), goqu.C("exchange").Asc(), goqu.C("currency").Asc())
+	if !from.IsZero() {
+		query = query.Where(goqu.C("timestamp").Gte(from))
+	}
+	if !to.IsZero() {
+		query = query.Where(goqu.C("timestamp").Lt(to))
+	}
+
+	scanner, err := query.Executor().Scanner()
+	if err != nil {
+		utils.Logger.Error("Failed to query price records.", zap.Error(err))
+		return err
+	}
+	defer scanner.Close()
+	for scanner.Next() {
+		var priceRecord PriceRecord
+		if err := scanner.ScanStruct(&priceRecord); err != nil {
+			return err
+		}
+		if err := handle(priceRecord); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *sqlStorage) RecordArbitrageRecords(arbitrageEventRecords []ArbitrageEventRecord) error {
+	if len(arbitrageEventRecords) == 0 {
+		return nil
+	}
+	insertArbitrageEventsSQL, _, _ := s.database().Insert("arbitrage_records").Rows(arbitrageRecordRows(arbitrageEventRecords)...).ToSQL()
+
+	_, err := s.db.Exec(insertArbitrageEventsSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: inserting %d arbitrage records.", len(arbitrageEventRecords)), zap.String("queryError,", err.Error()))
+		return err
+	}
+	utils.Logger.Debug(fmt.Sprintf("Inserted %d new arbitrageRecords into the database.", len(arbitrageEventRecords)))
+
+	return nil
+}
+
+func (s *sqlStorage) RecordTriangularArbitrageRecords(triangularArbitrageEventRecords []TriangularArbitrageEventRecord) error {
+	if len(triangularArbitrageEventRecords) == 0 {
+		return nil
+	}
+	insertTriangularArbitrageEventsSQL, _, _ := s.database().Insert("triangular_arbitrage_1_exchange").Rows(triangularArbitrageRecordRows(triangularArbitrageEventRecords)...).ToSQL()
+
+	_, err := s.db.Exec(insertTriangularArbitrageEventsSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: inserting %d triangular arbitrage records.", len(triangularArbitrageEventRecords)), zap.String("queryError,", err.Error()))
+		return err
+	}
+	utils.Logger.Debug(fmt.Sprintf("Inserted %d new triangularArbitrageRecords into the database.", len(triangularArbitrageEventRecords)))
+
+	return nil
+}
+
+/*
+Insert every cycle with its snapshot, quotes and events in one transaction, so that a cycle is either stored completely
+or not at all.
+*/
+func (s *sqlStorage) RecordCycles(cycles []Cycle) error {
+	if len(cycles) == 0 {
+		return nil
+	}
+	tx, err := s.database().Begin()
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database transaction failed: recording %d cycles.", len(cycles)), zap.String("queryError,", err.Error()))
+		return err
+	}
+	err = tx.Wrap(func() error {
+		for _, cycle := range cycles {
+			snapshot := cycle.Snapshot
+			inserts := []struct {
+				table string
+				rows  []interface{}
+			}{
+				{"cycle_snapshots", []interface{}{goqu.Record{"uuid": snapshot.Uuid.String(), "timestamp": snapshot.Timestamp, "quotes": snapshot.Quotes, "arbitrage_events": snapshot.ArbitrageEvents, "triangular_arbitrage_events": snapshot.TriangularArbitrageEvents, "spread_signals": snapshot.SpreadSignals, "opportunities": snapshot.Opportunities}}},
+				{"price_records", priceRecordRows(cycle.PriceRecords)},
+				{"arbitrage_records", arbitrageRecordRows(cycle.ArbitrageEventRecords)},
+				{"triangular_arbitrage_1_exchange", triangularArbitrageRecordRows(cycle.TriangularArbitrageEventRecords)},
+				{"spread_signal_records", spreadSignalRecordRows(cycle.SpreadSignalRecords)},
+			}
+			for _, insert := range inserts {
+				if len(insert.rows) == 0 {
+					continue
+				}
+				insertSQL, _, _ := tx.Insert(insert.table).Rows(insert.rows...).ToSQL()
+				if _, err := tx.Exec(insertSQL); err != nil {
+					return fmt.Errorf("inserting %d records into %s of cycle %s: %v", len(insert.rows), insert.table, snapshot.Uuid, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database transaction failed: recording %d cycles.", len(cycles)), zap.String("queryError,", err.Error()))
+		return err
+	}
+	utils.Logger.Debug(fmt.Sprintf("Inserted %d new cycles into the database.", len(cycles)))
+
+	return nil
+}
+
+func (s *sqlStorage) RecordOrderRecord(orderRecord OrderRecord) error {
+	record := goqu.Record{"uuid": orderRecord.Uuid.String(), "timestamp": orderRecord.Timestamp, "updated_at": orderRecord.UpdatedAt, "arbitrage_record_uuid": orderRecord.ArbitrageRecordUuid.String(), "leg": orderRecord.Leg, "client_order_id": orderRecord.ClientOrderId, "exchange": orderRecord.Exchange, "order_id": orderRecord.OrderId, "currency": orderRecord.Currency, "side": orderRecord.Side, "type": orderRecord.Type, "price": orderRecord.Price, "size": orderRecord.Size, "filled_size": orderRecord.FilledSize, "average_price": orderRecord.AveragePrice, "fee": orderRecord.Fee, "state": orderRecord.State, "reason": orderRecord.Reason}
+
+	insertOrderSQL, _, _ := s.database().Insert("order_records").Rows(record).ToSQL()
+
+	_, err := s.db.Exec(insertOrderSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: %s", insertOrderSQL), zap.String("queryError,", err.Error()))
+		return err
+	}
+
+	utils.Logger.Debug("Inserted new orderRecord into the database.", zap.Object("orderRecord", &orderRecord))
+	return nil
+}
+
+func (s *sqlStorage) UpdateOrderRecord(orderRecord OrderRecord) error {
+	updateOrderSQL, _, _ := s.database().Update("order_records").Set(goqu.Record{"updated_at": orderRecord.UpdatedAt, "order_id": orderRecord.OrderId, "filled_size": orderRecord.FilledSize, "average_price": orderRecord.AveragePrice, "fee": orderRecord.Fee, "state": orderRecord.State, "reason": orderRecord.Reason}).Where(goqu.Ex{"uuid": orderRecord.Uuid.String()}).ToSQL()
+
+	_, err := s.db.Exec(updateOrderSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: %s", updateOrderSQL), zap.String("queryError,", err.Error()))
+		return err
+	}
+
+	utils.Logger.Debug("Updated orderRecord in the database.", zap.Object("orderRecord", &orderRecord))
+	return nil
+}
+
+func (s *sqlStorage) GetOrderRecords(states ...string) ([]OrderRecord, error) {
+	var orderRecords []OrderRecord
+	err := s.database().From("order_records").Where(goqu.C("state").In(states)).Order(goqu.C("timestamp").Asc()).ScanStructs(&orderRecords)
+	if err != nil {
+		utils.Logger.Error("Failed to query order records.", zap.Error(err))
+		return nil, err
+	}
+	return orderRecords, nil
+}
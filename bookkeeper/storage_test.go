@@ -0,0 +1,252 @@
+package bookkeeper
+
+import (
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/migrations"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/magiconair/properties/assert"
+	_ "modernc.org/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/*
+Connect the bookkeeper to an empty SQLite database with the schema of the bot.
+*/
+func setupSqliteForTest(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "crypto_arbitrage_bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := migrations.LatestVersion(internal.Sqlite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrations.Migrate(db, internal.Sqlite, latest); err != nil {
+		t.Fatal(err)
+	}
+
+	dbPool, dialect := internal.DbPool, internal.Dialect
+	internal.DbPool, internal.Dialect = db, internal.Sqlite
+	t.Cleanup(func() {
+		internal.DbPool, internal.Dialect = dbPool, dialect
+		db.Close()
+	})
+	return db
+}
+
+func timeForTest(value string) time.Time {
+	timestamp, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		panic(err)
+	}
+	return timestamp
+}
+
+func Test_SqliteStorage(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+	db := setupSqliteForTest(t)
+
+	priceRecords := []PriceRecord{
+		{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T10:00:00Z"), Currency: "BTCUSD", Price: 27000, Fee: .006, Exchange: "Coinbase"},
+		{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T10:00:05Z"), Currency: "BTCUSD", Price: 27010, Fee: .0026, Exchange: "Kraken"},
+		{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T10:00:05.25Z"), Currency: "ETHUSD", Price: 1899, Fee: .0026, Exchange: "Kraken"},
+		{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T10:00:10Z"), Currency: "ETHUSD", Price: 1900, Fee: .004, Exchange: "Gemini"},
+	}
+	assert.Equal(t, RecordPriceRecord(priceRecords...) == nil, true)
+
+	tests := []struct {
+		name          string
+		from          time.Time
+		to            time.Time
+		wantExchanges []string
+	}{
+		{
+			name:          "Without limits every price record is streamed in timestamp order.",
+			wantExchanges: []string{"Coinbase", "Kraken", "Kraken", "Gemini"},
+		},
+		{
+			name:          "From is inclusive, to is exclusive.",
+			from:          timeForTest("2023-06-01T10:00:05Z"),
+			to:            timeForTest("2023-06-01T10:00:10Z"),
+			wantExchanges: []string{"Kraken", "Kraken"},
+		},
+		{
+			name:          "Timestamps keep their fractions of a second.",
+			from:          timeForTest("2023-06-01T10:00:05.1Z"),
+			to:            timeForTest("2023-06-01T10:00:05.3Z"),
+			wantExchanges: []string{"Kraken"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var exchanges []string
+			err := StreamPriceRecords(tt.from, tt.to, func(priceRecord PriceRecord) error {
+				exchanges = append(exchanges, priceRecord.Exchange)
+				assert.Equal(t, priceRecord.Timestamp.IsZero(), false)
+				return nil
+			})
+			assert.Equal(t, err, nil)
+			assert.Equal(t, exchanges, tt.wantExchanges)
+		})
+	}
+
+	t.Run("Arbitrage and triangular arbitrage events are stored with their booleans and quotes.", func(t *testing.T) {
+		err := RecordArbitrageRecords([]ArbitrageEventRecord{{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T10:00:05Z"), Currency: "BTCUSD", PriceA: 27000, ExchangeA: "Coinbase", PriceB: 27010, ExchangeB: "Kraken", IsArbitrageOpportunity: true, PriceRecordUuidA: priceRecords[0].Uuid, PriceRecordUuidB: priceRecords[1].Uuid}})
+		assert.Equal(t, err, nil)
+		err = RecordTriangularArbitrageRecord(TriangularArbitrageEventRecord{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T10:00:05Z"), Exchange: "Gemini", TradePair1: "ETHBTC", TradePair2: "LTCETH", TradePair3: "LTCBTC"})
+		assert.Equal(t, err, nil)
+
+		var opportunities int
+		assert.Equal(t, db.QueryRow("SELECT COUNT(*) FROM arbitrage_records WHERE is_arbitrage_opportunity").Scan(&opportunities), nil)
+		assert.Equal(t, opportunities, 1)
+		assert.Equal(t, db.QueryRow("SELECT COUNT(*) FROM triangular_arbitrage_1_exchange WHERE NOT is_triangular_arbitrage_opportunity").Scan(&opportunities), nil)
+		assert.Equal(t, opportunities, 1)
+
+		var exchangeA string
+		assert.Equal(t, db.QueryRow("SELECT price_records.exchange FROM arbitrage_records JOIN price_records ON price_records.uuid = arbitrage_records.price_record_uuid_a").Scan(&exchangeA), nil)
+		assert.Equal(t, exchangeA, "Coinbase")
+	})
+
+	t.Run("Orders are updated in place.", func(t *testing.T) {
+		orderRecord := OrderRecord{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T10:00:05Z"), UpdatedAt: timeForTest("2023-06-01T10:00:05Z"), ClientOrderId: "client-1", Exchange: "Kraken", Currency: "BTCUSD", Side: "sell", Size: .01, State: "open"}
+		assert.Equal(t, RecordOrderRecord(orderRecord), nil)
+		orderRecord.State, orderRecord.FilledSize = "filled", .01
+		assert.Equal(t, UpdateOrderRecord(orderRecord), nil)
+
+		orderRecords, err := GetOrderRecords("filled")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(orderRecords), 1)
+		assert.Equal(t, orderRecords[0].Uuid, orderRecord.Uuid)
+		assert.Equal(t, orderRecords[0].FilledSize, .01)
+	})
+
+	t.Run("Order times are stored in UTC with fractions of a second and ordered across offsets.", func(t *testing.T) {
+		earlier := timeForTest("2023-06-01T11:00:05.25+02:00")
+		later := timeForTest("2023-06-01T09:30:00Z")
+		for _, timestamp := range []time.Time{later, earlier} {
+			orderRecord := OrderRecord{Uuid: uuid.New(), Timestamp: timestamp, UpdatedAt: timestamp, ClientOrderId: timestamp.String(), Exchange: "Kraken", Currency: "BTCUSD", State: "acknowledged"}
+			assert.Equal(t, RecordOrderRecord(orderRecord), nil)
+		}
+
+		orderRecords, err := GetOrderRecords("acknowledged")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(orderRecords), 2)
+		assert.Equal(t, orderRecords[0].Timestamp.Equal(earlier), true)
+		assert.Equal(t, orderRecords[1].Timestamp.Equal(later), true)
+	})
+
+	t.Run("Market data upserts use the dialect of the database.", func(t *testing.T) {
+		candle := MarketCandle{Exchange: "Kraken", Pair: "XBTUSD", Currency: "BTCUSD", IntervalMinutes: 60, Timestamp: timeForTest("2023-06-01T10:00:00Z"), Close: 27000}
+		assert.Equal(t, RecordMarketCandles([]MarketCandle{candle}), nil)
+		candle.Close = 27100
+		assert.Equal(t, RecordMarketCandles([]MarketCandle{candle}), nil)
+		assert.Equal(t, RecordMarketDataCursor("Kraken", "XBTUSD", MarketTrades, 1), nil)
+		assert.Equal(t, RecordMarketDataCursor("Kraken", "XBTUSD", MarketTrades, 2), nil)
+
+		var quotes []PriceRecord
+		err := StreamMarketQuotes(MarketCandles, time.Time{}, time.Time{}, func(priceRecord PriceRecord) error {
+			quotes = append(quotes, priceRecord)
+			return nil
+		})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(quotes), 1)
+		assert.Equal(t, quotes[0].Timestamp, timeForTest("2023-06-01T11:00:00Z"))
+		assert.Equal(t, quotes[0].Price, 27100.0)
+
+		cursor, err := GetMarketDataCursor("Kraken", "XBTUSD", MarketTrades)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, cursor, int64(2))
+	})
+	t.Run("Price aggregates are upserted and pruning keeps the quotes of opportunities.", func(t *testing.T) {
+		aggregate := PriceAggregate{Exchange: "Kraken", Currency: "BTCUSD", IntervalMinutes: 60, Timestamp: timeForTest("2023-06-01T10:00:00Z"), Open: 27010, High: 27010, Low: 27010, Close: 27010, Average: 27010, Count: 1}
+		assert.Equal(t, RecordAggregates([]PriceAggregate{aggregate}, nil), nil)
+		aggregate.Close, aggregate.Count = 27020, 2
+		assert.Equal(t, RecordAggregates([]PriceAggregate{aggregate}, []SpreadAggregate{{Currency: "BTCUSD", ExchangeA: "Coinbase", ExchangeB: "Kraken", IntervalMinutes: 60, Timestamp: timeForTest("2023-06-01T10:00:00Z"), Count: 1}}), nil)
+
+		latest, err := GetLatestPriceAggregateTime(60)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, latest, timeForTest("2023-06-01T10:00:00Z"))
+		latest, err = GetLatestPriceAggregateTime(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, latest.IsZero(), true)
+		var count int
+		assert.Equal(t, db.QueryRow("SELECT count FROM price_aggregates").Scan(&count), nil)
+		assert.Equal(t, count, 2)
+
+		opportunity := PriceRecord{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T09:59:00Z"), Currency: "BTCUSD", Price: 27000, Exchange: "Gemini", IsArbitrageOpportunity: true}
+		assert.Equal(t, RecordPriceRecord(opportunity) == nil, true)
+		oldest, err := GetOldestPriceRecordTime(true)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, oldest, opportunity.Timestamp)
+		oldest, err = GetOldestPriceRecordTime(false)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, oldest, priceRecords[0].Timestamp)
+
+		deleted, err := DeletePriceRecords(timeForTest("2023-06-01T09:00:00Z"), timeForTest("2023-06-01T10:00:10Z"))
+		assert.Equal(t, err, nil)
+		assert.Equal(t, deleted, int64(3))
+		assert.Equal(t, db.QueryRow("SELECT COUNT(*) FROM price_records").Scan(&count), nil)
+		assert.Equal(t, count, 2)
+	})
+	t.Run("The aggregates of an interval are rolled back completely if one of them fails.", func(t *testing.T) {
+		_, err := db.Exec("ALTER TABLE spread_aggregates RENAME TO spread_aggregates_unavailable")
+		assert.Equal(t, err, nil)
+		aggregate := PriceAggregate{Exchange: "Kraken", Currency: "BTCUSD", IntervalMinutes: 60, Timestamp: timeForTest("2023-06-01T11:00:00Z"), Count: 1}
+		spreadAggregate := SpreadAggregate{Currency: "BTCUSD", ExchangeA: "Coinbase", ExchangeB: "Kraken", IntervalMinutes: 60, Timestamp: timeForTest("2023-06-01T11:00:00Z"), Count: 1}
+		assert.Equal(t, RecordAggregates([]PriceAggregate{aggregate}, []SpreadAggregate{spreadAggregate}) != nil, true)
+		_, err = db.Exec("ALTER TABLE spread_aggregates_unavailable RENAME TO spread_aggregates")
+		assert.Equal(t, err, nil)
+
+		var count int
+		assert.Equal(t, db.QueryRow("SELECT COUNT(*) FROM price_aggregates").Scan(&count), nil)
+		assert.Equal(t, count, 1)
+	})
+}
+
+func Test_RecordCycle(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+	db := setupSqliteForTest(t)
+
+	cycle := NewCycle(timeForTest("2023-06-01T10:00:05Z"))
+	cycle.AddPriceRecords(priceRecordsForTest(2)...)
+	cycle.AddArbitrageEventRecords(ArbitrageEventRecord{Uuid: uuid.New(), Timestamp: cycle.Snapshot.Timestamp, Currency: "BTCUSD", IsArbitrageOpportunity: true, PriceRecordUuidA: cycle.PriceRecords[0].Uuid, PriceRecordUuidB: cycle.PriceRecords[1].Uuid})
+	cycle.AddSpreadSignalRecords(SpreadSignalRecord{Uuid: uuid.New(), Timestamp: timeForTest("2023-06-01T10:00:05Z"), Currency: "BTCUSD", Action: "entry"})
+	cycle.Snapshot.Opportunities = 1
+
+	t.Run("A cycle is stored with its snapshot, quotes and events.", func(t *testing.T) {
+		assert.Equal(t, RecordCycle(*cycle), nil)
+
+		var snapshot CycleSnapshot
+		found, err := database().From("cycle_snapshots").ScanStruct(&snapshot)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, true)
+		assert.Equal(t, snapshot.Uuid, cycle.Snapshot.Uuid)
+		assert.Equal(t, snapshot.Timestamp, cycle.Snapshot.Timestamp)
+		assert.Equal(t, []int{snapshot.Quotes, snapshot.ArbitrageEvents, snapshot.TriangularArbitrageEvents, snapshot.SpreadSignals, snapshot.Opportunities}, []int{2, 1, 0, 1, 1})
+
+		for table, want := range map[string]int{"price_records": 2, "arbitrage_records": 1, "spread_signal_records": 1} {
+			var count int
+			assert.Equal(t, db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE cycle_uuid = ?", cycle.Snapshot.Uuid.String()).Scan(&count), nil)
+			assert.Equal(t, count, want)
+		}
+	})
+
+	t.Run("A cycle that fails is rolled back completely.", func(t *testing.T) {
+		failing := NewCycle(timeForTest("2023-06-01T10:00:10Z"))
+		failing.AddPriceRecords(priceRecordsForTest(1)...)
+		// The quote of the first cycle is stored already
+		failing.AddPriceRecords(cycle.PriceRecords[0])
+		assert.Equal(t, RecordCycle(*failing) != nil, true)
+
+		var count int
+		assert.Equal(t, db.QueryRow("SELECT COUNT(*) FROM cycle_snapshots").Scan(&count), nil)
+		assert.Equal(t, count, 1)
+		assert.Equal(t, db.QueryRow("SELECT COUNT(*) FROM price_records").Scan(&count), nil)
+		assert.Equal(t, count, 2)
+	})
+}
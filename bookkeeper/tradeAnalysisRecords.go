@@ -0,0 +1,71 @@
+package bookkeeper
+
+import (
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"time"
+)
+
+/*
+The comparison of the projected and the realized profit of an executed arbitrage opportunity, in the quote currency.
+The difference between both is split into its causes, so that ProjectedProfit - RealizedProfit = Slippage +
+FeeDifference + LatencyDecay + PartialFillLoss + CorrectionLoss. A positive component reduced the profit.
+*/
+type TradeAnalysisRecord struct {
+	Uuid                uuid.UUID `db:"uuid"`
+	Timestamp           time.Time `db:"timestamp"`
+	ArbitrageRecordUuid uuid.UUID `db:"arbitrage_record_uuid"`
+	Currency            string    `db:"currency"`
+	BuyExchange         string    `db:"buy_exchange"`
+	SellExchange        string    `db:"sell_exchange"`
+	Size                float64   `db:"size"`
+	MatchedSize         float64   `db:"matched_size"`
+	ProjectedProfit     float64   `db:"projected_profit"`
+	RealizedProfit      float64   `db:"realized_profit"`
+	Slippage            float64   `db:"slippage"`
+	FeeDifference       float64   `db:"fee_difference"`
+	LatencyDecay        float64   `db:"latency_decay"`
+	PartialFillLoss     float64   `db:"partial_fill_loss"`
+	CorrectionLoss      float64   `db:"correction_loss"`
+}
+
+/*
+Insert TradeAnalysisRecord into the database. The records are aggregated per exchange pair by the
+trade_analysis_by_exchange_pair view.
+*/
+func RecordTradeAnalysisRecord(tradeAnalysisRecord TradeAnalysisRecord) error {
+	database := database()
+
+	record := goqu.Record{"uuid": tradeAnalysisRecord.Uuid.String(), "timestamp": tradeAnalysisRecord.Timestamp, "arbitrage_record_uuid": tradeAnalysisRecord.ArbitrageRecordUuid.String(), "currency": tradeAnalysisRecord.Currency, "buy_exchange": tradeAnalysisRecord.BuyExchange, "sell_exchange": tradeAnalysisRecord.SellExchange, "size": tradeAnalysisRecord.Size, "matched_size": tradeAnalysisRecord.MatchedSize, "projected_profit": tradeAnalysisRecord.ProjectedProfit, "realized_profit": tradeAnalysisRecord.RealizedProfit, "slippage": tradeAnalysisRecord.Slippage, "fee_difference": tradeAnalysisRecord.FeeDifference, "latency_decay": tradeAnalysisRecord.LatencyDecay, "partial_fill_loss": tradeAnalysisRecord.PartialFillLoss, "correction_loss": tradeAnalysisRecord.CorrectionLoss}
+
+	insertTradeAnalysisSQL, _, _ := database.Insert("trade_analysis_records").Rows(record).ToSQL()
+
+	_, err := internal.DbPool.Exec(insertTradeAnalysisSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: %s", insertTradeAnalysisSQL), zap.String("queryError,", err.Error()))
+		return err
+	}
+
+	utils.Logger.Debug("Inserted new tradeAnalysisRecord into the database.", zap.Object("tradeAnalysisRecord", &tradeAnalysisRecord))
+	return nil
+}
+
+func (t TradeAnalysisRecord) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
+	encoder.AddString("uuid", t.Uuid.String())
+	encoder.AddString("arbitrage_record_uuid", t.ArbitrageRecordUuid.String())
+	encoder.AddString("buy_exchange", t.BuyExchange)
+	encoder.AddString("sell_exchange", t.SellExchange)
+	encoder.AddFloat64("projected_profit", t.ProjectedProfit)
+	encoder.AddFloat64("realized_profit", t.RealizedProfit)
+	encoder.AddFloat64("slippage", t.Slippage)
+	encoder.AddFloat64("fee_difference", t.FeeDifference)
+	encoder.AddFloat64("latency_decay", t.LatencyDecay)
+	encoder.AddFloat64("partial_fill_loss", t.PartialFillLoss)
+	encoder.AddFloat64("correction_loss", t.CorrectionLoss)
+	return nil
+}
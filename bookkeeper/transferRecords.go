@@ -0,0 +1,91 @@
+package bookkeeper
+
+import (
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"time"
+)
+
+type TransferRecord struct {
+	Uuid         uuid.UUID `db:"uuid"`
+	Timestamp    time.Time `db:"timestamp"`
+	UpdatedAt    time.Time `db:"updated_at"`
+	Asset        string    `db:"asset"`
+	FromExchange string    `db:"from_exchange"`
+	ToExchange   string    `db:"to_exchange"`
+	Amount       float64   `db:"amount"`
+	EstimatedFee float64   `db:"estimated_fee"`
+	Address      string    `db:"address"`
+	ReferenceId  string    `db:"reference_id"`
+	Status       string    `db:"status"`
+	Reason       string    `db:"reason"`
+}
+
+/*
+Insert TransferRecord into the database.
+*/
+func RecordTransferRecord(transferRecord TransferRecord) error {
+	database := database()
+
+	record := goqu.Record{"uuid": transferRecord.Uuid.String(), "timestamp": transferRecord.Timestamp, "updated_at": transferRecord.UpdatedAt, "asset": transferRecord.Asset, "from_exchange": transferRecord.FromExchange, "to_exchange": transferRecord.ToExchange, "amount": transferRecord.Amount, "estimated_fee": transferRecord.EstimatedFee, "address": transferRecord.Address, "reference_id": transferRecord.ReferenceId, "status": transferRecord.Status, "reason": transferRecord.Reason}
+
+	insertTransferSQL, _, _ := database.Insert("transfer_records").Rows(record).ToSQL()
+
+	_, err := internal.DbPool.Exec(insertTransferSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: %s", insertTransferSQL), zap.String("queryError,", err.Error()))
+		return err
+	}
+
+	utils.Logger.Debug("Inserted new transferRecord into the database.", zap.Object("transferRecord", &transferRecord))
+	return nil
+}
+
+/*
+Update the status, address, reference id and reason of a TransferRecord in the database.
+*/
+func UpdateTransferRecord(transferRecord TransferRecord) error {
+	database := database()
+
+	updateTransferSQL, _, _ := database.Update("transfer_records").Set(goqu.Record{"updated_at": transferRecord.UpdatedAt, "address": transferRecord.Address, "reference_id": transferRecord.ReferenceId, "status": transferRecord.Status, "reason": transferRecord.Reason}).Where(goqu.Ex{"uuid": transferRecord.Uuid.String()}).ToSQL()
+
+	_, err := internal.DbPool.Exec(updateTransferSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: %s", updateTransferSQL), zap.String("queryError,", err.Error()))
+		return err
+	}
+
+	utils.Logger.Debug("Updated transferRecord in the database.", zap.Object("transferRecord", &transferRecord))
+	return nil
+}
+
+/*
+Returns all TransferRecords with one of the given statuses.
+*/
+func GetTransferRecords(statuses ...string) ([]TransferRecord, error) {
+	database := database()
+
+	var transferRecords []TransferRecord
+	err := database.From("transfer_records").Where(goqu.C("status").In(statuses)).Order(goqu.C("timestamp").Asc()).ScanStructs(&transferRecords)
+	if err != nil {
+		utils.Logger.Error("Failed to query transfer records.", zap.Error(err))
+		return nil, err
+	}
+	return transferRecords, nil
+}
+
+func (t TransferRecord) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
+	encoder.AddString("uuid", t.Uuid.String())
+	encoder.AddString("asset", t.Asset)
+	encoder.AddString("from_exchange", t.FromExchange)
+	encoder.AddString("to_exchange", t.ToExchange)
+	encoder.AddFloat64("amount", t.Amount)
+	encoder.AddString("reference_id", t.ReferenceId)
+	encoder.AddString("status", t.Status)
+	return nil
+}
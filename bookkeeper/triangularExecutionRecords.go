@@ -0,0 +1,85 @@
+package bookkeeper
+
+import (
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"time"
+)
+
+/*
+The fill of one leg of a triangular arbitrage execution. Sequence is the position the leg was executed in (1-3), or 0 if
+it was not executed.
+*/
+type TriangularLegFill struct {
+	TradePair     string
+	Side          string
+	Sequence      int
+	RequestedSize float64
+	FilledSize    float64
+	AveragePrice  float64
+	Fee           float64
+	Status        string
+}
+
+type TriangularExecutionRecord struct {
+	Uuid                           uuid.UUID
+	Timestamp                      time.Time
+	TriangularArbitrageRecordUuid  uuid.UUID
+	Exchange                       string
+	Status                         string
+	PredictedCrossExchangeRateDiff float64
+	RealizedCrossExchangeRate      float64
+	RealizedCrossExchangeRateDiff  float64
+	Legs                           [3]TriangularLegFill
+	Reason                         string
+}
+
+/*
+Insert TriangularExecutionRecord into the database. The legs are stored in the trade_pair_1 to trade_pair_3 columns in
+the same order as in the TriangularArbitrageEventRecord.
+*/
+func RecordTriangularExecutionRecord(triangularExecutionRecord TriangularExecutionRecord) error {
+	database := database()
+
+	record := goqu.Record{"uuid": triangularExecutionRecord.Uuid.String(), "timestamp": triangularExecutionRecord.Timestamp, "triangular_arbitrage_record_uuid": triangularExecutionRecord.TriangularArbitrageRecordUuid.String(), "exchange": triangularExecutionRecord.Exchange, "status": triangularExecutionRecord.Status, "predicted_cross_exchange_rate_difference": triangularExecutionRecord.PredictedCrossExchangeRateDiff, "realized_cross_exchange_rate": triangularExecutionRecord.RealizedCrossExchangeRate, "realized_cross_exchange_rate_difference": triangularExecutionRecord.RealizedCrossExchangeRateDiff, "reason": triangularExecutionRecord.Reason}
+	for i, leg := range triangularExecutionRecord.Legs {
+		prefix := fmt.Sprintf("trade_pair_%d", i+1)
+		record[prefix] = leg.TradePair
+		record[prefix+"_side"] = leg.Side
+		record[prefix+"_sequence"] = leg.Sequence
+		record[prefix+"_requested_size"] = leg.RequestedSize
+		record[prefix+"_filled_size"] = leg.FilledSize
+		record[prefix+"_average_price"] = leg.AveragePrice
+		record[prefix+"_fee"] = leg.Fee
+		record[prefix+"_status"] = leg.Status
+	}
+
+	insertTriangularExecutionSQL, _, _ := database.Insert("triangular_execution_records").Rows(record).ToSQL()
+
+	_, err := internal.DbPool.Exec(insertTriangularExecutionSQL)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Database query failed: %s", insertTriangularExecutionSQL), zap.String("queryError,", err.Error()))
+		return err
+	}
+
+	utils.Logger.Debug("Inserted new triangularExecutionRecord into the database.", zap.Object("triangularExecutionRecord", &triangularExecutionRecord))
+	return nil
+}
+
+func (t TriangularExecutionRecord) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
+	encoder.AddString("uuid", t.Uuid.String())
+	encoder.AddString("triangular_arbitrage_record_uuid", t.TriangularArbitrageRecordUuid.String())
+	encoder.AddString("exchange", t.Exchange)
+	encoder.AddString("status", t.Status)
+	encoder.AddFloat64("predicted_cross_exchange_rate_difference", t.PredictedCrossExchangeRateDiff)
+	encoder.AddFloat64("realized_cross_exchange_rate_difference", t.RealizedCrossExchangeRateDiff)
+	for i, leg := range t.Legs {
+		encoder.AddFloat64(fmt.Sprintf("trade_pair_%d_filled_size", i+1), leg.FilledSize)
+	}
+	return nil
+}
@@ -2,11 +2,18 @@ package main
 
 import (
 	"cryptoArbitrageBot/arbitrageHunter"
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/downloader"
+	"cryptoArbitrageBot/exporter"
 	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/migrations"
 	"cryptoArbitrageBot/internal/utils"
 	"fmt"
 	"github.com/spf13/viper"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 var coinbaseProURL string
@@ -59,16 +66,148 @@ func init() {
 }
 
 /*
-Main entry point of the Crypto Arbitrage Bot.
+Main entry point of the Crypto Arbitrage Bot. Without arguments the Arbitrage Hunter is started, otherwise the given
+command is run.
 */
 func main() {
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
 
-	databaseErr := internal.ConnectToDatabase()
-	if databaseErr != nil {
-		utils.Logger.Error(databaseErr.Error())
+	eventLogOnly := viper.GetBool("DATABASE.EVENT_LOG.ENABLED") && viper.GetBool("DATABASE.EVENT_LOG.ONLY")
+	var closers []func()
+	if eventLogOnly {
+		for _, key := range []string{"EXECUTION.ENABLED", "REBALANCER.ENABLED", "RETENTION.ENABLED"} {
+			if viper.GetBool(key) {
+				log.Fatalf("%s requires the database, it cannot be used with DATABASE.EVENT_LOG.ONLY.", key)
+			}
+		}
+	} else {
+		if databaseErr := internal.ConnectToDatabase(); databaseErr != nil {
+			utils.Logger.Error(databaseErr.Error())
+			os.Exit(1)
+		}
+		if viper.GetBool("DATABASE.BATCH_WRITER.ENABLED") {
+			closers = append(closers, bookkeeper.StartBatchWriter(bookkeeper.LoadBatchWriterConfig()).Close)
+		}
+	}
+	if viper.GetBool("DATABASE.EVENT_LOG.ENABLED") {
+		eventLog, err := bookkeeper.StartEventLog(bookkeeper.LoadEventLogConfig(), eventLogOnly)
+		if err != nil {
+			log.Fatalf("Unable to open the event log: %v", err)
+		}
+		// The event log is in front of the batch writer, it is closed first
+		closers = append([]func(){func() {
+			if err := eventLog.Close(); err != nil {
+				utils.Logger.Error(fmt.Sprintf("Failed to close the event log: %v", err))
+			}
+		}}, closers...)
 	}
+	closeOnShutdown(closers...)
 	arbitrageHunterError := arbitrageHunter.Start()
 	if arbitrageHunterError != nil {
 		utils.Logger.Error(arbitrageHunterError.Error())
 	}
 }
+
+/*
+Write the records pending in the event log and the batch writer before the bot exits on SIGINT or SIGTERM.
+*/
+func closeOnShutdown(closers ...func()) {
+	if len(closers) == 0 {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		received := <-signals
+		utils.Logger.Info(fmt.Sprintf("Received %v. Writing the pending records before exiting.", received))
+		for _, close := range closers {
+			close()
+		}
+		os.Exit(0)
+	}()
+}
+
+/*
+Run a command instead of the Arbitrage Hunter.
+  - killswitch: block new orders in the running bot and cancel all open orders on every exchange.
+  - backtest [file]: replay the price history in the database, or the quotes in a CSV or JSON lines file or an event
+    log directory, through the enabled strategies and print the simulated results.
+  - sweep [file]: backtest every combination of the configured parameters on the same quotes and print them ranked.
+  - download: backfill the Kraken trade and candle history of the configured pairs into the database.
+  - migrate [up|down|status] [version]: migrate the database schema, up to the latest version by default.
+  - export <price|arbitrage|triangular> <file>: write the records that match the configured filter to a .csv, .jsonl or
+    .parquet file.
+  - replay [directory]: write the records of the event log that are missing in the database into it.
+*/
+func runCommand(command string, args []string) {
+	switch command {
+	case "killswitch":
+		if err := arbitrageHunter.KillSwitch(); err != nil {
+			utils.Logger.Error(err.Error())
+			os.Exit(1)
+		}
+	case "backtest":
+		path := ""
+		if len(args) > 0 {
+			path = args[0]
+		}
+		// Every detected opportunity is logged at INFO
+		utils.SetLoggerLevel("WARN")
+		if err := arbitrageHunter.Backtest(path); err != nil {
+			utils.Logger.Error(err.Error())
+			os.Exit(1)
+		}
+	case "sweep":
+		path := ""
+		if len(args) > 0 {
+			path = args[0]
+		}
+		utils.SetLoggerLevel("WARN")
+		if err := arbitrageHunter.Sweep(path); err != nil {
+			utils.Logger.Error(err.Error())
+			os.Exit(1)
+		}
+	case "download":
+		if err := downloader.Download(); err != nil {
+			utils.Logger.Error(err.Error())
+			os.Exit(1)
+		}
+	case "migrate":
+		if err := internal.OpenDatabase(); err != nil {
+			utils.Logger.Error(err.Error())
+			os.Exit(1)
+		}
+		if err := migrations.Run(internal.DbPool, internal.Dialect, args, os.Stdout); err != nil {
+			utils.Logger.Error(err.Error())
+			os.Exit(1)
+		}
+	case "export":
+		if len(args) < 2 {
+			log.Fatalln("Usage: export <price|arbitrage|triangular> <file.csv|file.jsonl|file.parquet>")
+		}
+		if err := exporter.Export(args[0], args[1]); err != nil {
+			utils.Logger.Error(err.Error())
+			os.Exit(1)
+		}
+	case "replay":
+		directory := bookkeeper.LoadEventLogConfig().Directory
+		if len(args) > 0 {
+			directory = args[0]
+		}
+		if databaseErr := internal.ConnectToDatabase(); databaseErr != nil {
+			utils.Logger.Error(databaseErr.Error())
+			os.Exit(1)
+		}
+		stats, err := bookkeeper.ReplayEventLog(directory)
+		if err != nil {
+			utils.Logger.Error(err.Error())
+			os.Exit(1)
+		}
+		utils.Logger.Info(fmt.Sprintf("Replayed %d records of the event log in %s, %d were already stored, %d corrupt entries were skipped.", stats.Replayed, directory, stats.Stored, stats.Corrupt))
+	default:
+		log.Fatalf("Unknown command `%s`. Available commands: killswitch, backtest, sweep, download, migrate, export, replay", command)
+	}
+}
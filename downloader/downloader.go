@@ -0,0 +1,328 @@
+package downloader
+
+import (
+	"cryptoArbitrageBot/api/kraken"
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+An exchange that serves its public trade and candle history page by page. Implemented by the Kraken client.
+  - Trades returns the trades after since (unix nanoseconds) and the since of the following page.
+  - OHLC returns the candles of an interval in minutes after since (unix seconds) and the since of the following page.
+*/
+type MarketDataSource interface {
+	Trades(pair string, since int64) (*kraken.TradesResponse, error)
+	OHLC(pair string, interval int, since int64) (*kraken.OHLCResponse, error)
+}
+
+/*
+Where downloaded market data and the cursors to resume a download from are stored.
+*/
+type MarketDataStore interface {
+	RecordTrades(trades []bookkeeper.MarketTrade) error
+	RecordCandles(candles []bookkeeper.MarketCandle) error
+	Cursor(exchange string, pair string, kind string) (int64, error)
+	RecordCursor(exchange string, pair string, kind string, cursor int64) error
+}
+
+type DownloaderError struct {
+	Msg string
+}
+
+func (e *DownloaderError) Error() string {
+	return fmt.Sprintf("Error downloading market data: %s", e.Msg)
+}
+
+/*
+Configuration of a download.
+  - Pairs: the trade pair on the exchange (e.g. XBTUSD) keyed by the currency used in price records (e.g. BTCUSD).
+  - From, To: the time range to download. A download that was interrupted continues from its stored cursor.
+  - Trades: download every public trade.
+  - OhlcIntervals: the candle intervals in minutes to download.
+  - RequestDelay: the pause between two requests to stay below the rate limit of the exchange.
+*/
+type Config struct {
+	Pairs         map[string]string
+	From          time.Time
+	To            time.Time
+	Trades        bool
+	OhlcIntervals []int
+	RequestDelay  time.Duration
+}
+
+/*
+Load the download configuration from the configuration file (DOWNLOADER). An empty TO downloads until now.
+*/
+func LoadConfig() (Config, error) {
+	config := Config{
+		Pairs:         make(map[string]string),
+		To:            time.Now().UTC(),
+		Trades:        viper.GetBool("DOWNLOADER.TRADES"),
+		OhlcIntervals: viper.GetIntSlice("DOWNLOADER.OHLC_INTERVALS"),
+		RequestDelay:  time.Duration(viper.GetInt("DOWNLOADER.REQUEST_DELAY_MILLISECONDS")) * time.Millisecond,
+	}
+	for currency, pair := range viper.GetStringMapString("DOWNLOADER.PAIRS") {
+		config.Pairs[strings.ToUpper(currency)] = strings.ToUpper(pair)
+	}
+
+	var err error
+	if config.From, err = parseTime(viper.GetString("DOWNLOADER.FROM")); err != nil {
+		return config, &DownloaderError{Msg: fmt.Sprintf("invalid DOWNLOADER.FROM: %v", err)}
+	}
+	if to := viper.GetString("DOWNLOADER.TO"); to != "" {
+		if config.To, err = parseTime(to); err != nil {
+			return config, &DownloaderError{Msg: fmt.Sprintf("invalid DOWNLOADER.TO: %v", err)}
+		}
+	}
+	if !config.From.Before(config.To) {
+		return config, &DownloaderError{Msg: fmt.Sprintf("DOWNLOADER.FROM %s is not before DOWNLOADER.TO %s", config.From, config.To)}
+	}
+	return config, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02 15:04:05", value)
+}
+
+/*
+Backfills the trade and candle history of the configured pairs of one exchange. Every page is stored together with the
+cursor of the following page, so an interrupted download continues where it stopped.
+*/
+type Downloader struct {
+	exchange string
+	source   MarketDataSource
+	store    MarketDataStore
+	config   Config
+}
+
+func NewDownloader(exchange string, source MarketDataSource, store MarketDataStore, config Config) *Downloader {
+	return &Downloader{
+		exchange: exchange,
+		source:   source,
+		store:    store,
+		config:   config,
+	}
+}
+
+/*
+Download the configured history of every pair. A pair that fails is logged and skipped, the others are still
+downloaded.
+*/
+func (d *Downloader) Run() error {
+	currencies := make([]string, 0, len(d.config.Pairs))
+	for currency := range d.config.Pairs {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	var failed []string
+	for _, currency := range currencies {
+		pair := d.config.Pairs[currency]
+		if d.config.Trades {
+			if err := d.downloadTrades(currency, pair); err != nil {
+				utils.Logger.Error(fmt.Sprintf("Failed to download the trades of %s on %s: %v", pair, d.exchange, err))
+				failed = append(failed, pair+" trades")
+			}
+		}
+		for _, interval := range d.config.OhlcIntervals {
+			if err := d.downloadCandles(currency, pair, interval); err != nil {
+				utils.Logger.Error(fmt.Sprintf("Failed to download the %d minute candles of %s on %s: %v", interval, pair, d.exchange, err))
+				failed = append(failed, fmt.Sprintf("%s %d minute candles", pair, interval))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return &DownloaderError{Msg: fmt.Sprintf("failed to download %s", strings.Join(failed, ", "))}
+	}
+	return nil
+}
+
+func (d *Downloader) downloadTrades(currency string, pair string) error {
+	kind := "trades"
+	cursor, err := d.store.Cursor(d.exchange, pair, kind)
+	if err != nil {
+		return err
+	}
+	if cursor == 0 {
+		cursor = d.config.From.UnixNano()
+	}
+
+	downloaded := 0
+	for cursor < d.config.To.UnixNano() {
+		response, err := d.source.Trades(pair, cursor)
+		if err != nil {
+			return err
+		}
+
+		trades := make([]bookkeeper.MarketTrade, 0, len(response.Trades))
+		reachedTo := false
+		for i, trade := range response.Trades {
+			timestamp := time.Unix(trade.Time, 0).UTC()
+			if !timestamp.Before(d.config.To) {
+				reachedTo = true
+				break
+			}
+			trades = append(trades, bookkeeper.MarketTrade{
+				// The same page always contains the same trades, a page that is downloaded again gets the same uuids
+				Uuid:      uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s/%s/%d/%d", d.exchange, pair, cursor, i))),
+				Exchange:  d.exchange,
+				Pair:      pair,
+				Currency:  currency,
+				Timestamp: timestamp,
+				Price:     trade.PriceFloat,
+				Volume:    trade.VolumeFloat,
+				Side:      tradeSide(trade),
+				OrderType: tradeOrderType(trade),
+			})
+		}
+		if err := d.store.RecordTrades(trades); err != nil {
+			return err
+		}
+		downloaded += len(trades)
+
+		// The cursor is not moved past TO, a download with a later TO continues with the rest of this page. Kraken keeps
+		// returning the last page with the same cursor once the download has caught up.
+		if reachedTo || len(response.Trades) == 0 || response.Last <= cursor {
+			break
+		}
+		cursor = response.Last
+		if err := d.store.RecordCursor(d.exchange, pair, kind, cursor); err != nil {
+			return err
+		}
+		time.Sleep(d.config.RequestDelay)
+	}
+
+	utils.Logger.Info(fmt.Sprintf("Downloaded %d trades of %s on %s.", downloaded, pair, d.exchange))
+	return nil
+}
+
+/*
+Download the candles of one interval. The exchange only serves a limited number of the most recent candles; when the
+first candle is later than the cursor the candles in between are not available and a warning is logged.
+*/
+func (d *Downloader) downloadCandles(currency string, pair string, interval int) error {
+	kind := fmt.Sprintf("ohlc_%d", interval)
+	cursor, err := d.store.Cursor(d.exchange, pair, kind)
+	if err != nil {
+		return err
+	}
+	if cursor == 0 {
+		cursor = d.config.From.Unix()
+	}
+	intervalSeconds := int64(interval) * 60
+
+	downloaded := 0
+	for cursor < d.config.To.Unix() {
+		response, err := d.source.OHLC(pair, interval, cursor)
+		if err != nil {
+			return err
+		}
+		sort.Slice(response.OHLC, func(i, j int) bool {
+			return response.OHLC[i].Time.Before(response.OHLC[j].Time)
+		})
+		if len(response.OHLC) > 0 && response.OHLC[0].Time.Unix() > cursor+intervalSeconds {
+			utils.Logger.Warn(fmt.Sprintf("The %d minute candles of %s on %s between %s and %s are no longer available.", interval, pair, d.exchange, time.Unix(cursor, 0).UTC().Format(time.RFC3339), response.OHLC[0].Time.UTC().Format(time.RFC3339)))
+		}
+
+		candles := make([]bookkeeper.MarketCandle, 0, len(response.OHLC))
+		reachedTo := false
+		for _, ohlc := range response.OHLC {
+			if !ohlc.Time.Before(d.config.To) {
+				reachedTo = true
+				break
+			}
+			candles = append(candles, bookkeeper.MarketCandle{
+				Exchange:        d.exchange,
+				Pair:            pair,
+				Currency:        currency,
+				IntervalMinutes: interval,
+				Timestamp:       ohlc.Time.UTC(),
+				Open:            ohlc.Open,
+				High:            ohlc.High,
+				Low:             ohlc.Low,
+				Close:           ohlc.Close,
+				Vwap:            ohlc.Vwap,
+				Volume:          ohlc.Volume,
+				Count:           ohlc.Count,
+			})
+		}
+		if err := d.store.RecordCandles(candles); err != nil {
+			return err
+		}
+		downloaded += len(candles)
+
+		last := int64(response.Last)
+		if reachedTo || len(response.OHLC) == 0 || last <= cursor {
+			break
+		}
+		cursor = last
+		if err := d.store.RecordCursor(d.exchange, pair, kind, cursor); err != nil {
+			return err
+		}
+		time.Sleep(d.config.RequestDelay)
+	}
+
+	utils.Logger.Info(fmt.Sprintf("Downloaded %d %d minute candles of %s on %s.", downloaded, interval, pair, d.exchange))
+	return nil
+}
+
+func tradeSide(trade kraken.TradeInfo) string {
+	if trade.Sell {
+		return "sell"
+	}
+	return "buy"
+}
+
+func tradeOrderType(trade kraken.TradeInfo) string {
+	if trade.Limit {
+		return "limit"
+	}
+	return "market"
+}
+
+/*
+Download the configured Kraken trade and candle history into the database.
+*/
+func Download() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	if databaseErr := internal.ConnectToDatabase(); databaseErr != nil {
+		return databaseErr
+	}
+	krakenClient := kraken.NewWithClient(viper.GetString("KRAKEN.TEST.KEY"), viper.GetString("KRAKEN.TEST.SECRET"), internal.GetClient())
+	return NewDownloader("Kraken", krakenClient, DatabaseStore{}, config).Run()
+}
+
+/*
+Stores downloaded market data in the database.
+*/
+type DatabaseStore struct{}
+
+func (DatabaseStore) RecordTrades(trades []bookkeeper.MarketTrade) error {
+	return bookkeeper.RecordMarketTrades(trades)
+}
+
+func (DatabaseStore) RecordCandles(candles []bookkeeper.MarketCandle) error {
+	return bookkeeper.RecordMarketCandles(candles)
+}
+
+func (DatabaseStore) Cursor(exchange string, pair string, kind string) (int64, error) {
+	return bookkeeper.GetMarketDataCursor(exchange, pair, kind)
+}
+
+func (DatabaseStore) RecordCursor(exchange string, pair string, kind string, cursor int64) error {
+	return bookkeeper.RecordMarketDataCursor(exchange, pair, kind, cursor)
+}
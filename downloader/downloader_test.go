@@ -0,0 +1,181 @@
+package downloader
+
+import (
+	"cryptoArbitrageBot/api/kraken"
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+	"log"
+	"testing"
+	"time"
+)
+
+func setupTest(tb testing.TB) func(tb testing.TB) {
+	log.Println("Setup tests.")
+
+	viper.SetConfigName("config-DEV")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath("../")
+	err := viper.ReadInConfig() // Find and read the config file
+	if err != nil {
+		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+	}
+
+	utils.InitializeLogger()
+	utils.SetLoggerLevel("DEBUG")
+
+	return func(tb testing.TB) {
+		log.Println("Teardown tests.")
+	}
+}
+
+/*
+Serves trades once per minute and hourly candles from start until now, in pages of pageSize.
+*/
+type fakeSource struct {
+	start    time.Time
+	now      time.Time
+	pageSize int
+	requests int
+}
+
+func (f *fakeSource) Trades(pair string, since int64) (*kraken.TradesResponse, error) {
+	f.requests++
+	response := &kraken.TradesResponse{Last: since}
+	for t := f.start; t.Before(f.now) && len(response.Trades) < f.pageSize; t = t.Add(time.Minute) {
+		if t.UnixNano() < since {
+			continue
+		}
+		response.Trades = append(response.Trades, kraken.TradeInfo{PriceFloat: 25000, VolumeFloat: .1, Time: t.Unix(), Buy: true, Market: true})
+		response.Last = t.UnixNano() + 1
+	}
+	return response, nil
+}
+
+func (f *fakeSource) OHLC(pair string, interval int, since int64) (*kraken.OHLCResponse, error) {
+	f.requests++
+	response := &kraken.OHLCResponse{Pair: pair, Last: float64(since)}
+	for t := f.start; t.Before(f.now) && len(response.OHLC) < f.pageSize; t = t.Add(time.Duration(interval) * time.Minute) {
+		if t.Unix() < since {
+			continue
+		}
+		response.OHLC = append(response.OHLC, &kraken.OHLC{Time: t, Open: 25000, High: 25100, Low: 24900, Close: 25050, Volume: 1, Count: 10})
+		response.Last = float64(t.Unix() + 1)
+	}
+	return response, nil
+}
+
+type fakeStore struct {
+	trades  map[string]bookkeeper.MarketTrade
+	candles map[string]bookkeeper.MarketCandle
+	cursors map[string]int64
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		trades:  make(map[string]bookkeeper.MarketTrade),
+		candles: make(map[string]bookkeeper.MarketCandle),
+		cursors: make(map[string]int64),
+	}
+}
+
+func (f *fakeStore) RecordTrades(trades []bookkeeper.MarketTrade) error {
+	for _, trade := range trades {
+		f.trades[trade.Uuid.String()] = trade
+	}
+	return nil
+}
+
+func (f *fakeStore) RecordCandles(candles []bookkeeper.MarketCandle) error {
+	for _, candle := range candles {
+		f.candles[fmt.Sprintf("%s/%d/%s", candle.Pair, candle.IntervalMinutes, candle.Timestamp)] = candle
+	}
+	return nil
+}
+
+func (f *fakeStore) Cursor(exchange string, pair string, kind string) (int64, error) {
+	return f.cursors[exchange+"/"+pair+"/"+kind], nil
+}
+
+func (f *fakeStore) RecordCursor(exchange string, pair string, kind string, cursor int64) error {
+	f.cursors[exchange+"/"+pair+"/"+kind] = cursor
+	return nil
+}
+
+func Test_Downloader(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start.Add(10 * time.Hour)
+
+	tests := []struct {
+		name        string
+		config      Config
+		cursors     map[string]int64
+		wantTrades  int
+		wantCandles int
+	}{
+		{
+			name:        "Downloads every page until the download has caught up.",
+			config:      Config{Pairs: map[string]string{"BTCUSD": "XBTUSD"}, From: start, To: now.Add(time.Hour), Trades: true, OhlcIntervals: []int{60}},
+			wantTrades:  600,
+			wantCandles: 10,
+		},
+		{
+			name:        "Stops at TO.",
+			config:      Config{Pairs: map[string]string{"BTCUSD": "XBTUSD"}, From: start.Add(time.Hour), To: start.Add(3 * time.Hour), Trades: true, OhlcIntervals: []int{60}},
+			wantTrades:  120,
+			wantCandles: 2,
+		},
+		{
+			name:        "Continues from the stored cursors.",
+			config:      Config{Pairs: map[string]string{"BTCUSD": "XBTUSD"}, From: start, To: now, Trades: true, OhlcIntervals: []int{60}},
+			cursors:     map[string]int64{"Kraken/XBTUSD/trades": start.Add(9 * time.Hour).UnixNano(), "Kraken/XBTUSD/ohlc_60": start.Add(8 * time.Hour).Unix()},
+			wantTrades:  60,
+			wantCandles: 2,
+		},
+		{
+			name:       "Only downloads trades without OHLC intervals.",
+			config:     Config{Pairs: map[string]string{"BTCUSD": "XBTUSD"}, From: start, To: now, Trades: true},
+			wantTrades: 600,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			for key, cursor := range tt.cursors {
+				store.cursors[key] = cursor
+			}
+			source := &fakeSource{start: start, now: now, pageSize: 100}
+
+			assert.Equal(t, NewDownloader("Kraken", source, store, tt.config).Run(), nil)
+			assert.Equal(t, len(store.trades), tt.wantTrades)
+			assert.Equal(t, len(store.candles), tt.wantCandles)
+			for _, trade := range store.trades {
+				assert.Equal(t, trade.Currency, "BTCUSD")
+				assert.Equal(t, trade.Side, "buy")
+			}
+		})
+	}
+}
+
+func Test_DownloaderResumesWithoutDuplicates(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := &fakeSource{start: start, now: start.Add(5 * time.Hour), pageSize: 100}
+	store := newFakeStore()
+	config := Config{Pairs: map[string]string{"BTCUSD": "XBTUSD"}, From: start, To: start.Add(2 * time.Hour), Trades: true}
+
+	assert.Equal(t, NewDownloader("Kraken", source, store, config).Run(), nil)
+	assert.Equal(t, len(store.trades), 120)
+
+	// The first download stopped in the middle of a page, the second one downloads that page again
+	config.To = start.Add(4 * time.Hour)
+	assert.Equal(t, NewDownloader("Kraken", source, store, config).Run(), nil)
+	assert.Equal(t, len(store.trades), 240)
+}
@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"github.com/google/uuid"
+	"math"
+	"time"
+)
+
+/*
+Compare the projected profit of an executed arbitrage opportunity with its realized profit. ProjectedProfit of the
+opportunity is a percentage of the average price, it is converted into the quote currency for the traded size. The
+market quotes are the prices of both exchanges when the orders were sent; without them the market is assumed not to
+have moved since the opportunity was detected.
+
+The difference is split into:
+  - PartialFillLoss: the projected profit of the size that was not filled on both legs.
+  - LatencyDecay: how much the spread of the matched size shrank between detection and execution.
+  - Slippage: how much worse than the market at execution the matched size was filled.
+  - FeeDifference: how much higher the fees of both legs were than the fees in the projection.
+  - CorrectionLoss: the loss of the orders that hedged or unwound an imbalance.
+*/
+func AnalyzeExecution(opportunity bookkeeper.ArbitrageEventRecord, size float64, report ExecutionReport, marketQuotes []bookkeeper.PriceRecord) bookkeeper.TradeAnalysisRecord {
+	buyPrice, sellPrice := math.Min(opportunity.PriceA, opportunity.PriceB), math.Max(opportunity.PriceA, opportunity.PriceB)
+	marketBuyPrice := marketPrice(marketQuotes, opportunity.Currency, report.BuyLeg.Exchange, buyPrice)
+	marketSellPrice := marketPrice(marketQuotes, opportunity.Currency, report.SellLeg.Exchange, sellPrice)
+
+	projectedProfit := opportunity.ProjectedProfit / 100 * (buyPrice + sellPrice) / 2 * size
+	projectedFees := size*(sellPrice-buyPrice) - projectedProfit
+	matchedSize := math.Min(report.BuyLeg.FilledSize, report.SellLeg.FilledSize)
+	matchedProfit := matchedSize*(report.SellLeg.AveragePrice-report.BuyLeg.AveragePrice) - report.BuyLeg.Fee - report.SellLeg.Fee
+
+	analysis := bookkeeper.TradeAnalysisRecord{
+		Uuid:                uuid.New(),
+		Timestamp:           time.Now().UTC(),
+		ArbitrageRecordUuid: opportunity.Uuid,
+		Currency:            opportunity.Currency,
+		BuyExchange:         report.BuyLeg.Exchange,
+		SellExchange:        report.SellLeg.Exchange,
+		Size:                size,
+		MatchedSize:         matchedSize,
+		ProjectedProfit:     projectedProfit,
+		RealizedProfit:      report.RealizedProfit,
+		LatencyDecay:        matchedSize * ((sellPrice - buyPrice) - (marketSellPrice - marketBuyPrice)),
+		Slippage:            matchedSize * ((marketSellPrice - report.SellLeg.AveragePrice) + (report.BuyLeg.AveragePrice - marketBuyPrice)),
+		CorrectionLoss:      matchedProfit - report.RealizedProfit,
+	}
+	if size > 0 {
+		analysis.PartialFillLoss = projectedProfit * (1 - matchedSize/size)
+		analysis.FeeDifference = report.BuyLeg.Fee + report.SellLeg.Fee - projectedFees*matchedSize/size
+	}
+	return analysis
+}
+
+/*
+Returns the price of a currency on an exchange from the quotes, or the fallback if the exchange did not quote it.
+*/
+func marketPrice(quotes []bookkeeper.PriceRecord, currency string, exchange string, fallback float64) float64 {
+	for _, quote := range quotes {
+		if quote.Currency == currency && quote.Exchange == exchange && quote.Price > 0 {
+			return quote.Price
+		}
+	}
+	return fallback
+}
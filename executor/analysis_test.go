@@ -0,0 +1,96 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"github.com/magiconair/properties/assert"
+	"math"
+	"testing"
+)
+
+func Test_AnalyzeExecution(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	// Spread of 1000 minus 0.1% fees on both exchanges, 9.49 USD for 0.01 BTC
+	opportunity := opportunityForTest()
+	opportunity.ProjectedProfit = (1000 - 26000*.001 - 25000*.001) / 25500 * 100
+	quotes := []bookkeeper.PriceRecord{
+		{Currency: "BTCUSD", Exchange: "Coinbase", Price: 25000},
+		{Currency: "BTCUSD", Exchange: "Gemini", Price: 26000},
+	}
+
+	tests := []struct {
+		name                string
+		report              ExecutionReport
+		marketQuotes        []bookkeeper.PriceRecord
+		wantSlippage        float64
+		wantFeeDifference   float64
+		wantLatencyDecay    float64
+		wantPartialFillLoss float64
+		wantCorrectionLoss  float64
+	}{
+		{
+			name: "Filled as projected.",
+			report: ExecutionReport{
+				BuyLeg:  OrderResult{Exchange: "Coinbase", FilledSize: .01, AveragePrice: 25000, Fee: .25},
+				SellLeg: OrderResult{Exchange: "Gemini", FilledSize: .01, AveragePrice: 26000, Fee: .26},
+			},
+			marketQuotes: quotes,
+		},
+		{
+			name: "Spread shrank before the orders were sent and the buy leg slipped.",
+			report: ExecutionReport{
+				BuyLeg:  OrderResult{Exchange: "Coinbase", FilledSize: .01, AveragePrice: 25100, Fee: .25},
+				SellLeg: OrderResult{Exchange: "Gemini", FilledSize: .01, AveragePrice: 25900, Fee: .3},
+			},
+			marketQuotes: []bookkeeper.PriceRecord{
+				{Currency: "BTCUSD", Exchange: "Coinbase", Price: 25050},
+				{Currency: "BTCUSD", Exchange: "Gemini", Price: 25900},
+			},
+			wantSlippage:      .5,
+			wantFeeDifference: .04,
+			wantLatencyDecay:  1.5,
+		},
+		{
+			name: "Half of the buy leg filled and the excess sell was unwound at a loss.",
+			report: ExecutionReport{
+				BuyLeg:  OrderResult{Exchange: "Coinbase", FilledSize: .005, AveragePrice: 25000, Fee: .125},
+				SellLeg: OrderResult{Exchange: "Gemini", FilledSize: .01, AveragePrice: 26000, Fee: .26},
+				Corrections: []bookkeeper.LegCorrectionRecord{
+					{Action: string(Unwind), Exchange: "Gemini", Side: string(Buy), FilledSize: .005, AveragePrice: 26100},
+				},
+			},
+			marketQuotes:        quotes,
+			wantFeeDifference:   .13,
+			wantPartialFillLoss: 4.745,
+			wantCorrectionLoss:  .5,
+		},
+		{
+			name: "Without market quotes the market is assumed not to have moved.",
+			report: ExecutionReport{
+				BuyLeg:  OrderResult{Exchange: "Coinbase", FilledSize: .01, AveragePrice: 25100, Fee: .25},
+				SellLeg: OrderResult{Exchange: "Gemini", FilledSize: .01, AveragePrice: 26000, Fee: .26},
+			},
+			wantSlippage: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.report.RealizedProfit = realizedProfit(tt.report)
+			analysis := AnalyzeExecution(opportunity, .01, tt.report, tt.marketQuotes)
+
+			rounded := func(value float64) float64 {
+				return math.Round(value*1e6) / 1e6
+			}
+			assert.Equal(t, analysis.BuyExchange, "Coinbase")
+			assert.Equal(t, analysis.SellExchange, "Gemini")
+			assert.Equal(t, rounded(analysis.ProjectedProfit), 9.49)
+			assert.Equal(t, rounded(analysis.Slippage), tt.wantSlippage)
+			assert.Equal(t, rounded(analysis.FeeDifference), tt.wantFeeDifference)
+			assert.Equal(t, rounded(analysis.LatencyDecay), tt.wantLatencyDecay)
+			assert.Equal(t, rounded(analysis.PartialFillLoss), tt.wantPartialFillLoss)
+			assert.Equal(t, rounded(analysis.CorrectionLoss), tt.wantCorrectionLoss)
+			assert.Equal(t, rounded(analysis.ProjectedProfit-analysis.RealizedProfit), rounded(analysis.Slippage+analysis.FeeDifference+analysis.LatencyDecay+analysis.PartialFillLoss+analysis.CorrectionLoss))
+		})
+	}
+}
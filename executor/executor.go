@@ -0,0 +1,345 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"math"
+	"sync"
+	"time"
+)
+
+type Side string
+
+const (
+	Buy  Side = "buy"
+	Sell Side = "sell"
+)
+
+type OrderType string
+
+const (
+	Market OrderType = "market"
+	Limit  OrderType = "limit"
+)
+
+type OrderStatus string
+
+const (
+	Open            OrderStatus = "open"
+	Filled          OrderStatus = "filled"
+	PartiallyFilled OrderStatus = "partially_filled"
+	Cancelled       OrderStatus = "cancelled"
+	Rejected        OrderStatus = "rejected"
+)
+
+/*
+An order to be placed on a single exchange. Currency uses the same notation as bookkeeper.PriceRecord (e.g. BTCUSD).
+The ClientOrderId is derived from the opportunity and leg, so that a retried order can be recognized by the exchange.
+*/
+type OrderRequest struct {
+	OpportunityUuid uuid.UUID
+	Leg             string
+	ClientOrderId   string
+	Exchange        string
+	Currency        string
+	Side            Side
+	Type            OrderType
+	Price           float64
+	Size            float64
+}
+
+/*
+The outcome of an order as reported by the exchange.
+*/
+type OrderResult struct {
+	OrderId       string
+	ClientOrderId string
+	Exchange      string
+	Currency      string
+	Side          Side
+	Status        OrderStatus
+	RequestedSize float64
+	FilledSize    float64
+	AveragePrice  float64
+	Fee           float64
+}
+
+/*
+An exchange that orders can be placed on. Implemented by the clients in the api packages. FindOrder looks up an order
+by its client order id and returns ErrOrderNotFound if the exchange never received it.
+*/
+type Venue interface {
+	Name() string
+	PlaceOrder(order OrderRequest) (OrderResult, error)
+	FindOrder(clientOrderId string, currency string) (OrderResult, error)
+	CancelAllOrders() (int, error)
+}
+
+type ExecutorError struct {
+	Msg string
+}
+
+func (e *ExecutorError) Error() string {
+	return fmt.Sprintf("Error executing orders: %s", e.Msg)
+}
+
+/*
+Returned by venues when an exchange does not know an order id.
+*/
+var ErrOrderNotFound = errors.New("order not found")
+
+/*
+Wraps the error of an order whose submission failed without telling whether the exchange received it.
+*/
+var errUnconfirmedOrder = errors.New("order submission could not be confirmed")
+
+/*
+Returns the client order id of a leg of an opportunity. The id is a name based uuid, so it is the same every time it is
+derived and a retried order carries the same id as the original.
+*/
+func ClientOrderId(opportunityUuid uuid.UUID, leg string) string {
+	return uuid.NewSHA1(opportunityUuid, []byte(leg)).String()
+}
+
+/*
+Places the legs of an arbitrage opportunity and corrects imbalanced fills according to the leg risk policy.
+*/
+type Executor struct {
+	venues           map[string]Venue
+	policy           LegRiskPolicy
+	riskManager      *RiskManager
+	orderStore       *OrderStore
+	submitAttempts   int
+	retryDelay       time.Duration
+	triangularPolicy TriangularPolicy
+}
+
+/*
+The result of executing an arbitrage opportunity, including any corrective actions that were taken.
+*/
+type ExecutionReport struct {
+	BuyLeg         OrderResult
+	SellLeg        OrderResult
+	Corrections    []bookkeeper.LegCorrectionRecord
+	RealizedProfit float64
+}
+
+/*
+Create a new Executor for the given venues.
+*/
+func NewExecutor(policy LegRiskPolicy, venues ...Venue) *Executor {
+	venueMap := make(map[string]Venue)
+	for _, venue := range venues {
+		venueMap[venue.Name()] = venue
+	}
+	return &Executor{
+		venues:         venueMap,
+		policy:         policy,
+		riskManager:    NewRiskManager(RiskLimits{}),
+		submitAttempts: 1,
+	}
+}
+
+/*
+Replaces the RiskManager of the Executor, which has no limits by default. Every order is checked against it before it
+is placed. Must be called before the Executor is used.
+*/
+func (e *Executor) WithRiskManager(riskManager *RiskManager) *Executor {
+	e.riskManager = riskManager
+	return e
+}
+
+/*
+Update the prices the risk manager values exposure with.
+*/
+func (e *Executor) MarkPrices(quotes []bookkeeper.PriceRecord) {
+	e.riskManager.MarkPrices(quotes)
+}
+
+/*
+Adds an OrderStore to the Executor. Every order and its result is tracked in it.
+*/
+func (e *Executor) WithOrderStore(orderStore *OrderStore) *Executor {
+	e.orderStore = orderStore
+	return e
+}
+
+/*
+Place failed orders up to attempts times in total. After a failure the executor waits for delay and looks the order up
+by its client order id, so an order that reached the exchange despite the error is never placed twice.
+*/
+func (e *Executor) WithRetries(attempts int, delay time.Duration) *Executor {
+	if attempts > 0 {
+		e.submitAttempts = attempts
+	}
+	e.retryDelay = delay
+	return e
+}
+
+/*
+Sets the policy used to execute triangular arbitrage opportunities.
+*/
+func (e *Executor) WithTriangularPolicy(policy TriangularPolicy) *Executor {
+	e.triangularPolicy = policy
+	return e
+}
+
+/*
+Execute an arbitrage opportunity by buying on the cheaper exchange and selling on the more expensive one. Both legs are
+submitted concurrently. If the fills do not match, the difference is hedged or unwound according to the leg risk policy.
+The quotes are used to select the best alternative venue for a hedge.
+*/
+func (e *Executor) ExecuteArbitrage(opportunity bookkeeper.ArbitrageEventRecord, size float64, quotes []bookkeeper.PriceRecord) (ExecutionReport, *ExecutorError) {
+	buyExchange, buyPrice, sellExchange, sellPrice := opportunity.ExchangeA, opportunity.PriceA, opportunity.ExchangeB, opportunity.PriceB
+	if buyPrice > sellPrice {
+		buyExchange, buyPrice, sellExchange, sellPrice = sellExchange, sellPrice, buyExchange, buyPrice
+	}
+
+	buyVenue, ok := e.venues[buyExchange]
+	if !ok {
+		return ExecutionReport{}, &ExecutorError{Msg: fmt.Sprintf("no venue configured for %s", buyExchange)}
+	}
+	sellVenue, ok := e.venues[sellExchange]
+	if !ok {
+		return ExecutionReport{}, &ExecutorError{Msg: fmt.Sprintf("no venue configured for %s", sellExchange)}
+	}
+
+	buyOrder := OrderRequest{
+		OpportunityUuid: opportunity.Uuid,
+		Leg:             "buy",
+		Exchange:        buyExchange,
+		Currency:        opportunity.Currency,
+		Side:            Buy,
+		Type:            Limit,
+		Price:           buyPrice,
+		Size:            size,
+	}
+	sellOrder := OrderRequest{
+		OpportunityUuid: opportunity.Uuid,
+		Leg:             "sell",
+		Exchange:        sellExchange,
+		Currency:        opportunity.Currency,
+		Side:            Sell,
+		Type:            Limit,
+		Price:           sellPrice,
+		Size:            size,
+	}
+
+	if riskErr := e.riskManager.CheckOrders(buyOrder, sellOrder); riskErr != nil {
+		return ExecutionReport{}, &ExecutorError{Msg: riskErr.Error()}
+	}
+
+	var report ExecutionReport
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		report.BuyLeg = e.placeOrder(buyVenue, buyOrder)
+	}()
+	go func() {
+		defer wg.Done()
+		report.SellLeg = e.placeOrder(sellVenue, sellOrder)
+	}()
+	wg.Wait()
+
+	report.Corrections = e.correctImbalance(opportunity, report.BuyLeg, report.SellLeg, quotes)
+	report.RealizedProfit = realizedProfit(report)
+	e.riskManager.RecordRealizedPnl(report.RealizedProfit)
+	return report, nil
+}
+
+/*
+Calculate the realized profit of an execution. The matched part of both legs realizes the price difference, corrective
+orders realize the difference to the leg they offset. Fees of both legs are deducted.
+*/
+func realizedProfit(report ExecutionReport) float64 {
+	matchedSize := math.Min(report.BuyLeg.FilledSize, report.SellLeg.FilledSize)
+	profit := matchedSize*(report.SellLeg.AveragePrice-report.BuyLeg.AveragePrice) - report.BuyLeg.Fee - report.SellLeg.Fee
+	for _, correction := range report.Corrections {
+		if correction.Side == string(Sell) {
+			profit += correction.FilledSize * (correction.AveragePrice - report.BuyLeg.AveragePrice)
+		} else {
+			profit += correction.FilledSize * (report.SellLeg.AveragePrice - correction.AveragePrice)
+		}
+	}
+	return profit
+}
+
+/*
+Place an order and convert a failed request into a rejected OrderResult, so that the caller can treat both the same way.
+The order must have been reserved with the risk manager, which is informed about its result like the order store. An
+order whose submission could not be confirmed may still be live, it is tracked as unknown until it is reconciled.
+*/
+func (e *Executor) placeOrder(venue Venue, order OrderRequest) OrderResult {
+	if order.ClientOrderId == "" {
+		order.ClientOrderId = ClientOrderId(order.OpportunityUuid, order.Leg)
+	}
+	rejected := OrderResult{
+		Exchange:      order.Exchange,
+		Currency:      order.Currency,
+		Side:          order.Side,
+		Status:        Rejected,
+		RequestedSize: order.Size,
+	}
+
+	var record bookkeeper.OrderRecord
+	if e.orderStore != nil {
+		var storeErr *ExecutorError
+		record, storeErr = e.orderStore.Add(order)
+		if storeErr != nil {
+			utils.Logger.Error(fmt.Sprintf("Refusing to place %s order on %s.", order.Side, order.Exchange), zap.Error(storeErr))
+			e.riskManager.Release(order)
+			return rejected
+		}
+	}
+
+	result, err := e.submitOrder(venue, order)
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Failed to place %s order on %s.", order.Side, order.Exchange), zap.Error(err))
+		if e.orderStore != nil {
+			state := StateRejected
+			if result.OrderId != "" || errors.Is(err, errUnconfirmedOrder) {
+				state = StateUnknown
+			}
+			e.orderStore.Transition(record.Uuid, state, OrderResult{OrderId: result.OrderId}, err.Error())
+		}
+		rejected.OrderId = result.OrderId
+		result = rejected
+	} else if e.orderStore != nil {
+		if _, storeErr := e.orderStore.Apply(record.Uuid, result); storeErr != nil {
+			utils.Logger.Error("Failed to update the order store.", zap.Error(storeErr))
+		}
+	}
+	e.riskManager.OrderCompleted(order, result)
+	return result
+}
+
+/*
+Submit an order and retry failed submissions. A failed submission may still have reached the exchange, so the order is
+looked up by its client order id and only placed again if the exchange does not know it. If the lookup fails as well,
+the order is not retried and the returned error wraps errUnconfirmedOrder.
+*/
+func (e *Executor) submitOrder(venue Venue, order OrderRequest) (OrderResult, error) {
+	result, err := venue.PlaceOrder(order)
+	for attempt := 1; err != nil && result.OrderId == ""; attempt++ {
+		time.Sleep(e.retryDelay)
+		existing, findErr := venue.FindOrder(order.ClientOrderId, order.Currency)
+		if findErr == nil {
+			utils.Logger.Warn(fmt.Sprintf("%s order %s on %s was placed despite the error: %v", order.Side, order.ClientOrderId, order.Exchange, err))
+			return existing, nil
+		}
+		if !errors.Is(findErr, ErrOrderNotFound) {
+			return result, fmt.Errorf("%w: %v (lookup failed: %v)", errUnconfirmedOrder, err, findErr)
+		}
+		if attempt >= e.submitAttempts {
+			break
+		}
+		utils.Logger.Warn(fmt.Sprintf("Retrying %s order %s on %s (attempt %d of %d) after: %v", order.Side, order.ClientOrderId, order.Exchange, attempt+1, e.submitAttempts, err))
+		result, err = venue.PlaceOrder(order)
+	}
+	return result, err
+}
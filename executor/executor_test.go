@@ -0,0 +1,310 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+	"log"
+	"testing"
+)
+
+func setupTest(tb testing.TB) func(tb testing.TB) {
+	log.Println("Setup tests.")
+
+	viper.SetConfigName("config-DEV")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath("../")
+	err := viper.ReadInConfig() // Find and read the config file
+	if err != nil {
+		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+	}
+
+	utils.InitializeLogger()
+	utils.SetLoggerLevel("DEBUG")
+
+	return func(tb testing.TB) {
+		log.Println("Teardown tests.")
+	}
+}
+
+/*
+A venue that fills a fixed fraction of every order, or rejects it when err is set. The first failures orders time out;
+if reachedOnFailure is set, they are placed on the exchange anyway. Placed orders can be found by client order id
+unless findErr is set.
+*/
+type fakeVenue struct {
+	name             string
+	fillRatio        float64
+	err              error
+	failures         int
+	reachedOnFailure bool
+	findErr          error
+	orders           []OrderRequest
+	placed           map[string]OrderResult
+}
+
+func (f *fakeVenue) Name() string {
+	return f.name
+}
+
+func (f *fakeVenue) PlaceOrder(order OrderRequest) (OrderResult, error) {
+	f.orders = append(f.orders, order)
+	if f.err != nil {
+		return OrderResult{}, f.err
+	}
+	status := Filled
+	if f.fillRatio < 1 {
+		status = PartiallyFilled
+	}
+	result := OrderResult{
+		OrderId:       uuid.New().String(),
+		Exchange:      f.name,
+		Currency:      order.Currency,
+		Side:          order.Side,
+		Status:        status,
+		RequestedSize: order.Size,
+		FilledSize:    order.Size * f.fillRatio,
+		AveragePrice:  order.Price,
+	}
+	if f.failures > 0 {
+		f.failures--
+		if !f.reachedOnFailure {
+			return OrderResult{}, errors.New("timeout")
+		}
+		f.record(order, result)
+		return OrderResult{}, errors.New("timeout")
+	}
+	f.record(order, result)
+	return result, nil
+}
+
+func (f *fakeVenue) record(order OrderRequest, result OrderResult) {
+	if f.placed == nil {
+		f.placed = make(map[string]OrderResult)
+	}
+	f.placed[order.ClientOrderId] = result
+}
+
+func (f *fakeVenue) FindOrder(clientOrderId string, currency string) (OrderResult, error) {
+	if f.findErr != nil {
+		return OrderResult{}, f.findErr
+	}
+	result, ok := f.placed[clientOrderId]
+	if !ok {
+		return OrderResult{}, ErrOrderNotFound
+	}
+	return result, nil
+}
+
+func (f *fakeVenue) CancelAllOrders() (int, error) {
+	return len(f.orders), f.err
+}
+
+/*
+A BTCUSD opportunity to buy on Coinbase and sell on Gemini.
+*/
+func opportunityForTest() bookkeeper.ArbitrageEventRecord {
+	return bookkeeper.ArbitrageEventRecord{
+		Uuid:                   uuid.New(),
+		Currency:               "BTCUSD",
+		PriceA:                 26000,
+		ExchangeA:              "Gemini",
+		PriceB:                 25000,
+		ExchangeB:              "Coinbase",
+		IsArbitrageOpportunity: true,
+	}
+}
+
+func Test_ExecuteArbitrage(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	opportunity := opportunityForTest()
+	quotes := []bookkeeper.PriceRecord{
+		{Currency: "BTCUSD", Exchange: "Coinbase", Price: 25000},
+		{Currency: "BTCUSD", Exchange: "Gemini", Price: 26000},
+		{Currency: "BTCUSD", Exchange: "Kraken", Price: 25950},
+	}
+
+	tests := []struct {
+		name              string
+		policy            LegRiskPolicy
+		coinbase          *fakeVenue
+		gemini            *fakeVenue
+		kraken            *fakeVenue
+		quotes            []bookkeeper.PriceRecord
+		wantActions       []string
+		wantExchanges     []string
+		wantSide          string
+		wantRequestedSize float64
+	}{
+		{
+			name:     "Both legs filled, no correction.",
+			policy:   LegRiskPolicy{Mode: Hedge, MinImbalance: .00001},
+			coinbase: &fakeVenue{name: "Coinbase", fillRatio: 1},
+			gemini:   &fakeVenue{name: "Gemini", fillRatio: 1},
+			kraken:   &fakeVenue{name: "Kraken", fillRatio: 1},
+			quotes:   quotes,
+		},
+		{
+			name:              "Sell leg rejected, hedged on the best alternative venue.",
+			policy:            LegRiskPolicy{Mode: Hedge, MaxSlippage: .005, MinImbalance: .00001},
+			coinbase:          &fakeVenue{name: "Coinbase", fillRatio: 1},
+			gemini:            &fakeVenue{name: "Gemini", err: errors.New("insufficient funds")},
+			kraken:            &fakeVenue{name: "Kraken", fillRatio: 1},
+			quotes:            quotes,
+			wantActions:       []string{"hedge"},
+			wantExchanges:     []string{"Kraken"},
+			wantSide:          "sell",
+			wantRequestedSize: .01,
+		},
+		{
+			name:     "Sell leg rejected, alternative venue exceeds the slippage limit so the buy leg is unwound.",
+			policy:   LegRiskPolicy{Mode: Hedge, MaxSlippage: .001, MinImbalance: .00001},
+			coinbase: &fakeVenue{name: "Coinbase", fillRatio: 1},
+			gemini:   &fakeVenue{name: "Gemini", err: errors.New("insufficient funds")},
+			kraken:   &fakeVenue{name: "Kraken", fillRatio: 1},
+			quotes: []bookkeeper.PriceRecord{
+				{Currency: "BTCUSD", Exchange: "Kraken", Price: 25500},
+			},
+			wantActions:       []string{"unwind"},
+			wantExchanges:     []string{"Coinbase"},
+			wantSide:          "sell",
+			wantRequestedSize: .01,
+		},
+		{
+			name:              "Buy leg partially filled, excess sell is unwound.",
+			policy:            LegRiskPolicy{Mode: Unwind, MinImbalance: .00001},
+			coinbase:          &fakeVenue{name: "Coinbase", fillRatio: .5},
+			gemini:            &fakeVenue{name: "Gemini", fillRatio: 1},
+			kraken:            &fakeVenue{name: "Kraken", fillRatio: 1},
+			quotes:            quotes,
+			wantActions:       []string{"unwind"},
+			wantExchanges:     []string{"Gemini"},
+			wantSide:          "buy",
+			wantRequestedSize: .005,
+		},
+		{
+			name:              "Hedge only partially fills, remainder is unwound.",
+			policy:            LegRiskPolicy{Mode: Hedge, MaxSlippage: .005, MinImbalance: .00001},
+			coinbase:          &fakeVenue{name: "Coinbase", fillRatio: 1},
+			gemini:            &fakeVenue{name: "Gemini", err: errors.New("timeout")},
+			kraken:            &fakeVenue{name: "Kraken", fillRatio: .4},
+			quotes:            quotes,
+			wantActions:       []string{"hedge", "unwind"},
+			wantExchanges:     []string{"Kraken", "Coinbase"},
+			wantSide:          "sell",
+			wantRequestedSize: .01,
+		},
+		{
+			name:              "Imbalance is capped at the maximum correction size.",
+			policy:            LegRiskPolicy{Mode: Unwind, MaxCorrectionSize: .004, MinImbalance: .00001},
+			coinbase:          &fakeVenue{name: "Coinbase", fillRatio: 1},
+			gemini:            &fakeVenue{name: "Gemini", err: errors.New("timeout")},
+			kraken:            &fakeVenue{name: "Kraken", fillRatio: 1},
+			quotes:            quotes,
+			wantActions:       []string{"unwind"},
+			wantExchanges:     []string{"Coinbase"},
+			wantSide:          "sell",
+			wantRequestedSize: .004,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tradeExecutor := NewExecutor(tt.policy, tt.coinbase, tt.gemini, tt.kraken)
+			report, err := tradeExecutor.ExecuteArbitrage(opportunity, .01, tt.quotes)
+			assert.Equal(t, err == nil, true)
+			assert.Equal(t, report.BuyLeg.Exchange, "Coinbase")
+			assert.Equal(t, report.SellLeg.Exchange, "Gemini")
+			assert.Equal(t, len(report.Corrections), len(tt.wantActions))
+			for i, correction := range report.Corrections {
+				assert.Equal(t, correction.ArbitrageRecordUuid, opportunity.Uuid)
+				assert.Equal(t, correction.Action, tt.wantActions[i])
+				assert.Equal(t, correction.Exchange, tt.wantExchanges[i])
+				assert.Equal(t, correction.Side, tt.wantSide)
+			}
+			if len(report.Corrections) > 0 {
+				assert.Equal(t, report.Corrections[0].RequestedSize, tt.wantRequestedSize)
+			}
+		})
+	}
+}
+
+func Test_ExecuteArbitrageUnknownVenue(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tradeExecutor := NewExecutor(LegRiskPolicy{Mode: Unwind}, &fakeVenue{name: "Coinbase", fillRatio: 1})
+	_, err := tradeExecutor.ExecuteArbitrage(bookkeeper.ArbitrageEventRecord{Currency: "BTCUSD", ExchangeA: "Coinbase", PriceA: 1, ExchangeB: "Binance", PriceB: 2}, .01, nil)
+	assert.Equal(t, err != nil, true)
+}
+
+func Test_CorrectionsAreCheckedByTheRiskManager(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	opportunity := opportunityForTest()
+	buyOrder := OrderRequest{OpportunityUuid: opportunity.Uuid, Leg: "buy", Exchange: "Coinbase", Currency: "BTCUSD", Side: Buy, Type: Limit, Price: 25000, Size: .01}
+	sellOrder := OrderRequest{OpportunityUuid: opportunity.Uuid, Leg: "sell", Exchange: "Gemini", Currency: "BTCUSD", Side: Sell, Type: Limit, Price: 26000, Size: .01}
+	buyLeg := OrderResult{Exchange: "Coinbase", Currency: "BTCUSD", Side: Buy, Status: Filled, RequestedSize: .01, FilledSize: .01, AveragePrice: 25000}
+	sellLeg := OrderResult{Exchange: "Gemini", Currency: "BTCUSD", Side: Sell, Status: Rejected, RequestedSize: .01}
+	executeLegs := func(r *RiskManager) {
+		r.CheckOrders(buyOrder, sellOrder)
+		r.OrderCompleted(buyOrder, buyLeg)
+		r.OrderCompleted(sellOrder, sellLeg)
+	}
+
+	tests := []struct {
+		name            string
+		limits          RiskLimits
+		prepare         func(r *RiskManager)
+		wantCorrections int
+	}{
+		{
+			name: "An unwind that reduces the position is placed while the kill switch is active.",
+			prepare: func(r *RiskManager) {
+				executeLegs(r)
+				r.Kill("test")
+			},
+			wantCorrections: 1,
+		},
+		{
+			name:            "An unwind that reduces the position is placed after too many consecutive failures.",
+			limits:          RiskLimits{MaxConsecutiveFailures: 1},
+			prepare:         executeLegs,
+			wantCorrections: 1,
+		},
+		{
+			name: "A correction that opens a position is blocked while the kill switch is active.",
+			prepare: func(r *RiskManager) {
+				r.Kill("test")
+			},
+		},
+		{
+			name:   "A correction that exceeds a limit is blocked.",
+			limits: RiskLimits{MaxOpenOrders: 2},
+			prepare: func(r *RiskManager) {
+				executeLegs(r)
+				r.CheckOrders(buyOrder, sellOrder)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coinbase := &fakeVenue{name: "Coinbase", fillRatio: 1}
+			gemini := &fakeVenue{name: "Gemini", err: errors.New("insufficient funds")}
+			riskManager := NewRiskManager(tt.limits)
+			tradeExecutor := NewExecutor(LegRiskPolicy{Mode: Unwind, MinImbalance: .00001}, coinbase, gemini).WithRiskManager(riskManager)
+			riskManager.MarkPrices([]bookkeeper.PriceRecord{{Currency: "BTCUSD", Exchange: "Coinbase", Price: 25000}})
+			tt.prepare(riskManager)
+
+			corrections := tradeExecutor.correctImbalance(opportunity, buyLeg, sellLeg, nil)
+			assert.Equal(t, len(corrections), tt.wantCorrections)
+			assert.Equal(t, len(coinbase.orders), tt.wantCorrections)
+		})
+	}
+}
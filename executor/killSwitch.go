@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"go.uber.org/zap"
+	"strings"
+)
+
+/*
+Activate the kill switch and cancel all open orders on every venue of the Executor. New orders stay blocked until
+ResetKillSwitch is called. Returns the number of cancelled orders.
+*/
+func (e *Executor) KillSwitch(reason string) (int, *ExecutorError) {
+	e.riskManager.Kill(reason)
+	utils.Logger.Warn(fmt.Sprintf("Kill switch activated: %s", reason))
+
+	var venues []Venue
+	for _, venue := range e.venues {
+		venues = append(venues, venue)
+	}
+	return CancelAllOrders(venues...)
+}
+
+/*
+Deactivate the kill switch so that new orders can be placed again.
+*/
+func (e *Executor) ResetKillSwitch() {
+	e.riskManager.Reset()
+	utils.Logger.Info("Kill switch reset. New orders are allowed again.")
+}
+
+/*
+Cancel all open orders on the given venues. Every venue is attempted even if another one fails. Returns the number of
+cancelled orders and an error listing the venues that failed.
+*/
+func CancelAllOrders(venues ...Venue) (int, *ExecutorError) {
+	cancelled := 0
+	var failures []string
+	for _, venue := range venues {
+		count, err := venue.CancelAllOrders()
+		cancelled += count
+		if err != nil {
+			utils.Logger.Error(fmt.Sprintf("Failed to cancel open orders on %s.", venue.Name()), zap.Error(err))
+			failures = append(failures, fmt.Sprintf("%s: %v", venue.Name(), err))
+			continue
+		}
+		utils.Logger.Info(fmt.Sprintf("Cancelled %d open orders on %s.", count, venue.Name()))
+	}
+	if len(failures) > 0 {
+		return cancelled, &ExecutorError{Msg: fmt.Sprintf("failed to cancel orders on %s", strings.Join(failures, ", "))}
+	}
+	return cancelled, nil
+}
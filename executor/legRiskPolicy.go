@@ -0,0 +1,171 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"math"
+	"time"
+)
+
+type CorrectionMode string
+
+const (
+	Hedge  CorrectionMode = "hedge"
+	Unwind CorrectionMode = "unwind"
+)
+
+/*
+Configures how the executor reacts when one leg of an arbitrage fills and the other does not (or only partially does).
+Hedge places a market order on the best alternative venue and falls back to unwinding when no venue is within
+MaxSlippage. Unwind reverses the excess on the venue that filled. A MaxCorrectionSize of 0 means no limit.
+*/
+type LegRiskPolicy struct {
+	Mode              CorrectionMode
+	MaxCorrectionSize float64
+	MaxSlippage       float64
+	MinImbalance      float64
+}
+
+/*
+Load the leg risk policy from the configuration file (EXECUTION.LEG_RISK).
+*/
+func LoadLegRiskPolicy() LegRiskPolicy {
+	mode := CorrectionMode(viper.GetString("EXECUTION.LEG_RISK.MODE"))
+	if mode != Hedge && mode != Unwind {
+		mode = Unwind
+	}
+	return LegRiskPolicy{
+		Mode:              mode,
+		MaxCorrectionSize: viper.GetFloat64("EXECUTION.LEG_RISK.MAX_CORRECTION_SIZE"),
+		MaxSlippage:       viper.GetFloat64("EXECUTION.LEG_RISK.MAX_SLIPPAGE"),
+		MinImbalance:      viper.GetFloat64("EXECUTION.LEG_RISK.MIN_IMBALANCE"),
+	}
+}
+
+/*
+A venue to place a corrective order on, and why it was chosen.
+*/
+type correctionTarget struct {
+	action CorrectionMode
+	venue  Venue
+	reason string
+}
+
+/*
+Detect an imbalance between the filled size of the buy and sell legs and correct it. Every corrective order is returned
+as a LegCorrectionRecord referencing the original opportunity. Corrective orders are checked by the risk manager like
+every other order, so while the kill switch is active only orders that reduce the position are placed.
+*/
+func (e *Executor) correctImbalance(opportunity bookkeeper.ArbitrageEventRecord, buyLeg OrderResult, sellLeg OrderResult, quotes []bookkeeper.PriceRecord) []bookkeeper.LegCorrectionRecord {
+	imbalance := buyLeg.FilledSize - sellLeg.FilledSize
+	if math.Abs(imbalance) <= e.policy.MinImbalance {
+		return nil
+	}
+
+	// A positive imbalance means we bought more than we sold and have to sell the difference, and vice versa.
+	side, failedLeg, filledLeg := Sell, sellLeg, buyLeg
+	if imbalance < 0 {
+		side, failedLeg, filledLeg = Buy, buyLeg, sellLeg
+	}
+	remaining := math.Abs(imbalance)
+	if e.policy.MaxCorrectionSize > 0 && remaining > e.policy.MaxCorrectionSize {
+		utils.Logger.Warn(fmt.Sprintf("Imbalance of %v for opportunity %s exceeds the maximum correction size of %v. The remainder stays open.", remaining, opportunity.Uuid, e.policy.MaxCorrectionSize))
+		remaining = e.policy.MaxCorrectionSize
+	}
+
+	referencePrice := opportunity.PriceA
+	if failedLeg.Exchange == opportunity.ExchangeB {
+		referencePrice = opportunity.PriceB
+	}
+
+	var targets []correctionTarget
+	if e.policy.Mode == Hedge {
+		venue, reason := e.bestAlternativeVenue(opportunity.Currency, side, failedLeg.Exchange, referencePrice, quotes)
+		if venue != nil {
+			targets = append(targets, correctionTarget{action: Hedge, venue: venue, reason: reason})
+		} else {
+			utils.Logger.Warn(fmt.Sprintf("Unable to hedge opportunity %s: %s. Unwinding instead.", opportunity.Uuid, reason))
+		}
+	}
+	if unwindVenue, ok := e.venues[filledLeg.Exchange]; ok {
+		targets = append(targets, correctionTarget{action: Unwind, venue: unwindVenue, reason: fmt.Sprintf("%s leg on %s was %s", failedLeg.Side, failedLeg.Exchange, failedLeg.Status)})
+	}
+
+	var corrections []bookkeeper.LegCorrectionRecord
+	for _, target := range targets {
+		if remaining <= e.policy.MinImbalance {
+			break
+		}
+		correction := OrderRequest{
+			OpportunityUuid: opportunity.Uuid,
+			Leg:             string(target.action),
+			Exchange:        target.venue.Name(),
+			Currency:        opportunity.Currency,
+			Side:            side,
+			Type:            Market,
+			Size:            remaining,
+		}
+		if riskErr := e.riskManager.CheckOrder(correction); riskErr != nil {
+			utils.Logger.Warn(fmt.Sprintf("Skipping the %s of opportunity %s on %s.", target.action, opportunity.Uuid, target.venue.Name()), zap.Error(riskErr))
+			continue
+		}
+		result := e.placeOrder(target.venue, correction)
+		corrections = append(corrections, bookkeeper.LegCorrectionRecord{
+			Uuid:                uuid.New(),
+			Timestamp:           time.Now().UTC(),
+			ArbitrageRecordUuid: opportunity.Uuid,
+			Action:              string(target.action),
+			Exchange:            target.venue.Name(),
+			Currency:            opportunity.Currency,
+			Side:                string(side),
+			RequestedSize:       remaining,
+			FilledSize:          result.FilledSize,
+			AveragePrice:        result.AveragePrice,
+			Status:              string(result.Status),
+			Reason:              target.reason,
+		})
+		remaining -= result.FilledSize
+		utils.Logger.Info(fmt.Sprintf("Corrected leg imbalance for opportunity %s: %s %v %s on %s (%s).", opportunity.Uuid, target.action, result.FilledSize, opportunity.Currency, target.venue.Name(), result.Status))
+	}
+
+	if remaining > e.policy.MinImbalance {
+		utils.Logger.Error(fmt.Sprintf("Opportunity %s still has an open position of %v %s after corrections.", opportunity.Uuid, remaining, opportunity.Currency))
+	}
+	return corrections
+}
+
+/*
+Find the venue with the best quoted price for the given side, excluding the venue whose leg failed. Returns nil and the
+reason when no venue is available or the best price is further than MaxSlippage away from the reference price.
+*/
+func (e *Executor) bestAlternativeVenue(currency string, side Side, excludeExchange string, referencePrice float64, quotes []bookkeeper.PriceRecord) (Venue, string) {
+	var best *bookkeeper.PriceRecord
+	for i := range quotes {
+		quote := &quotes[i]
+		if quote.Currency != currency || quote.Exchange == excludeExchange || quote.Price <= 0 {
+			continue
+		}
+		if _, ok := e.venues[quote.Exchange]; !ok {
+			continue
+		}
+		if best == nil || (side == Sell && quote.Price > best.Price) || (side == Buy && quote.Price < best.Price) {
+			best = quote
+		}
+	}
+	if best == nil {
+		return nil, fmt.Sprintf("no alternative venue quotes %s", currency)
+	}
+
+	slippage := (referencePrice - best.Price) / referencePrice
+	if side == Buy {
+		slippage = (best.Price - referencePrice) / referencePrice
+	}
+	if e.policy.MaxSlippage > 0 && slippage > e.policy.MaxSlippage {
+		return nil, fmt.Sprintf("best alternative price on %s is %v, %.4f%% away from %v", best.Exchange, best.Price, slippage*100, referencePrice)
+	}
+	return e.venues[best.Exchange], fmt.Sprintf("hedged on %s at a quoted price of %v", best.Exchange, best.Price)
+}
@@ -0,0 +1,275 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+)
+
+type OrderState string
+
+const (
+	StateNew             OrderState = "new"
+	StateAcknowledged    OrderState = "acknowledged"
+	StatePartiallyFilled OrderState = "partially_filled"
+	StateFilled          OrderState = "filled"
+	StateCancelled       OrderState = "cancelled"
+	StateRejected        OrderState = "rejected"
+	StateUnknown         OrderState = "unknown"
+)
+
+/*
+The states an order may move to from each state. Filled, cancelled and rejected orders are final. Unknown orders are
+resolved by the reconciliation once the exchange reports them again.
+*/
+var orderTransitions = map[OrderState][]OrderState{
+	StateNew:             {StateAcknowledged, StatePartiallyFilled, StateFilled, StateCancelled, StateRejected, StateUnknown},
+	StateAcknowledged:    {StateAcknowledged, StatePartiallyFilled, StateFilled, StateCancelled, StateUnknown},
+	StatePartiallyFilled: {StatePartiallyFilled, StateFilled, StateCancelled, StateUnknown},
+	StateUnknown:         {StateUnknown, StateAcknowledged, StatePartiallyFilled, StateFilled, StateCancelled, StateRejected},
+}
+
+/*
+Returns true if an order may move from one state to the other.
+*/
+func CanTransition(from OrderState, to OrderState) bool {
+	for _, state := range orderTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Returns true if the order state will not change anymore.
+*/
+func (s OrderState) IsFinal() bool {
+	return len(orderTransitions[s]) == 0
+}
+
+/*
+The order state that corresponds to an OrderResult reported by an exchange. Partially filled immediate-or-cancel orders
+are done, the remainder was cancelled.
+*/
+func stateOf(result OrderResult) OrderState {
+	switch result.Status {
+	case Open:
+		if result.FilledSize > 0 {
+			return StatePartiallyFilled
+		}
+		return StateAcknowledged
+	case Filled:
+		return StateFilled
+	case PartiallyFilled, Cancelled:
+		return StateCancelled
+	default:
+		return StateRejected
+	}
+}
+
+/*
+Keeps track of the state of every order that is not final. If the store is persistent, every order and state change is
+written to the database, so that open orders can be reconciled after a restart. Final orders are only kept in the
+database.
+*/
+type OrderStore struct {
+	mu             sync.Mutex
+	orders         map[uuid.UUID]bookkeeper.OrderRecord
+	clientOrderIds map[string]bool
+	persistent     bool
+	ledger         *bookkeeper.Ledger
+}
+
+/*
+Create an OrderStore that only keeps orders in memory.
+*/
+func NewOrderStore() *OrderStore {
+	return &OrderStore{
+		orders:         make(map[uuid.UUID]bookkeeper.OrderRecord),
+		clientOrderIds: make(map[string]bool),
+	}
+}
+
+/*
+Create an OrderStore that is backed by the database and load all orders that were not final when the bot stopped.
+*/
+func LoadOrderStore() (*OrderStore, error) {
+	var openStates []string
+	for state, transitions := range orderTransitions {
+		if len(transitions) > 0 {
+			openStates = append(openStates, string(state))
+		}
+	}
+	openOrders, err := bookkeeper.GetOrderRecords(openStates...)
+	if err != nil {
+		return nil, err
+	}
+
+	store := NewOrderStore()
+	store.persistent = true
+	for _, order := range openOrders {
+		store.orders[order.Uuid] = order
+		store.clientOrderIds[order.ClientOrderId] = true
+	}
+	utils.Logger.Info(fmt.Sprintf("Loaded %d open orders.", len(openOrders)))
+	return store, nil
+}
+
+/*
+Post the fills of orders to a ledger.
+*/
+func (s *OrderStore) WithLedger(ledger *bookkeeper.Ledger) *OrderStore {
+	s.ledger = ledger
+	return s
+}
+
+/*
+Add a new order that is about to be submitted. Returns an error if an order with the same client order id was added
+before, so that the same leg of an opportunity is never submitted twice.
+*/
+func (s *OrderStore) Add(order OrderRequest) (bookkeeper.OrderRecord, *ExecutorError) {
+	now := time.Now().UTC()
+	record := bookkeeper.OrderRecord{
+		Uuid:                uuid.New(),
+		Timestamp:           now,
+		UpdatedAt:           now,
+		ArbitrageRecordUuid: order.OpportunityUuid,
+		Leg:                 order.Leg,
+		ClientOrderId:       order.ClientOrderId,
+		Exchange:            order.Exchange,
+		Currency:            order.Currency,
+		Side:                string(order.Side),
+		Type:                string(order.Type),
+		Price:               order.Price,
+		Size:                order.Size,
+		State:               string(StateNew),
+	}
+
+	s.mu.Lock()
+	if order.ClientOrderId != "" && s.clientOrderIds[order.ClientOrderId] {
+		s.mu.Unlock()
+		return record, &ExecutorError{Msg: fmt.Sprintf("order %s was already submitted", order.ClientOrderId)}
+	}
+	s.orders[record.Uuid] = record
+	if order.ClientOrderId != "" {
+		s.clientOrderIds[order.ClientOrderId] = true
+	}
+	s.mu.Unlock()
+
+	// The client order id is unique in the database, which protects against submitting an order twice across restarts
+	if s.persistent {
+		if err := bookkeeper.RecordOrderRecord(record); err != nil {
+			s.mu.Lock()
+			delete(s.orders, record.Uuid)
+			delete(s.clientOrderIds, order.ClientOrderId)
+			s.mu.Unlock()
+			return record, &ExecutorError{Msg: fmt.Sprintf("unable to record order %s: %v", order.ClientOrderId, err)}
+		}
+	}
+	return record, nil
+}
+
+/*
+Move an order to a new state and update it with the order id and fills reported by the exchange. Returns the updated
+order, or an error if the order is unknown to the store or the transition is not allowed.
+*/
+func (s *OrderStore) Transition(id uuid.UUID, state OrderState, result OrderResult, reason string) (bookkeeper.OrderRecord, *ExecutorError) {
+	s.mu.Lock()
+	record, ok := s.orders[id]
+	if !ok {
+		s.mu.Unlock()
+		return bookkeeper.OrderRecord{}, &ExecutorError{Msg: fmt.Sprintf("order %s is not open", id)}
+	}
+	if !CanTransition(OrderState(record.State), state) {
+		s.mu.Unlock()
+		return record, &ExecutorError{Msg: fmt.Sprintf("order %s can not move from %s to %s", id, record.State, state)}
+	}
+
+	previous := record
+	changed := record.State != string(state) || record.FilledSize != result.FilledSize || record.Reason != reason
+	if result.OrderId != "" {
+		changed = changed || record.OrderId != result.OrderId
+		record.OrderId = result.OrderId
+	}
+	record.State = string(state)
+	record.FilledSize = result.FilledSize
+	record.AveragePrice = result.AveragePrice
+	record.Fee = result.Fee
+	record.Reason = reason
+	if changed {
+		record.UpdatedAt = time.Now().UTC()
+	}
+	if state.IsFinal() {
+		delete(s.orders, id)
+	} else {
+		s.orders[id] = record
+	}
+	s.mu.Unlock()
+
+	// The state in memory stays authoritative. A stale record is loaded again after a restart and reconciled
+	if changed && s.persistent {
+		if err := bookkeeper.UpdateOrderRecord(record); err != nil {
+			utils.Logger.Error(fmt.Sprintf("Unable to update order %s to %s in the database.", record.Uuid, record.State), zap.Error(err))
+		}
+	}
+	if s.ledger != nil && record.FilledSize > previous.FilledSize {
+		s.postFill(previous, record)
+	}
+	return record, nil
+}
+
+/*
+Post the part of an order that was filled since its previous update to the ledger. The price and fee of the new part
+are derived from the totals reported by the exchange.
+*/
+func (s *OrderStore) postFill(previous bookkeeper.OrderRecord, record bookkeeper.OrderRecord) {
+	size := record.FilledSize - previous.FilledSize
+	baseAsset, quoteAsset := bookkeeper.SplitCurrency(record.Currency)
+	err := s.ledger.PostFill(bookkeeper.Fill{
+		Timestamp:  record.UpdatedAt,
+		Reference:  record.ClientOrderId,
+		Exchange:   record.Exchange,
+		BaseAsset:  baseAsset,
+		QuoteAsset: quoteAsset,
+		Side:       record.Side,
+		Size:       size,
+		Price:      (record.FilledSize*record.AveragePrice - previous.FilledSize*previous.AveragePrice) / size,
+		Fee:        record.Fee - previous.Fee,
+	})
+	if err != nil {
+		utils.Logger.Error(fmt.Sprintf("Unable to post the fill of order %s to the ledger.", record.Uuid), zap.Error(err))
+	}
+}
+
+/*
+Update an order with the result reported by the exchange.
+*/
+func (s *OrderStore) Apply(id uuid.UUID, result OrderResult) (bookkeeper.OrderRecord, *ExecutorError) {
+	return s.Transition(id, stateOf(result), result, "")
+}
+
+/*
+Returns all orders that are not final, oldest first.
+*/
+func (s *OrderStore) OpenOrders() []bookkeeper.OrderRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := make([]bookkeeper.OrderRecord, 0, len(s.orders))
+	for _, order := range s.orders {
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].Timestamp != orders[j].Timestamp {
+			return orders[i].Timestamp.Before(orders[j].Timestamp)
+		}
+		return orders[i].Uuid.String() < orders[j].Uuid.String()
+	})
+	return orders
+}
@@ -0,0 +1,223 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/magiconair/properties/assert"
+	"testing"
+)
+
+func Test_OrderStoreTransition(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name       string
+		states     []OrderState
+		wantState  OrderState
+		wantErr    bool
+		wantIsOpen bool
+	}{
+		{
+			name:       "Acknowledged order is open.",
+			states:     []OrderState{StateAcknowledged},
+			wantState:  StateAcknowledged,
+			wantIsOpen: true,
+		},
+		{
+			name:       "Order fills partially and then completely.",
+			states:     []OrderState{StateAcknowledged, StatePartiallyFilled, StateFilled},
+			wantState:  StateFilled,
+			wantIsOpen: false,
+		},
+		{
+			name:       "Unknown order is resolved.",
+			states:     []OrderState{StateUnknown, StateCancelled},
+			wantState:  StateCancelled,
+			wantIsOpen: false,
+		},
+		{
+			name:       "Acknowledged order can not be rejected.",
+			states:     []OrderState{StateAcknowledged, StateRejected},
+			wantState:  StateAcknowledged,
+			wantErr:    true,
+			wantIsOpen: true,
+		},
+		{
+			name:       "Filled order is final.",
+			states:     []OrderState{StateFilled, StateCancelled},
+			wantErr:    true,
+			wantIsOpen: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewOrderStore()
+			order, _ := store.Add(OrderRequest{Exchange: "Coinbase", Currency: "BTCUSD", Side: Buy, Type: Limit, Price: 25000, Size: .01})
+			assert.Equal(t, order.State, string(StateNew))
+
+			var gotErr *ExecutorError
+			for _, state := range tt.states {
+				record, err := store.Transition(order.Uuid, state, OrderResult{OrderId: "1"}, "")
+				if err != nil {
+					gotErr = err
+					continue
+				}
+				order = record
+			}
+			if !tt.wantErr || tt.wantState != "" {
+				assert.Equal(t, order.State, string(tt.wantState))
+			}
+			assert.Equal(t, gotErr != nil, tt.wantErr)
+			assert.Equal(t, len(store.OpenOrders()) == 1, tt.wantIsOpen)
+		})
+	}
+}
+
+/*
+Storage that fails to record orders while fail is set.
+*/
+type failingStorage struct {
+	bookkeeper.Storage
+	fail bool
+}
+
+func (s *failingStorage) RecordOrderRecord(orderRecord bookkeeper.OrderRecord) error {
+	if s.fail {
+		return errors.New("database is offline")
+	}
+	return nil
+}
+
+func Test_OrderStoreAddFails(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	storage := &failingStorage{fail: true}
+	bookkeeper.UseStorage(storage)
+	defer bookkeeper.UseStorage(nil)
+	store := NewOrderStore()
+	store.persistent = true
+	order := OrderRequest{ClientOrderId: "client-1", Exchange: "Coinbase", Currency: "BTCUSD", Side: Buy, Type: Limit, Price: 25000, Size: .01}
+
+	_, err := store.Add(order)
+	assert.Equal(t, err != nil, true)
+	assert.Equal(t, len(store.OpenOrders()), 0)
+
+	// The order was never recorded, so it can be added again once the database is back
+	storage.fail = false
+	_, err = store.Add(order)
+	assert.Equal(t, err == nil, true)
+	assert.Equal(t, len(store.OpenOrders()), 1)
+	_, err = store.Add(order)
+	assert.Equal(t, err != nil, true)
+}
+
+func Test_OrderStorePostsFills(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	ledger := bookkeeper.NewLedger("USD")
+	store := NewOrderStore().WithLedger(ledger)
+	order, _ := store.Add(OrderRequest{Exchange: "Coinbase", Currency: "BTCUSD", Side: Buy, Type: Limit, Price: 25000, Size: .02})
+	store.Transition(order.Uuid, StatePartiallyFilled, OrderResult{OrderId: "1", FilledSize: .01, AveragePrice: 25000, Fee: 2.5}, "")
+	store.Transition(order.Uuid, StateFilled, OrderResult{OrderId: "1", FilledSize: .02, AveragePrice: 25100, Fee: 5}, "")
+
+	assert.Equal(t, ledger.Balance("Coinbase", "BTC"), .02)
+	assert.Equal(t, ledger.Balance("Coinbase", "USD"), -.02*25100-5)
+	assert.Equal(t, ledger.RealizedPnl(), -5.0)
+}
+
+func Test_ExecuteArbitrageTracksOrders(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	store := NewOrderStore()
+	executor := NewExecutor(LegRiskPolicy{Mode: Hedge}, &fakeVenue{name: "Coinbase", fillRatio: 1}, &fakeVenue{name: "Gemini", err: errors.New("timeout")}).WithOrderStore(store)
+
+	_, err := executor.ExecuteArbitrage(opportunityForTest(), .01, nil)
+	assert.Equal(t, err == nil, true)
+
+	// The filled and the rejected leg and any correction are final
+	assert.Equal(t, len(store.OpenOrders()), 0)
+}
+
+func Test_SubmitOrder(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name           string
+		venue          fakeVenue
+		attempts       int
+		wantPlacements int
+		wantStatus     OrderStatus
+		wantState      OrderState
+	}{
+		{
+			name:           "Order placed on the first attempt.",
+			venue:          fakeVenue{name: "Coinbase", fillRatio: 1},
+			attempts:       2,
+			wantPlacements: 1,
+			wantStatus:     Filled,
+		},
+		{
+			name:           "Timed out order reached the exchange and is not placed again.",
+			venue:          fakeVenue{name: "Coinbase", fillRatio: 1, failures: 1, reachedOnFailure: true},
+			attempts:       2,
+			wantPlacements: 1,
+			wantStatus:     Filled,
+		},
+		{
+			name:           "Timed out order did not reach the exchange and is retried.",
+			venue:          fakeVenue{name: "Coinbase", fillRatio: 1, failures: 1},
+			attempts:       2,
+			wantPlacements: 2,
+			wantStatus:     Filled,
+		},
+		{
+			name:           "Timed out order is not retried without attempts left.",
+			venue:          fakeVenue{name: "Coinbase", fillRatio: 1, failures: 1},
+			attempts:       1,
+			wantPlacements: 1,
+			wantStatus:     Rejected,
+		},
+		{
+			name:           "Timed out order that can not be looked up is unknown.",
+			venue:          fakeVenue{name: "Coinbase", fillRatio: 1, failures: 1, findErr: errors.New("timeout")},
+			attempts:       2,
+			wantPlacements: 1,
+			wantStatus:     Rejected,
+			wantState:      StateUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewOrderStore()
+			executor := NewExecutor(LegRiskPolicy{}, &tt.venue).WithOrderStore(store).WithRetries(tt.attempts, 0)
+
+			order := OrderRequest{OpportunityUuid: uuid.New(), Leg: "buy", Exchange: "Coinbase", Currency: "BTCUSD", Side: Buy, Type: Limit, Price: 25000, Size: .01}
+			executor.riskManager.CheckOrder(order)
+			result := executor.placeOrder(&tt.venue, order)
+
+			assert.Equal(t, len(tt.venue.orders), tt.wantPlacements)
+			assert.Equal(t, result.Status, tt.wantStatus)
+			for _, placed := range tt.venue.orders {
+				assert.Equal(t, placed.ClientOrderId, ClientOrderId(order.OpportunityUuid, "buy"))
+			}
+
+			var state OrderState
+			for _, openOrder := range store.OpenOrders() {
+				state = OrderState(openOrder.State)
+			}
+			assert.Equal(t, state, tt.wantState)
+
+			// The same leg is never submitted twice
+			executor.riskManager.CheckOrder(order)
+			again := executor.placeOrder(&tt.venue, order)
+			assert.Equal(t, again.Status, Rejected)
+			assert.Equal(t, len(tt.venue.orders), tt.wantPlacements)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"time"
+)
+
+/*
+New orders without an exchange order id are expected to be in flight. After this time they are looked up by their client
+order id, e.g. because the bot stopped while placing them.
+*/
+const unacknowledgedTimeout = 5 * time.Minute
+
+/*
+An exchange whose orders can be looked up. Implemented by the clients in the api packages. GetOrder and FindOrder
+return ErrOrderNotFound if the exchange does not know the order id or client order id.
+*/
+type OrderSource interface {
+	Name() string
+	ListOpenOrders() ([]OrderResult, error)
+	GetOrder(orderId string, currency string) (OrderResult, error)
+	FindOrder(clientOrderId string, currency string) (OrderResult, error)
+}
+
+/*
+The outcome of a reconciliation run.
+  - Repaired: orders whose local state was updated from the exchange or that never reached it.
+  - Unknown: orders that could not be found on the exchange.
+  - Foreign: open orders on an exchange that were not placed by the bot.
+*/
+type ReconciliationReport struct {
+	Repaired []bookkeeper.OrderRecord
+	Unknown  []bookkeeper.OrderRecord
+	Foreign  []OrderResult
+}
+
+/*
+Compares the state of open orders in the OrderStore with the state reported by the exchanges.
+*/
+type Reconciler struct {
+	store   *OrderStore
+	sources map[string]OrderSource
+}
+
+/*
+Create a new Reconciler for the given exchanges.
+*/
+func NewReconciler(store *OrderStore, sources ...OrderSource) *Reconciler {
+	sourceMap := make(map[string]OrderSource)
+	for _, source := range sources {
+		sourceMap[source.Name()] = source
+	}
+	return &Reconciler{
+		store:   store,
+		sources: sourceMap,
+	}
+}
+
+/*
+Reconcile the open orders of every exchange. Local orders that drifted from the exchange are repaired, orders the
+exchange does not know are marked unknown, and open orders the bot did not place are reported as an error.
+*/
+func (r *Reconciler) Reconcile() ReconciliationReport {
+	var report ReconciliationReport
+
+	localOrders := make(map[string][]bookkeeper.OrderRecord)
+	for _, order := range r.store.OpenOrders() {
+		localOrders[order.Exchange] = append(localOrders[order.Exchange], order)
+	}
+
+	for exchange, source := range r.sources {
+		exchangeOrders, err := source.ListOpenOrders()
+		if err != nil {
+			utils.Logger.Error(fmt.Sprintf("Unable to load the open orders of %s. Skipping reconciliation.", exchange), zap.Error(err))
+			continue
+		}
+		openOnExchange := make(map[string]OrderResult)
+		for _, exchangeOrder := range exchangeOrders {
+			openOnExchange[exchangeOrder.OrderId] = exchangeOrder
+		}
+
+		inFlight := make(map[string]bool)
+		for _, order := range localOrders[exchange] {
+			if order.OrderId == "" && OrderState(order.State) == StateNew && !r.unacknowledgedTooLong(order) {
+				if order.ClientOrderId != "" {
+					inFlight[order.ClientOrderId] = true
+				}
+				continue
+			}
+
+			exchangeOrder, isOpen := openOnExchange[order.OrderId]
+			if !isOpen {
+				exchangeOrder, err = r.lookUp(source, order)
+				if errors.Is(err, ErrOrderNotFound) && order.OrderId == "" && order.ClientOrderId != "" {
+					r.resolve(order, StateRejected, "order never reached the exchange", &report)
+					continue
+				}
+				if errors.Is(err, ErrOrderNotFound) {
+					r.markUnknown(order, fmt.Sprintf("order %s was not found on %s", order.OrderId, exchange), &report)
+					continue
+				}
+				if err != nil {
+					utils.Logger.Error(fmt.Sprintf("Unable to look up order %s on %s.", order.Uuid, exchange), zap.Error(err))
+					continue
+				}
+			}
+			delete(openOnExchange, exchangeOrder.OrderId)
+
+			repaired, storeErr := r.store.Apply(order.Uuid, exchangeOrder)
+			if storeErr != nil {
+				utils.Logger.Error(fmt.Sprintf("Unable to reconcile order %s on %s.", order.Uuid, exchange), zap.Error(storeErr))
+				continue
+			}
+			if repaired.State != order.State || repaired.FilledSize != order.FilledSize || repaired.OrderId != order.OrderId {
+				utils.Logger.Warn(fmt.Sprintf("Reconciled order %s on %s from %s to %s.", repaired.OrderId, exchange, order.State, repaired.State), zap.Object("orderRecord", &repaired))
+				report.Repaired = append(report.Repaired, repaired)
+			}
+		}
+
+		// Orders that are still being placed may already be open on the exchange before their id is known locally
+		for orderId, exchangeOrder := range openOnExchange {
+			if exchangeOrder.ClientOrderId != "" && inFlight[exchangeOrder.ClientOrderId] {
+				delete(openOnExchange, orderId)
+			}
+		}
+		for _, foreignOrder := range openOnExchange {
+			utils.Logger.Error(fmt.Sprintf("Found open %s order %s for %v %s on %s that was not placed by the bot.", foreignOrder.Side, foreignOrder.OrderId, foreignOrder.RequestedSize, foreignOrder.Currency, exchange))
+			report.Foreign = append(report.Foreign, foreignOrder)
+		}
+	}
+
+	utils.Logger.Info(fmt.Sprintf("Reconciled orders. %d repaired, %d unknown, %d not placed by the bot.", len(report.Repaired), len(report.Unknown), len(report.Foreign)))
+	return report
+}
+
+/*
+Returns true if an order without an exchange order id was added longer than unacknowledgedTimeout ago.
+*/
+func (r *Reconciler) unacknowledgedTooLong(order bookkeeper.OrderRecord) bool {
+	if OrderState(order.State) != StateNew {
+		return false
+	}
+	return time.Since(order.Timestamp) > unacknowledgedTimeout
+}
+
+/*
+Look up an order by its exchange order id, or by its client order id if the exchange never acknowledged it.
+*/
+func (r *Reconciler) lookUp(source OrderSource, order bookkeeper.OrderRecord) (OrderResult, error) {
+	if order.OrderId != "" {
+		return source.GetOrder(order.OrderId, order.Currency)
+	}
+	if order.ClientOrderId != "" {
+		return source.FindOrder(order.ClientOrderId, order.Currency)
+	}
+	return OrderResult{}, ErrOrderNotFound
+}
+
+/*
+Mark an order as unknown, keeping the fills that were last reported for it.
+*/
+func (r *Reconciler) markUnknown(order bookkeeper.OrderRecord, reason string, report *ReconciliationReport) {
+	if OrderState(order.State) == StateUnknown {
+		return
+	}
+	unknown, ok := r.transition(order, StateUnknown, reason)
+	if !ok {
+		return
+	}
+	utils.Logger.Error(fmt.Sprintf("Order %s on %s is unknown: %s", order.Uuid, order.Exchange, reason))
+	report.Unknown = append(report.Unknown, unknown)
+}
+
+/*
+Move an order to a final state that was not reported by the exchange, e.g. because it never reached it.
+*/
+func (r *Reconciler) resolve(order bookkeeper.OrderRecord, state OrderState, reason string, report *ReconciliationReport) {
+	resolved, ok := r.transition(order, state, reason)
+	if !ok {
+		return
+	}
+	utils.Logger.Warn(fmt.Sprintf("Order %s on %s is %s: %s", order.Uuid, order.Exchange, state, reason))
+	report.Repaired = append(report.Repaired, resolved)
+}
+
+/*
+Move an order to a new state, keeping the fills that were last reported for it.
+*/
+func (r *Reconciler) transition(order bookkeeper.OrderRecord, state OrderState, reason string) (bookkeeper.OrderRecord, bool) {
+	lastKnown := OrderResult{OrderId: order.OrderId, FilledSize: order.FilledSize, AveragePrice: order.AveragePrice, Fee: order.Fee}
+	updated, storeErr := r.store.Transition(order.Uuid, state, lastKnown, reason)
+	if storeErr != nil {
+		utils.Logger.Error(fmt.Sprintf("Unable to mark order %s as %s.", order.Uuid, state), zap.Error(storeErr))
+		return updated, false
+	}
+	return updated, true
+}
@@ -0,0 +1,145 @@
+package executor
+
+import (
+	"errors"
+	"github.com/magiconair/properties/assert"
+	"testing"
+)
+
+/*
+An exchange that reports a fixed set of open and closed orders.
+*/
+type fakeOrderSource struct {
+	name   string
+	open   []OrderResult
+	closed map[string]OrderResult
+	err    error
+}
+
+func (f *fakeOrderSource) Name() string {
+	return f.name
+}
+
+func (f *fakeOrderSource) ListOpenOrders() ([]OrderResult, error) {
+	return f.open, f.err
+}
+
+func (f *fakeOrderSource) GetOrder(orderId string, currency string) (OrderResult, error) {
+	order, ok := f.closed[orderId]
+	if !ok {
+		return OrderResult{}, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (f *fakeOrderSource) FindOrder(clientOrderId string, currency string) (OrderResult, error) {
+	for _, order := range f.open {
+		if order.OrderId == clientOrderId {
+			return order, nil
+		}
+	}
+	return OrderResult{}, ErrOrderNotFound
+}
+
+func Test_Reconcile(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name         string
+		source       fakeOrderSource
+		wantState    OrderState
+		wantRepaired int
+		wantUnknown  int
+		wantForeign  int
+	}{
+		{
+			name:      "Open order matches the exchange.",
+			source:    fakeOrderSource{name: "Kraken", open: []OrderResult{{OrderId: "1", Status: Open}}},
+			wantState: StateAcknowledged,
+		},
+		{
+			name:         "Open order was partially filled.",
+			source:       fakeOrderSource{name: "Kraken", open: []OrderResult{{OrderId: "1", Status: Open, FilledSize: .005}}},
+			wantState:    StatePartiallyFilled,
+			wantRepaired: 1,
+		},
+		{
+			name:         "Open order was filled.",
+			source:       fakeOrderSource{name: "Kraken", closed: map[string]OrderResult{"1": {OrderId: "1", Status: Filled, FilledSize: .01}}},
+			wantState:    StateFilled,
+			wantRepaired: 1,
+		},
+		{
+			name:        "Open order is not known by the exchange.",
+			source:      fakeOrderSource{name: "Kraken"},
+			wantState:   StateUnknown,
+			wantUnknown: 1,
+		},
+		{
+			name:        "Exchange has an order the bot did not place.",
+			source:      fakeOrderSource{name: "Kraken", open: []OrderResult{{OrderId: "1", Status: Open}, {OrderId: "2", Status: Open}}},
+			wantState:   StateAcknowledged,
+			wantForeign: 1,
+		},
+		{
+			name:      "Open orders of the exchange are unavailable.",
+			source:    fakeOrderSource{name: "Kraken", err: errors.New("timeout")},
+			wantState: StateAcknowledged,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewOrderStore()
+			order, _ := store.Add(OrderRequest{Exchange: "Kraken", Currency: "BTCUSD", Side: Buy, Type: Limit, Price: 25000, Size: .01})
+			store.Transition(order.Uuid, StateAcknowledged, OrderResult{OrderId: "1"}, "")
+
+			report := NewReconciler(store, &tt.source).Reconcile()
+
+			assert.Equal(t, len(report.Repaired), tt.wantRepaired)
+			assert.Equal(t, len(report.Unknown), tt.wantUnknown)
+			assert.Equal(t, len(report.Foreign), tt.wantForeign)
+
+			state := StateFilled
+			for _, openOrder := range store.OpenOrders() {
+				state = OrderState(openOrder.State)
+			}
+			assert.Equal(t, state, tt.wantState)
+		})
+	}
+}
+
+func Test_ReconcileInFlightOrders(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name        string
+		source      fakeOrderSource
+		wantForeign int
+	}{
+		{
+			name:   "An order that is still being placed is not foreign once it is open on the exchange.",
+			source: fakeOrderSource{name: "Kraken", open: []OrderResult{{OrderId: "1", ClientOrderId: "in-flight", Status: Open}}},
+		},
+		{
+			name:        "An open order with another client order id is foreign.",
+			source:      fakeOrderSource{name: "Kraken", open: []OrderResult{{OrderId: "1", ClientOrderId: "in-flight", Status: Open}, {OrderId: "2", ClientOrderId: "manual", Status: Open}}},
+			wantForeign: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewOrderStore()
+			store.Add(OrderRequest{ClientOrderId: "in-flight", Exchange: "Kraken", Currency: "BTCUSD", Side: Buy, Type: Limit, Price: 25000, Size: .01})
+
+			report := NewReconciler(store, &tt.source).Reconcile()
+
+			assert.Equal(t, len(report.Foreign), tt.wantForeign)
+			assert.Equal(t, len(report.Repaired), 0)
+			for _, openOrder := range store.OpenOrders() {
+				assert.Equal(t, OrderState(openOrder.State), StateNew)
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/spf13/viper"
+	"math"
+	"sync"
+	"time"
+)
+
+/*
+Configurable risk limits. A limit of 0 disables the check. Notional values are in the reference currency (USD unless
+configured otherwise), whatever the quote currency of the order is.
+*/
+type RiskLimits struct {
+	ReferenceCurrency      string
+	MaxNotionalPerTrade    float64
+	MaxNotionalPerExchange float64
+	MaxNotionalPerAsset    float64
+	MaxOpenOrders          int
+	MaxDailyRealizedLoss   float64
+	MaxConsecutiveFailures int
+}
+
+/*
+Load the risk limits from the configuration file (EXECUTION.RISK).
+*/
+func LoadRiskLimits() RiskLimits {
+	return RiskLimits{
+		ReferenceCurrency:      viper.GetString("EXECUTION.RISK.REFERENCE_CURRENCY"),
+		MaxNotionalPerTrade:    viper.GetFloat64("EXECUTION.RISK.MAX_NOTIONAL_PER_TRADE"),
+		MaxNotionalPerExchange: viper.GetFloat64("EXECUTION.RISK.MAX_NOTIONAL_PER_EXCHANGE"),
+		MaxNotionalPerAsset:    viper.GetFloat64("EXECUTION.RISK.MAX_NOTIONAL_PER_ASSET"),
+		MaxOpenOrders:          viper.GetInt("EXECUTION.RISK.MAX_OPEN_ORDERS"),
+		MaxDailyRealizedLoss:   viper.GetFloat64("EXECUTION.RISK.MAX_DAILY_REALIZED_LOSS"),
+		MaxConsecutiveFailures: viper.GetInt("EXECUTION.RISK.MAX_CONSECUTIVE_FAILURES"),
+	}
+}
+
+type RiskError struct {
+	Msg string
+}
+
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("Order blocked by the risk manager: %s", e.Msg)
+}
+
+/*
+Tracks exposure, open orders, realized losses and failures, and blocks new orders when a limit is breached or the kill
+switch was triggered. Positions and the exposure reserved for orders that have not completed yet are tracked per
+exchange and asset in units of the asset and valued with the marks of the assets in the reference currency.
+*/
+type RiskManager struct {
+	mu                  sync.Mutex
+	limits              RiskLimits
+	positions           map[string]map[string]float64
+	pending             map[string]map[string]float64
+	marks               map[string]float64
+	openOrders          int
+	realizedDay         string
+	dailyRealizedPnl    float64
+	consecutiveFailures int
+	killed              bool
+	killReason          string
+}
+
+/*
+Create a new RiskManager with the given limits. The reference currency defaults to USD.
+*/
+func NewRiskManager(limits RiskLimits) *RiskManager {
+	if limits.ReferenceCurrency == "" {
+		limits.ReferenceCurrency = "USD"
+	}
+	return &RiskManager{
+		limits:    limits,
+		positions: make(map[string]map[string]float64),
+		pending:   make(map[string]map[string]float64),
+		marks:     map[string]float64{limits.ReferenceCurrency: 1},
+	}
+}
+
+/*
+Update the marks of the assets from quotes. Assets quoted in the reference currency are marked to the average price of
+all exchanges, other assets through the pair they are quoted in (e.g. LTC through LTCETH and ETHUSD).
+*/
+func (r *RiskManager) MarkPrices(priceRecords []bookkeeper.PriceRecord) {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for _, priceRecord := range priceRecords {
+		if priceRecord.Price > 0 {
+			sums[priceRecord.Currency] += priceRecord.Price
+			counts[priceRecord.Currency]++
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	marks := map[string]float64{r.limits.ReferenceCurrency: 1}
+	for changed := true; changed; {
+		changed = false
+		for currency, sum := range sums {
+			base, quote := bookkeeper.SplitCurrency(currency)
+			if _, ok := marks[base]; ok {
+				continue
+			}
+			if quoteMark, ok := marks[quote]; ok {
+				marks[base] = sum / float64(counts[currency]) * quoteMark
+				changed = true
+			}
+		}
+	}
+	for asset, mark := range marks {
+		r.marks[asset] = mark
+	}
+}
+
+/*
+Check whether an order may be placed and reserve it. See CheckOrders.
+*/
+func (r *RiskManager) CheckOrder(order OrderRequest) *RiskError {
+	return r.CheckOrders(order)
+}
+
+/*
+Check whether a set of orders, e.g. both legs of an arbitrage opportunity, may be placed together. The orders are
+assumed to fill completely and count as one trade. If they may be placed, their open order slots and exposure are
+reserved before the lock is released, so that concurrent checks see them. Every reserved order has to be released with
+OrderCompleted once it was placed or with Release if it is not placed. Orders that only reduce the net positions of
+their assets, e.g. corrections of an imbalance, are allowed while the kill switch is active, after consecutive failures
+and after the daily realized loss was reached, but are still checked against the other limits.
+*/
+func (r *RiskManager) CheckOrders(orders ...OrderRequest) *RiskError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.reducesPositions(orders) {
+		if r.killed {
+			return &RiskError{Msg: fmt.Sprintf("kill switch is active (%s)", r.killReason)}
+		}
+		if r.limits.MaxConsecutiveFailures > 0 && r.consecutiveFailures >= r.limits.MaxConsecutiveFailures {
+			return &RiskError{Msg: fmt.Sprintf("%d consecutive failed orders", r.consecutiveFailures)}
+		}
+		r.resetDailyPnl()
+		if r.limits.MaxDailyRealizedLoss > 0 && -r.dailyRealizedPnl >= r.limits.MaxDailyRealizedLoss {
+			return &RiskError{Msg: fmt.Sprintf("daily realized loss of %v reached the limit of %v", -r.dailyRealizedPnl, r.limits.MaxDailyRealizedLoss)}
+		}
+	}
+	if r.limits.MaxOpenOrders > 0 && r.openOrders+len(orders) > r.limits.MaxOpenOrders {
+		return &RiskError{Msg: fmt.Sprintf("%d open and %d new orders exceed the limit of %d", r.openOrders, len(orders), r.limits.MaxOpenOrders)}
+	}
+	if riskErr := r.checkExposure(orders); riskErr != nil {
+		return riskErr
+	}
+
+	for _, order := range orders {
+		asset, _ := bookkeeper.SplitCurrency(order.Currency)
+		r.openOrders++
+		addPosition(r.pending, order.Exchange, asset, signedSize(order.Side, order.Size))
+	}
+	return nil
+}
+
+/*
+Release the reservation of orders that passed CheckOrders but were not placed.
+*/
+func (r *RiskManager) Release(orders ...OrderRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, order := range orders {
+		r.release(order)
+	}
+}
+
+/*
+Register the result of a placed order and release its reservation. Fills update the positions, orders without any fill
+count as failures.
+*/
+func (r *RiskManager) OrderCompleted(order OrderRequest, result OrderResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.release(order)
+
+	if result.FilledSize == 0 {
+		r.consecutiveFailures++
+		if r.limits.MaxConsecutiveFailures > 0 && r.consecutiveFailures == r.limits.MaxConsecutiveFailures {
+			utils.Logger.Error(fmt.Sprintf("Reached %d consecutive failed orders. New orders are blocked until the risk manager is reset.", r.consecutiveFailures))
+		}
+		return
+	}
+	r.consecutiveFailures = 0
+
+	asset, quote := bookkeeper.SplitCurrency(result.Currency)
+	addPosition(r.positions, result.Exchange, asset, signedSize(result.Side, result.FilledSize))
+	if quoteMark, ok := r.marks[quote]; ok && result.AveragePrice > 0 && asset != r.limits.ReferenceCurrency {
+		r.marks[asset] = result.AveragePrice * quoteMark
+	}
+}
+
+/*
+Add realized profit (or loss, when negative) to today's total.
+*/
+func (r *RiskManager) RecordRealizedPnl(pnl float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resetDailyPnl()
+	r.dailyRealizedPnl += pnl
+	if r.limits.MaxDailyRealizedLoss > 0 && -r.dailyRealizedPnl >= r.limits.MaxDailyRealizedLoss {
+		utils.Logger.Error(fmt.Sprintf("Daily realized loss of %v reached the limit of %v. New orders are blocked for the rest of the day.", -r.dailyRealizedPnl, r.limits.MaxDailyRealizedLoss))
+	}
+}
+
+/*
+Activate the kill switch. All new orders are blocked until Reset is called.
+*/
+func (r *RiskManager) Kill(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.killed = true
+	r.killReason = reason
+}
+
+/*
+Returns true if the kill switch is active.
+*/
+func (r *RiskManager) IsKilled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.killed
+}
+
+/*
+Deactivate the kill switch and clear the consecutive failure count.
+*/
+func (r *RiskManager) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.killed = false
+	r.killReason = ""
+	r.consecutiveFailures = 0
+}
+
+/*
+Check the exposure limits for the positions and reservations with the orders added. The notional of all orders counts
+against the per trade limit. Must be called with the lock held.
+*/
+func (r *RiskManager) checkExposure(orders []OrderRequest) *RiskError {
+	if r.limits.MaxNotionalPerTrade <= 0 && r.limits.MaxNotionalPerExchange <= 0 && r.limits.MaxNotionalPerAsset <= 0 {
+		return nil
+	}
+
+	tradeNotional := 0.0
+	// An asset without a mark is valued at the price of an order for it.
+	orderMarks := make(map[string]float64)
+	projected := make(map[string]map[string]float64)
+	for _, positions := range []map[string]map[string]float64{r.positions, r.pending} {
+		for exchange, assets := range positions {
+			for asset, position := range assets {
+				addPosition(projected, exchange, asset, position)
+			}
+		}
+	}
+	for _, order := range orders {
+		notional, riskErr := r.orderNotional(order)
+		if riskErr != nil {
+			return riskErr
+		}
+		tradeNotional += notional
+		asset, _ := bookkeeper.SplitCurrency(order.Currency)
+		if order.Size > 0 {
+			orderMarks[asset] = notional / order.Size
+		}
+		addPosition(projected, order.Exchange, asset, signedSize(order.Side, order.Size))
+	}
+	if r.limits.MaxNotionalPerTrade > 0 && tradeNotional > r.limits.MaxNotionalPerTrade {
+		return &RiskError{Msg: fmt.Sprintf("notional of %v %s exceeds the per trade limit of %v", tradeNotional, r.limits.ReferenceCurrency, r.limits.MaxNotionalPerTrade)}
+	}
+
+	assetMark := func(asset string) (float64, *RiskError) {
+		if mark, ok := r.marks[asset]; ok {
+			return mark, nil
+		}
+		if mark, ok := orderMarks[asset]; ok {
+			return mark, nil
+		}
+		return 0, &RiskError{Msg: fmt.Sprintf("no price of %s in %s is known", asset, r.limits.ReferenceCurrency)}
+	}
+
+	for _, order := range orders {
+		if r.limits.MaxNotionalPerExchange > 0 {
+			exchangeNotional := 0.0
+			for asset, position := range projected[order.Exchange] {
+				if position == 0 {
+					continue
+				}
+				mark, riskErr := assetMark(asset)
+				if riskErr != nil {
+					return riskErr
+				}
+				exchangeNotional += math.Abs(position) * mark
+			}
+			if exchangeNotional > r.limits.MaxNotionalPerExchange {
+				return &RiskError{Msg: fmt.Sprintf("exposure of %v %s on %s exceeds the per exchange limit of %v", exchangeNotional, r.limits.ReferenceCurrency, order.Exchange, r.limits.MaxNotionalPerExchange)}
+			}
+		}
+
+		if r.limits.MaxNotionalPerAsset > 0 {
+			asset, _ := bookkeeper.SplitCurrency(order.Currency)
+			netPosition := 0.0
+			for _, positions := range projected {
+				netPosition += positions[asset]
+			}
+			mark, riskErr := assetMark(asset)
+			if riskErr != nil {
+				return riskErr
+			}
+			assetNotional := math.Abs(netPosition) * mark
+			if assetNotional > r.limits.MaxNotionalPerAsset {
+				return &RiskError{Msg: fmt.Sprintf("net exposure of %v %s in %s exceeds the per asset limit of %v", assetNotional, r.limits.ReferenceCurrency, asset, r.limits.MaxNotionalPerAsset)}
+			}
+		}
+	}
+	return nil
+}
+
+/*
+Returns true if every order reduces the net position of its asset over all exchanges, including the exposure reserved
+for other orders, assuming the orders before it filled completely. Must be called with the lock held.
+*/
+func (r *RiskManager) reducesPositions(orders []OrderRequest) bool {
+	netPositions := make(map[string]float64)
+	for _, positions := range []map[string]map[string]float64{r.positions, r.pending} {
+		for _, assets := range positions {
+			for asset, position := range assets {
+				netPositions[asset] += position
+			}
+		}
+	}
+	for _, order := range orders {
+		asset, _ := bookkeeper.SplitCurrency(order.Currency)
+		reduced := netPositions[asset] + signedSize(order.Side, order.Size)
+		if math.Abs(reduced) >= math.Abs(netPositions[asset]) {
+			return false
+		}
+		netPositions[asset] = reduced
+	}
+	return len(orders) > 0
+}
+
+/*
+Release the open order slot and the exposure reserved for an order. Must be called with the lock held.
+*/
+func (r *RiskManager) release(order OrderRequest) {
+	if r.openOrders > 0 {
+		r.openOrders--
+	}
+	asset, _ := bookkeeper.SplitCurrency(order.Currency)
+	addPosition(r.pending, order.Exchange, asset, -signedSize(order.Side, order.Size))
+}
+
+/*
+Add a signed size to the position in an asset on an exchange.
+*/
+func addPosition(positions map[string]map[string]float64, exchange string, asset string, size float64) {
+	if positions[exchange] == nil {
+		positions[exchange] = make(map[string]float64)
+	}
+	positions[exchange][asset] += size
+	if positions[exchange][asset] == 0 {
+		delete(positions[exchange], asset)
+	}
+}
+
+/*
+Returns the size of an order or fill, negative for a sell.
+*/
+func signedSize(side Side, size float64) float64 {
+	if side == Sell {
+		return -size
+	}
+	return size
+}
+
+/*
+Reset the realized PnL when a new (UTC) day has started. Must be called with the lock held.
+*/
+func (r *RiskManager) resetDailyPnl() {
+	today := time.Now().UTC().Format("2006-01-02")
+	if r.realizedDay != today {
+		r.realizedDay = today
+		r.dailyRealizedPnl = 0
+	}
+}
+
+/*
+Returns the notional of an order in the reference currency: its size at its price, valued with the mark of the quote
+asset, or at the mark of the base asset if the order has no price. Returns a RiskError if neither is known. Must be
+called with the lock held.
+*/
+func (r *RiskManager) orderNotional(order OrderRequest) (float64, *RiskError) {
+	asset, quote := bookkeeper.SplitCurrency(order.Currency)
+	if quoteMark, ok := r.marks[quote]; ok && order.Price > 0 {
+		return order.Size * order.Price * quoteMark, nil
+	}
+	if mark, ok := r.marks[asset]; ok {
+		return order.Size * mark, nil
+	}
+	return 0, &RiskError{Msg: fmt.Sprintf("no price of %s in %s is known", order.Currency, r.limits.ReferenceCurrency)}
+}
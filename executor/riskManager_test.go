@@ -0,0 +1,292 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"github.com/magiconair/properties/assert"
+	"testing"
+)
+
+func Test_CheckOrder(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	order := OrderRequest{Exchange: "Coinbase", Currency: "BTCUSD", Side: Buy, Type: Limit, Price: 25000, Size: .01}
+
+	tests := []struct {
+		name        string
+		limits      RiskLimits
+		prepare     func(r *RiskManager)
+		wantBlocked bool
+	}{
+		{
+			name:   "No limits configured.",
+			limits: RiskLimits{},
+		},
+		{
+			name:        "Notional per trade exceeded.",
+			limits:      RiskLimits{MaxNotionalPerTrade: 200},
+			wantBlocked: true,
+		},
+		{
+			name:   "Exposure per exchange exceeded by an existing position.",
+			limits: RiskLimits{MaxNotionalPerExchange: 400},
+			prepare: func(r *RiskManager) {
+				r.CheckOrder(order)
+				r.OrderCompleted(order, OrderResult{Exchange: "Coinbase", Currency: "BTCUSD", Side: Buy, FilledSize: .01, AveragePrice: 25000})
+			},
+			wantBlocked: true,
+		},
+		{
+			name:   "Offsetting position on another exchange keeps the net asset exposure within the limit.",
+			limits: RiskLimits{MaxNotionalPerAsset: 200},
+			prepare: func(r *RiskManager) {
+				sell := OrderRequest{Exchange: "Gemini", Currency: "BTCUSD", Side: Sell, Type: Limit, Price: 25100, Size: .01}
+				r.CheckOrders(order, sell)
+				r.OrderCompleted(order, OrderResult{Exchange: "Coinbase", Currency: "BTCUSD", Status: Rejected})
+				r.OrderCompleted(sell, OrderResult{Exchange: "Gemini", Currency: "BTCUSD", Side: Sell, FilledSize: .01, AveragePrice: 25100})
+			},
+		},
+		{
+			name:   "Too many open orders.",
+			limits: RiskLimits{MaxOpenOrders: 2},
+			prepare: func(r *RiskManager) {
+				r.CheckOrders(order, order)
+			},
+			wantBlocked: true,
+		},
+		{
+			name:   "Daily realized loss reached.",
+			limits: RiskLimits{MaxDailyRealizedLoss: 10},
+			prepare: func(r *RiskManager) {
+				r.RecordRealizedPnl(5)
+				r.RecordRealizedPnl(-15)
+			},
+			wantBlocked: true,
+		},
+		{
+			name:   "Consecutive failures reached.",
+			limits: RiskLimits{MaxConsecutiveFailures: 2},
+			prepare: func(r *RiskManager) {
+				r.CheckOrders(order, order)
+				r.OrderCompleted(order, OrderResult{Exchange: "Coinbase", Currency: "BTCUSD", Status: Rejected})
+				r.OrderCompleted(order, OrderResult{Exchange: "Coinbase", Currency: "BTCUSD", Status: Rejected})
+			},
+			wantBlocked: true,
+		},
+		{
+			name:   "Kill switch active.",
+			limits: RiskLimits{},
+			prepare: func(r *RiskManager) {
+				r.Kill("test")
+			},
+			wantBlocked: true,
+		},
+		{
+			name:   "Kill switch reset.",
+			limits: RiskLimits{},
+			prepare: func(r *RiskManager) {
+				r.Kill("test")
+				r.Reset()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			riskManager := NewRiskManager(tt.limits)
+			if tt.prepare != nil {
+				tt.prepare(riskManager)
+			}
+			err := riskManager.CheckOrder(order)
+			assert.Equal(t, err != nil, tt.wantBlocked)
+		})
+	}
+}
+
+func Test_CheckOrderNotional(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	quotes := []bookkeeper.PriceRecord{
+		{Exchange: "Coinbase", Currency: "BTCUSD", Price: 25000},
+		{Exchange: "Gemini", Currency: "BTCUSD", Price: 25200},
+		{Exchange: "Gemini", Currency: "ETHBTC", Price: .06},
+	}
+
+	tests := []struct {
+		name        string
+		limits      RiskLimits
+		marked      bool
+		order       OrderRequest
+		prepare     func(r *RiskManager)
+		wantBlocked bool
+	}{
+		{
+			name:   "An order quoted in another asset is valued in the reference currency.",
+			limits: RiskLimits{MaxNotionalPerTrade: 200},
+			marked: true,
+			// .1 ETH at .06 BTC and 25100 USD per BTC is 150.6 USD, not .006 BTC.
+			order: OrderRequest{Exchange: "Gemini", Currency: "ETHBTC", Side: Buy, Type: Limit, Price: .06, Size: .1},
+		},
+		{
+			name:        "An order quoted in another asset exceeding the limit in the reference currency.",
+			limits:      RiskLimits{MaxNotionalPerTrade: 100},
+			marked:      true,
+			order:       OrderRequest{Exchange: "Gemini", Currency: "ETHBTC", Side: Buy, Type: Limit, Price: .06, Size: .1},
+			wantBlocked: true,
+		},
+		{
+			name:        "An order is blocked if the quote asset has no price in the reference currency.",
+			limits:      RiskLimits{MaxNotionalPerTrade: 1000},
+			order:       OrderRequest{Exchange: "Gemini", Currency: "ETHBTC", Side: Buy, Type: Limit, Price: .06, Size: .1},
+			wantBlocked: true,
+		},
+		{
+			name:        "A market order is valued at the mark of its asset.",
+			limits:      RiskLimits{MaxNotionalPerTrade: 200},
+			marked:      true,
+			order:       OrderRequest{Exchange: "Coinbase", Currency: "BTCUSD", Side: Sell, Type: Market, Size: .01},
+			wantBlocked: true,
+		},
+		{
+			name:        "A market order without a known price is blocked.",
+			limits:      RiskLimits{MaxNotionalPerTrade: 1000},
+			order:       OrderRequest{Exchange: "Coinbase", Currency: "BTCUSD", Side: Sell, Type: Market, Size: .01},
+			wantBlocked: true,
+		},
+		{
+			name:   "Positions in other assets are valued at their own mark.",
+			limits: RiskLimits{MaxNotionalPerExchange: 400},
+			marked: true,
+			order:  OrderRequest{Exchange: "Gemini", Currency: "BTCUSD", Side: Buy, Type: Limit, Price: 25200, Size: .01},
+			prepare: func(r *RiskManager) {
+				addPosition(r.positions, "Gemini", "ETH", 1)
+			},
+			// 1 ETH is worth 1506 USD, not .06.
+			wantBlocked: true,
+		},
+		{
+			name:   "Limits are not checked without marks when no notional limit is configured.",
+			limits: RiskLimits{MaxOpenOrders: 2},
+			order:  OrderRequest{Exchange: "Gemini", Currency: "ETHBTC", Side: Buy, Type: Market, Size: .1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			riskManager := NewRiskManager(tt.limits)
+			if tt.marked {
+				riskManager.MarkPrices(quotes)
+			}
+			if tt.prepare != nil {
+				tt.prepare(riskManager)
+			}
+			err := riskManager.CheckOrder(tt.order)
+			assert.Equal(t, err != nil, tt.wantBlocked)
+		})
+	}
+}
+
+func Test_KillSwitch(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	coinbase := &fakeVenue{name: "Coinbase", fillRatio: 1}
+	gemini := &fakeVenue{name: "Gemini", fillRatio: 1}
+	tradeExecutor := NewExecutor(LegRiskPolicy{Mode: Unwind}, coinbase, gemini).WithRiskManager(NewRiskManager(RiskLimits{}))
+
+	_, err := tradeExecutor.KillSwitch("test")
+	assert.Equal(t, err == nil, true)
+
+	_, err = tradeExecutor.ExecuteArbitrage(opportunityForTest(), .01, nil)
+	assert.Equal(t, err != nil, true)
+	assert.Equal(t, len(coinbase.orders), 0)
+	assert.Equal(t, len(gemini.orders), 0)
+
+	tradeExecutor.ResetKillSwitch()
+	_, err = tradeExecutor.ExecuteArbitrage(opportunityForTest(), .01, nil)
+	assert.Equal(t, err == nil, true)
+	assert.Equal(t, len(coinbase.orders), 1)
+	assert.Equal(t, len(gemini.orders), 1)
+}
+
+func Test_CheckOrdersReserves(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	buy := OrderRequest{Exchange: "Coinbase", Currency: "BTCUSD", Side: Buy, Type: Limit, Price: 25000, Size: .01}
+	sell := OrderRequest{Exchange: "Gemini", Currency: "BTCUSD", Side: Sell, Type: Limit, Price: 25100, Size: .01}
+
+	tests := []struct {
+		name        string
+		limits      RiskLimits
+		prepare     func(r *RiskManager)
+		wantBlocked bool
+	}{
+		{
+			name:        "Both legs of a trade count against the open orders at once.",
+			limits:      RiskLimits{MaxOpenOrders: 1},
+			wantBlocked: true,
+		},
+		{
+			name:        "Both legs of a trade count against the per trade limit.",
+			limits:      RiskLimits{MaxNotionalPerTrade: 400},
+			wantBlocked: true,
+		},
+		{
+			name:   "Reserved orders count against the open orders until they complete.",
+			limits: RiskLimits{MaxOpenOrders: 3},
+			prepare: func(r *RiskManager) {
+				r.CheckOrders(buy, sell)
+			},
+			wantBlocked: true,
+		},
+		{
+			name:   "Completed orders release their open order slots.",
+			limits: RiskLimits{MaxOpenOrders: 3},
+			prepare: func(r *RiskManager) {
+				r.CheckOrders(buy, sell)
+				r.OrderCompleted(buy, OrderResult{Exchange: "Coinbase", Currency: "BTCUSD", Side: Buy, FilledSize: .01, AveragePrice: 25000})
+				r.OrderCompleted(sell, OrderResult{Exchange: "Gemini", Currency: "BTCUSD", Side: Sell, FilledSize: .01, AveragePrice: 25100})
+			},
+		},
+		{
+			name:   "Reserved orders count against the exposure per exchange.",
+			limits: RiskLimits{MaxNotionalPerExchange: 400},
+			prepare: func(r *RiskManager) {
+				r.CheckOrders(buy, sell)
+			},
+			wantBlocked: true,
+		},
+		{
+			name:   "Released orders do not count against the exposure per exchange.",
+			limits: RiskLimits{MaxNotionalPerExchange: 400},
+			prepare: func(r *RiskManager) {
+				r.CheckOrders(buy, sell)
+				r.Release(buy, sell)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			riskManager := NewRiskManager(tt.limits)
+			if tt.prepare != nil {
+				tt.prepare(riskManager)
+			}
+			err := riskManager.CheckOrders(buy, sell)
+			assert.Equal(t, err != nil, tt.wantBlocked)
+		})
+	}
+}
+
+func Test_ExecuteArbitrageChecksBothLegs(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	coinbase := &fakeVenue{name: "Coinbase", fillRatio: 1}
+	gemini := &fakeVenue{name: "Gemini", fillRatio: 1}
+	tradeExecutor := NewExecutor(LegRiskPolicy{Mode: Unwind}, coinbase, gemini).WithRiskManager(NewRiskManager(RiskLimits{MaxOpenOrders: 1}))
+
+	_, err := tradeExecutor.ExecuteArbitrage(opportunityForTest(), .01, nil)
+	assert.Equal(t, err != nil, true)
+	assert.Equal(t, len(coinbase.orders), 0)
+	assert.Equal(t, len(gemini.orders), 0)
+}
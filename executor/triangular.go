@@ -0,0 +1,350 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"math"
+	"sort"
+	"time"
+)
+
+type TriangularStatus string
+
+const (
+	TriangularCompleted TriangularStatus = "completed"
+	TriangularAborted   TriangularStatus = "aborted"
+	TriangularSkipped   TriangularStatus = "skipped"
+)
+
+/*
+The best price of one side of an order book and the size available at it.
+*/
+type BookLevel struct {
+	Price float64
+	Size  float64
+}
+
+/*
+An exchange that reports the top of its order books. Implemented by the clients in the api packages that support
+triangular arbitrage.
+*/
+type OrderBookSource interface {
+	TopOfBook(currency string) (bid BookLevel, ask BookLevel, err error)
+}
+
+/*
+Configuration of triangular arbitrage execution.
+  - MaxSize: the largest amount of the base asset of the first trade pair (ETH for ETHBTC) to trade. 0 disables the cap.
+  - FillTolerance: the share of a leg that may stay unfilled before the execution is aborted.
+*/
+type TriangularPolicy struct {
+	MaxSize       float64
+	FillTolerance float64
+}
+
+/*
+Load the triangular arbitrage policy from the configuration file (EXECUTION.TRIANGULAR).
+*/
+func LoadTriangularPolicy() TriangularPolicy {
+	return TriangularPolicy{
+		MaxSize:       viper.GetFloat64("EXECUTION.TRIANGULAR.MAX_SIZE"),
+		FillTolerance: viper.GetFloat64("EXECUTION.TRIANGULAR.FILL_TOLERANCE"),
+	}
+}
+
+/*
+The result of executing a triangular arbitrage opportunity, including the orders that unwound an aborted execution.
+*/
+type TriangularReport struct {
+	Record  bookkeeper.TriangularExecutionRecord
+	Unwinds []bookkeeper.LegCorrectionRecord
+}
+
+/*
+A planned leg of a triangular arbitrage cycle. Index is the position of the trade pair in the
+TriangularArbitrageEventRecord, liquidity the size available at the best price.
+*/
+type triangularLeg struct {
+	index     int
+	currency  string
+	side      Side
+	price     float64
+	size      float64
+	liquidity float64
+}
+
+/*
+Execute a triangular arbitrage opportunity on a single exchange. The cycle starts and ends in the quote asset of the
+first trade pair: buy ETH with BTC (ETHBTC), buy LTC with ETH (LTCETH) and sell LTC for BTC (LTCBTC).
+
+Every leg is an immediate-or-cancel limit order at the best price of the order book, sized to the leg with the least
+liquidity. The legs are placed one after another, starting with the leg that takes the largest share of its book, so
+that the leg most likely to miss is placed first. A leg placed before the leg it depends on spends an asset that was not
+bought yet (e.g. LTC for the LTCBTC sell), so this order is only used if the exchange account holds enough of the
+assets spent by such legs. Otherwise the legs are placed in trade pair order, which only needs the quote asset of the
+first trade pair. If a leg fills less than the fill tolerance allows, the remaining legs are not placed and the legs that
+filled are unwound.
+*/
+func (e *Executor) ExecuteTriangular(opportunity bookkeeper.TriangularArbitrageEventRecord, fee float64) (TriangularReport, *ExecutorError) {
+	report := TriangularReport{Record: bookkeeper.TriangularExecutionRecord{
+		Uuid:                           uuid.New(),
+		Timestamp:                      time.Now().UTC(),
+		TriangularArbitrageRecordUuid:  opportunity.Uuid,
+		Exchange:                       opportunity.Exchange,
+		PredictedCrossExchangeRateDiff: opportunity.CrossExchangeRateDiff,
+	}}
+
+	venue, ok := e.venues[opportunity.Exchange]
+	if !ok {
+		return report, &ExecutorError{Msg: fmt.Sprintf("no venue configured for %s", opportunity.Exchange)}
+	}
+	books, ok := venue.(OrderBookSource)
+	if !ok {
+		return report, &ExecutorError{Msg: fmt.Sprintf("%s does not provide order books", opportunity.Exchange)}
+	}
+
+	currencies := []string{opportunity.TradePair1, opportunity.TradePair2, opportunity.TradePair3}
+	var bids, asks [3]BookLevel
+	for i, currency := range currencies {
+		bid, ask, err := books.TopOfBook(currency)
+		if err != nil {
+			return report, &ExecutorError{Msg: fmt.Sprintf("unable to load the %s order book: %v", currency, err)}
+		}
+		bids[i], asks[i] = bid, ask
+	}
+
+	legs, bookDiff := planTriangularLegs(currencies, bids, asks, fee, e.triangularPolicy.MaxSize)
+	for _, leg := range legs {
+		report.Record.Legs[leg.index] = bookkeeper.TriangularLegFill{TradePair: leg.currency, Side: string(leg.side), RequestedSize: leg.size}
+	}
+	if bookDiff <= 0 {
+		report.Record.Status = string(TriangularSkipped)
+		report.Record.Reason = fmt.Sprintf("cross exchange rate difference at the top of the books is %v", bookDiff)
+		return report, nil
+	}
+	if legs[0].size <= 0 {
+		report.Record.Status = string(TriangularSkipped)
+		report.Record.Reason = "no liquidity at the top of the books"
+		return report, nil
+	}
+
+	orders := make([]OrderRequest, len(legs))
+	for i, leg := range legs {
+		orders[i] = OrderRequest{
+			OpportunityUuid: opportunity.Uuid,
+			Leg:             leg.currency,
+			Exchange:        opportunity.Exchange,
+			Currency:        leg.currency,
+			Side:            leg.side,
+			Type:            Limit,
+			Price:           leg.price,
+			Size:            leg.size,
+		}
+	}
+	if riskErr := e.riskManager.CheckOrders(orders...); riskErr != nil {
+		return report, &ExecutorError{Msg: riskErr.Error()}
+	}
+
+	sequence := triangularSequence(venue, legs)
+
+	var results [3]OrderResult
+	var executed []int
+	for position, i := range sequence {
+		if e.riskManager.IsKilled() {
+			report.Record.Reason = "kill switch is active"
+			break
+		}
+		results[i] = e.placeOrder(venue, orders[i])
+		executed = append(executed, i)
+
+		leg := &report.Record.Legs[legs[i].index]
+		leg.Sequence = position + 1
+		leg.FilledSize = results[i].FilledSize
+		leg.AveragePrice = results[i].AveragePrice
+		leg.Fee = results[i].Fee
+		leg.Status = string(results[i].Status)
+
+		if results[i].FilledSize < legs[i].size*(1-e.triangularPolicy.FillTolerance) {
+			report.Record.Reason = fmt.Sprintf("%s leg filled %v of %v", legs[i].currency, results[i].FilledSize, legs[i].size)
+			break
+		}
+	}
+
+	if len(executed) < len(legs) || report.Record.Reason != "" {
+		report.Record.Status = string(TriangularAborted)
+		for _, i := range sequence[len(executed):] {
+			e.riskManager.Release(orders[i])
+		}
+		utils.Logger.Warn(fmt.Sprintf("Aborted triangular arbitrage %s on %s: %s. Unwinding the executed legs.", opportunity.Uuid, opportunity.Exchange, report.Record.Reason))
+		for _, i := range executed {
+			if results[i].FilledSize > 0 {
+				report.Unwinds = append(report.Unwinds, e.unwindTriangularLeg(venue, opportunity, orders[i], results[i]))
+			}
+		}
+		return report, nil
+	}
+
+	report.Record.Status = string(TriangularCompleted)
+	report.Record.RealizedCrossExchangeRate, report.Record.RealizedCrossExchangeRateDiff = realizedCrossExchangeRate(results)
+	utils.Logger.Info(fmt.Sprintf("Executed triangular arbitrage %s on %s. Predicted cross exchange rate difference %v, realized %v.", opportunity.Uuid, opportunity.Exchange, opportunity.CrossExchangeRateDiff, report.Record.RealizedCrossExchangeRateDiff))
+	return report, nil
+}
+
+/*
+An exchange account that reports its available balances, keyed by asset.
+*/
+type balanceSource interface {
+	GetBalances() (map[string]float64, error)
+}
+
+/*
+Returns the order in which the legs are placed, as indexes into legs. The legs are sorted by the share of their book
+they take if the balances of the venue cover what every leg placed before the leg it depends on spends, otherwise they
+are placed in trade pair order.
+*/
+func triangularSequence(venue Venue, legs []triangularLeg) []int {
+	sequence := make([]int, len(legs))
+	for i := range sequence {
+		sequence[i] = i
+	}
+	byBookShare := make([]int, len(legs))
+	copy(byBookShare, sequence)
+	sort.SliceStable(byBookShare, func(i, j int) bool {
+		return legs[byBookShare[i]].size/legs[byBookShare[i]].liquidity > legs[byBookShare[j]].size/legs[byBookShare[j]].liquidity
+	})
+
+	// Every leg spends what the leg before it bought, the first leg spends the quote asset the cycle starts with
+	position := make([]int, len(legs))
+	for i, leg := range byBookShare {
+		position[leg] = i
+	}
+	required := make(map[string]float64)
+	for i := 1; i < len(legs); i++ {
+		if position[i] > position[i-1] {
+			continue
+		}
+		baseAsset, quoteAsset := bookkeeper.SplitCurrency(legs[i].currency)
+		if legs[i].side == Buy {
+			required[quoteAsset] += legs[i].size * legs[i].price
+		} else {
+			required[baseAsset] += legs[i].size
+		}
+	}
+	if len(required) == 0 {
+		return byBookShare
+	}
+
+	source, ok := venue.(balanceSource)
+	if !ok {
+		return sequence
+	}
+	balances, err := source.GetBalances()
+	if err != nil {
+		utils.Logger.Warn(fmt.Sprintf("Unable to load the balances on %s, placing the triangular legs in trade pair order.", venue.Name()), zap.Error(err))
+		return sequence
+	}
+	for asset, amount := range required {
+		if balances[asset] < amount {
+			utils.Logger.Info(fmt.Sprintf("%v %s available on %s, %v needed to place the triangular legs out of order. Placing them in trade pair order.", balances[asset], asset, venue.Name(), amount))
+			return sequence
+		}
+	}
+	return byBookShare
+}
+
+/*
+Plan the legs of the cycle from the top of the order books of its three trade pairs (ETHBTC, LTCETH, LTCBTC): buy the
+first pair at its ask, buy the second pair at its ask and sell the third pair at its bid. The size of the first leg is
+limited by the liquidity of every leg, converted into units of the first pair's base asset. Returns the legs in trade
+pair order and the cross exchange rate difference after fees at the top of the books.
+*/
+func planTriangularLegs(currencies []string, bids [3]BookLevel, asks [3]BookLevel, fee float64, maxSize float64) ([]triangularLeg, float64) {
+	size := math.Min(asks[0].Size, math.Min(asks[1].Size*asks[1].Price, bids[2].Size*asks[1].Price))
+	if maxSize > 0 {
+		size = math.Min(size, maxSize)
+	}
+	secondSize := 0.0
+	if asks[1].Price > 0 {
+		secondSize = size / asks[1].Price
+	}
+
+	legs := []triangularLeg{
+		{index: 0, currency: currencies[0], side: Buy, price: asks[0].Price, size: size, liquidity: asks[0].Size},
+		{index: 1, currency: currencies[1], side: Buy, price: asks[1].Price, size: secondSize, liquidity: asks[1].Size},
+		{index: 2, currency: currencies[2], side: Sell, price: bids[2].Price, size: secondSize, liquidity: bids[2].Size},
+	}
+
+	if asks[0].Price <= 0 || asks[1].Price <= 0 {
+		return legs, 0
+	}
+	crossExchangeRate := bids[2].Price / asks[1].Price
+	return legs, (crossExchangeRate-asks[0].Price)/asks[0].Price - fee*3
+}
+
+/*
+Calculate the realized cross exchange rate of an executed cycle and its difference to the realized rate of the first
+trade pair after fees. Fees are taken as a share of each leg's notional, like the fee in the predicted difference.
+*/
+func realizedCrossExchangeRate(results [3]OrderResult) (float64, float64) {
+	if results[0].AveragePrice <= 0 || results[1].AveragePrice <= 0 {
+		return 0, 0
+	}
+	crossExchangeRate := results[2].AveragePrice / results[1].AveragePrice
+	feeShare := 0.0
+	for _, result := range results {
+		if notional := result.FilledSize * result.AveragePrice; notional > 0 {
+			feeShare += result.Fee / notional
+		}
+	}
+	return crossExchangeRate, (crossExchangeRate-results[0].AveragePrice)/results[0].AveragePrice - feeShare
+}
+
+/*
+Reverse the fill of a leg of an aborted triangular execution with a market order on the same exchange. The order is
+checked by the risk manager, which allows it while the kill switch is active because it reduces a position.
+*/
+func (e *Executor) unwindTriangularLeg(venue Venue, opportunity bookkeeper.TriangularArbitrageEventRecord, order OrderRequest, filled OrderResult) bookkeeper.LegCorrectionRecord {
+	side := Sell
+	if order.Side == Sell {
+		side = Buy
+	}
+	unwind := OrderRequest{
+		OpportunityUuid: opportunity.Uuid,
+		Leg:             "unwind " + order.Leg,
+		Exchange:        order.Exchange,
+		Currency:        order.Currency,
+		Side:            side,
+		Type:            Market,
+		Size:            filled.FilledSize,
+	}
+	reason := fmt.Sprintf("triangular arbitrage aborted after the %s leg", order.Leg)
+	var result OrderResult
+	if riskErr := e.riskManager.CheckOrder(unwind); riskErr != nil {
+		utils.Logger.Error(fmt.Sprintf("Unable to unwind the %s leg of triangular arbitrage %s.", order.Leg, opportunity.Uuid), zap.Error(riskErr))
+		result = OrderResult{Exchange: order.Exchange, Currency: order.Currency, Side: side, Status: Rejected, RequestedSize: filled.FilledSize}
+		reason = fmt.Sprintf("%s, %s", reason, riskErr.Error())
+	} else {
+		result = e.placeOrder(venue, unwind)
+	}
+	if result.FilledSize < filled.FilledSize {
+		utils.Logger.Error(fmt.Sprintf("Triangular arbitrage %s still has an open position of %v %s after unwinding.", opportunity.Uuid, filled.FilledSize-result.FilledSize, order.Currency))
+	}
+	return bookkeeper.LegCorrectionRecord{
+		Uuid:                uuid.New(),
+		Timestamp:           time.Now().UTC(),
+		ArbitrageRecordUuid: opportunity.Uuid,
+		Action:              string(Unwind),
+		Exchange:            order.Exchange,
+		Currency:            order.Currency,
+		Side:                string(side),
+		RequestedSize:       filled.FilledSize,
+		FilledSize:          result.FilledSize,
+		AveragePrice:        result.AveragePrice,
+		Status:              string(result.Status),
+		Reason:              reason,
+	}
+}
@@ -0,0 +1,195 @@
+package executor
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/magiconair/properties/assert"
+	"math"
+	"testing"
+)
+
+/*
+A venue with fixed order books and balances that fills a fixed fraction of the orders of every currency.
+*/
+type fakeBookVenue struct {
+	fakeVenue
+	bids       map[string]BookLevel
+	asks       map[string]BookLevel
+	balances   map[string]float64
+	fillRatios map[string]float64
+}
+
+func (f *fakeBookVenue) GetBalances() (map[string]float64, error) {
+	return f.balances, nil
+}
+
+func (f *fakeBookVenue) TopOfBook(currency string) (BookLevel, BookLevel, error) {
+	bid, ok := f.bids[currency]
+	if !ok {
+		return BookLevel{}, BookLevel{}, errors.New("unknown currency")
+	}
+	return bid, f.asks[currency], nil
+}
+
+func (f *fakeBookVenue) PlaceOrder(order OrderRequest) (OrderResult, error) {
+	f.fillRatio = f.fillRatios[order.Currency]
+	return f.fakeVenue.PlaceOrder(order)
+}
+
+/*
+An ETHBTC, LTCETH, LTCBTC cycle on Gemini with a cross exchange rate difference of about 4% at the top of the books.
+*/
+func bookVenueForTest(fillRatios map[string]float64) *fakeBookVenue {
+	return &fakeBookVenue{
+		fakeVenue: fakeVenue{name: "Gemini"},
+		bids: map[string]BookLevel{
+			"ETHBTC": {Price: .0599, Size: 1},
+			"LTCETH": {Price: .0399, Size: 10},
+			"LTCBTC": {Price: .0025, Size: 20},
+		},
+		asks: map[string]BookLevel{
+			"ETHBTC": {Price: .06, Size: 1},
+			"LTCETH": {Price: .04, Size: 10},
+			"LTCBTC": {Price: .0026, Size: 20},
+		},
+		balances:   map[string]float64{"BTC": 1, "ETH": 1, "LTC": 10},
+		fillRatios: fillRatios,
+	}
+}
+
+func triangularOpportunityForTest() bookkeeper.TriangularArbitrageEventRecord {
+	return bookkeeper.TriangularArbitrageEventRecord{
+		Uuid:                             uuid.New(),
+		Exchange:                         "Gemini",
+		TradePair1:                       "ETHBTC",
+		TradePair2:                       "LTCETH",
+		TradePair3:                       "LTCBTC",
+		CrossExchangeRateDiff:            .04,
+		IsTriangularArbitrageOpportunity: true,
+	}
+}
+
+func Test_ExecuteTriangular(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name           string
+		venue          *fakeBookVenue
+		balances       map[string]float64
+		bid3           float64
+		wantStatus     TriangularStatus
+		wantOrders     []string
+		wantSequence   [3]int
+		wantUnwinds    []string
+		wantRealizedOk bool
+	}{
+		{
+			name:           "All legs filled, the leg taking the largest share of its book goes first.",
+			venue:          bookVenueForTest(map[string]float64{"ETHBTC": 1, "LTCETH": 1, "LTCBTC": 1}),
+			wantStatus:     TriangularCompleted,
+			wantOrders:     []string{"LTCETH", "LTCBTC", "ETHBTC"},
+			wantSequence:   [3]int{3, 1, 2},
+			wantRealizedOk: true,
+		},
+		{
+			name:           "Not enough ETH to buy LTC before ETH is bought, the legs are placed in trade pair order.",
+			venue:          bookVenueForTest(map[string]float64{"ETHBTC": 1, "LTCETH": 1, "LTCBTC": 1}),
+			balances:       map[string]float64{"BTC": 1, "ETH": .01, "LTC": 10},
+			wantStatus:     TriangularCompleted,
+			wantOrders:     []string{"ETHBTC", "LTCETH", "LTCBTC"},
+			wantSequence:   [3]int{1, 2, 3},
+			wantRealizedOk: true,
+		},
+		{
+			name:         "Second leg misses, the executed legs are unwound.",
+			venue:        bookVenueForTest(map[string]float64{"ETHBTC": 1, "LTCETH": 1, "LTCBTC": .5}),
+			wantStatus:   TriangularAborted,
+			wantOrders:   []string{"LTCETH", "LTCBTC", "LTCETH", "LTCBTC"},
+			wantSequence: [3]int{0, 1, 2},
+			wantUnwinds:  []string{"LTCETH", "LTCBTC"},
+		},
+		{
+			name:         "First leg is rejected, nothing to unwind.",
+			venue:        bookVenueForTest(map[string]float64{"ETHBTC": 1, "LTCETH": 0, "LTCBTC": 1}),
+			wantStatus:   TriangularAborted,
+			wantOrders:   []string{"LTCETH"},
+			wantSequence: [3]int{0, 1, 0},
+		},
+		{
+			name:       "No cross exchange rate difference left at the top of the books.",
+			venue:      bookVenueForTest(map[string]float64{"ETHBTC": 1, "LTCETH": 1, "LTCBTC": 1}),
+			bid3:       .0024,
+			wantStatus: TriangularSkipped,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.balances != nil {
+				tt.venue.balances = tt.balances
+			}
+			if tt.bid3 > 0 {
+				tt.venue.bids["LTCBTC"] = BookLevel{Price: tt.bid3, Size: 20}
+			}
+			tradeExecutor := NewExecutor(LegRiskPolicy{Mode: Unwind}, tt.venue).WithTriangularPolicy(TriangularPolicy{MaxSize: .05, FillTolerance: .001})
+			opportunity := triangularOpportunityForTest()
+
+			report, err := tradeExecutor.ExecuteTriangular(opportunity, .001)
+			assert.Equal(t, err == nil, true)
+			assert.Equal(t, report.Record.Status, string(tt.wantStatus))
+			assert.Equal(t, report.Record.TriangularArbitrageRecordUuid, opportunity.Uuid)
+
+			var orders []string
+			for _, order := range tt.venue.orders {
+				orders = append(orders, order.Currency)
+			}
+			assert.Equal(t, orders, tt.wantOrders)
+			for i, leg := range report.Record.Legs {
+				assert.Equal(t, leg.Sequence, tt.wantSequence[i])
+			}
+
+			var unwinds []string
+			for _, unwind := range report.Unwinds {
+				assert.Equal(t, unwind.ArbitrageRecordUuid, opportunity.Uuid)
+				unwinds = append(unwinds, unwind.Currency)
+			}
+			assert.Equal(t, unwinds, tt.wantUnwinds)
+			assert.Equal(t, report.Record.RealizedCrossExchangeRateDiff > 0, tt.wantRealizedOk)
+		})
+	}
+}
+
+func Test_ExecuteTriangularWithoutOrderBooks(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tradeExecutor := NewExecutor(LegRiskPolicy{Mode: Unwind}, &fakeVenue{name: "Gemini", fillRatio: 1})
+	_, err := tradeExecutor.ExecuteTriangular(triangularOpportunityForTest(), .001)
+	assert.Equal(t, err != nil, true)
+}
+
+func Test_PlanTriangularLegs(t *testing.T) {
+	venue := bookVenueForTest(nil)
+	currencies := []string{"ETHBTC", "LTCETH", "LTCBTC"}
+	bids := [3]BookLevel{venue.bids["ETHBTC"], venue.bids["LTCETH"], venue.bids["LTCBTC"]}
+	asks := [3]BookLevel{venue.asks["ETHBTC"], venue.asks["LTCETH"], venue.asks["LTCBTC"]}
+
+	tests := []struct {
+		name     string
+		maxSize  float64
+		wantSize float64
+	}{
+		{name: "Sized to the leg with the least liquidity.", maxSize: 0, wantSize: .4},
+		{name: "Sized to the maximum size.", maxSize: .05, wantSize: .05},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legs, bookDiff := planTriangularLegs(currencies, bids, asks, .001, tt.maxSize)
+			assert.Equal(t, legs[0].size, tt.wantSize)
+			assert.Equal(t, math.Abs(legs[1].size-tt.wantSize/.04) < 1e-9, true)
+			assert.Equal(t, legs[2].size, legs[1].size)
+			assert.Equal(t, math.Abs(bookDiff-((.0025/.04-.06)/.06-.003)) < 1e-9, true)
+		})
+	}
+}
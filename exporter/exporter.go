@@ -0,0 +1,237 @@
+package exporter
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Progress is logged every progressInterval records.
+*/
+const progressInterval = 100000
+
+type ExportError struct {
+	Msg string
+}
+
+func (e *ExportError) Error() string {
+	return fmt.Sprintf("Export failed: %s", e.Msg)
+}
+
+/*
+The type of the values of a column: string, float64, bool or time.Time.
+*/
+type columnType int
+
+const (
+	stringColumn columnType = iota
+	doubleColumn
+	boolColumn
+	timeColumn
+)
+
+type column struct {
+	Name string
+	Type columnType
+}
+
+/*
+A table that can be exported. Stream passes the values of every record that matches the filter, in the order of Columns.
+*/
+type kind struct {
+	Table   string
+	Columns []column
+	Stream  func(filter bookkeeper.RecordFilter, handle func([]interface{}) error) error
+}
+
+var kinds = map[string]kind{
+	"price": {
+		Table: "price_records",
+		Columns: []column{
+			{"uuid", stringColumn}, {"timestamp", timeColumn}, {"currency", stringColumn}, {"price", doubleColumn},
+			{"fee", doubleColumn}, {"exchange", stringColumn}, {"arbitrage_record_uuid", stringColumn},
+			{"is_arbitrage_opportunity", boolColumn}, {"cycle_uuid", stringColumn},
+		},
+		Stream: func(filter bookkeeper.RecordFilter, handle func([]interface{}) error) error {
+			return bookkeeper.StreamFilteredPriceRecords(filter, func(p bookkeeper.PriceRecord) error {
+				return handle([]interface{}{p.Uuid.String(), p.Timestamp, p.Currency, p.Price, p.Fee, p.Exchange, p.ArbitrageRecordUuid.String(), p.IsArbitrageOpportunity, p.CycleUuid.String()})
+			})
+		},
+	},
+	"arbitrage": {
+		Table: "arbitrage_records",
+		Columns: []column{
+			{"uuid", stringColumn}, {"timestamp", timeColumn}, {"currency", stringColumn}, {"price_a", doubleColumn},
+			{"exchange_a", stringColumn}, {"price_b", doubleColumn}, {"exchange_b", stringColumn},
+			{"projected_profit", doubleColumn}, {"is_arbitrage_opportunity", boolColumn}, {"trade_size", doubleColumn},
+			{"is_blocked_by_inventory", boolColumn}, {"price_record_uuid_a", stringColumn},
+			{"price_record_uuid_b", stringColumn}, {"cycle_uuid", stringColumn},
+		},
+		Stream: func(filter bookkeeper.RecordFilter, handle func([]interface{}) error) error {
+			return bookkeeper.StreamArbitrageRecords(filter, func(a bookkeeper.ArbitrageEventRecord) error {
+				return handle([]interface{}{a.Uuid.String(), a.Timestamp, a.Currency, a.PriceA, a.ExchangeA, a.PriceB, a.ExchangeB, a.ProjectedProfit, a.IsArbitrageOpportunity, a.TradeSize, a.IsBlockedByInventory, a.PriceRecordUuidA.String(), a.PriceRecordUuidB.String(), a.CycleUuid.String()})
+			})
+		},
+	},
+	"triangular": {
+		Table: "triangular_arbitrage_1_exchange",
+		Columns: []column{
+			{"uuid", stringColumn}, {"timestamp", timeColumn}, {"exchange", stringColumn},
+			{"trade_pair_1", stringColumn}, {"trade_pair_1_exchange_rate", doubleColumn},
+			{"trade_pair_2", stringColumn}, {"trade_pair_2_exchange_rate", doubleColumn},
+			{"trade_pair_3", stringColumn}, {"trade_pair_3_exchange_rate", doubleColumn},
+			{"cross_exchange_rate_difference", doubleColumn}, {"is_triangular_arbitrage_opportunity", boolColumn},
+			{"trade_pair_1_price_record_uuid", stringColumn}, {"trade_pair_2_price_record_uuid", stringColumn},
+			{"trade_pair_3_price_record_uuid", stringColumn}, {"cycle_uuid", stringColumn},
+		},
+		Stream: func(filter bookkeeper.RecordFilter, handle func([]interface{}) error) error {
+			return bookkeeper.StreamTriangularArbitrageRecords(filter, func(t bookkeeper.TriangularArbitrageEventRecord) error {
+				return handle([]interface{}{t.Uuid.String(), t.Timestamp, t.Exchange, t.TradePair1, t.TradePair1ExchangeRate, t.TradePair2, t.TradePair2ExchangeRate, t.TradePair3, t.TradePair3ExchangeRate, t.CrossExchangeRateDiff, t.IsTriangularArbitrageOpportunity, t.TradePair1PriceRecordUuid.String(), t.TradePair2PriceRecordUuid.String(), t.TradePair3PriceRecordUuid.String(), t.CycleUuid.String()})
+			})
+		},
+	},
+}
+
+/*
+Writes records to a file, one call of Write per record with the values in the order of the columns.
+*/
+type recordWriter interface {
+	Write(values []interface{}) error
+	Close() error
+}
+
+/*
+Load the filter of the export from the configuration file (EXPORT).
+*/
+func LoadFilter() (bookkeeper.RecordFilter, error) {
+	filter := bookkeeper.RecordFilter{
+		Exchange:          viper.GetString("EXPORT.EXCHANGE"),
+		Currency:          viper.GetString("EXPORT.CURRENCY"),
+		OpportunitiesOnly: viper.GetBool("EXPORT.OPPORTUNITIES_ONLY"),
+	}
+	var err error
+	if filter.From, err = configTimestamp("EXPORT.FROM"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = configTimestamp("EXPORT.TO"); err != nil {
+		return filter, err
+	}
+	if cycle := viper.GetString("EXPORT.CYCLE"); cycle != "" {
+		if filter.Cycle, err = uuid.Parse(cycle); err != nil {
+			return filter, &ExportError{Msg: fmt.Sprintf("EXPORT.CYCLE: invalid uuid %q", cycle)}
+		}
+	}
+	return filter, nil
+}
+
+/*
+Returns a time of the configuration file (RFC3339 or "2006-01-02 15:04:05" in UTC), zero if it is not set.
+*/
+func configTimestamp(key string) (time.Time, error) {
+	value := viper.GetString(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if timestamp, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return timestamp.UTC(), nil
+	}
+	timestamp, err := time.Parse("2006-01-02 15:04:05.999999999", value)
+	if err != nil {
+		return time.Time{}, &ExportError{Msg: fmt.Sprintf("%s: invalid time %q", key, value)}
+	}
+	return timestamp, nil
+}
+
+/*
+Export the records of a kind (price, arbitrage or triangular) that match the configured filter to a file. The format is
+chosen by the extension of the file: .csv, .jsonl or .parquet. Records are streamed from the database to the file, only
+a Parquet row group is held in memory.
+*/
+func Export(kindName string, path string) error {
+	exportKind, ok := kinds[kindName]
+	if !ok {
+		return &ExportError{Msg: fmt.Sprintf("unknown kind %s, expected one of %s", kindName, strings.Join(kindNames(), ", "))}
+	}
+	filter, err := LoadFilter()
+	if err != nil {
+		return err
+	}
+	if databaseErr := internal.ConnectToDatabaseReadOnly(); databaseErr != nil {
+		return databaseErr
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return &ExportError{Msg: err.Error()}
+	}
+	count, err := exportTo(file, path, exportKind, filter)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return &ExportError{Msg: fmt.Sprintf("%s to %s: %v", exportKind.Table, path, err)}
+	}
+	utils.Logger.Info(fmt.Sprintf("Exported %d %s to %s.", count, exportKind.Table, path))
+	return nil
+}
+
+/*
+Stream the records of a kind that match filter into file in the format of path. Returns the number of records written.
+*/
+func exportTo(file io.Writer, path string, exportKind kind, filter bookkeeper.RecordFilter) (int, error) {
+	writer, err := newRecordWriter(file, path, exportKind.Columns)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	err = exportKind.Stream(filter, func(values []interface{}) error {
+		if err := writer.Write(values); err != nil {
+			return err
+		}
+		count++
+		if count%progressInterval == 0 {
+			utils.Logger.Info(fmt.Sprintf("Exported %d %s.", count, exportKind.Table))
+		}
+		return nil
+	})
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	return count, err
+}
+
+/*
+Returns the writer of the format of path.
+*/
+func newRecordWriter(file io.Writer, path string, columns []column) (recordWriter, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return newCsvWriter(file, columns)
+	case ".jsonl", ".json":
+		return newJsonLinesWriter(file, columns), nil
+	case ".parquet":
+		return newParquetWriter(file, columns, parquetRowGroupSize), nil
+	default:
+		return nil, fmt.Errorf("unsupported file %s, expected .csv, .jsonl or .parquet", path)
+	}
+}
+
+func kindNames() []string {
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,303 @@
+package exporter
+
+import (
+	"bytes"
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal"
+	"cryptoArbitrageBot/internal/migrations"
+	"cryptoArbitrageBot/internal/utils"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+	"log"
+	"math"
+	_ "modernc.org/sqlite"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupTest(tb testing.TB) func(tb testing.TB) {
+	log.Println("Setup tests.")
+
+	viper.SetConfigName("config-DEV")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath("../")
+	err := viper.ReadInConfig() // Find and read the config file
+	if err != nil {
+		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+	}
+
+	utils.InitializeLogger()
+	utils.SetLoggerLevel("DEBUG")
+
+	return func(tb testing.TB) {
+		log.Println("Teardown tests.")
+	}
+}
+
+/*
+Connect the bookkeeper to an empty SQLite database with the schema of the bot.
+*/
+func setupSqliteForTest(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "crypto_arbitrage_bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := migrations.LatestVersion(internal.Sqlite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrations.Migrate(db, internal.Sqlite, latest); err != nil {
+		t.Fatal(err)
+	}
+
+	dbPool, dialect := internal.DbPool, internal.Dialect
+	internal.DbPool, internal.Dialect = db, internal.Sqlite
+	t.Cleanup(func() {
+		internal.DbPool, internal.Dialect = dbPool, dialect
+		db.Close()
+	})
+}
+
+var timestampForTest = time.Date(2023, 7, 26, 18, 0, 5, 250000000, time.UTC)
+
+var columnsForTest = []column{{"timestamp", timeColumn}, {"exchange", stringColumn}, {"price", doubleColumn}, {"is_arbitrage_opportunity", boolColumn}}
+
+func Test_TextWriters(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "CSV files have a header and RFC3339 timestamps.",
+			path: "quotes.csv",
+			want: "timestamp,exchange,price,is_arbitrage_opportunity\n2023-07-26T18:00:05.25Z,\"Kraken, Inc.\",29250.5,true\n",
+		},
+		{
+			name: "JSON lines files keep the order of the columns.",
+			path: "quotes.jsonl",
+			want: `{"timestamp":"2023-07-26T18:00:05.25Z","exchange":"Kraken, Inc.","price":29250.5,"is_arbitrage_opportunity":true}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var file bytes.Buffer
+			writer, err := newRecordWriter(&file, tt.path, columnsForTest)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, writer.Write([]interface{}{timestampForTest, "Kraken, Inc.", 29250.5, true}), nil)
+			assert.Equal(t, writer.Close(), nil)
+			assert.Equal(t, file.String(), tt.want)
+		})
+	}
+
+	t.Run("Unknown formats are refused.", func(t *testing.T) {
+		_, err := newRecordWriter(&bytes.Buffer{}, "quotes.xlsx", columnsForTest)
+		assert.Equal(t, err != nil, true)
+	})
+}
+
+/*
+Decodes a struct of the Thrift compact protocol into its fields by id. Lists are decoded into slices, structs into maps,
+integers into int64 and binaries into strings.
+*/
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) varint() uint64 {
+	value, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return value
+}
+
+func (r *thriftReader) zigzag() int64 {
+	value := r.varint()
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+func (r *thriftReader) value(valueType byte) interface{} {
+	switch valueType {
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		length := int(r.varint())
+		r.pos += length
+		return string(r.data[r.pos-length : r.pos])
+	case thriftList:
+		header := r.data[r.pos]
+		r.pos++
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.varint())
+		}
+		values := make([]interface{}, size)
+		for i := range values {
+			values[i] = r.value(header & 0x0f)
+		}
+		return values
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic(fmt.Sprintf("unexpected thrift type %d", valueType))
+}
+
+func (r *thriftReader) readStruct() map[int64]interface{} {
+	fields := make(map[int64]interface{})
+	var id int64
+	for {
+		header := r.data[r.pos]
+		r.pos++
+		if header == 0 {
+			return fields
+		}
+		if delta := int64(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = r.zigzag()
+		}
+		fields[id] = r.value(header & 0x0f)
+	}
+}
+
+/*
+The file is decoded with the Thrift reader above, not with an independent Parquet library, which is not a dependency of
+the bot. This checks the layout the writer intends, not that other readers accept it.
+*/
+func Test_ParquetWriter(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	var file bytes.Buffer
+	writer := newParquetWriter(&file, columnsForTest, 2)
+	prices := []float64{29250.5, 29251, 29249.75}
+	for i, price := range prices {
+		assert.Equal(t, writer.Write([]interface{}{timestampForTest, "Kraken", price, i == 1}), nil)
+	}
+	assert.Equal(t, writer.Close(), nil)
+
+	data := file.Bytes()
+	assert.Equal(t, string(data[:4]), "PAR1")
+	assert.Equal(t, string(data[len(data)-4:]), "PAR1")
+	footerLength := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	metadata := (&thriftReader{data: data[len(data)-8-footerLength:]}).readStruct()
+
+	t.Run("The footer describes the schema and the rows.", func(t *testing.T) {
+		assert.Equal(t, metadata[3], int64(3))
+		schema := metadata[2].([]interface{})
+		assert.Equal(t, len(schema), len(columnsForTest)+1)
+		assert.Equal(t, schema[0].(map[int64]interface{})[5], int64(len(columnsForTest)))
+		for i, column := range columnsForTest {
+			element := schema[i+1].(map[int64]interface{})
+			assert.Equal(t, element[4], column.Name)
+			physicalType, _ := column.parquetType()
+			assert.Equal(t, element[1], int64(physicalType))
+		}
+		assert.Equal(t, schema[1].(map[int64]interface{})[6], int64(parquetTimestampMicros))
+	})
+
+	t.Run("Records are split into row groups of the row group size.", func(t *testing.T) {
+		rowGroups := metadata[4].([]interface{})
+		assert.Equal(t, len(rowGroups), 2)
+		assert.Equal(t, rowGroups[0].(map[int64]interface{})[3], int64(2))
+		assert.Equal(t, rowGroups[1].(map[int64]interface{})[3], int64(1))
+	})
+
+	t.Run("Column chunks point to PLAIN encoded data pages.", func(t *testing.T) {
+		chunks := metadata[4].([]interface{})[0].(map[int64]interface{})[1].([]interface{})
+		readPage := func(column int) (map[int64]interface{}, []byte) {
+			meta := chunks[column].(map[int64]interface{})[3].(map[int64]interface{})
+			reader := &thriftReader{data: data[meta[9].(int64):]}
+			header := reader.readStruct()
+			return header, reader.data[reader.pos : reader.pos+int(header[2].(int64))]
+		}
+
+		header, values := readPage(0)
+		assert.Equal(t, header[5].(map[int64]interface{})[1], int64(2))
+		assert.Equal(t, int64(binary.LittleEndian.Uint64(values)), timestampForTest.UnixMicro())
+
+		_, values = readPage(1)
+		assert.Equal(t, binary.LittleEndian.Uint32(values), uint32(len("Kraken")))
+		assert.Equal(t, string(values[4:10]), "Kraken")
+
+		_, values = readPage(2)
+		assert.Equal(t, math.Float64frombits(binary.LittleEndian.Uint64(values[8:])), prices[1])
+
+		_, values = readPage(3)
+		assert.Equal(t, values, []byte{0b10})
+	})
+}
+
+func Test_Export(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+	setupSqliteForTest(t)
+
+	cycleUuid := uuid.New()
+	arbitrageRecords := []bookkeeper.ArbitrageEventRecord{
+		{Uuid: uuid.New(), Timestamp: timestampForTest, Currency: "BTCUSD", PriceA: 29250, ExchangeA: "Coinbase", PriceB: 29300, ExchangeB: "Kraken", IsArbitrageOpportunity: true},
+		{Uuid: uuid.New(), Timestamp: timestampForTest.Add(5 * time.Second), Currency: "BTCUSD", PriceA: 29250, ExchangeA: "Coinbase", PriceB: 29260, ExchangeB: "Gemini", CycleUuid: cycleUuid},
+		{Uuid: uuid.New(), Timestamp: timestampForTest.Add(10 * time.Second), Currency: "ETHUSD", PriceA: 1850, ExchangeA: "Gemini", PriceB: 1860, ExchangeB: "Kraken", IsArbitrageOpportunity: true},
+	}
+	assert.Equal(t, bookkeeper.RecordArbitrageRecords(arbitrageRecords), nil)
+
+	tests := []struct {
+		name   string
+		filter bookkeeper.RecordFilter
+		want   []float64
+	}{
+		{name: "Without a filter every record is exported in timestamp order.", want: []float64{29300, 29260, 1860}},
+		{name: "An exchange matches either exchange of an arbitrage event.", filter: bookkeeper.RecordFilter{Exchange: "Kraken"}, want: []float64{29300, 1860}},
+		{name: "Currency and opportunities are filtered.", filter: bookkeeper.RecordFilter{Currency: "BTCUSD", OpportunitiesOnly: true}, want: []float64{29300}},
+		{name: "A cycle selects the records of one detection cycle.", filter: bookkeeper.RecordFilter{Cycle: cycleUuid}, want: []float64{29260}},
+		{name: "From is inclusive, to is exclusive.", filter: bookkeeper.RecordFilter{From: timestampForTest.Add(5 * time.Second), To: timestampForTest.Add(10 * time.Second)}, want: []float64{29260}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var file bytes.Buffer
+			count, err := exportTo(&file, "arbitrage.csv", kinds["arbitrage"], tt.filter)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, count, len(tt.want))
+
+			lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+			assert.Equal(t, strings.HasPrefix(lines[0], "uuid,timestamp,currency,price_a"), true)
+			var pricesB []float64
+			for _, line := range lines[1:] {
+				var priceB float64
+				fmt.Sscan(strings.Split(line, ",")[5], &priceB)
+				pricesB = append(pricesB, priceB)
+			}
+			assert.Equal(t, pricesB, tt.want)
+		})
+	}
+}
+
+func Test_ExportKinds(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+	setupSqliteForTest(t)
+
+	assert.Equal(t, bookkeeper.RecordPriceRecord(bookkeeper.PriceRecord{Uuid: uuid.New(), Timestamp: timestampForTest, Currency: "BTCUSD", Price: 29250, Fee: .0026, Exchange: "Kraken"}) == nil, true)
+	assert.Equal(t, bookkeeper.RecordTriangularArbitrageRecord(bookkeeper.TriangularArbitrageEventRecord{Uuid: uuid.New(), Timestamp: timestampForTest, Exchange: "Gemini", TradePair1: "ETHBTC", TradePair2: "LTCETH", TradePair3: "LTCBTC"}), nil)
+
+	for _, name := range kindNames() {
+		t.Run(fmt.Sprintf("Every column of %s is exported.", kinds[name].Table), func(t *testing.T) {
+			var file bytes.Buffer
+			count, err := exportTo(&file, name+".jsonl", kinds[name], bookkeeper.RecordFilter{})
+			assert.Equal(t, err, nil)
+			assert.Equal(t, count, map[string]int{"price": 1, "arbitrage": 0, "triangular": 1}[name])
+			if count > 0 {
+				assert.Equal(t, strings.Count(file.String(), ":"), len(kinds[name].Columns)+2)
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+/*
+The number of records of a Parquet row group, the records of a row group are held in memory until it is written.
+*/
+const parquetRowGroupSize = 50000
+
+var parquetMagic = []byte("PAR1")
+
+/*
+Parquet physical types, converted types and encodings, see parquet.thrift of the Parquet format.
+*/
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUtf8            = 0
+	parquetTimestampMicros = 10
+
+	parquetPlain = 0
+	parquetRle   = 3
+)
+
+/*
+Writes an uncompressed Parquet file. Every column is required and PLAIN encoded in one data page per row group, so no
+definition or repetition levels are written. Strings are UTF8 byte arrays, times are microseconds since the epoch.
+
+The files are only checked by the tests of this package, which decode them with their own Thrift reader. They have not
+been read back with an independent Parquet implementation (e.g. Arrow or DuckDB).
+*/
+type parquetWriter struct {
+	writer       *countingWriter
+	columns      []column
+	rowGroupSize int
+	values       []*bytes.Buffer
+	bits         []uint
+	rows         int
+	totalRows    int64
+	rowGroups    []parquetRowGroup
+	started      bool
+}
+
+type parquetRowGroup struct {
+	columns   []parquetColumnChunk
+	totalSize int64
+	rows      int64
+}
+
+type parquetColumnChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+type countingWriter struct {
+	writer io.Writer
+	offset int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.offset += int64(n)
+	return n, err
+}
+
+func newParquetWriter(file io.Writer, columns []column, rowGroupSize int) *parquetWriter {
+	values := make([]*bytes.Buffer, len(columns))
+	for i := range values {
+		values[i] = &bytes.Buffer{}
+	}
+	return &parquetWriter{
+		writer:       &countingWriter{writer: file},
+		columns:      columns,
+		rowGroupSize: rowGroupSize,
+		values:       values,
+		bits:         make([]uint, len(columns)),
+	}
+}
+
+func (w *parquetWriter) Write(values []interface{}) error {
+	for i, value := range values {
+		buffer := w.values[i]
+		switch value := value.(type) {
+		case string:
+			binary.Write(buffer, binary.LittleEndian, uint32(len(value)))
+			buffer.WriteString(value)
+		case float64:
+			binary.Write(buffer, binary.LittleEndian, math.Float64bits(value))
+		case time.Time:
+			binary.Write(buffer, binary.LittleEndian, value.UnixMicro())
+		case bool:
+			// Booleans are bit packed, the first value in the least significant bit
+			if w.bits[i]%8 == 0 {
+				buffer.WriteByte(0)
+			}
+			if value {
+				buffer.Bytes()[buffer.Len()-1] |= 1 << (w.bits[i] % 8)
+			}
+			w.bits[i]++
+		}
+	}
+	w.rows++
+	if w.rows >= w.rowGroupSize {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *parquetWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	_, err := w.writer.Write(parquetMagic)
+	return err
+}
+
+/*
+Write the buffered records as a row group.
+*/
+func (w *parquetWriter) flush() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if w.rows == 0 {
+		return nil
+	}
+	rowGroup := parquetRowGroup{rows: int64(w.rows)}
+	for i, values := range w.values {
+		header := &thriftWriter{}
+		header.structBegin()
+		header.i32Field(1, 0) // DATA_PAGE
+		header.i32Field(2, int32(values.Len()))
+		header.i32Field(3, int32(values.Len()))
+		header.structField(5, func() {
+			header.i32Field(1, int32(w.rows))
+			header.i32Field(2, parquetPlain)
+			header.i32Field(3, parquetRle)
+			header.i32Field(4, parquetRle)
+		})
+		header.structEnd()
+
+		chunk := parquetColumnChunk{offset: w.writer.offset, size: int64(header.buffer.Len() + values.Len()), numValues: int64(w.rows)}
+		if _, err := w.writer.Write(header.buffer.Bytes()); err != nil {
+			return err
+		}
+		if _, err := w.writer.Write(values.Bytes()); err != nil {
+			return err
+		}
+		rowGroup.columns = append(rowGroup.columns, chunk)
+		rowGroup.totalSize += chunk.size
+		values.Reset()
+		w.bits[i] = 0
+	}
+	w.rowGroups = append(w.rowGroups, rowGroup)
+	w.totalRows += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+/*
+Write the pending records and the footer with the schema and the row groups.
+*/
+func (w *parquetWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	footer := &thriftWriter{}
+	footer.structBegin()
+	footer.i32Field(1, 1)
+	footer.listField(2, thriftStruct, len(w.columns)+1)
+	footer.structBegin()
+	footer.stringField(4, "schema")
+	footer.i32Field(5, int32(len(w.columns)))
+	footer.structEnd()
+	for _, column := range w.columns {
+		physicalType, convertedType := column.parquetType()
+		footer.structBegin()
+		footer.i32Field(1, physicalType)
+		footer.i32Field(3, 0) // REQUIRED
+		footer.stringField(4, column.Name)
+		if convertedType >= 0 {
+			footer.i32Field(6, convertedType)
+		}
+		footer.structEnd()
+	}
+	footer.i64Field(3, w.totalRows)
+	footer.listField(4, thriftStruct, len(w.rowGroups))
+	for _, rowGroup := range w.rowGroups {
+		footer.structBegin()
+		footer.listField(1, thriftStruct, len(rowGroup.columns))
+		for i, chunk := range rowGroup.columns {
+			physicalType, _ := w.columns[i].parquetType()
+			footer.structBegin()
+			footer.i64Field(2, chunk.offset)
+			footer.structField(3, func() {
+				footer.i32Field(1, physicalType)
+				footer.listField(2, thriftI32, 2)
+				footer.i32(parquetPlain)
+				footer.i32(parquetRle)
+				footer.listField(3, thriftBinary, 1)
+				footer.string(w.columns[i].Name)
+				footer.i32Field(4, 0) // UNCOMPRESSED
+				footer.i64Field(5, chunk.numValues)
+				footer.i64Field(6, chunk.size)
+				footer.i64Field(7, chunk.size)
+				footer.i64Field(9, chunk.offset)
+			})
+			footer.structEnd()
+		}
+		footer.i64Field(2, rowGroup.totalSize)
+		footer.i64Field(3, rowGroup.rows)
+		footer.structEnd()
+	}
+	footer.stringField(6, "cryptoArbitrageBot")
+	footer.structEnd()
+
+	if _, err := w.writer.Write(footer.buffer.Bytes()); err != nil {
+		return err
+	}
+	if err := binary.Write(w.writer, binary.LittleEndian, uint32(footer.buffer.Len())); err != nil {
+		return err
+	}
+	_, err := w.writer.Write(parquetMagic)
+	return err
+}
+
+/*
+Returns the physical type and the converted type of a column, -1 if it has none.
+*/
+func (c column) parquetType() (int32, int32) {
+	switch c.Type {
+	case doubleColumn:
+		return parquetDouble, -1
+	case boolColumn:
+		return parquetBoolean, -1
+	case timeColumn:
+		return parquetInt64, parquetTimestampMicros
+	default:
+		return parquetByteArray, parquetUtf8
+	}
+}
+
+/*
+Types of the Thrift compact protocol.
+*/
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+/*
+Encodes the Parquet metadata in the Thrift compact protocol. Field ids are written as the difference to the previous
+field of the same struct.
+*/
+type thriftWriter struct {
+	buffer      bytes.Buffer
+	lastFieldId []int16
+}
+
+func (w *thriftWriter) structBegin() {
+	w.lastFieldId = append(w.lastFieldId, 0)
+}
+
+func (w *thriftWriter) structEnd() {
+	w.buffer.WriteByte(0)
+	w.lastFieldId = w.lastFieldId[:len(w.lastFieldId)-1]
+}
+
+func (w *thriftWriter) fieldHeader(id int16, fieldType byte) {
+	last := &w.lastFieldId[len(w.lastFieldId)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buffer.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		w.buffer.WriteByte(fieldType)
+		w.varint(uint64((int64(id) << 1) ^ (int64(id) >> 63)))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) varint(value uint64) {
+	var encoded [binary.MaxVarintLen64]byte
+	w.buffer.Write(encoded[:binary.PutUvarint(encoded[:], value)])
+}
+
+func (w *thriftWriter) i32(value int32) {
+	w.varint(uint64(uint32((value << 1) ^ (value >> 31))))
+}
+
+func (w *thriftWriter) i64(value int64) {
+	w.varint(uint64((value << 1) ^ (value >> 63)))
+}
+
+func (w *thriftWriter) string(value string) {
+	w.varint(uint64(len(value)))
+	w.buffer.WriteString(value)
+}
+
+func (w *thriftWriter) i32Field(id int16, value int32) {
+	w.fieldHeader(id, thriftI32)
+	w.i32(value)
+}
+
+func (w *thriftWriter) i64Field(id int16, value int64) {
+	w.fieldHeader(id, thriftI64)
+	w.i64(value)
+}
+
+func (w *thriftWriter) stringField(id int16, value string) {
+	w.fieldHeader(id, thriftBinary)
+	w.string(value)
+}
+
+/*
+Write the header of a list field, followed by its size elements of elementType.
+*/
+func (w *thriftWriter) listField(id int16, elementType byte, size int) {
+	w.fieldHeader(id, thriftList)
+	if size < 15 {
+		w.buffer.WriteByte(byte(size)<<4 | elementType)
+	} else {
+		w.buffer.WriteByte(0xf0 | elementType)
+		w.varint(uint64(size))
+	}
+}
+
+func (w *thriftWriter) structField(id int16, fields func()) {
+	w.fieldHeader(id, thriftStruct)
+	w.structBegin()
+	fields()
+	w.structEnd()
+}
@@ -0,0 +1,99 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+/*
+Format a value of a record as text. Times are written in RFC3339 with fractions of a second in UTC, so that exported
+quotes can be replayed by the backtester.
+*/
+func formatValue(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+/*
+Writes a CSV file with a header of the column names.
+*/
+type csvWriter struct {
+	writer *csv.Writer
+	row    []string
+}
+
+func newCsvWriter(file io.Writer, columns []column) (*csvWriter, error) {
+	writer := csv.NewWriter(file)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: writer, row: make([]string, len(columns))}, nil
+}
+
+func (w *csvWriter) Write(values []interface{}) error {
+	for i, value := range values {
+		w.row[i] = formatValue(value)
+	}
+	return w.writer.Write(w.row)
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+/*
+Writes one JSON object per line with the values keyed by column name, in the order of the columns.
+*/
+type jsonLinesWriter struct {
+	writer  *bufio.Writer
+	columns []column
+}
+
+func newJsonLinesWriter(file io.Writer, columns []column) *jsonLinesWriter {
+	return &jsonLinesWriter{writer: bufio.NewWriter(file), columns: columns}
+}
+
+func (w *jsonLinesWriter) Write(values []interface{}) error {
+	w.writer.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			w.writer.WriteByte(',')
+		}
+		name, _ := json.Marshal(w.columns[i].Name)
+		w.writer.Write(name)
+		w.writer.WriteByte(':')
+		if timestamp, ok := value.(time.Time); ok {
+			value = formatValue(timestamp)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		w.writer.Write(encoded)
+	}
+	w.writer.WriteByte('}')
+	_, err := w.writer.WriteString("\n")
+	return err
+}
+
+func (w *jsonLinesWriter) Close() error {
+	return w.writer.Flush()
+}
@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"context"
+	"cryptoArbitrageBot/internal/migrations"
+	"cryptoArbitrageBot/internal/utils"
+	"database/sql"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/dialect/mysql"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/doug-martin/goqu/v9/dialect/sqlite3"
+	"github.com/spf13/viper"
+	"log"
+	"strings"
+	"time"
+)
+
+/*
+Supported databases, named after their goqu dialect. The database is selected with DATABASE.DRIVER.
+*/
+const (
+	MySql    = "mysql"
+	Postgres = "postgres"
+	Sqlite   = "sqlite3"
+)
+
+var (
+	DbPool  *sql.DB
+	Dialect = MySql
+	err     error
+)
+
+/*
+Timestamps are written with microseconds to MySQL, whose goqu dialect drops them, and with a fixed number of digits to
+SQLite, which stores them as text and compares them as strings.
+*/
+func init() {
+	mySqlOptions := mysql.DialectOptions()
+	mySqlOptions.TimeFormat = "2006-01-02 15:04:05.000000"
+	goqu.RegisterDialect(MySql, mySqlOptions)
+
+	sqliteOptions := sqlite3.DialectOptions()
+	sqliteOptions.TimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+	goqu.RegisterDialect(Sqlite, sqliteOptions)
+}
+
+type DatabaseError struct {
+	Msg string
+}
+
+func (e *DatabaseError) Error() string {
+	return fmt.Sprint("Error establishing connection to database: ") + e.Msg
+}
+
+/*
+Connect to the database selected in the configuration file (DATABASE.DRIVER, MySQL if not set), check that its schema
+is at the version of the bot and that it has every table, column and privilege the bot needs. Returns error if
+connection fails or the schema is not usable. The bot exits if the schema is at another version.
+*/
+func ConnectToDatabase() *DatabaseError {
+	return connectToDatabase(false)
+}
+
+/*
+Connect to the database like ConnectToDatabase for reading only, e.g. to export or backtest from a read-only replica.
+Only SELECT is required on the tables and the schema is never migrated.
+*/
+func ConnectToDatabaseReadOnly() *DatabaseError {
+	return connectToDatabase(true)
+}
+
+func connectToDatabase(readOnly bool) *DatabaseError {
+	if err := OpenDatabase(); err != nil {
+		return err
+	}
+	if viper.GetBool("DATABASE.AUTO_MIGRATE") && !readOnly {
+		latest, migrateErr := migrations.LatestVersion(Dialect)
+		if migrateErr == nil {
+			migrateErr = migrations.Migrate(DbPool, Dialect, latest)
+		}
+		if migrateErr != nil {
+			return &DatabaseError{
+				Msg: migrateErr.Error(),
+			}
+		}
+	}
+	if schemaErr := migrations.Check(DbPool, Dialect); schemaErr != nil {
+		return &DatabaseError{
+			Msg: schemaErr.Error(),
+		}
+	}
+	if schemaErr := migrations.Validate(DbPool, Dialect, readOnly); schemaErr != nil {
+		return &DatabaseError{
+			Msg: schemaErr.Error(),
+		}
+	}
+	utils.Logger.Info(fmt.Sprintf("Database (%s) is online and fully operational.", Dialect))
+	return nil
+}
+
+/*
+Connect to the database selected in the configuration file without checking its schema, e.g. to migrate it.
+*/
+func OpenDatabase() *DatabaseError {
+	driver := strings.ToLower(viper.GetString("DATABASE.DRIVER"))
+	switch driver {
+	case "", MySql:
+		Dialect = MySql
+		DbPool, err = connectTCPSocket()
+	case Postgres:
+		Dialect = Postgres
+		DbPool, err = connectPostgres()
+	case Sqlite, "sqlite":
+		Dialect = Sqlite
+		DbPool, err = connectSqlite()
+	default:
+		return &DatabaseError{
+			Msg: fmt.Sprintf("unknown DATABASE.DRIVER %s, expected mysql, postgres or sqlite3", driver),
+		}
+	}
+	if err != nil {
+		return &DatabaseError{
+			Msg: err.Error(),
+		}
+	}
+	DbPool.SetMaxIdleConns(0)
+	DbPool.SetConnMaxLifetime(time.Second)
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	if dbErr := DbPool.PingContext(ctx); dbErr != nil {
+		return &DatabaseError{
+			Msg: dbErr.Error(),
+		}
+	}
+	return nil
+}
+
+/*
+Returns a setting of the database from the configuration file, exits if it is not set.
+*/
+func mustGetenv(k string) string {
+	v := viper.Get(k).(string)
+	if v == "" {
+		log.Fatalf("Warning: %s environment variable not set.", k)
+	}
+	return v
+}
@@ -0,0 +1,375 @@
+package migrations
+
+import (
+	"cryptoArbitrageBot/internal/utils"
+	"database/sql"
+	"embed"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+The migrations of every database, in a directory named after its goqu dialect. Every version has an up and a down
+migration: <version>_<name>.up.sql and <version>_<name>.down.sql.
+*/
+//go:embed mysql postgres sqlite3
+var migrationFiles embed.FS
+
+/*
+The table that holds the applied versions.
+*/
+const VersionTable = "schema_migrations"
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+/*
+A column of the initial schema that the tables of mysql/database_crypto_arbitrage_bot.sql lack. That file created the
+databases before the migrations, its tables are kept by the initial schema and get these columns before version 1 is
+recorded. The definition of the column by goqu dialect.
+*/
+type baselineColumn struct {
+	Table       string
+	Column      string
+	Definitions map[string]string
+}
+
+var baselineColumns = []baselineColumn{
+	{Table: "arbitrage_records", Column: "trade_size", Definitions: map[string]string{"mysql": "double NOT NULL DEFAULT 0", "postgres": "double precision NOT NULL DEFAULT 0", "sqlite3": "real NOT NULL DEFAULT 0"}},
+	{Table: "arbitrage_records", Column: "is_blocked_by_inventory", Definitions: map[string]string{"mysql": "varchar(255) NOT NULL DEFAULT '0'", "postgres": "boolean NOT NULL DEFAULT FALSE", "sqlite3": "boolean NOT NULL DEFAULT 0"}},
+}
+
+type MigrationError struct {
+	Msg string
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprint("Schema migration failed: ") + e.Msg
+}
+
+/*
+A version of the schema. Up upgrades the previous version to it, Down reverts it.
+*/
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+/*
+Returns the migrations of a database ordered by version.
+*/
+func Load(dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, dialect)
+	if err != nil {
+		return nil, &MigrationError{Msg: fmt.Sprintf("no migrations for database %s", dialect)}
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, &MigrationError{Msg: fmt.Sprintf("unexpected migration file %s/%s", dialect, entry.Name())}
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := migrationFiles.ReadFile(path.Join(dialect, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, &MigrationError{Msg: fmt.Sprintf("version %d of %s needs an up and a down migration", migration.Version, dialect)}
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, &MigrationError{Msg: fmt.Sprintf("version %d of %s is missing", i+1, dialect)}
+		}
+	}
+	return migrations, nil
+}
+
+/*
+Returns the version of the schema the bot is built for, the latest migration.
+*/
+func LatestVersion(dialect string) (int, error) {
+	migrations, err := Load(dialect)
+	if err != nil {
+		return 0, err
+	}
+	return len(migrations), nil
+}
+
+/*
+Returns the version of the schema of a database, 0 if no migration has been applied.
+*/
+func CurrentVersion(db *sql.DB, dialect string) (int, error) {
+	exists, err := versionTableExists(db, dialect)
+	if err != nil || !exists {
+		return 0, err
+	}
+	var version sql.NullInt64
+	if _, err := goqu.New(dialect, db).From(VersionTable).Select(goqu.MAX("version")).ScanVal(&version); err != nil {
+		return 0, &MigrationError{Msg: fmt.Sprintf("unable to read the schema version: %v", err)}
+	}
+	return int(version.Int64), nil
+}
+
+/*
+Refuse a database whose schema is not at the version the bot is built for.
+*/
+func Check(db *sql.DB, dialect string) error {
+	current, err := CurrentVersion(db, dialect)
+	if err != nil {
+		return err
+	}
+	latest, err := LatestVersion(dialect)
+	if err != nil {
+		return err
+	}
+	if current != latest {
+		return &MigrationError{Msg: fmt.Sprintf("the database schema is at version %d, the bot expects version %d. Run `make migrate`.", current, latest)}
+	}
+	utils.Logger.Info(fmt.Sprintf("Database schema is at version %d.", current))
+	return nil
+}
+
+/*
+Migrate the schema of a database up or down to the given version, 0 reverts every migration.
+*/
+func Migrate(db *sql.DB, dialect string, target int) error {
+	migrations, err := Load(dialect)
+	if err != nil {
+		return err
+	}
+	if target < 0 || target > len(migrations) {
+		return &MigrationError{Msg: fmt.Sprintf("unknown version %d, the latest version is %d", target, len(migrations))}
+	}
+	if err := createVersionTable(db); err != nil {
+		return err
+	}
+	current, err := CurrentVersion(db, dialect)
+	if err != nil {
+		return err
+	}
+	if current > len(migrations) {
+		return &MigrationError{Msg: fmt.Sprintf("the database schema is at version %d, which is newer than this bot (%d)", current, len(migrations))}
+	}
+
+	if current == 0 && target > 0 {
+		if err := adoptBaseline(db, dialect); err != nil {
+			return err
+		}
+	}
+	for current < target {
+		migration := migrations[current]
+		if err := apply(db, dialect, migration, true); err != nil {
+			return err
+		}
+		current = migration.Version
+		utils.Logger.Info(fmt.Sprintf("Migrated the database schema up to version %d (%s).", current, migration.Name))
+	}
+	for current > target {
+		migration := migrations[current-1]
+		if err := apply(db, dialect, migration, false); err != nil {
+			return err
+		}
+		current = migration.Version - 1
+		utils.Logger.Info(fmt.Sprintf("Migrated the database schema down to version %d, reverted %s.", current, migration.Name))
+	}
+	return nil
+}
+
+/*
+Run the statements of one migration and record the new version in the same transaction. MySQL commits every schema
+change immediately, a MySQL migration that fails part way has to be completed or reverted by hand.
+*/
+func apply(db *sql.DB, dialect string, migration Migration, up bool) error {
+	statements := migration.Down
+	if up {
+		statements = migration.Up
+	}
+	database := goqu.New(dialect, db)
+	tx, err := database.Begin()
+	if err != nil {
+		return &MigrationError{Msg: err.Error()}
+	}
+	for _, statement := range splitStatements(statements) {
+		if _, err := tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return &MigrationError{Msg: fmt.Sprintf("version %d (%s): %v. Statement: %s", migration.Version, migration.Name, err, statement)}
+		}
+	}
+
+	var recordVersionSQL string
+	if up {
+		recordVersionSQL, _, _ = tx.Insert(VersionTable).Rows(goqu.Record{"version": migration.Version, "name": migration.Name, "applied_at": time.Now().UTC().Format(time.RFC3339)}).ToSQL()
+	} else {
+		recordVersionSQL, _, _ = tx.Delete(VersionTable).Where(goqu.Ex{"version": migration.Version}).ToSQL()
+	}
+	if _, err := tx.Exec(recordVersionSQL); err != nil {
+		tx.Rollback()
+		return &MigrationError{Msg: fmt.Sprintf("unable to record version %d: %v", migration.Version, err)}
+	}
+	if err := tx.Commit(); err != nil {
+		return &MigrationError{Msg: err.Error()}
+	}
+	return nil
+}
+
+/*
+Add the columns of the initial schema to the tables of a database set up with mysql/database_crypto_arbitrage_bot.sql,
+so that the migrations can upgrade its data. Tables that don't exist yet are created by the initial schema.
+*/
+func adoptBaseline(db *sql.DB, dialect string) error {
+	columns, err := readColumns(db, dialect)
+	if err != nil {
+		return &MigrationError{Msg: fmt.Sprintf("unable to read the columns of the database: %v", err)}
+	}
+	for _, baseline := range baselineColumns {
+		dataTypes, ok := columns[baseline.Table]
+		if !ok {
+			continue
+		}
+		if _, ok := dataTypes[baseline.Column]; ok {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", baseline.Table, baseline.Column, baseline.Definitions[dialect])); err != nil {
+			return &MigrationError{Msg: fmt.Sprintf("unable to add %s.%s to the existing schema: %v", baseline.Table, baseline.Column, err)}
+		}
+		utils.Logger.Info(fmt.Sprintf("Added %s.%s to the existing schema before migrating it.", baseline.Table, baseline.Column))
+	}
+	return nil
+}
+
+func versionTableExists(db *sql.DB, dialect string) (bool, error) {
+	database := goqu.New(dialect, db)
+	var query *goqu.SelectDataset
+	switch dialect {
+	case "sqlite3":
+		query = database.From("sqlite_master").Select(goqu.COUNT("*")).Where(goqu.Ex{"type": "table", "name": VersionTable})
+	case "postgres":
+		query = database.From(goqu.S("information_schema").Table("tables")).Select(goqu.COUNT("*")).Where(goqu.Ex{"table_schema": goqu.L("current_schema()"), "table_name": VersionTable})
+	default:
+		query = database.From(goqu.S("information_schema").Table("tables")).Select(goqu.COUNT("*")).Where(goqu.Ex{"table_schema": goqu.L("DATABASE()"), "table_name": VersionTable})
+	}
+	var count int
+	if _, err := query.ScanVal(&count); err != nil {
+		return false, &MigrationError{Msg: fmt.Sprintf("unable to look up %s: %v", VersionTable, err)}
+	}
+	return count > 0, nil
+}
+
+func createVersionTable(db *sql.DB) error {
+	_, err := db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version integer NOT NULL, name varchar(255) NOT NULL, applied_at varchar(255) NOT NULL, PRIMARY KEY (version))", VersionTable))
+	if err != nil {
+		return &MigrationError{Msg: fmt.Sprintf("unable to create %s: %v", VersionTable, err)}
+	}
+	return nil
+}
+
+/*
+Split a migration into its statements, which end with a semicolon at the end of a line. Comment lines are skipped.
+*/
+func splitStatements(migration string) []string {
+	var statements []string
+	var statement strings.Builder
+	for _, line := range strings.Split(migration, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		statement.WriteString(line)
+		statement.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(statement.String()))
+			statement.Reset()
+		}
+	}
+	if strings.TrimSpace(statement.String()) != "" {
+		statements = append(statements, strings.TrimSpace(statement.String()))
+	}
+	return statements
+}
+
+/*
+Run the migrate command.
+  - up [version]: migrate up to the version, the latest if not given.
+  - down [version]: migrate down to the version, the previous one if not given.
+  - status: print the version of the database and the migrations.
+*/
+func Run(db *sql.DB, dialect string, args []string, writer io.Writer) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	migrations, err := Load(dialect)
+	if err != nil {
+		return err
+	}
+	current, err := CurrentVersion(db, dialect)
+	if err != nil {
+		return err
+	}
+
+	target := -1
+	if len(args) > 1 {
+		if target, err = strconv.Atoi(args[1]); err != nil {
+			return &MigrationError{Msg: fmt.Sprintf("invalid version %s", args[1])}
+		}
+	}
+	switch action {
+	case "up":
+		if target == -1 {
+			target = len(migrations)
+		}
+		if target < current {
+			return &MigrationError{Msg: fmt.Sprintf("the database schema is at version %d, use down to revert it to version %d", current, target)}
+		}
+		return Migrate(db, dialect, target)
+	case "down":
+		if target == -1 {
+			target = current - 1
+		}
+		if target < 0 || target > current {
+			return &MigrationError{Msg: fmt.Sprintf("the database schema is at version %d, use up to migrate it to version %d", current, target)}
+		}
+		return Migrate(db, dialect, target)
+	case "status":
+		fmt.Fprintf(writer, "Database schema (%s) is at version %d, the latest version is %d.\n", dialect, current, len(migrations))
+		for _, migration := range migrations {
+			state := "pending"
+			if migration.Version <= current {
+				state = "applied"
+			}
+			fmt.Fprintf(writer, "%04d %-40s %s\n", migration.Version, migration.Name, state)
+		}
+		return nil
+	}
+	return &MigrationError{Msg: fmt.Sprintf("unknown action %s, expected up, down or status", action)}
+}
@@ -0,0 +1,244 @@
+package migrations
+
+import (
+	"bytes"
+	"cryptoArbitrageBot/internal/utils"
+	"database/sql"
+	"fmt"
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+	"log"
+	_ "modernc.org/sqlite"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupTest(tb testing.TB) func(tb testing.TB) {
+	log.Println("Setup tests.")
+
+	viper.SetConfigName("config-DEV")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath("../../")
+	err := viper.ReadInConfig() // Find and read the config file
+	if err != nil {
+		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+	}
+
+	utils.InitializeLogger()
+	utils.SetLoggerLevel("DEBUG")
+
+	return func(tb testing.TB) {
+		log.Println("Teardown tests.")
+	}
+}
+
+func Test_Load(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	sqlite, err := Load("sqlite3")
+	assert.Equal(t, err, nil)
+
+	tests := []struct {
+		name    string
+		dialect string
+	}{
+		{name: "MySQL has the same versions as SQLite.", dialect: "mysql"},
+		{name: "PostgreSQL has the same versions as SQLite.", dialect: "postgres"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := Load(tt.dialect)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, len(migrations), len(sqlite))
+			for i, migration := range migrations {
+				assert.Equal(t, migration.Version, sqlite[i].Version)
+				assert.Equal(t, migration.Name, sqlite[i].Name)
+			}
+		})
+	}
+
+	t.Run("An unknown database has no migrations.", func(t *testing.T) {
+		_, err := Load("oracle")
+		assert.Equal(t, err != nil, true)
+	})
+}
+
+func Test_Migrate(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "crypto_arbitrage_bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	latest, err := LatestVersion("sqlite3")
+	assert.Equal(t, err, nil)
+
+	t.Run("An empty database is refused.", func(t *testing.T) {
+		assert.Equal(t, Check(db, "sqlite3") != nil, true)
+		var tables int
+		assert.Equal(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&tables), nil)
+		assert.Equal(t, tables, 0)
+	})
+
+	t.Run("Migrating up creates the schema of the bot.", func(t *testing.T) {
+		assert.Equal(t, Run(db, "sqlite3", []string{"up"}, &bytes.Buffer{}), nil)
+		assert.Equal(t, Check(db, "sqlite3"), nil)
+		_, err := db.Exec("SELECT uuid, timestamp, currency, price, fee, exchange FROM price_records")
+		assert.Equal(t, err, nil)
+
+		var status bytes.Buffer
+		assert.Equal(t, Run(db, "sqlite3", []string{"status"}, &status), nil)
+		assert.Equal(t, strings.Contains(status.String(), fmt.Sprintf("is at version %d", latest)), true)
+		assert.Equal(t, strings.Contains(status.String(), "pending"), false)
+	})
+
+	t.Run("Migrating down to version 0 drops the schema of the bot.", func(t *testing.T) {
+		assert.Equal(t, Run(db, "sqlite3", []string{"down", "0"}, &bytes.Buffer{}), nil)
+		current, err := CurrentVersion(db, "sqlite3")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, current, 0)
+		assert.Equal(t, Check(db, "sqlite3") != nil, true)
+		_, err = db.Exec("SELECT uuid FROM price_records")
+		assert.Equal(t, err != nil, true)
+	})
+
+	t.Run("Existing timestamps are converted to the notation of the typed columns.", func(t *testing.T) {
+		assert.Equal(t, Migrate(db, "sqlite3", 1), nil)
+		_, err := db.Exec("INSERT INTO price_records VALUES ('uuid-1', '2023-06-01T10:00:05Z', 'BTCUSD', 27000, 0.006, 'Kraken', '', 0)")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, Migrate(db, "sqlite3", 2), nil)
+
+		var timestamp string
+		assert.Equal(t, db.QueryRow("SELECT CAST(timestamp AS TEXT) FROM price_records").Scan(&timestamp), nil)
+		assert.Equal(t, timestamp, "2023-06-01T10:00:05.000000000Z")
+		var indexes int
+		assert.Equal(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = 'price_records' AND name LIKE 'price_records_%'").Scan(&indexes), nil)
+		assert.Equal(t, indexes, 3)
+
+		assert.Equal(t, Migrate(db, "sqlite3", 1), nil)
+		assert.Equal(t, db.QueryRow("SELECT CAST(timestamp AS TEXT) FROM price_records").Scan(&timestamp), nil)
+		assert.Equal(t, timestamp, "2023-06-01T10:00:05Z")
+		assert.Equal(t, Migrate(db, "sqlite3", 0), nil)
+	})
+
+	t.Run("Timestamps with an offset are converted to UTC.", func(t *testing.T) {
+		assert.Equal(t, Migrate(db, "sqlite3", 5), nil)
+		_, err := db.Exec("INSERT INTO order_records (uuid, timestamp, updated_at, arbitrage_record_uuid, leg, client_order_id, exchange, order_id, currency, side, type, price, size, filled_size, average_price, fee, state, reason) VALUES ('uuid-1', '2023-06-01T12:00:05+02:00', '2023-06-01T10:00:06.000000000Z', '', 'buy', 'client-1', 'Kraken', '', 'BTCUSD', 'buy', 'market', 0, 0.01, 0, 0, 0, 'new', '')")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, Migrate(db, "sqlite3", 6), nil)
+
+		var timestamp, updatedAt string
+		assert.Equal(t, db.QueryRow("SELECT CAST(timestamp AS TEXT), CAST(updated_at AS TEXT) FROM order_records").Scan(&timestamp, &updatedAt), nil)
+		assert.Equal(t, timestamp, "2023-06-01T10:00:05.000000000Z")
+		assert.Equal(t, updatedAt, "2023-06-01T10:00:06.000000000Z")
+		assert.Equal(t, Migrate(db, "sqlite3", 0), nil)
+	})
+
+	t.Run("Unknown versions and actions are refused.", func(t *testing.T) {
+		assert.Equal(t, Migrate(db, "sqlite3", latest+1) != nil, true)
+		assert.Equal(t, Run(db, "sqlite3", []string{"sideways"}, &bytes.Buffer{}) != nil, true)
+	})
+}
+
+/*
+Returns a SQLite database with the tables of mysql/database_crypto_arbitrage_bot.sql, which set up the databases before
+the migrations.
+*/
+func baselineSqliteForTest(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "crypto_arbitrage_bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	baseline, err := os.ReadFile(filepath.Join("testdata", "baseline_schema.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range splitStatements(string(baseline)) {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func Test_MigrateBaseline(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	db := baselineSqliteForTest(t)
+	for _, statement := range []string{
+		"INSERT INTO arbitrage_records VALUES ('arbitrage-1', '2023-06-01 10:00:05', 'BTCUSD', 'Coinbase', '27000.5', 'Kraken', '27100', 42.5, 'true')",
+		"INSERT INTO arbitrage_records VALUES ('arbitrage-2', '2023-06-01T10:00:06Z', 'BTCUSD', 'Coinbase', '27000', 'Kraken', '26900.25', -3, '0')",
+		"INSERT INTO triangular_arbitrage_1_exchange VALUES ('triangular-1', '2023-06-01 10:00:05', 'Kraken', 'BTCUSD', 27000, 'ETHBTC', 0.065, 'ETHUSD', 1750, 0.002, 'false')",
+		"INSERT INTO price_records VALUES ('price-1', '2023-06-01 10:00:05', 'BTCUSD', 27000.5, '0.0026', 'Coinbase', 'arbitrage-1', '1')",
+	} {
+		_, err := db.Exec(statement)
+		assert.Equal(t, err, nil)
+	}
+	latest, err := LatestVersion("sqlite3")
+	assert.Equal(t, err, nil)
+
+	t.Run("A database set up before the migrations is migrated to the latest version.", func(t *testing.T) {
+		assert.Equal(t, Migrate(db, "sqlite3", latest), nil)
+		assert.Equal(t, Check(db, "sqlite3"), nil)
+		assert.Equal(t, Validate(db, "sqlite3", false), nil)
+	})
+
+	t.Run("The baseline columns are only added once.", func(t *testing.T) {
+		assert.Equal(t, adoptBaseline(db, "sqlite3"), nil)
+	})
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "Arbitrage records get numeric prices, boolean flags and timestamps in UTC with fractional seconds.",
+			query: "SELECT uuid, CAST(timestamp AS TEXT), typeof(price_a), price_a, price_b, is_arbitrage_opportunity, trade_size, is_blocked_by_inventory FROM arbitrage_records ORDER BY uuid",
+			want: []string{
+				"arbitrage-1 2023-06-01T10:00:05.000000000Z real 27000.5 27100 1 0 0",
+				"arbitrage-2 2023-06-01T10:00:06.000000000Z real 27000 26900.25 0 0 0",
+			},
+		},
+		{
+			name:  "Triangular arbitrage records get a boolean flag.",
+			query: "SELECT uuid, CAST(timestamp AS TEXT), typeof(is_triangular_arbitrage_opportunity), is_triangular_arbitrage_opportunity FROM triangular_arbitrage_1_exchange",
+			want:  []string{"triangular-1 2023-06-01T10:00:05.000000000Z integer 0"},
+		},
+		{
+			name:  "Price records get a numeric fee and a boolean flag.",
+			query: "SELECT uuid, CAST(timestamp AS TEXT), typeof(fee), fee, is_arbitrage_opportunity FROM price_records",
+			want:  []string{"price-1 2023-06-01T10:00:05.000000000Z real 0.0026 1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := db.Query(tt.query)
+			assert.Equal(t, err, nil)
+			defer rows.Close()
+			columns, _ := rows.Columns()
+			var got []string
+			for rows.Next() {
+				values := make([]sql.RawBytes, len(columns))
+				pointers := make([]interface{}, len(columns))
+				for i := range values {
+					pointers[i] = &values[i]
+				}
+				assert.Equal(t, rows.Scan(pointers...), nil)
+				fields := make([]string, len(values))
+				for i, value := range values {
+					fields[i] = string(value)
+				}
+				got = append(got, strings.Join(fields, " "))
+			}
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
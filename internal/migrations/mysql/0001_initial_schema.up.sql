@@ -0,0 +1,266 @@
+CREATE TABLE IF NOT EXISTS `arbitrage_records` (
+  `uuid` varchar(255) NOT NULL,
+  `timestamp` timestamp NOT NULL,
+  `currency` varchar(255) NOT NULL,
+  `exchange_a` varchar(255) NOT NULL,
+  `price_a` varchar(255) NOT NULL,
+  `exchange_b` varchar(255) NOT NULL,
+  `price_b` varchar(255) NOT NULL,
+  `projected_profit` double NOT NULL,
+  `is_arbitrage_opportunity` varchar(255) NOT NULL,
+  `trade_size` double NOT NULL DEFAULT 0,
+  `is_blocked_by_inventory` varchar(255) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`uuid`)
+);
+
+CREATE TABLE IF NOT EXISTS `triangular_arbitrage_1_exchange` (
+    `uuid` varchar(255) NOT NULL,
+    `timestamp` timestamp NOT NULL,
+    `exchange` varchar(255) NOT NULL,
+    `trade_pair_1` varchar(255) NOT NULL,
+    `trade_pair_1_exchange_rate` double NOT NULL,
+    `trade_pair_2` varchar(255) NOT NULL,
+    `trade_pair_2_exchange_rate` double NOT NULL,
+    `trade_pair_3` varchar(255) NOT NULL,
+    `trade_pair_3_exchange_rate` double NOT NULL,
+    `cross_exchange_rate_difference` double NOT NULL,
+    `is_triangular_arbitrage_opportunity` varchar(255) NOT NULL,
+    PRIMARY KEY (`uuid`)
+);
+
+CREATE TABLE IF NOT EXISTS `price_records` (
+    `uuid` varchar(255) NOT NULL,
+    `timestamp` timestamp NOT NULL,
+    `currency` varchar(255) NOT NULL,
+    `price` double NOT NULL,
+    `fee` varchar(255) NOT NULL,
+    `exchange` varchar(255) NOT NULL,
+    `arbitrage_record_uuid` varchar(255) NOT NULL,
+    `is_arbitrage_opportunity` varchar(255) NOT NULL,
+    PRIMARY KEY (`uuid`)
+);
+
+CREATE TABLE IF NOT EXISTS `leg_correction_records` (
+    `uuid` varchar(255) NOT NULL,
+    `timestamp` timestamp NOT NULL,
+    `arbitrage_record_uuid` varchar(255) NOT NULL,
+    `action` varchar(255) NOT NULL,
+    `exchange` varchar(255) NOT NULL,
+    `currency` varchar(255) NOT NULL,
+    `side` varchar(255) NOT NULL,
+    `requested_size` double NOT NULL,
+    `filled_size` double NOT NULL,
+    `average_price` double NOT NULL,
+    `status` varchar(255) NOT NULL,
+    `reason` varchar(255) NOT NULL,
+    PRIMARY KEY (`uuid`)
+);
+
+CREATE TABLE IF NOT EXISTS `transfer_records` (
+    `uuid` varchar(255) NOT NULL,
+    `timestamp` timestamp NOT NULL,
+    `updated_at` timestamp NOT NULL,
+    `asset` varchar(255) NOT NULL,
+    `from_exchange` varchar(255) NOT NULL,
+    `to_exchange` varchar(255) NOT NULL,
+    `amount` double NOT NULL,
+    `estimated_fee` double NOT NULL,
+    `address` varchar(255) NOT NULL,
+    `reference_id` varchar(255) NOT NULL,
+    `status` varchar(255) NOT NULL,
+    `reason` varchar(255) NOT NULL,
+    PRIMARY KEY (`uuid`)
+);
+
+CREATE TABLE IF NOT EXISTS `order_records` (
+    `uuid` varchar(255) NOT NULL,
+    `timestamp` timestamp NOT NULL,
+    `updated_at` timestamp NOT NULL,
+    `arbitrage_record_uuid` varchar(255) NOT NULL,
+    `leg` varchar(255) NOT NULL,
+    `client_order_id` varchar(255) NOT NULL,
+    `exchange` varchar(255) NOT NULL,
+    `order_id` varchar(255) NOT NULL,
+    `currency` varchar(255) NOT NULL,
+    `side` varchar(255) NOT NULL,
+    `type` varchar(255) NOT NULL,
+    `price` double NOT NULL,
+    `size` double NOT NULL,
+    `filled_size` double NOT NULL,
+    `average_price` double NOT NULL,
+    `fee` double NOT NULL,
+    `state` varchar(255) NOT NULL,
+    `reason` varchar(1024) NOT NULL,
+    PRIMARY KEY (`uuid`),
+    UNIQUE KEY `client_order_id` (`client_order_id`),
+    KEY `state` (`state`)
+);
+
+CREATE TABLE IF NOT EXISTS `triangular_execution_records` (
+    `uuid` varchar(255) NOT NULL,
+    `timestamp` timestamp NOT NULL,
+    `triangular_arbitrage_record_uuid` varchar(255) NOT NULL,
+    `exchange` varchar(255) NOT NULL,
+    `status` varchar(255) NOT NULL,
+    `predicted_cross_exchange_rate_difference` double NOT NULL,
+    `realized_cross_exchange_rate` double NOT NULL,
+    `realized_cross_exchange_rate_difference` double NOT NULL,
+    `trade_pair_1` varchar(255) NOT NULL,
+    `trade_pair_1_side` varchar(255) NOT NULL,
+    `trade_pair_1_sequence` int NOT NULL,
+    `trade_pair_1_requested_size` double NOT NULL,
+    `trade_pair_1_filled_size` double NOT NULL,
+    `trade_pair_1_average_price` double NOT NULL,
+    `trade_pair_1_fee` double NOT NULL,
+    `trade_pair_1_status` varchar(255) NOT NULL,
+    `trade_pair_2` varchar(255) NOT NULL,
+    `trade_pair_2_side` varchar(255) NOT NULL,
+    `trade_pair_2_sequence` int NOT NULL,
+    `trade_pair_2_requested_size` double NOT NULL,
+    `trade_pair_2_filled_size` double NOT NULL,
+    `trade_pair_2_average_price` double NOT NULL,
+    `trade_pair_2_fee` double NOT NULL,
+    `trade_pair_2_status` varchar(255) NOT NULL,
+    `trade_pair_3` varchar(255) NOT NULL,
+    `trade_pair_3_side` varchar(255) NOT NULL,
+    `trade_pair_3_sequence` int NOT NULL,
+    `trade_pair_3_requested_size` double NOT NULL,
+    `trade_pair_3_filled_size` double NOT NULL,
+    `trade_pair_3_average_price` double NOT NULL,
+    `trade_pair_3_fee` double NOT NULL,
+    `trade_pair_3_status` varchar(255) NOT NULL,
+    `reason` varchar(1024) NOT NULL,
+    PRIMARY KEY (`uuid`)
+);
+
+CREATE TABLE IF NOT EXISTS `ledger_entries` (
+    `uuid` varchar(255) NOT NULL,
+    `timestamp` timestamp NOT NULL,
+    `transaction_uuid` varchar(255) NOT NULL,
+    `reference` varchar(255) NOT NULL,
+    `account` varchar(255) NOT NULL,
+    `exchange` varchar(255) NOT NULL,
+    `asset` varchar(255) NOT NULL,
+    `amount` double NOT NULL,
+    `value` double NOT NULL,
+    PRIMARY KEY (`uuid`),
+    KEY `transaction_uuid` (`transaction_uuid`)
+);
+
+CREATE TABLE IF NOT EXISTS `pnl_daily` (
+    `period` date NOT NULL,
+    `base_currency` varchar(255) NOT NULL,
+    `realized_pnl` double NOT NULL,
+    `unrealized_pnl` double NOT NULL,
+    `fees` double NOT NULL,
+    `total_pnl` double NOT NULL,
+    `updated_at` timestamp NOT NULL,
+    PRIMARY KEY (`period`, `base_currency`)
+);
+
+CREATE TABLE IF NOT EXISTS `pnl_monthly` (
+    `period` date NOT NULL,
+    `base_currency` varchar(255) NOT NULL,
+    `realized_pnl` double NOT NULL,
+    `unrealized_pnl` double NOT NULL,
+    `fees` double NOT NULL,
+    `total_pnl` double NOT NULL,
+    `updated_at` timestamp NOT NULL,
+    PRIMARY KEY (`period`, `base_currency`)
+);
+
+CREATE TABLE IF NOT EXISTS `trade_analysis_records` (
+    `uuid` varchar(255) NOT NULL,
+    `timestamp` timestamp NOT NULL,
+    `arbitrage_record_uuid` varchar(255) NOT NULL,
+    `currency` varchar(255) NOT NULL,
+    `buy_exchange` varchar(255) NOT NULL,
+    `sell_exchange` varchar(255) NOT NULL,
+    `size` double NOT NULL,
+    `matched_size` double NOT NULL,
+    `projected_profit` double NOT NULL,
+    `realized_profit` double NOT NULL,
+    `slippage` double NOT NULL,
+    `fee_difference` double NOT NULL,
+    `latency_decay` double NOT NULL,
+    `partial_fill_loss` double NOT NULL,
+    `correction_loss` double NOT NULL,
+    PRIMARY KEY (`uuid`),
+    KEY `exchange_pair` (`buy_exchange`, `sell_exchange`)
+);
+
+CREATE OR REPLACE VIEW `trade_analysis_by_exchange_pair` AS
+SELECT
+    `currency`,
+    `buy_exchange`,
+    `sell_exchange`,
+    COUNT(*) AS `trades`,
+    SUM(`projected_profit`) AS `projected_profit`,
+    SUM(`realized_profit`) AS `realized_profit`,
+    SUM(`slippage`) AS `slippage`,
+    SUM(`fee_difference`) AS `fee_difference`,
+    SUM(`latency_decay`) AS `latency_decay`,
+    SUM(`partial_fill_loss`) AS `partial_fill_loss`,
+    SUM(`correction_loss`) AS `correction_loss`,
+    SUM(`matched_size`) / SUM(`size`) AS `fill_ratio`
+FROM `trade_analysis_records`
+GROUP BY `currency`, `buy_exchange`, `sell_exchange`;
+
+CREATE TABLE IF NOT EXISTS `spread_signal_records` (
+    `uuid` varchar(255) NOT NULL,
+    `timestamp` timestamp NOT NULL,
+    `currency` varchar(255) NOT NULL,
+    `exchange_a` varchar(255) NOT NULL,
+    `price_a` double NOT NULL,
+    `exchange_b` varchar(255) NOT NULL,
+    `price_b` double NOT NULL,
+    `spread` double NOT NULL,
+    `mean` double NOT NULL,
+    `standard_deviation` double NOT NULL,
+    `z_score` double NOT NULL,
+    `action` varchar(255) NOT NULL,
+    `direction` varchar(255) NOT NULL,
+    `entry_uuid` varchar(255) NOT NULL,
+    `spread_change` double NOT NULL,
+    PRIMARY KEY (`uuid`),
+    KEY `entry_uuid` (`entry_uuid`)
+);
+
+CREATE TABLE IF NOT EXISTS `market_trades` (
+    `uuid` varchar(255) NOT NULL,
+    `exchange` varchar(255) NOT NULL,
+    `pair` varchar(255) NOT NULL,
+    `currency` varchar(255) NOT NULL,
+    `timestamp` timestamp NOT NULL,
+    `price` double NOT NULL,
+    `volume` double NOT NULL,
+    `side` varchar(255) NOT NULL,
+    `order_type` varchar(255) NOT NULL,
+    PRIMARY KEY (`uuid`),
+    KEY `currency_timestamp` (`currency`, `timestamp`)
+);
+
+CREATE TABLE IF NOT EXISTS `market_candles` (
+    `exchange` varchar(255) NOT NULL,
+    `pair` varchar(255) NOT NULL,
+    `currency` varchar(255) NOT NULL,
+    `interval_minutes` int NOT NULL,
+    `timestamp` timestamp NOT NULL,
+    `open` double NOT NULL,
+    `high` double NOT NULL,
+    `low` double NOT NULL,
+    `close` double NOT NULL,
+    `vwap` double NOT NULL,
+    `volume` double NOT NULL,
+    `count` int NOT NULL,
+    PRIMARY KEY (`exchange`, `pair`, `interval_minutes`, `timestamp`)
+);
+
+CREATE TABLE IF NOT EXISTS `market_data_cursors` (
+    `exchange` varchar(255) NOT NULL,
+    `pair` varchar(255) NOT NULL,
+    `kind` varchar(255) NOT NULL,
+    `cursor` bigint NOT NULL,
+    `updated_at` timestamp NOT NULL,
+    PRIMARY KEY (`exchange`, `pair`, `kind`)
+);
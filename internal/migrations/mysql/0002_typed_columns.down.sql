@@ -0,0 +1,22 @@
+ALTER TABLE `price_records`
+    DROP KEY `timestamp`,
+    DROP KEY `currency_timestamp`,
+    DROP KEY `exchange_timestamp`,
+    MODIFY `timestamp` timestamp NOT NULL,
+    MODIFY `fee` varchar(255) NOT NULL,
+    MODIFY `is_arbitrage_opportunity` varchar(255) NOT NULL;
+
+ALTER TABLE `triangular_arbitrage_1_exchange`
+    DROP KEY `timestamp`,
+    DROP KEY `exchange_timestamp`,
+    MODIFY `timestamp` timestamp NOT NULL,
+    MODIFY `is_triangular_arbitrage_opportunity` varchar(255) NOT NULL;
+
+ALTER TABLE `arbitrage_records`
+    DROP KEY `timestamp`,
+    DROP KEY `currency_timestamp`,
+    MODIFY `timestamp` timestamp NOT NULL,
+    MODIFY `price_a` varchar(255) NOT NULL,
+    MODIFY `price_b` varchar(255) NOT NULL,
+    MODIFY `is_arbitrage_opportunity` varchar(255) NOT NULL,
+    MODIFY `is_blocked_by_inventory` varchar(255) NOT NULL DEFAULT '0';
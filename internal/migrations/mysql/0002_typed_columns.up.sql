@@ -0,0 +1,29 @@
+-- Booleans were stored as '1'/'0' (or 'true'/'false') and prices and fees as text.
+UPDATE `arbitrage_records` SET `is_arbitrage_opportunity` = IF(LOWER(`is_arbitrage_opportunity`) IN ('1', 'true'), '1', '0'), `is_blocked_by_inventory` = IF(LOWER(`is_blocked_by_inventory`) IN ('1', 'true'), '1', '0');
+
+UPDATE `triangular_arbitrage_1_exchange` SET `is_triangular_arbitrage_opportunity` = IF(LOWER(`is_triangular_arbitrage_opportunity`) IN ('1', 'true'), '1', '0');
+
+UPDATE `price_records` SET `is_arbitrage_opportunity` = IF(LOWER(`is_arbitrage_opportunity`) IN ('1', 'true'), '1', '0');
+
+ALTER TABLE `arbitrage_records`
+    MODIFY `timestamp` timestamp(6) NOT NULL,
+    MODIFY `price_a` double NOT NULL,
+    MODIFY `price_b` double NOT NULL,
+    MODIFY `is_arbitrage_opportunity` boolean NOT NULL,
+    MODIFY `is_blocked_by_inventory` boolean NOT NULL DEFAULT FALSE,
+    ADD KEY `timestamp` (`timestamp`),
+    ADD KEY `currency_timestamp` (`currency`, `timestamp`);
+
+ALTER TABLE `triangular_arbitrage_1_exchange`
+    MODIFY `timestamp` timestamp(6) NOT NULL,
+    MODIFY `is_triangular_arbitrage_opportunity` boolean NOT NULL,
+    ADD KEY `timestamp` (`timestamp`),
+    ADD KEY `exchange_timestamp` (`exchange`, `timestamp`);
+
+ALTER TABLE `price_records`
+    MODIFY `timestamp` timestamp(6) NOT NULL,
+    MODIFY `fee` double NOT NULL,
+    MODIFY `is_arbitrage_opportunity` boolean NOT NULL,
+    ADD KEY `timestamp` (`timestamp`),
+    ADD KEY `currency_timestamp` (`currency`, `timestamp`),
+    ADD KEY `exchange_timestamp` (`exchange`, `timestamp`);
@@ -0,0 +1,11 @@
+ALTER TABLE `price_records`
+    DROP KEY `arbitrage_record_uuid`;
+
+ALTER TABLE `triangular_arbitrage_1_exchange`
+    DROP `trade_pair_1_price_record_uuid`,
+    DROP `trade_pair_2_price_record_uuid`,
+    DROP `trade_pair_3_price_record_uuid`;
+
+ALTER TABLE `arbitrage_records`
+    DROP `price_record_uuid_a`,
+    DROP `price_record_uuid_b`;
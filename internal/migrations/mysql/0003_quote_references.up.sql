@@ -0,0 +1,11 @@
+ALTER TABLE `arbitrage_records`
+    ADD `price_record_uuid_a` varchar(255) NOT NULL DEFAULT '',
+    ADD `price_record_uuid_b` varchar(255) NOT NULL DEFAULT '';
+
+ALTER TABLE `triangular_arbitrage_1_exchange`
+    ADD `trade_pair_1_price_record_uuid` varchar(255) NOT NULL DEFAULT '',
+    ADD `trade_pair_2_price_record_uuid` varchar(255) NOT NULL DEFAULT '',
+    ADD `trade_pair_3_price_record_uuid` varchar(255) NOT NULL DEFAULT '';
+
+ALTER TABLE `price_records`
+    ADD KEY `arbitrage_record_uuid` (`arbitrage_record_uuid`);
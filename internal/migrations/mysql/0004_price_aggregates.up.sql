@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS `price_aggregates` (
+    `exchange` varchar(255) NOT NULL,
+    `currency` varchar(255) NOT NULL,
+    `interval_minutes` int NOT NULL,
+    `timestamp` timestamp(6) NOT NULL,
+    `open` double NOT NULL,
+    `high` double NOT NULL,
+    `low` double NOT NULL,
+    `close` double NOT NULL,
+    `average` double NOT NULL,
+    `count` int NOT NULL,
+    PRIMARY KEY (`exchange`, `currency`, `interval_minutes`, `timestamp`),
+    KEY `interval_timestamp` (`interval_minutes`, `timestamp`)
+);
+
+CREATE TABLE IF NOT EXISTS `spread_aggregates` (
+    `currency` varchar(255) NOT NULL,
+    `exchange_a` varchar(255) NOT NULL,
+    `exchange_b` varchar(255) NOT NULL,
+    `interval_minutes` int NOT NULL,
+    `timestamp` timestamp(6) NOT NULL,
+    `open` double NOT NULL,
+    `high` double NOT NULL,
+    `low` double NOT NULL,
+    `close` double NOT NULL,
+    `average` double NOT NULL,
+    `count` int NOT NULL,
+    PRIMARY KEY (`currency`, `exchange_a`, `exchange_b`, `interval_minutes`, `timestamp`),
+    KEY `interval_timestamp` (`interval_minutes`, `timestamp`)
+);
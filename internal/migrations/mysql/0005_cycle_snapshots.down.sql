@@ -0,0 +1,17 @@
+ALTER TABLE `spread_signal_records`
+    DROP KEY `cycle_uuid`,
+    DROP `cycle_uuid`;
+
+ALTER TABLE `triangular_arbitrage_1_exchange`
+    DROP KEY `cycle_uuid`,
+    DROP `cycle_uuid`;
+
+ALTER TABLE `arbitrage_records`
+    DROP KEY `cycle_uuid`,
+    DROP `cycle_uuid`;
+
+ALTER TABLE `price_records`
+    DROP KEY `cycle_uuid`,
+    DROP `cycle_uuid`;
+
+DROP TABLE IF EXISTS `cycle_snapshots`;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS `cycle_snapshots` (
+    `uuid` varchar(255) NOT NULL,
+    `timestamp` timestamp(6) NOT NULL,
+    `quotes` int NOT NULL DEFAULT 0,
+    `arbitrage_events` int NOT NULL DEFAULT 0,
+    `triangular_arbitrage_events` int NOT NULL DEFAULT 0,
+    `spread_signals` int NOT NULL DEFAULT 0,
+    `opportunities` int NOT NULL DEFAULT 0,
+    PRIMARY KEY (`uuid`),
+    KEY `timestamp` (`timestamp`)
+);
+
+ALTER TABLE `price_records`
+    ADD `cycle_uuid` varchar(255) NOT NULL DEFAULT '',
+    ADD KEY `cycle_uuid` (`cycle_uuid`);
+
+ALTER TABLE `arbitrage_records`
+    ADD `cycle_uuid` varchar(255) NOT NULL DEFAULT '',
+    ADD KEY `cycle_uuid` (`cycle_uuid`);
+
+ALTER TABLE `triangular_arbitrage_1_exchange`
+    ADD `cycle_uuid` varchar(255) NOT NULL DEFAULT '',
+    ADD KEY `cycle_uuid` (`cycle_uuid`);
+
+ALTER TABLE `spread_signal_records`
+    ADD `cycle_uuid` varchar(255) NOT NULL DEFAULT '',
+    ADD KEY `cycle_uuid` (`cycle_uuid`);
@@ -0,0 +1,37 @@
+ALTER TABLE `market_data_cursors`
+    MODIFY `updated_at` timestamp NOT NULL;
+
+ALTER TABLE `market_candles`
+    MODIFY `timestamp` timestamp NOT NULL;
+
+ALTER TABLE `market_trades`
+    MODIFY `timestamp` timestamp NOT NULL;
+
+ALTER TABLE `spread_signal_records`
+    MODIFY `timestamp` timestamp NOT NULL;
+
+ALTER TABLE `trade_analysis_records`
+    MODIFY `timestamp` timestamp NOT NULL;
+
+ALTER TABLE `pnl_monthly`
+    MODIFY `updated_at` timestamp NOT NULL;
+
+ALTER TABLE `pnl_daily`
+    MODIFY `updated_at` timestamp NOT NULL;
+
+ALTER TABLE `ledger_entries`
+    MODIFY `timestamp` timestamp NOT NULL;
+
+ALTER TABLE `triangular_execution_records`
+    MODIFY `timestamp` timestamp NOT NULL;
+
+ALTER TABLE `order_records`
+    MODIFY `timestamp` timestamp NOT NULL,
+    MODIFY `updated_at` timestamp NOT NULL;
+
+ALTER TABLE `transfer_records`
+    MODIFY `timestamp` timestamp NOT NULL,
+    MODIFY `updated_at` timestamp NOT NULL;
+
+ALTER TABLE `leg_correction_records`
+    MODIFY `timestamp` timestamp NOT NULL;
@@ -0,0 +1,40 @@
+-- The timestamps of orders, transfers, executions, the ledger and market data were written in RFC3339 with seconds and
+-- the local offset. They are written in UTC with microseconds like those of the quotes now.
+
+ALTER TABLE `leg_correction_records`
+    MODIFY `timestamp` timestamp(6) NOT NULL;
+
+ALTER TABLE `transfer_records`
+    MODIFY `timestamp` timestamp(6) NOT NULL,
+    MODIFY `updated_at` timestamp(6) NOT NULL;
+
+ALTER TABLE `order_records`
+    MODIFY `timestamp` timestamp(6) NOT NULL,
+    MODIFY `updated_at` timestamp(6) NOT NULL;
+
+ALTER TABLE `triangular_execution_records`
+    MODIFY `timestamp` timestamp(6) NOT NULL;
+
+ALTER TABLE `ledger_entries`
+    MODIFY `timestamp` timestamp(6) NOT NULL;
+
+ALTER TABLE `pnl_daily`
+    MODIFY `updated_at` timestamp(6) NOT NULL;
+
+ALTER TABLE `pnl_monthly`
+    MODIFY `updated_at` timestamp(6) NOT NULL;
+
+ALTER TABLE `trade_analysis_records`
+    MODIFY `timestamp` timestamp(6) NOT NULL;
+
+ALTER TABLE `spread_signal_records`
+    MODIFY `timestamp` timestamp(6) NOT NULL;
+
+ALTER TABLE `market_trades`
+    MODIFY `timestamp` timestamp(6) NOT NULL;
+
+ALTER TABLE `market_candles`
+    MODIFY `timestamp` timestamp(6) NOT NULL;
+
+ALTER TABLE `market_data_cursors`
+    MODIFY `updated_at` timestamp(6) NOT NULL;
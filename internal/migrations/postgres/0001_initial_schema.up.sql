@@ -0,0 +1,271 @@
+CREATE TABLE IF NOT EXISTS arbitrage_records (
+    uuid varchar(255) NOT NULL,
+    timestamp timestamptz NOT NULL,
+    currency varchar(255) NOT NULL,
+    exchange_a varchar(255) NOT NULL,
+    price_a double precision NOT NULL,
+    exchange_b varchar(255) NOT NULL,
+    price_b double precision NOT NULL,
+    projected_profit double precision NOT NULL,
+    is_arbitrage_opportunity boolean NOT NULL,
+    trade_size double precision NOT NULL DEFAULT 0,
+    is_blocked_by_inventory boolean NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS triangular_arbitrage_1_exchange (
+    uuid varchar(255) NOT NULL,
+    timestamp timestamptz NOT NULL,
+    exchange varchar(255) NOT NULL,
+    trade_pair_1 varchar(255) NOT NULL,
+    trade_pair_1_exchange_rate double precision NOT NULL,
+    trade_pair_2 varchar(255) NOT NULL,
+    trade_pair_2_exchange_rate double precision NOT NULL,
+    trade_pair_3 varchar(255) NOT NULL,
+    trade_pair_3_exchange_rate double precision NOT NULL,
+    cross_exchange_rate_difference double precision NOT NULL,
+    is_triangular_arbitrage_opportunity boolean NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS price_records (
+    uuid varchar(255) NOT NULL,
+    timestamp timestamptz NOT NULL,
+    currency varchar(255) NOT NULL,
+    price double precision NOT NULL,
+    fee double precision NOT NULL,
+    exchange varchar(255) NOT NULL,
+    arbitrage_record_uuid varchar(255) NOT NULL,
+    is_arbitrage_opportunity boolean NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS leg_correction_records (
+    uuid varchar(255) NOT NULL,
+    timestamp timestamptz NOT NULL,
+    arbitrage_record_uuid varchar(255) NOT NULL,
+    action varchar(255) NOT NULL,
+    exchange varchar(255) NOT NULL,
+    currency varchar(255) NOT NULL,
+    side varchar(255) NOT NULL,
+    requested_size double precision NOT NULL,
+    filled_size double precision NOT NULL,
+    average_price double precision NOT NULL,
+    status varchar(255) NOT NULL,
+    reason varchar(255) NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS transfer_records (
+    uuid varchar(255) NOT NULL,
+    timestamp timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    asset varchar(255) NOT NULL,
+    from_exchange varchar(255) NOT NULL,
+    to_exchange varchar(255) NOT NULL,
+    amount double precision NOT NULL,
+    estimated_fee double precision NOT NULL,
+    address varchar(255) NOT NULL,
+    reference_id varchar(255) NOT NULL,
+    status varchar(255) NOT NULL,
+    reason varchar(255) NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS order_records (
+    uuid varchar(255) NOT NULL,
+    timestamp timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    arbitrage_record_uuid varchar(255) NOT NULL,
+    leg varchar(255) NOT NULL,
+    client_order_id varchar(255) NOT NULL,
+    exchange varchar(255) NOT NULL,
+    order_id varchar(255) NOT NULL,
+    currency varchar(255) NOT NULL,
+    side varchar(255) NOT NULL,
+    type varchar(255) NOT NULL,
+    price double precision NOT NULL,
+    size double precision NOT NULL,
+    filled_size double precision NOT NULL,
+    average_price double precision NOT NULL,
+    fee double precision NOT NULL,
+    state varchar(255) NOT NULL,
+    reason varchar(1024) NOT NULL,
+    PRIMARY KEY (uuid),
+    UNIQUE (client_order_id)
+);
+
+CREATE INDEX IF NOT EXISTS order_records_state ON order_records (state);
+
+CREATE TABLE IF NOT EXISTS triangular_execution_records (
+    uuid varchar(255) NOT NULL,
+    timestamp timestamptz NOT NULL,
+    triangular_arbitrage_record_uuid varchar(255) NOT NULL,
+    exchange varchar(255) NOT NULL,
+    status varchar(255) NOT NULL,
+    predicted_cross_exchange_rate_difference double precision NOT NULL,
+    realized_cross_exchange_rate double precision NOT NULL,
+    realized_cross_exchange_rate_difference double precision NOT NULL,
+    trade_pair_1 varchar(255) NOT NULL,
+    trade_pair_1_side varchar(255) NOT NULL,
+    trade_pair_1_sequence int NOT NULL,
+    trade_pair_1_requested_size double precision NOT NULL,
+    trade_pair_1_filled_size double precision NOT NULL,
+    trade_pair_1_average_price double precision NOT NULL,
+    trade_pair_1_fee double precision NOT NULL,
+    trade_pair_1_status varchar(255) NOT NULL,
+    trade_pair_2 varchar(255) NOT NULL,
+    trade_pair_2_side varchar(255) NOT NULL,
+    trade_pair_2_sequence int NOT NULL,
+    trade_pair_2_requested_size double precision NOT NULL,
+    trade_pair_2_filled_size double precision NOT NULL,
+    trade_pair_2_average_price double precision NOT NULL,
+    trade_pair_2_fee double precision NOT NULL,
+    trade_pair_2_status varchar(255) NOT NULL,
+    trade_pair_3 varchar(255) NOT NULL,
+    trade_pair_3_side varchar(255) NOT NULL,
+    trade_pair_3_sequence int NOT NULL,
+    trade_pair_3_requested_size double precision NOT NULL,
+    trade_pair_3_filled_size double precision NOT NULL,
+    trade_pair_3_average_price double precision NOT NULL,
+    trade_pair_3_fee double precision NOT NULL,
+    trade_pair_3_status varchar(255) NOT NULL,
+    reason varchar(1024) NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    uuid varchar(255) NOT NULL,
+    timestamp timestamptz NOT NULL,
+    transaction_uuid varchar(255) NOT NULL,
+    reference varchar(255) NOT NULL,
+    account varchar(255) NOT NULL,
+    exchange varchar(255) NOT NULL,
+    asset varchar(255) NOT NULL,
+    amount double precision NOT NULL,
+    value double precision NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entries_transaction_uuid ON ledger_entries (transaction_uuid);
+
+CREATE TABLE IF NOT EXISTS pnl_daily (
+    period date NOT NULL,
+    base_currency varchar(255) NOT NULL,
+    realized_pnl double precision NOT NULL,
+    unrealized_pnl double precision NOT NULL,
+    fees double precision NOT NULL,
+    total_pnl double precision NOT NULL,
+    updated_at timestamptz NOT NULL,
+    PRIMARY KEY (period, base_currency)
+);
+
+CREATE TABLE IF NOT EXISTS pnl_monthly (
+    period date NOT NULL,
+    base_currency varchar(255) NOT NULL,
+    realized_pnl double precision NOT NULL,
+    unrealized_pnl double precision NOT NULL,
+    fees double precision NOT NULL,
+    total_pnl double precision NOT NULL,
+    updated_at timestamptz NOT NULL,
+    PRIMARY KEY (period, base_currency)
+);
+
+CREATE TABLE IF NOT EXISTS trade_analysis_records (
+    uuid varchar(255) NOT NULL,
+    timestamp timestamptz NOT NULL,
+    arbitrage_record_uuid varchar(255) NOT NULL,
+    currency varchar(255) NOT NULL,
+    buy_exchange varchar(255) NOT NULL,
+    sell_exchange varchar(255) NOT NULL,
+    size double precision NOT NULL,
+    matched_size double precision NOT NULL,
+    projected_profit double precision NOT NULL,
+    realized_profit double precision NOT NULL,
+    slippage double precision NOT NULL,
+    fee_difference double precision NOT NULL,
+    latency_decay double precision NOT NULL,
+    partial_fill_loss double precision NOT NULL,
+    correction_loss double precision NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS trade_analysis_records_exchange_pair ON trade_analysis_records (buy_exchange, sell_exchange);
+
+CREATE OR REPLACE VIEW trade_analysis_by_exchange_pair AS
+SELECT
+    currency,
+    buy_exchange,
+    sell_exchange,
+    COUNT(*) AS trades,
+    SUM(projected_profit) AS projected_profit,
+    SUM(realized_profit) AS realized_profit,
+    SUM(slippage) AS slippage,
+    SUM(fee_difference) AS fee_difference,
+    SUM(latency_decay) AS latency_decay,
+    SUM(partial_fill_loss) AS partial_fill_loss,
+    SUM(correction_loss) AS correction_loss,
+    SUM(matched_size) / SUM(size) AS fill_ratio
+FROM trade_analysis_records
+GROUP BY currency, buy_exchange, sell_exchange;
+
+CREATE TABLE IF NOT EXISTS spread_signal_records (
+    uuid varchar(255) NOT NULL,
+    timestamp timestamptz NOT NULL,
+    currency varchar(255) NOT NULL,
+    exchange_a varchar(255) NOT NULL,
+    price_a double precision NOT NULL,
+    exchange_b varchar(255) NOT NULL,
+    price_b double precision NOT NULL,
+    spread double precision NOT NULL,
+    mean double precision NOT NULL,
+    standard_deviation double precision NOT NULL,
+    z_score double precision NOT NULL,
+    action varchar(255) NOT NULL,
+    direction varchar(255) NOT NULL,
+    entry_uuid varchar(255) NOT NULL,
+    spread_change double precision NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS spread_signal_records_entry_uuid ON spread_signal_records (entry_uuid);
+
+CREATE TABLE IF NOT EXISTS market_trades (
+    uuid varchar(255) NOT NULL,
+    exchange varchar(255) NOT NULL,
+    pair varchar(255) NOT NULL,
+    currency varchar(255) NOT NULL,
+    timestamp timestamptz NOT NULL,
+    price double precision NOT NULL,
+    volume double precision NOT NULL,
+    side varchar(255) NOT NULL,
+    order_type varchar(255) NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS market_trades_currency_timestamp ON market_trades (currency, timestamp);
+
+CREATE TABLE IF NOT EXISTS market_candles (
+    exchange varchar(255) NOT NULL,
+    pair varchar(255) NOT NULL,
+    currency varchar(255) NOT NULL,
+    interval_minutes int NOT NULL,
+    timestamp timestamptz NOT NULL,
+    open double precision NOT NULL,
+    high double precision NOT NULL,
+    low double precision NOT NULL,
+    close double precision NOT NULL,
+    vwap double precision NOT NULL,
+    volume double precision NOT NULL,
+    count int NOT NULL,
+    PRIMARY KEY (exchange, pair, interval_minutes, timestamp)
+);
+
+CREATE TABLE IF NOT EXISTS market_data_cursors (
+    exchange varchar(255) NOT NULL,
+    pair varchar(255) NOT NULL,
+    kind varchar(255) NOT NULL,
+    cursor bigint NOT NULL,
+    updated_at timestamptz NOT NULL,
+    PRIMARY KEY (exchange, pair, kind)
+);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS price_records_exchange_timestamp;
+DROP INDEX IF EXISTS price_records_currency_timestamp;
+DROP INDEX IF EXISTS price_records_timestamp;
+
+DROP INDEX IF EXISTS triangular_arbitrage_1_exchange_exchange_timestamp;
+DROP INDEX IF EXISTS triangular_arbitrage_1_exchange_timestamp;
+
+DROP INDEX IF EXISTS arbitrage_records_currency_timestamp;
+DROP INDEX IF EXISTS arbitrage_records_timestamp;
@@ -0,0 +1,10 @@
+-- The columns of PostgreSQL were typed from the start, only the indexes are new.
+CREATE INDEX IF NOT EXISTS arbitrage_records_timestamp ON arbitrage_records (timestamp);
+CREATE INDEX IF NOT EXISTS arbitrage_records_currency_timestamp ON arbitrage_records (currency, timestamp);
+
+CREATE INDEX IF NOT EXISTS triangular_arbitrage_1_exchange_timestamp ON triangular_arbitrage_1_exchange (timestamp);
+CREATE INDEX IF NOT EXISTS triangular_arbitrage_1_exchange_exchange_timestamp ON triangular_arbitrage_1_exchange (exchange, timestamp);
+
+CREATE INDEX IF NOT EXISTS price_records_timestamp ON price_records (timestamp);
+CREATE INDEX IF NOT EXISTS price_records_currency_timestamp ON price_records (currency, timestamp);
+CREATE INDEX IF NOT EXISTS price_records_exchange_timestamp ON price_records (exchange, timestamp);
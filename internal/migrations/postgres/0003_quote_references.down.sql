@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS price_records_arbitrage_record_uuid;
+
+ALTER TABLE triangular_arbitrage_1_exchange
+    DROP COLUMN IF EXISTS trade_pair_1_price_record_uuid,
+    DROP COLUMN IF EXISTS trade_pair_2_price_record_uuid,
+    DROP COLUMN IF EXISTS trade_pair_3_price_record_uuid;
+
+ALTER TABLE arbitrage_records
+    DROP COLUMN IF EXISTS price_record_uuid_a,
+    DROP COLUMN IF EXISTS price_record_uuid_b;
@@ -0,0 +1,10 @@
+ALTER TABLE arbitrage_records
+    ADD COLUMN IF NOT EXISTS price_record_uuid_a varchar(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS price_record_uuid_b varchar(255) NOT NULL DEFAULT '';
+
+ALTER TABLE triangular_arbitrage_1_exchange
+    ADD COLUMN IF NOT EXISTS trade_pair_1_price_record_uuid varchar(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS trade_pair_2_price_record_uuid varchar(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS trade_pair_3_price_record_uuid varchar(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS price_records_arbitrage_record_uuid ON price_records (arbitrage_record_uuid);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS spread_aggregates;
+DROP TABLE IF EXISTS price_aggregates;
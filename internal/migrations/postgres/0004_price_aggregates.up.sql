@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS price_aggregates (
+    exchange varchar(255) NOT NULL,
+    currency varchar(255) NOT NULL,
+    interval_minutes int NOT NULL,
+    timestamp timestamptz NOT NULL,
+    open double precision NOT NULL,
+    high double precision NOT NULL,
+    low double precision NOT NULL,
+    close double precision NOT NULL,
+    average double precision NOT NULL,
+    count int NOT NULL,
+    PRIMARY KEY (exchange, currency, interval_minutes, timestamp)
+);
+CREATE INDEX IF NOT EXISTS price_aggregates_interval_timestamp ON price_aggregates (interval_minutes, timestamp);
+
+CREATE TABLE IF NOT EXISTS spread_aggregates (
+    currency varchar(255) NOT NULL,
+    exchange_a varchar(255) NOT NULL,
+    exchange_b varchar(255) NOT NULL,
+    interval_minutes int NOT NULL,
+    timestamp timestamptz NOT NULL,
+    open double precision NOT NULL,
+    high double precision NOT NULL,
+    low double precision NOT NULL,
+    close double precision NOT NULL,
+    average double precision NOT NULL,
+    count int NOT NULL,
+    PRIMARY KEY (currency, exchange_a, exchange_b, interval_minutes, timestamp)
+);
+CREATE INDEX IF NOT EXISTS spread_aggregates_interval_timestamp ON spread_aggregates (interval_minutes, timestamp);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS cycle_snapshots (
+    uuid varchar(255) NOT NULL,
+    timestamp timestamptz NOT NULL,
+    quotes int NOT NULL DEFAULT 0,
+    arbitrage_events int NOT NULL DEFAULT 0,
+    triangular_arbitrage_events int NOT NULL DEFAULT 0,
+    spread_signals int NOT NULL DEFAULT 0,
+    opportunities int NOT NULL DEFAULT 0,
+    PRIMARY KEY (uuid)
+);
+CREATE INDEX IF NOT EXISTS cycle_snapshots_timestamp ON cycle_snapshots (timestamp);
+
+ALTER TABLE price_records ADD COLUMN IF NOT EXISTS cycle_uuid varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE arbitrage_records ADD COLUMN IF NOT EXISTS cycle_uuid varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE triangular_arbitrage_1_exchange ADD COLUMN IF NOT EXISTS cycle_uuid varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE spread_signal_records ADD COLUMN IF NOT EXISTS cycle_uuid varchar(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS price_records_cycle_uuid ON price_records (cycle_uuid);
+CREATE INDEX IF NOT EXISTS arbitrage_records_cycle_uuid ON arbitrage_records (cycle_uuid);
+CREATE INDEX IF NOT EXISTS triangular_arbitrage_1_exchange_cycle_uuid ON triangular_arbitrage_1_exchange (cycle_uuid);
+CREATE INDEX IF NOT EXISTS spread_signal_records_cycle_uuid ON spread_signal_records (cycle_uuid);
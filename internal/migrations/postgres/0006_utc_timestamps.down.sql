@@ -0,0 +1 @@
+-- Version 6 changed nothing in PostgreSQL.
@@ -0,0 +1 @@
+-- timestamptz columns store microseconds and convert every offset to UTC, the timestamps of PostgreSQL need no change.
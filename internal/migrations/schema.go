@@ -0,0 +1,417 @@
+package migrations
+
+import (
+	"cryptoArbitrageBot/internal/utils"
+	"database/sql"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"strings"
+)
+
+/*
+The kind of values a column holds. The type names differ between the databases, e.g. a text column is varchar(255) in
+MySQL and PostgreSQL and text in SQLite.
+*/
+type columnType string
+
+const (
+	textColumn      columnType = "text"
+	numberColumn    columnType = "number"
+	integerColumn   columnType = "integer"
+	booleanColumn   columnType = "boolean"
+	timestampColumn columnType = "timestamp"
+	dateColumn      columnType = "date"
+)
+
+type columnGroup struct {
+	Type    columnType
+	Columns []string
+}
+
+/*
+A table the bot uses and the privileges it needs on it. A read-only connection only needs SELECT.
+*/
+type requiredTable struct {
+	Name       string
+	Privileges []string
+	Columns    []columnGroup
+}
+
+/*
+The tables and columns of the latest schema version that the bot uses. A migration that adds or changes a table or a
+column has to change them too.
+*/
+var requiredTables = []requiredTable{
+	{
+		Name:       "arbitrage_records",
+		Privileges: []string{"SELECT", "INSERT"},
+		Columns: []columnGroup{
+			{textColumn, []string{"uuid", "currency", "exchange_a", "exchange_b", "price_record_uuid_a", "price_record_uuid_b", "cycle_uuid"}},
+			{timestampColumn, []string{"timestamp"}},
+			{numberColumn, []string{"price_a", "price_b", "projected_profit", "trade_size"}},
+			{booleanColumn, []string{"is_arbitrage_opportunity", "is_blocked_by_inventory"}},
+		},
+	},
+	{
+		Name:       "cycle_snapshots",
+		Privileges: []string{"SELECT", "INSERT"},
+		Columns: []columnGroup{
+			{textColumn, []string{"uuid"}},
+			{timestampColumn, []string{"timestamp"}},
+			{integerColumn, []string{"quotes", "arbitrage_events", "triangular_arbitrage_events", "spread_signals", "opportunities"}},
+		},
+	},
+	{
+		Name:       "ledger_entries",
+		Privileges: []string{"SELECT", "INSERT"},
+		Columns: []columnGroup{
+			{textColumn, []string{"uuid", "transaction_uuid", "reference", "account", "exchange", "asset"}},
+			{timestampColumn, []string{"timestamp"}},
+			{numberColumn, []string{"amount", "value"}},
+		},
+	},
+	{
+		Name:       "leg_correction_records",
+		Privileges: []string{"SELECT", "INSERT"},
+		Columns: []columnGroup{
+			{textColumn, []string{"uuid", "arbitrage_record_uuid", "action", "exchange", "currency", "side", "status", "reason"}},
+			{timestampColumn, []string{"timestamp"}},
+			{numberColumn, []string{"requested_size", "filled_size", "average_price"}},
+		},
+	},
+	{
+		Name:       "market_candles",
+		Privileges: []string{"SELECT", "INSERT", "UPDATE"},
+		Columns: []columnGroup{
+			{textColumn, []string{"exchange", "pair", "currency"}},
+			{integerColumn, []string{"interval_minutes", "count"}},
+			{timestampColumn, []string{"timestamp"}},
+			{numberColumn, []string{"open", "high", "low", "close", "vwap", "volume"}},
+		},
+	},
+	{
+		Name:       "market_data_cursors",
+		Privileges: []string{"SELECT", "INSERT", "UPDATE"},
+		Columns: []columnGroup{
+			{textColumn, []string{"exchange", "pair", "kind"}},
+			{integerColumn, []string{"cursor"}},
+			{timestampColumn, []string{"updated_at"}},
+		},
+	},
+	{
+		Name:       "market_trades",
+		Privileges: []string{"SELECT", "INSERT"},
+		Columns: []columnGroup{
+			{textColumn, []string{"uuid", "exchange", "pair", "currency", "side", "order_type"}},
+			{timestampColumn, []string{"timestamp"}},
+			{numberColumn, []string{"price", "volume"}},
+		},
+	},
+	{
+		Name:       "order_records",
+		Privileges: []string{"SELECT", "INSERT", "UPDATE"},
+		Columns: []columnGroup{
+			{textColumn, []string{"uuid", "arbitrage_record_uuid", "leg", "client_order_id", "exchange", "order_id", "currency", "side", "type", "state", "reason"}},
+			{timestampColumn, []string{"timestamp", "updated_at"}},
+			{numberColumn, []string{"price", "size", "filled_size", "average_price", "fee"}},
+		},
+	},
+	{
+		Name:       "pnl_daily",
+		Privileges: []string{"SELECT", "INSERT", "UPDATE"},
+		Columns: []columnGroup{
+			{dateColumn, []string{"period"}},
+			{textColumn, []string{"base_currency"}},
+			{numberColumn, []string{"realized_pnl", "unrealized_pnl", "fees", "total_pnl"}},
+			{timestampColumn, []string{"updated_at"}},
+		},
+	},
+	{
+		Name:       "pnl_monthly",
+		Privileges: []string{"SELECT", "INSERT", "UPDATE"},
+		Columns: []columnGroup{
+			{dateColumn, []string{"period"}},
+			{textColumn, []string{"base_currency"}},
+			{numberColumn, []string{"realized_pnl", "unrealized_pnl", "fees", "total_pnl"}},
+			{timestampColumn, []string{"updated_at"}},
+		},
+	},
+	{
+		Name:       "price_aggregates",
+		Privileges: []string{"SELECT", "INSERT", "UPDATE"},
+		Columns: []columnGroup{
+			{textColumn, []string{"exchange", "currency"}},
+			{integerColumn, []string{"interval_minutes", "count"}},
+			{timestampColumn, []string{"timestamp"}},
+			{numberColumn, []string{"open", "high", "low", "close", "average"}},
+		},
+	},
+	{
+		Name:       "price_records",
+		Privileges: []string{"SELECT", "INSERT", "DELETE"},
+		Columns: []columnGroup{
+			{textColumn, []string{"uuid", "currency", "exchange", "arbitrage_record_uuid", "cycle_uuid"}},
+			{timestampColumn, []string{"timestamp"}},
+			{numberColumn, []string{"price", "fee"}},
+			{booleanColumn, []string{"is_arbitrage_opportunity"}},
+		},
+	},
+	{
+		Name:       "spread_aggregates",
+		Privileges: []string{"SELECT", "INSERT", "UPDATE"},
+		Columns: []columnGroup{
+			{textColumn, []string{"currency", "exchange_a", "exchange_b"}},
+			{integerColumn, []string{"interval_minutes", "count"}},
+			{timestampColumn, []string{"timestamp"}},
+			{numberColumn, []string{"open", "high", "low", "close", "average"}},
+		},
+	},
+	{
+		Name:       "spread_signal_records",
+		Privileges: []string{"SELECT", "INSERT"},
+		Columns: []columnGroup{
+			{textColumn, []string{"uuid", "currency", "exchange_a", "exchange_b", "action", "direction", "entry_uuid", "cycle_uuid"}},
+			{timestampColumn, []string{"timestamp"}},
+			{numberColumn, []string{"price_a", "price_b", "spread", "mean", "standard_deviation", "z_score", "spread_change"}},
+		},
+	},
+	{
+		Name:       "trade_analysis_records",
+		Privileges: []string{"SELECT", "INSERT"},
+		Columns: []columnGroup{
+			{textColumn, []string{"uuid", "arbitrage_record_uuid", "currency", "buy_exchange", "sell_exchange"}},
+			{timestampColumn, []string{"timestamp"}},
+			{numberColumn, []string{"size", "matched_size", "projected_profit", "realized_profit", "slippage", "fee_difference", "latency_decay", "partial_fill_loss", "correction_loss"}},
+		},
+	},
+	{
+		Name:       "transfer_records",
+		Privileges: []string{"SELECT", "INSERT", "UPDATE"},
+		Columns: []columnGroup{
+			{textColumn, []string{"uuid", "asset", "from_exchange", "to_exchange", "address", "reference_id", "status", "reason"}},
+			{timestampColumn, []string{"timestamp", "updated_at"}},
+			{numberColumn, []string{"amount", "estimated_fee"}},
+		},
+	},
+	{
+		Name:       "triangular_arbitrage_1_exchange",
+		Privileges: []string{"SELECT", "INSERT"},
+		Columns: []columnGroup{
+			{textColumn, []string{"uuid", "exchange", "trade_pair_1", "trade_pair_2", "trade_pair_3", "trade_pair_1_price_record_uuid", "trade_pair_2_price_record_uuid", "trade_pair_3_price_record_uuid", "cycle_uuid"}},
+			{timestampColumn, []string{"timestamp"}},
+			{numberColumn, []string{"trade_pair_1_exchange_rate", "trade_pair_2_exchange_rate", "trade_pair_3_exchange_rate", "cross_exchange_rate_difference"}},
+			{booleanColumn, []string{"is_triangular_arbitrage_opportunity"}},
+		},
+	},
+	{
+		Name:       "triangular_execution_records",
+		Privileges: []string{"SELECT", "INSERT"},
+		Columns: []columnGroup{
+			{textColumn, []string{"uuid", "triangular_arbitrage_record_uuid", "exchange", "status", "trade_pair_1", "trade_pair_1_side", "trade_pair_1_status", "trade_pair_2", "trade_pair_2_side", "trade_pair_2_status", "trade_pair_3", "trade_pair_3_side", "trade_pair_3_status", "reason"}},
+			{timestampColumn, []string{"timestamp"}},
+			{numberColumn, []string{"predicted_cross_exchange_rate_difference", "realized_cross_exchange_rate", "realized_cross_exchange_rate_difference", "trade_pair_1_requested_size", "trade_pair_1_filled_size", "trade_pair_1_average_price", "trade_pair_1_fee", "trade_pair_2_requested_size", "trade_pair_2_filled_size", "trade_pair_2_average_price", "trade_pair_2_fee", "trade_pair_3_requested_size", "trade_pair_3_filled_size", "trade_pair_3_average_price", "trade_pair_3_fee"}},
+			{integerColumn, []string{"trade_pair_1_sequence", "trade_pair_2_sequence", "trade_pair_3_sequence"}},
+		},
+	},
+}
+
+/*
+The problems found by Validate, e.g. a missing table or column, a column of another type or a missing privilege.
+*/
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("The database schema is not usable by the bot: %s", strings.Join(e.Problems, "; "))
+}
+
+/*
+Verify that the database has every table and column the bot uses with the expected types, and that the user of the bot
+has the privileges it needs on them, only SELECT if readOnly. Nothing is written, so that a read-only replica can be
+used. Tables, columns and types are read from information_schema (sqlite_master and table_info in SQLite), privileges
+from the grants of the user in information_schema in MySQL and from has_table_privilege in PostgreSQL, which includes
+the privileges of roles and superusers. SQLite has no privileges. Every problem is reported, not only the first one.
+*/
+func Validate(db *sql.DB, dialect string, readOnly bool) error {
+	columns, err := readColumns(db, dialect)
+	if err != nil {
+		return &MigrationError{Msg: fmt.Sprintf("unable to read the columns of the database: %v", err)}
+	}
+
+	var problems []string
+	var existingTables []requiredTable
+	for _, table := range requiredTables {
+		dataTypes, ok := columns[table.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("missing table %s", table.Name))
+			continue
+		}
+		existingTables = append(existingTables, table)
+		for _, group := range table.Columns {
+			for _, column := range group.Columns {
+				dataType, ok := dataTypes[column]
+				if !ok {
+					problems = append(problems, fmt.Sprintf("missing column %s.%s (%s)", table.Name, column, group.Type))
+				} else if columnTypeOf(dataType) != group.Type {
+					problems = append(problems, fmt.Sprintf("column %s.%s is %s, expected %s", table.Name, column, dataType, group.Type))
+				}
+			}
+		}
+	}
+
+	missing, err := missingPrivileges(db, dialect, existingTables, readOnly)
+	if err != nil {
+		return &MigrationError{Msg: fmt.Sprintf("unable to read the privileges of the database user: %v", err)}
+	}
+	problems = append(problems, missing...)
+
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	utils.Logger.Info(fmt.Sprintf("Database schema has the %d tables the bot uses with their columns and privileges.", len(requiredTables)))
+	return nil
+}
+
+/*
+Returns the data type of every column of the database by table.
+*/
+func readColumns(db *sql.DB, dialect string) (map[string]map[string]string, error) {
+	database := goqu.New(dialect, db)
+	var query *goqu.SelectDataset
+	switch dialect {
+	case "sqlite3":
+		query = database.From(goqu.T("sqlite_master").As("m")).CrossJoin(goqu.L("pragma_table_info(m.name) AS p")).Select(goqu.I("m.name"), goqu.I("p.name"), goqu.I("p.type")).Where(goqu.Ex{"m.type": "table"})
+	case "postgres":
+		query = database.From(goqu.S("information_schema").Table("columns")).Select("table_name", "column_name", "data_type").Where(goqu.Ex{"table_schema": goqu.L("current_schema()")})
+	default:
+		query = database.From(goqu.S("information_schema").Table("columns")).Select("table_name", "column_name", "data_type").Where(goqu.Ex{"table_schema": goqu.L("DATABASE()")})
+	}
+	querySQL, _, _ := query.ToSQL()
+	rows, err := db.Query(querySQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]map[string]string)
+	for rows.Next() {
+		var table, column, dataType string
+		if err := rows.Scan(&table, &column, &dataType); err != nil {
+			return nil, err
+		}
+		if columns[table] == nil {
+			columns[table] = make(map[string]string)
+		}
+		columns[table][column] = dataType
+	}
+	return columns, rows.Err()
+}
+
+/*
+Returns the kind of values of a data type of MySQL, PostgreSQL or SQLite. MySQL stores booleans as tinyint.
+*/
+func columnTypeOf(dataType string) columnType {
+	dataType = strings.ToLower(dataType)
+	switch {
+	case dataType == "boolean" || dataType == "bool" || strings.HasPrefix(dataType, "tinyint"):
+		return booleanColumn
+	case strings.Contains(dataType, "char") || strings.Contains(dataType, "text"):
+		return textColumn
+	case strings.HasPrefix(dataType, "timestamp") || strings.HasPrefix(dataType, "datetime"):
+		return timestampColumn
+	case dataType == "date":
+		return dateColumn
+	case strings.Contains(dataType, "int"):
+		return integerColumn
+	case strings.HasPrefix(dataType, "double") || strings.HasPrefix(dataType, "real") || strings.HasPrefix(dataType, "float") || strings.HasPrefix(dataType, "decimal") || strings.HasPrefix(dataType, "numeric"):
+		return numberColumn
+	}
+	return columnType(dataType)
+}
+
+/*
+Returns the privileges the user of the bot lacks, one problem per table.
+*/
+func missingPrivileges(db *sql.DB, dialect string, tables []requiredTable, readOnly bool) ([]string, error) {
+	var isGranted func(table string, privilege string) (bool, error)
+	switch dialect {
+	case "sqlite3":
+		return nil, nil
+	case "postgres":
+		database := goqu.New(dialect, db)
+		isGranted = func(table string, privilege string) (bool, error) {
+			var granted bool
+			_, err := database.Select(goqu.Func("has_table_privilege", goqu.L("quote_ident(current_schema()) || '.' || quote_ident(?)", table), privilege)).ScanVal(&granted)
+			return granted, err
+		}
+	default:
+		grants, err := mySqlGrants(db)
+		if err != nil {
+			return nil, err
+		}
+		isGranted = func(table string, privilege string) (bool, error) {
+			return grants[""][privilege] || grants[table][privilege], nil
+		}
+	}
+
+	var missing []string
+	for _, table := range tables {
+		privileges := table.Privileges
+		if readOnly {
+			privileges = []string{"SELECT"}
+		}
+		var lacking []string
+		for _, privilege := range privileges {
+			granted, err := isGranted(table.Name, privilege)
+			if err != nil {
+				return nil, err
+			}
+			if !granted {
+				lacking = append(lacking, privilege)
+			}
+		}
+		if len(lacking) > 0 {
+			missing = append(missing, fmt.Sprintf("missing privilege %s on %s", strings.Join(lacking, ", "), table.Name))
+		}
+	}
+	return missing, nil
+}
+
+/*
+Returns the privileges granted to the current MySQL user by table, those on every table of the database (global or on
+the database) under "". Privileges granted through roles are not included.
+*/
+func mySqlGrants(db *sql.DB) (map[string]map[string]bool, error) {
+	database := goqu.New("mysql", db)
+	var currentUser string
+	if _, err := database.Select(goqu.L("CURRENT_USER()")).ScanVal(&currentUser); err != nil {
+		return nil, err
+	}
+	// CURRENT_USER() returns name@host, information_schema names the user 'name'@'host'
+	at := strings.LastIndex(currentUser, "@")
+	grantee := fmt.Sprintf("'%s'@'%s'", currentUser[:at], currentUser[at+1:])
+
+	query := database.From(goqu.S("information_schema").Table("user_privileges")).Select(goqu.V("").As("table_name"), goqu.C("privilege_type")).Where(goqu.Ex{"grantee": grantee}).
+		UnionAll(database.From(goqu.S("information_schema").Table("schema_privileges")).Select(goqu.V("").As("table_name"), goqu.C("privilege_type")).Where(goqu.Ex{"grantee": grantee, "table_schema": goqu.L("DATABASE()")})).
+		UnionAll(database.From(goqu.S("information_schema").Table("table_privileges")).Select(goqu.C("table_name"), goqu.C("privilege_type")).Where(goqu.Ex{"grantee": grantee, "table_schema": goqu.L("DATABASE()")}))
+	querySQL, _, _ := query.ToSQL()
+	rows, err := db.Query(querySQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, privilege string
+		if err := rows.Scan(&table, &privilege); err != nil {
+			return nil, err
+		}
+		if grants[table] == nil {
+			grants[table] = make(map[string]bool)
+		}
+		grants[table][strings.ToUpper(privilege)] = true
+	}
+	return grants, rows.Err()
+}
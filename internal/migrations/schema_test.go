@@ -0,0 +1,127 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/magiconair/properties/assert"
+	"path/filepath"
+	"testing"
+)
+
+/*
+Returns a SQLite database migrated to the latest version and the path of its file.
+*/
+func latestSqliteForTest(t *testing.T) (*sql.DB, string) {
+	path := filepath.Join(t.TempDir(), "crypto_arbitrage_bot.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	latest, err := LatestVersion("sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(db, "sqlite3", latest); err != nil {
+		t.Fatal(err)
+	}
+	return db, path
+}
+
+func Test_Validate(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	t.Run("The latest schema has every table and column the bot uses.", func(t *testing.T) {
+		db, _ := latestSqliteForTest(t)
+		assert.Equal(t, Validate(db, "sqlite3", false), nil)
+	})
+
+	t.Run("A read-only database can be validated.", func(t *testing.T) {
+		_, path := latestSqliteForTest(t)
+		readOnly, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+		assert.Equal(t, err, nil)
+		defer readOnly.Close()
+		_, err = readOnly.Exec("DELETE FROM arbitrage_records")
+		assert.Equal(t, err != nil, true)
+		assert.Equal(t, Validate(readOnly, "sqlite3", false), nil)
+	})
+
+	t.Run("Every table and column of the latest schema is required.", func(t *testing.T) {
+		db, _ := latestSqliteForTest(t)
+		columns, err := readColumns(db, "sqlite3")
+		assert.Equal(t, err, nil)
+		delete(columns, VersionTable)
+
+		required := make(map[string]int)
+		for _, table := range requiredTables {
+			for _, group := range table.Columns {
+				required[table.Name] += len(group.Columns)
+			}
+		}
+		assert.Equal(t, len(required), len(columns))
+		for table, dataTypes := range columns {
+			assert.Equal(t, required[table], len(dataTypes), table)
+		}
+	})
+
+	tests := []struct {
+		name         string
+		statements   []string
+		wantProblems []string
+	}{
+		{
+			name:         "A missing table is reported.",
+			statements:   []string{"DROP TABLE cycle_snapshots"},
+			wantProblems: []string{"missing table cycle_snapshots"},
+		},
+		{
+			name:         "Every missing column is reported.",
+			statements:   []string{"DROP INDEX price_records_cycle_uuid", "ALTER TABLE price_records DROP COLUMN cycle_uuid", "ALTER TABLE pnl_daily DROP COLUMN fees"},
+			wantProblems: []string{"missing column pnl_daily.fees (number)", "missing column price_records.cycle_uuid (text)"},
+		},
+		{
+			name:         "A column of another type is reported.",
+			statements:   []string{"ALTER TABLE cycle_snapshots DROP COLUMN quotes", "ALTER TABLE cycle_snapshots ADD COLUMN quotes text NOT NULL DEFAULT ''"},
+			wantProblems: []string{"column cycle_snapshots.quotes is TEXT, expected integer"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _ := latestSqliteForTest(t)
+			for _, statement := range tt.statements {
+				_, err := db.Exec(statement)
+				assert.Equal(t, err, nil)
+			}
+
+			err := Validate(db, "sqlite3", false)
+			schemaErr, ok := err.(*SchemaError)
+			assert.Equal(t, ok, true)
+			assert.Equal(t, schemaErr.Problems, tt.wantProblems)
+		})
+	}
+}
+
+func Test_columnTypeOf(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name      string
+		dataTypes []string
+		want      columnType
+	}{
+		{name: "Text columns.", dataTypes: []string{"varchar", "character varying", "text", "TEXT", "varchar(255)"}, want: textColumn},
+		{name: "Number columns.", dataTypes: []string{"double", "double precision", "REAL", "decimal"}, want: numberColumn},
+		{name: "Integer columns.", dataTypes: []string{"int", "integer", "INTEGER", "bigint"}, want: integerColumn},
+		{name: "Boolean columns, MySQL stores them as tinyint.", dataTypes: []string{"tinyint", "boolean"}, want: booleanColumn},
+		{name: "Timestamp columns.", dataTypes: []string{"timestamp", "timestamp with time zone", "datetime"}, want: timestampColumn},
+		{name: "Date columns.", dataTypes: []string{"date"}, want: dateColumn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, dataType := range tt.dataTypes {
+				assert.Equal(t, columnTypeOf(dataType), tt.want, dataType)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+DROP VIEW IF EXISTS trade_analysis_by_exchange_pair;
+DROP TABLE IF EXISTS market_data_cursors;
+DROP TABLE IF EXISTS market_candles;
+DROP TABLE IF EXISTS market_trades;
+DROP TABLE IF EXISTS spread_signal_records;
+DROP TABLE IF EXISTS trade_analysis_records;
+DROP TABLE IF EXISTS pnl_monthly;
+DROP TABLE IF EXISTS pnl_daily;
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS triangular_execution_records;
+DROP TABLE IF EXISTS order_records;
+DROP TABLE IF EXISTS transfer_records;
+DROP TABLE IF EXISTS leg_correction_records;
+DROP TABLE IF EXISTS price_records;
+DROP TABLE IF EXISTS triangular_arbitrage_1_exchange;
+DROP TABLE IF EXISTS arbitrage_records;
@@ -0,0 +1,271 @@
+CREATE TABLE IF NOT EXISTS arbitrage_records (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    currency text NOT NULL,
+    exchange_a text NOT NULL,
+    price_a real NOT NULL,
+    exchange_b text NOT NULL,
+    price_b real NOT NULL,
+    projected_profit real NOT NULL,
+    is_arbitrage_opportunity boolean NOT NULL,
+    trade_size real NOT NULL DEFAULT 0,
+    is_blocked_by_inventory boolean NOT NULL DEFAULT 0,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS triangular_arbitrage_1_exchange (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    exchange text NOT NULL,
+    trade_pair_1 text NOT NULL,
+    trade_pair_1_exchange_rate real NOT NULL,
+    trade_pair_2 text NOT NULL,
+    trade_pair_2_exchange_rate real NOT NULL,
+    trade_pair_3 text NOT NULL,
+    trade_pair_3_exchange_rate real NOT NULL,
+    cross_exchange_rate_difference real NOT NULL,
+    is_triangular_arbitrage_opportunity boolean NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS price_records (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    currency text NOT NULL,
+    price real NOT NULL,
+    fee real NOT NULL,
+    exchange text NOT NULL,
+    arbitrage_record_uuid text NOT NULL,
+    is_arbitrage_opportunity boolean NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS leg_correction_records (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    arbitrage_record_uuid text NOT NULL,
+    action text NOT NULL,
+    exchange text NOT NULL,
+    currency text NOT NULL,
+    side text NOT NULL,
+    requested_size real NOT NULL,
+    filled_size real NOT NULL,
+    average_price real NOT NULL,
+    status text NOT NULL,
+    reason text NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS transfer_records (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    asset text NOT NULL,
+    from_exchange text NOT NULL,
+    to_exchange text NOT NULL,
+    amount real NOT NULL,
+    estimated_fee real NOT NULL,
+    address text NOT NULL,
+    reference_id text NOT NULL,
+    status text NOT NULL,
+    reason text NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS order_records (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    arbitrage_record_uuid text NOT NULL,
+    leg text NOT NULL,
+    client_order_id text NOT NULL,
+    exchange text NOT NULL,
+    order_id text NOT NULL,
+    currency text NOT NULL,
+    side text NOT NULL,
+    type text NOT NULL,
+    price real NOT NULL,
+    size real NOT NULL,
+    filled_size real NOT NULL,
+    average_price real NOT NULL,
+    fee real NOT NULL,
+    state text NOT NULL,
+    reason text NOT NULL,
+    PRIMARY KEY (uuid),
+    UNIQUE (client_order_id)
+);
+
+CREATE INDEX IF NOT EXISTS order_records_state ON order_records (state);
+
+CREATE TABLE IF NOT EXISTS triangular_execution_records (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    triangular_arbitrage_record_uuid text NOT NULL,
+    exchange text NOT NULL,
+    status text NOT NULL,
+    predicted_cross_exchange_rate_difference real NOT NULL,
+    realized_cross_exchange_rate real NOT NULL,
+    realized_cross_exchange_rate_difference real NOT NULL,
+    trade_pair_1 text NOT NULL,
+    trade_pair_1_side text NOT NULL,
+    trade_pair_1_sequence integer NOT NULL,
+    trade_pair_1_requested_size real NOT NULL,
+    trade_pair_1_filled_size real NOT NULL,
+    trade_pair_1_average_price real NOT NULL,
+    trade_pair_1_fee real NOT NULL,
+    trade_pair_1_status text NOT NULL,
+    trade_pair_2 text NOT NULL,
+    trade_pair_2_side text NOT NULL,
+    trade_pair_2_sequence integer NOT NULL,
+    trade_pair_2_requested_size real NOT NULL,
+    trade_pair_2_filled_size real NOT NULL,
+    trade_pair_2_average_price real NOT NULL,
+    trade_pair_2_fee real NOT NULL,
+    trade_pair_2_status text NOT NULL,
+    trade_pair_3 text NOT NULL,
+    trade_pair_3_side text NOT NULL,
+    trade_pair_3_sequence integer NOT NULL,
+    trade_pair_3_requested_size real NOT NULL,
+    trade_pair_3_filled_size real NOT NULL,
+    trade_pair_3_average_price real NOT NULL,
+    trade_pair_3_fee real NOT NULL,
+    trade_pair_3_status text NOT NULL,
+    reason text NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    transaction_uuid text NOT NULL,
+    reference text NOT NULL,
+    account text NOT NULL,
+    exchange text NOT NULL,
+    asset text NOT NULL,
+    amount real NOT NULL,
+    value real NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entries_transaction_uuid ON ledger_entries (transaction_uuid);
+
+CREATE TABLE IF NOT EXISTS pnl_daily (
+    period date NOT NULL,
+    base_currency text NOT NULL,
+    realized_pnl real NOT NULL,
+    unrealized_pnl real NOT NULL,
+    fees real NOT NULL,
+    total_pnl real NOT NULL,
+    updated_at timestamp NOT NULL,
+    PRIMARY KEY (period, base_currency)
+);
+
+CREATE TABLE IF NOT EXISTS pnl_monthly (
+    period date NOT NULL,
+    base_currency text NOT NULL,
+    realized_pnl real NOT NULL,
+    unrealized_pnl real NOT NULL,
+    fees real NOT NULL,
+    total_pnl real NOT NULL,
+    updated_at timestamp NOT NULL,
+    PRIMARY KEY (period, base_currency)
+);
+
+CREATE TABLE IF NOT EXISTS trade_analysis_records (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    arbitrage_record_uuid text NOT NULL,
+    currency text NOT NULL,
+    buy_exchange text NOT NULL,
+    sell_exchange text NOT NULL,
+    size real NOT NULL,
+    matched_size real NOT NULL,
+    projected_profit real NOT NULL,
+    realized_profit real NOT NULL,
+    slippage real NOT NULL,
+    fee_difference real NOT NULL,
+    latency_decay real NOT NULL,
+    partial_fill_loss real NOT NULL,
+    correction_loss real NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS trade_analysis_records_exchange_pair ON trade_analysis_records (buy_exchange, sell_exchange);
+
+CREATE VIEW IF NOT EXISTS trade_analysis_by_exchange_pair AS
+SELECT
+    currency,
+    buy_exchange,
+    sell_exchange,
+    COUNT(*) AS trades,
+    SUM(projected_profit) AS projected_profit,
+    SUM(realized_profit) AS realized_profit,
+    SUM(slippage) AS slippage,
+    SUM(fee_difference) AS fee_difference,
+    SUM(latency_decay) AS latency_decay,
+    SUM(partial_fill_loss) AS partial_fill_loss,
+    SUM(correction_loss) AS correction_loss,
+    SUM(matched_size) / SUM(size) AS fill_ratio
+FROM trade_analysis_records
+GROUP BY currency, buy_exchange, sell_exchange;
+
+CREATE TABLE IF NOT EXISTS spread_signal_records (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    currency text NOT NULL,
+    exchange_a text NOT NULL,
+    price_a real NOT NULL,
+    exchange_b text NOT NULL,
+    price_b real NOT NULL,
+    spread real NOT NULL,
+    mean real NOT NULL,
+    standard_deviation real NOT NULL,
+    z_score real NOT NULL,
+    action text NOT NULL,
+    direction text NOT NULL,
+    entry_uuid text NOT NULL,
+    spread_change real NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS spread_signal_records_entry_uuid ON spread_signal_records (entry_uuid);
+
+CREATE TABLE IF NOT EXISTS market_trades (
+    uuid text NOT NULL,
+    exchange text NOT NULL,
+    pair text NOT NULL,
+    currency text NOT NULL,
+    timestamp timestamp NOT NULL,
+    price real NOT NULL,
+    volume real NOT NULL,
+    side text NOT NULL,
+    order_type text NOT NULL,
+    PRIMARY KEY (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS market_trades_currency_timestamp ON market_trades (currency, timestamp);
+
+CREATE TABLE IF NOT EXISTS market_candles (
+    exchange text NOT NULL,
+    pair text NOT NULL,
+    currency text NOT NULL,
+    interval_minutes integer NOT NULL,
+    timestamp timestamp NOT NULL,
+    open real NOT NULL,
+    high real NOT NULL,
+    low real NOT NULL,
+    close real NOT NULL,
+    vwap real NOT NULL,
+    volume real NOT NULL,
+    count integer NOT NULL,
+    PRIMARY KEY (exchange, pair, interval_minutes, timestamp)
+);
+
+CREATE TABLE IF NOT EXISTS market_data_cursors (
+    exchange text NOT NULL,
+    pair text NOT NULL,
+    kind text NOT NULL,
+    cursor integer NOT NULL,
+    updated_at timestamp NOT NULL,
+    PRIMARY KEY (exchange, pair, kind)
+);
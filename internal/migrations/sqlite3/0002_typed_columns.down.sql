@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS price_records_exchange_timestamp;
+DROP INDEX IF EXISTS price_records_currency_timestamp;
+DROP INDEX IF EXISTS price_records_timestamp;
+
+DROP INDEX IF EXISTS triangular_arbitrage_1_exchange_exchange_timestamp;
+DROP INDEX IF EXISTS triangular_arbitrage_1_exchange_timestamp;
+
+DROP INDEX IF EXISTS arbitrage_records_currency_timestamp;
+DROP INDEX IF EXISTS arbitrage_records_timestamp;
+
+UPDATE arbitrage_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp);
+UPDATE triangular_arbitrage_1_exchange SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp);
+UPDATE price_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp);
@@ -0,0 +1,81 @@
+-- Tables created by mysql/database_crypto_arbitrage_bot.sql stored booleans as '1'/'0' (or 'true'/'false') and prices
+-- and fees as text. SQLite can't change the type of a column, the tables are copied into typed ones.
+-- SQLite stores timestamps as text and compares them as strings. They are written with a fixed number of fractional
+-- digits (2006-01-02T15:04:05.000000000Z), existing timestamps with seconds are converted to that notation.
+CREATE TABLE arbitrage_records_typed (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    currency text NOT NULL,
+    exchange_a text NOT NULL,
+    price_a real NOT NULL,
+    exchange_b text NOT NULL,
+    price_b real NOT NULL,
+    projected_profit real NOT NULL,
+    is_arbitrage_opportunity boolean NOT NULL,
+    trade_size real NOT NULL DEFAULT 0,
+    is_blocked_by_inventory boolean NOT NULL DEFAULT 0,
+    PRIMARY KEY (uuid)
+);
+INSERT INTO arbitrage_records_typed
+SELECT uuid,
+    CASE WHEN length(timestamp) < 30 THEN strftime('%Y-%m-%dT%H:%M:%f', timestamp) || '000000Z' ELSE timestamp END,
+    currency, exchange_a, CAST(price_a AS REAL), exchange_b, CAST(price_b AS REAL), CAST(projected_profit AS REAL),
+    lower(CAST(is_arbitrage_opportunity AS TEXT)) IN ('1', 'true'), CAST(trade_size AS REAL),
+    lower(CAST(is_blocked_by_inventory AS TEXT)) IN ('1', 'true')
+FROM arbitrage_records;
+DROP TABLE arbitrage_records;
+ALTER TABLE arbitrage_records_typed RENAME TO arbitrage_records;
+
+CREATE TABLE triangular_arbitrage_1_exchange_typed (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    exchange text NOT NULL,
+    trade_pair_1 text NOT NULL,
+    trade_pair_1_exchange_rate real NOT NULL,
+    trade_pair_2 text NOT NULL,
+    trade_pair_2_exchange_rate real NOT NULL,
+    trade_pair_3 text NOT NULL,
+    trade_pair_3_exchange_rate real NOT NULL,
+    cross_exchange_rate_difference real NOT NULL,
+    is_triangular_arbitrage_opportunity boolean NOT NULL,
+    PRIMARY KEY (uuid)
+);
+INSERT INTO triangular_arbitrage_1_exchange_typed
+SELECT uuid,
+    CASE WHEN length(timestamp) < 30 THEN strftime('%Y-%m-%dT%H:%M:%f', timestamp) || '000000Z' ELSE timestamp END,
+    exchange, trade_pair_1, CAST(trade_pair_1_exchange_rate AS REAL), trade_pair_2, CAST(trade_pair_2_exchange_rate AS REAL),
+    trade_pair_3, CAST(trade_pair_3_exchange_rate AS REAL), CAST(cross_exchange_rate_difference AS REAL),
+    lower(CAST(is_triangular_arbitrage_opportunity AS TEXT)) IN ('1', 'true')
+FROM triangular_arbitrage_1_exchange;
+DROP TABLE triangular_arbitrage_1_exchange;
+ALTER TABLE triangular_arbitrage_1_exchange_typed RENAME TO triangular_arbitrage_1_exchange;
+
+CREATE TABLE price_records_typed (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    currency text NOT NULL,
+    price real NOT NULL,
+    fee real NOT NULL,
+    exchange text NOT NULL,
+    arbitrage_record_uuid text NOT NULL,
+    is_arbitrage_opportunity boolean NOT NULL,
+    PRIMARY KEY (uuid)
+);
+INSERT INTO price_records_typed
+SELECT uuid,
+    CASE WHEN length(timestamp) < 30 THEN strftime('%Y-%m-%dT%H:%M:%f', timestamp) || '000000Z' ELSE timestamp END,
+    currency, CAST(price AS REAL), CAST(fee AS REAL), exchange, arbitrage_record_uuid,
+    lower(CAST(is_arbitrage_opportunity AS TEXT)) IN ('1', 'true')
+FROM price_records;
+DROP TABLE price_records;
+ALTER TABLE price_records_typed RENAME TO price_records;
+
+CREATE INDEX IF NOT EXISTS arbitrage_records_timestamp ON arbitrage_records (timestamp);
+CREATE INDEX IF NOT EXISTS arbitrage_records_currency_timestamp ON arbitrage_records (currency, timestamp);
+
+CREATE INDEX IF NOT EXISTS triangular_arbitrage_1_exchange_timestamp ON triangular_arbitrage_1_exchange (timestamp);
+CREATE INDEX IF NOT EXISTS triangular_arbitrage_1_exchange_exchange_timestamp ON triangular_arbitrage_1_exchange (exchange, timestamp);
+
+CREATE INDEX IF NOT EXISTS price_records_timestamp ON price_records (timestamp);
+CREATE INDEX IF NOT EXISTS price_records_currency_timestamp ON price_records (currency, timestamp);
+CREATE INDEX IF NOT EXISTS price_records_exchange_timestamp ON price_records (exchange, timestamp);
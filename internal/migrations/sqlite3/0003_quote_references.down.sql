@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS price_records_arbitrage_record_uuid;
+
+ALTER TABLE triangular_arbitrage_1_exchange DROP COLUMN trade_pair_1_price_record_uuid;
+ALTER TABLE triangular_arbitrage_1_exchange DROP COLUMN trade_pair_2_price_record_uuid;
+ALTER TABLE triangular_arbitrage_1_exchange DROP COLUMN trade_pair_3_price_record_uuid;
+
+ALTER TABLE arbitrage_records DROP COLUMN price_record_uuid_a;
+ALTER TABLE arbitrage_records DROP COLUMN price_record_uuid_b;
@@ -0,0 +1,8 @@
+ALTER TABLE arbitrage_records ADD COLUMN price_record_uuid_a text NOT NULL DEFAULT '';
+ALTER TABLE arbitrage_records ADD COLUMN price_record_uuid_b text NOT NULL DEFAULT '';
+
+ALTER TABLE triangular_arbitrage_1_exchange ADD COLUMN trade_pair_1_price_record_uuid text NOT NULL DEFAULT '';
+ALTER TABLE triangular_arbitrage_1_exchange ADD COLUMN trade_pair_2_price_record_uuid text NOT NULL DEFAULT '';
+ALTER TABLE triangular_arbitrage_1_exchange ADD COLUMN trade_pair_3_price_record_uuid text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS price_records_arbitrage_record_uuid ON price_records (arbitrage_record_uuid);
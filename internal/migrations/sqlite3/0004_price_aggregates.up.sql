@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS price_aggregates (
+    exchange text NOT NULL,
+    currency text NOT NULL,
+    interval_minutes integer NOT NULL,
+    timestamp timestamp NOT NULL,
+    open real NOT NULL,
+    high real NOT NULL,
+    low real NOT NULL,
+    close real NOT NULL,
+    average real NOT NULL,
+    count integer NOT NULL,
+    PRIMARY KEY (exchange, currency, interval_minutes, timestamp)
+);
+CREATE INDEX IF NOT EXISTS price_aggregates_interval_timestamp ON price_aggregates (interval_minutes, timestamp);
+
+CREATE TABLE IF NOT EXISTS spread_aggregates (
+    currency text NOT NULL,
+    exchange_a text NOT NULL,
+    exchange_b text NOT NULL,
+    interval_minutes integer NOT NULL,
+    timestamp timestamp NOT NULL,
+    open real NOT NULL,
+    high real NOT NULL,
+    low real NOT NULL,
+    close real NOT NULL,
+    average real NOT NULL,
+    count integer NOT NULL,
+    PRIMARY KEY (currency, exchange_a, exchange_b, interval_minutes, timestamp)
+);
+CREATE INDEX IF NOT EXISTS spread_aggregates_interval_timestamp ON spread_aggregates (interval_minutes, timestamp);
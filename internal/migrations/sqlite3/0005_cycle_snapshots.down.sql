@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS spread_signal_records_cycle_uuid;
+DROP INDEX IF EXISTS triangular_arbitrage_1_exchange_cycle_uuid;
+DROP INDEX IF EXISTS arbitrage_records_cycle_uuid;
+DROP INDEX IF EXISTS price_records_cycle_uuid;
+
+ALTER TABLE spread_signal_records DROP COLUMN cycle_uuid;
+ALTER TABLE triangular_arbitrage_1_exchange DROP COLUMN cycle_uuid;
+ALTER TABLE arbitrage_records DROP COLUMN cycle_uuid;
+ALTER TABLE price_records DROP COLUMN cycle_uuid;
+
+DROP TABLE IF EXISTS cycle_snapshots;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS cycle_snapshots (
+    uuid text NOT NULL,
+    timestamp timestamp NOT NULL,
+    quotes integer NOT NULL DEFAULT 0,
+    arbitrage_events integer NOT NULL DEFAULT 0,
+    triangular_arbitrage_events integer NOT NULL DEFAULT 0,
+    spread_signals integer NOT NULL DEFAULT 0,
+    opportunities integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (uuid)
+);
+CREATE INDEX IF NOT EXISTS cycle_snapshots_timestamp ON cycle_snapshots (timestamp);
+
+ALTER TABLE price_records ADD COLUMN cycle_uuid text NOT NULL DEFAULT '';
+ALTER TABLE arbitrage_records ADD COLUMN cycle_uuid text NOT NULL DEFAULT '';
+ALTER TABLE triangular_arbitrage_1_exchange ADD COLUMN cycle_uuid text NOT NULL DEFAULT '';
+ALTER TABLE spread_signal_records ADD COLUMN cycle_uuid text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS price_records_cycle_uuid ON price_records (cycle_uuid);
+CREATE INDEX IF NOT EXISTS arbitrage_records_cycle_uuid ON arbitrage_records (cycle_uuid);
+CREATE INDEX IF NOT EXISTS triangular_arbitrage_1_exchange_cycle_uuid ON triangular_arbitrage_1_exchange (cycle_uuid);
+CREATE INDEX IF NOT EXISTS spread_signal_records_cycle_uuid ON spread_signal_records (cycle_uuid);
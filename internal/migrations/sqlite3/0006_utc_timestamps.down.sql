@@ -0,0 +1,14 @@
+UPDATE market_data_cursors SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', updated_at);
+UPDATE market_candles SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp);
+UPDATE market_trades SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp);
+UPDATE spread_signal_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp);
+UPDATE trade_analysis_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp);
+UPDATE pnl_monthly SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', updated_at);
+UPDATE pnl_daily SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', updated_at);
+UPDATE ledger_entries SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp);
+UPDATE triangular_execution_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp);
+UPDATE order_records SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', updated_at);
+UPDATE order_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp);
+UPDATE transfer_records SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', updated_at);
+UPDATE transfer_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp);
+UPDATE leg_correction_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp);
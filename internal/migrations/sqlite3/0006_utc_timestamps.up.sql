@@ -0,0 +1,17 @@
+-- The timestamps of orders, transfers, executions, the ledger and market data were written in RFC3339 with seconds and
+-- the local offset. They are converted to UTC in the notation of the quotes (2006-01-02T15:04:05.000000000Z), so that
+-- they compare as strings in time order.
+UPDATE leg_correction_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%f', timestamp) || '000000Z' WHERE timestamp NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE transfer_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%f', timestamp) || '000000Z' WHERE timestamp NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE transfer_records SET updated_at = strftime('%Y-%m-%dT%H:%M:%f', updated_at) || '000000Z' WHERE updated_at NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE order_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%f', timestamp) || '000000Z' WHERE timestamp NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE order_records SET updated_at = strftime('%Y-%m-%dT%H:%M:%f', updated_at) || '000000Z' WHERE updated_at NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE triangular_execution_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%f', timestamp) || '000000Z' WHERE timestamp NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE ledger_entries SET timestamp = strftime('%Y-%m-%dT%H:%M:%f', timestamp) || '000000Z' WHERE timestamp NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE pnl_daily SET updated_at = strftime('%Y-%m-%dT%H:%M:%f', updated_at) || '000000Z' WHERE updated_at NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE pnl_monthly SET updated_at = strftime('%Y-%m-%dT%H:%M:%f', updated_at) || '000000Z' WHERE updated_at NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE trade_analysis_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%f', timestamp) || '000000Z' WHERE timestamp NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE spread_signal_records SET timestamp = strftime('%Y-%m-%dT%H:%M:%f', timestamp) || '000000Z' WHERE timestamp NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE market_trades SET timestamp = strftime('%Y-%m-%dT%H:%M:%f', timestamp) || '000000Z' WHERE timestamp NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE market_candles SET timestamp = strftime('%Y-%m-%dT%H:%M:%f', timestamp) || '000000Z' WHERE timestamp NOT GLOB '????-??-??T??:??:??.?????????Z';
+UPDATE market_data_cursors SET updated_at = strftime('%Y-%m-%dT%H:%M:%f', updated_at) || '000000Z' WHERE updated_at NOT GLOB '????-??-??T??:??:??.?????????Z';
@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/lib/pq"
+	"github.com/spf13/viper"
+)
+
+/*
+Initializes a TCP connection pool for PostgreSQL. DATABASE.POSTGRES.SSL_MODE defaults to "disable".
+*/
+func connectPostgres() (*sql.DB, error) {
+	var (
+		dbUser    = mustGetenv("DATABASE.POSTGRES.USERNAME")
+		dbPwd     = mustGetenv("DATABASE.POSTGRES.PASSWORD")
+		dbName    = mustGetenv("DATABASE.POSTGRES.NAME")
+		dbTCPHost = mustGetenv("DATABASE.POSTGRES.HOST")
+		dbPort    = mustGetenv("DATABASE.POSTGRES.PORT")
+		sslMode   = viper.GetString("DATABASE.POSTGRES.SSL_MODE")
+	)
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	dbURI := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		dbTCPHost, dbPort, dbUser, dbPwd, dbName, sslMode)
+
+	dbPool, err := sql.Open("postgres", dbURI)
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open: %v", err)
+	}
+
+	return dbPool, nil
+}
@@ -0,0 +1,22 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	_ "modernc.org/sqlite"
+)
+
+/*
+Opens the SQLite database file DATABASE.SQLITE.PATH, which is created if it does not exist. Concurrent writers wait for
+each other instead of failing.
+*/
+func connectSqlite() (*sql.DB, error) {
+	path := mustGetenv("DATABASE.SQLITE.PATH")
+
+	dbPool, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path))
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open: %v", err)
+	}
+
+	return dbPool, nil
+}
@@ -39,7 +39,7 @@ func InitializeLogger() {
 }
 
 /*
-Sets the logger level. Accepts "DEBUG" or "INFO".
+Sets the logger level. Accepts "DEBUG", "INFO" or "WARN".
 */
 func SetLoggerLevel(level string) {
 	switch strings.ToUpper(level) {
@@ -47,6 +47,8 @@ func SetLoggerLevel(level string) {
 		cfg.Level.SetLevel(zap.DebugLevel)
 	case "INFO":
 		cfg.Level.SetLevel(zap.InfoLevel)
+	case "WARN":
+		cfg.Level.SetLevel(zap.WarnLevel)
 	default:
 		cfg.Level.SetLevel(zap.InfoLevel)
 	}
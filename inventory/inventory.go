@@ -0,0 +1,104 @@
+package inventory
+
+import (
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+/*
+An exchange account that balances can be read from. Implemented by the clients in the api packages. Balances are the
+available amounts keyed by asset (e.g. BTC, USD).
+*/
+type BalanceSource interface {
+	Name() string
+	GetBalances() (map[string]float64, error)
+}
+
+/*
+Keeps the latest known balances of every exchange. Balances are refreshed periodically, a failed refresh keeps the
+previous balances of that exchange.
+*/
+type Inventory struct {
+	mu        sync.RWMutex
+	sources   []BalanceSource
+	balances  map[string]map[string]float64
+	updatedAt map[string]time.Time
+}
+
+/*
+Create a new Inventory for the given balance sources. Call Refresh to load the balances.
+*/
+func NewInventory(sources ...BalanceSource) *Inventory {
+	return &Inventory{
+		sources:   sources,
+		balances:  make(map[string]map[string]float64),
+		updatedAt: make(map[string]time.Time),
+	}
+}
+
+/*
+Fetch the balances from every source.
+*/
+func (i *Inventory) Refresh() {
+	for _, source := range i.sources {
+		balances, err := source.GetBalances()
+		if err != nil {
+			utils.Logger.Error(fmt.Sprintf("Failed to refresh balances on %s.", source.Name()), zap.Error(err))
+			continue
+		}
+		i.SetBalances(source.Name(), balances)
+	}
+	utils.Logger.Debug("Refreshed exchange balances.")
+}
+
+/*
+Replace the balances of an exchange.
+*/
+func (i *Inventory) SetBalances(exchange string, balances map[string]float64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.balances[exchange] = balances
+	i.updatedAt[exchange] = time.Now()
+}
+
+/*
+Returns the available balance of an asset on an exchange. The second return value is false if the balances of the
+exchange are unknown.
+*/
+func (i *Inventory) Available(exchange string, asset string) (float64, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	balances, ok := i.balances[exchange]
+	if !ok {
+		return 0, false
+	}
+	return balances[asset], true
+}
+
+/*
+Returns when the balances of an exchange were last refreshed successfully.
+*/
+func (i *Inventory) UpdatedAt(exchange string) time.Time {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.updatedAt[exchange]
+}
+
+/*
+Returns a copy of the latest balances of every exchange, keyed by exchange and asset.
+*/
+func (i *Inventory) Snapshot() map[string]map[string]float64 {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	snapshot := make(map[string]map[string]float64)
+	for exchange, balances := range i.balances {
+		snapshot[exchange] = make(map[string]float64)
+		for asset, balance := range balances {
+			snapshot[exchange][asset] = balance
+		}
+	}
+	return snapshot
+}
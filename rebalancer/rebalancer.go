@@ -0,0 +1,342 @@
+package rebalancer
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"cryptoArbitrageBot/inventory"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type TransferStatus string
+
+const (
+	Proposed  TransferStatus = "proposed"
+	Submitted TransferStatus = "submitted"
+	Pending   TransferStatus = "pending"
+	Completed TransferStatus = "completed"
+	Failed    TransferStatus = "failed"
+)
+
+/*
+The exchange and deposit address a withdrawal is sent to.
+*/
+type Destination struct {
+	Exchange string
+	Address  string
+}
+
+/*
+An exchange that crypto can be withdrawn from and deposited to. Implemented by the clients in the api packages.
+WithdrawTo returns the exchange's reference id of the withdrawal, which is used to look up its status.
+*/
+type TransferVenue interface {
+	Name() string
+	DepositAddress(asset string) (string, error)
+	WithdrawTo(asset string, amount float64, destination Destination) (string, error)
+	TransferStatus(asset string, referenceId string) (TransferStatus, error)
+}
+
+type RebalancerError struct {
+	Msg string
+}
+
+func (e *RebalancerError) Error() string {
+	return fmt.Sprintf("Error rebalancing inventory: %s", e.Msg)
+}
+
+/*
+Configuration of the rebalancer.
+  - Targets: the share of the total balance of an asset every exchange should hold, keyed by asset and exchange.
+  - Threshold: an exchange is rebalanced when it deviates more than this share of the total from its target.
+  - MaxFeeFraction: transfers whose withdrawal fee is a larger share of the amount are skipped.
+  - MinTransfer: the smallest amount of an asset worth transferring.
+  - WithdrawalFees: the withdrawal fee in units of the asset, keyed by exchange and asset.
+  - TransferMinutes: how long a withdrawal from an exchange usually takes until it is credited.
+  - Execute: submit the planned transfers instead of only recording them as proposals.
+*/
+type Config struct {
+	Targets         map[string]map[string]float64
+	Threshold       float64
+	MaxFeeFraction  float64
+	MinTransfer     map[string]float64
+	WithdrawalFees  map[string]map[string]float64
+	TransferMinutes map[string]float64
+	Execute         bool
+}
+
+/*
+Load the rebalancer configuration from the configuration file (REBALANCER).
+*/
+func LoadConfig() Config {
+	config := Config{
+		Targets:         make(map[string]map[string]float64),
+		Threshold:       viper.GetFloat64("REBALANCER.THRESHOLD"),
+		MaxFeeFraction:  viper.GetFloat64("REBALANCER.MAX_FEE_FRACTION"),
+		MinTransfer:     make(map[string]float64),
+		WithdrawalFees:  make(map[string]map[string]float64),
+		TransferMinutes: make(map[string]float64),
+		Execute:         viper.GetBool("REBALANCER.EXECUTE"),
+	}
+	for asset := range viper.GetStringMap("REBALANCER.TARGETS") {
+		config.Targets[strings.ToUpper(asset)] = make(map[string]float64)
+		for exchange := range viper.GetStringMap("REBALANCER.TARGETS." + asset) {
+			config.Targets[strings.ToUpper(asset)][exchangeName(exchange)] = viper.GetFloat64("REBALANCER.TARGETS." + asset + "." + exchange)
+		}
+	}
+	for asset := range viper.GetStringMap("REBALANCER.MIN_TRANSFER") {
+		config.MinTransfer[strings.ToUpper(asset)] = viper.GetFloat64("REBALANCER.MIN_TRANSFER." + asset)
+	}
+	for exchange := range viper.GetStringMap("REBALANCER.WITHDRAWAL_FEES") {
+		config.WithdrawalFees[exchangeName(exchange)] = make(map[string]float64)
+		for asset := range viper.GetStringMap("REBALANCER.WITHDRAWAL_FEES." + exchange) {
+			config.WithdrawalFees[exchangeName(exchange)][strings.ToUpper(asset)] = viper.GetFloat64("REBALANCER.WITHDRAWAL_FEES." + exchange + "." + asset)
+		}
+	}
+	for exchange := range viper.GetStringMap("REBALANCER.TRANSFER_MINUTES") {
+		config.TransferMinutes[exchangeName(exchange)] = viper.GetFloat64("REBALANCER.TRANSFER_MINUTES." + exchange)
+	}
+	return config
+}
+
+/*
+Viper lower cases all keys. Restore the exchange name as it is used in price records (e.g. kraken -> Kraken).
+*/
+func exchangeName(key string) string {
+	if key == "" {
+		return key
+	}
+	return strings.ToUpper(key[:1]) + strings.ToLower(key[1:])
+}
+
+/*
+Monitors the balances of every exchange against the target allocation and plans, records and optionally executes the
+transfers that bring them back in line.
+*/
+type Rebalancer struct {
+	config   Config
+	balances *inventory.Inventory
+	venues   map[string]TransferVenue
+	ledger   *bookkeeper.Ledger
+}
+
+/*
+Create a new Rebalancer for the given venues.
+*/
+func NewRebalancer(config Config, balances *inventory.Inventory, venues ...TransferVenue) *Rebalancer {
+	venueMap := make(map[string]TransferVenue)
+	for _, venue := range venues {
+		venueMap[venue.Name()] = venue
+	}
+	return &Rebalancer{
+		config:   config,
+		balances: balances,
+		venues:   venueMap,
+	}
+}
+
+/*
+Post completed transfers to a ledger.
+*/
+func (r *Rebalancer) WithLedger(ledger *bookkeeper.Ledger) *Rebalancer {
+	r.ledger = ledger
+	return r
+}
+
+/*
+Update the status of open transfers, then plan new transfers for the current balances. Assets with a transfer in flight
+are not rebalanced until it is completed, because the balances do not reflect it yet.
+*/
+func (r *Rebalancer) Run() {
+	openTransfers, err := bookkeeper.GetTransferRecords(string(Submitted), string(Pending))
+	if err != nil {
+		utils.Logger.Error("Unable to load open transfers. Skipping the rebalancing run.", zap.Error(err))
+		return
+	}
+	openTransfers = r.updateTransferStatuses(openTransfers)
+
+	transfers := PlanTransfers(r.config, r.balances.Snapshot(), openTransfers)
+	for _, transfer := range transfers {
+		if r.config.Execute {
+			transfer = r.executeTransfer(transfer)
+		}
+		bookkeeper.RecordTransferRecord(transfer)
+	}
+	utils.Logger.Info(fmt.Sprintf("Ran rebalancer. Planned %d transfers, %d transfers in flight.", len(transfers), len(openTransfers)))
+}
+
+/*
+Plan the transfers that bring the balances of every asset back to the target allocation. Exchanges that deviate more
+than the threshold from their target are rebalanced. The biggest deficits are filled first, from the exchange with the
+lowest withdrawal fee and the fastest transfer time that has a surplus.
+*/
+func PlanTransfers(config Config, balances map[string]map[string]float64, openTransfers []bookkeeper.TransferRecord) []bookkeeper.TransferRecord {
+	inFlight := make(map[string]bool)
+	for _, transfer := range openTransfers {
+		inFlight[transfer.Asset] = true
+	}
+
+	var assets []string
+	for asset := range config.Targets {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+
+	var transfers []bookkeeper.TransferRecord
+	for _, asset := range assets {
+		if inFlight[asset] {
+			utils.Logger.Debug(fmt.Sprintf("A transfer of %s is in flight. Skipping.", asset))
+			continue
+		}
+
+		total := 0.0
+		for exchange := range config.Targets[asset] {
+			total += balances[exchange][asset]
+		}
+		if total == 0 {
+			continue
+		}
+
+		type deviation struct {
+			exchange string
+			amount   float64
+		}
+		var surpluses, deficits []deviation
+		for exchange, target := range config.Targets[asset] {
+			difference := balances[exchange][asset] - target*total
+			if math.Abs(difference) <= config.Threshold*total {
+				continue
+			}
+			if difference > 0 {
+				surpluses = append(surpluses, deviation{exchange: exchange, amount: difference})
+			} else {
+				deficits = append(deficits, deviation{exchange: exchange, amount: -difference})
+			}
+		}
+		sort.Slice(deficits, func(i, j int) bool {
+			return deficits[i].amount > deficits[j].amount
+		})
+		sort.Slice(surpluses, func(i, j int) bool {
+			feeI, feeJ := config.WithdrawalFees[surpluses[i].exchange][asset], config.WithdrawalFees[surpluses[j].exchange][asset]
+			if feeI != feeJ {
+				return feeI < feeJ
+			}
+			minutesI, minutesJ := config.TransferMinutes[surpluses[i].exchange], config.TransferMinutes[surpluses[j].exchange]
+			if minutesI != minutesJ {
+				return minutesI < minutesJ
+			}
+			return surpluses[i].amount > surpluses[j].amount
+		})
+
+		for _, deficit := range deficits {
+			for i := range surpluses {
+				source := &surpluses[i]
+				if deficit.amount <= 0 || source.amount <= 0 {
+					continue
+				}
+				amount := math.Min(deficit.amount, source.amount)
+				fee := config.WithdrawalFees[source.exchange][asset]
+				if amount < config.MinTransfer[asset] {
+					continue
+				}
+				if config.MaxFeeFraction > 0 && fee > amount*config.MaxFeeFraction {
+					utils.Logger.Debug(fmt.Sprintf("Withdrawal fee of %v %s on %s is too high for a transfer of %v.", fee, asset, source.exchange, amount))
+					continue
+				}
+				now := time.Now().UTC()
+				transfers = append(transfers, bookkeeper.TransferRecord{
+					Uuid:         uuid.New(),
+					Timestamp:    now,
+					UpdatedAt:    now,
+					Asset:        asset,
+					FromExchange: source.exchange,
+					ToExchange:   deficit.exchange,
+					Amount:       amount,
+					EstimatedFee: fee,
+					Status:       string(Proposed),
+				})
+				source.amount -= amount
+				deficit.amount -= amount
+			}
+		}
+	}
+	return transfers
+}
+
+/*
+Submit a planned transfer: look up the deposit address on the receiving exchange and withdraw from the sending one.
+*/
+func (r *Rebalancer) executeTransfer(transfer bookkeeper.TransferRecord) bookkeeper.TransferRecord {
+	transfer.Status = string(Failed)
+	from, ok := r.venues[transfer.FromExchange]
+	if !ok {
+		transfer.Reason = fmt.Sprintf("no transfer venue configured for %s", transfer.FromExchange)
+		return transfer
+	}
+	to, ok := r.venues[transfer.ToExchange]
+	if !ok {
+		transfer.Reason = fmt.Sprintf("no transfer venue configured for %s", transfer.ToExchange)
+		return transfer
+	}
+
+	address, err := to.DepositAddress(transfer.Asset)
+	if err != nil {
+		transfer.Reason = fmt.Sprintf("unable to get a deposit address: %v", err)
+		utils.Logger.Error(fmt.Sprintf("Unable to get a %s deposit address on %s.", transfer.Asset, transfer.ToExchange), zap.Error(err))
+		return transfer
+	}
+	transfer.Address = address
+
+	referenceId, err := from.WithdrawTo(transfer.Asset, transfer.Amount, Destination{Exchange: transfer.ToExchange, Address: address})
+	if err != nil {
+		transfer.Reason = fmt.Sprintf("withdrawal failed: %v", err)
+		utils.Logger.Error(fmt.Sprintf("Unable to withdraw %v %s from %s.", transfer.Amount, transfer.Asset, transfer.FromExchange), zap.Error(err))
+		return transfer
+	}
+	transfer.ReferenceId = referenceId
+	transfer.Status = string(Submitted)
+	transfer.UpdatedAt = time.Now().UTC()
+	utils.Logger.Info(fmt.Sprintf("Submitted transfer of %v %s from %s to %s (%s).", transfer.Amount, transfer.Asset, transfer.FromExchange, transfer.ToExchange, referenceId))
+	return transfer
+}
+
+/*
+Look up the status of open transfers and store changes. Returns the transfers that are still open.
+*/
+func (r *Rebalancer) updateTransferStatuses(openTransfers []bookkeeper.TransferRecord) []bookkeeper.TransferRecord {
+	var stillOpen []bookkeeper.TransferRecord
+	for _, transfer := range openTransfers {
+		venue, ok := r.venues[transfer.FromExchange]
+		if !ok {
+			stillOpen = append(stillOpen, transfer)
+			continue
+		}
+		status, err := venue.TransferStatus(transfer.Asset, transfer.ReferenceId)
+		if err != nil {
+			utils.Logger.Error(fmt.Sprintf("Unable to get the status of transfer %s.", transfer.Uuid), zap.Error(err))
+			stillOpen = append(stillOpen, transfer)
+			continue
+		}
+		if string(status) != transfer.Status {
+			transfer.Status = string(status)
+			transfer.UpdatedAt = time.Now().UTC()
+			bookkeeper.UpdateTransferRecord(transfer)
+			utils.Logger.Info(fmt.Sprintf("Transfer %s of %v %s from %s to %s is %s.", transfer.Uuid, transfer.Amount, transfer.Asset, transfer.FromExchange, transfer.ToExchange, status))
+			if status == Completed && r.ledger != nil {
+				if err := r.ledger.PostTransfer(transfer); err != nil {
+					utils.Logger.Error(fmt.Sprintf("Unable to post transfer %s to the ledger.", transfer.Uuid), zap.Error(err))
+				}
+			}
+		}
+		if status == Submitted || status == Pending {
+			stillOpen = append(stillOpen, transfer)
+		}
+	}
+	return stillOpen
+}
@@ -0,0 +1,120 @@
+package rebalancer
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+	"log"
+	"testing"
+)
+
+func setupTest(tb testing.TB) func(tb testing.TB) {
+	log.Println("Setup tests.")
+
+	viper.SetConfigName("config-DEV")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath("../")
+	err := viper.ReadInConfig() // Find and read the config file
+	if err != nil {
+		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+	}
+
+	utils.InitializeLogger()
+	utils.SetLoggerLevel("DEBUG")
+
+	return func(tb testing.TB) {
+		log.Println("Teardown tests.")
+	}
+}
+
+func configForTest() Config {
+	return Config{
+		Targets: map[string]map[string]float64{
+			"BTC": {"Coinbase": .5, "Gemini": .25, "Kraken": .25},
+		},
+		Threshold:      .1,
+		MaxFeeFraction: .01,
+		MinTransfer:    map[string]float64{"BTC": .01},
+		WithdrawalFees: map[string]map[string]float64{
+			"Coinbase": {"BTC": .0001},
+			"Gemini":   {"BTC": 0},
+			"Kraken":   {"BTC": .0002},
+		},
+		TransferMinutes: map[string]float64{"Coinbase": 60, "Gemini": 40, "Kraken": 40},
+	}
+}
+
+type plannedTransfer struct {
+	from   string
+	to     string
+	amount float64
+}
+
+func Test_PlanTransfers(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	tests := []struct {
+		name          string
+		config        func(c *Config)
+		balances      map[string]map[string]float64
+		openTransfers []bookkeeper.TransferRecord
+		want          []plannedTransfer
+	}{
+		{
+			name:     "Balances within the threshold.",
+			balances: map[string]map[string]float64{"Coinbase": {"BTC": .55}, "Gemini": {"BTC": .2}, "Kraken": {"BTC": .25}},
+		},
+		{
+			name:     "Deficit on Coinbase is filled from the cheapest exchange.",
+			balances: map[string]map[string]float64{"Coinbase": {"BTC": .2}, "Gemini": {"BTC": .4}, "Kraken": {"BTC": .4}},
+			want: []plannedTransfer{
+				{from: "Gemini", to: "Coinbase", amount: .15},
+				{from: "Kraken", to: "Coinbase", amount: .15},
+			},
+		},
+		{
+			name:     "Surplus on Coinbase fills the biggest deficit first.",
+			balances: map[string]map[string]float64{"Coinbase": {"BTC": .8}, "Gemini": {"BTC": .2}, "Kraken": {"BTC": 0}},
+			want: []plannedTransfer{
+				{from: "Coinbase", to: "Kraken", amount: .25},
+			},
+		},
+		{
+			name:     "Transfer below the minimum is skipped.",
+			config:   func(c *Config) { c.MinTransfer["BTC"] = 1 },
+			balances: map[string]map[string]float64{"Coinbase": {"BTC": .8}, "Gemini": {"BTC": .2}, "Kraken": {"BTC": 0}},
+		},
+		{
+			name:     "Transfer with a too high withdrawal fee is skipped.",
+			config:   func(c *Config) { c.WithdrawalFees["Coinbase"]["BTC"] = .01 },
+			balances: map[string]map[string]float64{"Coinbase": {"BTC": .8}, "Gemini": {"BTC": .2}, "Kraken": {"BTC": 0}},
+		},
+		{
+			name:          "Asset with a transfer in flight is skipped.",
+			balances:      map[string]map[string]float64{"Coinbase": {"BTC": .8}, "Gemini": {"BTC": .2}, "Kraken": {"BTC": 0}},
+			openTransfers: []bookkeeper.TransferRecord{{Asset: "BTC", Status: string(Pending)}},
+		},
+		{
+			name:     "No balances.",
+			balances: map[string]map[string]float64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := configForTest()
+			if tt.config != nil {
+				tt.config(&config)
+			}
+
+			var got []plannedTransfer
+			for _, transfer := range PlanTransfers(config, tt.balances, tt.openTransfers) {
+				assert.Equal(t, transfer.Status, string(Proposed))
+				got = append(got, plannedTransfer{from: transfer.FromExchange, to: transfer.ToExchange, amount: float64(int(transfer.Amount*1e8+.5)) / 1e8})
+			}
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package retention
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/spf13/viper"
+	"sort"
+	"time"
+)
+
+/*
+The intervals in minutes raw quotes are rolled up into.
+*/
+var AggregateIntervals = []int{1, 60}
+
+/*
+Quotes of the last minute may still be on their way to the database (see bookkeeper.BatchWriter), an hour is only
+rolled up once it ended at least this long ago.
+*/
+const settleTime = time.Minute
+
+/*
+Where the price history and its aggregates are stored.
+*/
+type PriceHistoryStore interface {
+	StreamPriceRecords(from time.Time, to time.Time, handle func(bookkeeper.PriceRecord) error) error
+	RecordAggregates(priceAggregates []bookkeeper.PriceAggregate, spreadAggregates []bookkeeper.SpreadAggregate) error
+	LatestAggregateTime(intervalMinutes int) (time.Time, error)
+	OldestPriceRecordTime(withOpportunities bool) (time.Time, error)
+	DeletePriceRecords(from time.Time, to time.Time) (int64, error)
+}
+
+type RetentionError struct {
+	Msg string
+}
+
+func (e *RetentionError) Error() string {
+	return fmt.Sprintf("Error applying the price history retention: %s", e.Msg)
+}
+
+/*
+Configuration of the retention.
+  - RawMaxAge: raw quotes older than this are deleted once they are rolled up. 0 keeps them forever.
+  - SpreadMaxQuoteAge: a quote is only compared with the quotes of other exchanges that are at most this old.
+*/
+type Config struct {
+	RawMaxAge         time.Duration
+	SpreadMaxQuoteAge time.Duration
+}
+
+/*
+Load the retention configuration from the configuration file (RETENTION).
+*/
+func LoadConfig() Config {
+	return Config{
+		RawMaxAge:         time.Duration(viper.GetInt("RETENTION.RAW_MAX_AGE_HOURS")) * time.Hour,
+		SpreadMaxQuoteAge: time.Duration(viper.GetInt("RETENTION.SPREAD_MAX_QUOTE_AGE_SECONDS")) * time.Second,
+	}
+}
+
+/*
+Rolls the raw quotes of price_records into price and spread aggregates of every exchange and currency pair and deletes
+the raw quotes that are older than the configured age. Hours are rolled up completely and in order, so the next run
+continues after the latest hourly aggregate. Arbitrage events and the quotes they were detected with are kept.
+*/
+type Retention struct {
+	store  PriceHistoryStore
+	config Config
+}
+
+func NewRetention(store PriceHistoryStore, config Config) *Retention {
+	if config.SpreadMaxQuoteAge <= 0 {
+		config.SpreadMaxQuoteAge = 10 * time.Second
+	}
+	return &Retention{
+		store:  store,
+		config: config,
+	}
+}
+
+/*
+Roll up and delete the raw quotes up to now. Run by the scheduler of the Arbitrage Hunter, errors are logged.
+*/
+func (r *Retention) Run() {
+	if err := r.Apply(time.Now().UTC()); err != nil {
+		utils.Logger.Error(err.Error())
+	}
+}
+
+/*
+Roll up every complete hour before now that was not rolled up yet, then delete the raw quotes that are older than the
+configured age and were rolled up.
+*/
+func (r *Retention) Apply(now time.Time) error {
+	rolledUpTo, err := r.rollUp(now)
+	if err != nil {
+		return err
+	}
+	if r.config.RawMaxAge <= 0 || rolledUpTo.IsZero() {
+		return nil
+	}
+	cutoff := now.Add(-r.config.RawMaxAge)
+	if cutoff.After(rolledUpTo) {
+		cutoff = rolledUpTo
+	}
+	return r.prune(cutoff)
+}
+
+/*
+Roll up the hours after the latest hourly aggregate. Returns the end of the rolled up history, zero if there is none.
+*/
+func (r *Retention) rollUp(now time.Time) (time.Time, error) {
+	to := now.Add(-settleTime).Truncate(time.Hour)
+	latest, err := r.store.LatestAggregateTime(60)
+	if err != nil {
+		return time.Time{}, &RetentionError{Msg: err.Error()}
+	}
+	from := latest.Add(time.Hour)
+	if latest.IsZero() {
+		oldest, err := r.store.OldestPriceRecordTime(true)
+		if err != nil {
+			return time.Time{}, &RetentionError{Msg: err.Error()}
+		}
+		if oldest.IsZero() {
+			return time.Time{}, nil
+		}
+		from = oldest.Truncate(time.Hour)
+	}
+	if !from.Before(to) {
+		return from, nil
+	}
+
+	aggregator := newAggregator(r.config.SpreadMaxQuoteAge)
+	var hour time.Time
+	flush := func() error {
+		quotes, priceAggregates, spreadAggregates := aggregator.flush()
+		if quotes == 0 {
+			return nil
+		}
+		if err := r.store.RecordAggregates(priceAggregates, spreadAggregates); err != nil {
+			return err
+		}
+		utils.Logger.Info(fmt.Sprintf("Rolled up %d quotes of %s into %d price and %d spread aggregates.", quotes, hour.Format(time.RFC3339), len(priceAggregates), len(spreadAggregates)))
+		return nil
+	}
+	err = r.store.StreamPriceRecords(from, to, func(quote bookkeeper.PriceRecord) error {
+		if quoteHour := quote.Timestamp.UTC().Truncate(time.Hour); quoteHour != hour {
+			if err := flush(); err != nil {
+				return err
+			}
+			hour = quoteHour
+		}
+		aggregator.add(quote)
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return time.Time{}, &RetentionError{Msg: fmt.Sprintf("rolling up the quotes of %s: %v", hour.Format(time.RFC3339), err)}
+	}
+	return to, nil
+}
+
+/*
+Delete the raw quotes before cutoff one hour at a time, starting with the oldest.
+*/
+func (r *Retention) prune(cutoff time.Time) error {
+	var deleted int64
+	for {
+		oldest, err := r.store.OldestPriceRecordTime(false)
+		if err != nil {
+			return &RetentionError{Msg: err.Error()}
+		}
+		if oldest.IsZero() || !oldest.Before(cutoff) {
+			break
+		}
+		to := oldest.Truncate(time.Hour).Add(time.Hour)
+		if to.After(cutoff) {
+			to = cutoff
+		}
+		count, err := r.store.DeletePriceRecords(oldest, to)
+		if err != nil {
+			return &RetentionError{Msg: err.Error()}
+		}
+		deleted += count
+		utils.Logger.Info(fmt.Sprintf("Deleted %d raw quotes before %s, %d in total.", count, to.Format(time.RFC3339), deleted))
+	}
+	return nil
+}
+
+type priceKey struct {
+	exchange        string
+	currency        string
+	intervalMinutes int
+	timestamp       time.Time
+}
+
+type spreadKey struct {
+	currency        string
+	exchangeA       string
+	exchangeB       string
+	intervalMinutes int
+	timestamp       time.Time
+}
+
+/*
+The open, high, low and close of a series and the sum of its values for the average.
+*/
+type ohlc struct {
+	open  float64
+	high  float64
+	low   float64
+	close float64
+	sum   float64
+	count int
+}
+
+func (o *ohlc) add(value float64) {
+	if o.count == 0 {
+		o.open, o.high, o.low = value, value, value
+	}
+	if value > o.high {
+		o.high = value
+	}
+	if value < o.low {
+		o.low = value
+	}
+	o.close = value
+	o.sum += value
+	o.count++
+}
+
+/*
+Aggregates quotes in timestamp order. The latest quote of every exchange is kept across flushes, so that the spreads at
+the start of an hour are compared with the quotes at the end of the previous one.
+*/
+type aggregator struct {
+	spreadMaxQuoteAge time.Duration
+	quotes            int
+	prices            map[priceKey]*ohlc
+	spreads           map[spreadKey]*ohlc
+	latest            map[string]map[string]bookkeeper.PriceRecord
+}
+
+func newAggregator(spreadMaxQuoteAge time.Duration) *aggregator {
+	return &aggregator{
+		spreadMaxQuoteAge: spreadMaxQuoteAge,
+		prices:            make(map[priceKey]*ohlc),
+		spreads:           make(map[spreadKey]*ohlc),
+		latest:            make(map[string]map[string]bookkeeper.PriceRecord),
+	}
+}
+
+func (a *aggregator) add(quote bookkeeper.PriceRecord) {
+	a.quotes++
+	timestamp := quote.Timestamp.UTC()
+	if a.latest[quote.Currency] == nil {
+		a.latest[quote.Currency] = make(map[string]bookkeeper.PriceRecord)
+	}
+	for _, interval := range AggregateIntervals {
+		start := timestamp.Truncate(time.Duration(interval) * time.Minute)
+		a.price(priceKey{quote.Exchange, quote.Currency, interval, start}).add(quote.Price)
+
+		for exchange, other := range a.latest[quote.Currency] {
+			if exchange == quote.Exchange || timestamp.Sub(other.Timestamp) > a.spreadMaxQuoteAge {
+				continue
+			}
+			quoteA, quoteB := quote, other
+			if quoteA.Exchange > quoteB.Exchange {
+				quoteA, quoteB = quoteB, quoteA
+			}
+			spread := (quoteA.Price - quoteB.Price) / quoteB.Price * 100
+			a.spread(spreadKey{quote.Currency, quoteA.Exchange, quoteB.Exchange, interval, start}).add(spread)
+		}
+	}
+	a.latest[quote.Currency][quote.Exchange] = quote
+}
+
+func (a *aggregator) price(key priceKey) *ohlc {
+	if a.prices[key] == nil {
+		a.prices[key] = &ohlc{}
+	}
+	return a.prices[key]
+}
+
+func (a *aggregator) spread(key spreadKey) *ohlc {
+	if a.spreads[key] == nil {
+		a.spreads[key] = &ohlc{}
+	}
+	return a.spreads[key]
+}
+
+/*
+Returns the number of quotes and the aggregates since the last flush, ordered by interval, time, currency and exchange.
+*/
+func (a *aggregator) flush() (int, []bookkeeper.PriceAggregate, []bookkeeper.SpreadAggregate) {
+	priceAggregates := make([]bookkeeper.PriceAggregate, 0, len(a.prices))
+	for key, value := range a.prices {
+		priceAggregates = append(priceAggregates, bookkeeper.PriceAggregate{Exchange: key.exchange, Currency: key.currency, IntervalMinutes: key.intervalMinutes, Timestamp: key.timestamp, Open: value.open, High: value.high, Low: value.low, Close: value.close, Average: value.sum / float64(value.count), Count: value.count})
+	}
+	sort.Slice(priceAggregates, func(i, j int) bool {
+		x, y := priceAggregates[i], priceAggregates[j]
+		if x.IntervalMinutes != y.IntervalMinutes {
+			return x.IntervalMinutes < y.IntervalMinutes
+		}
+		if !x.Timestamp.Equal(y.Timestamp) {
+			return x.Timestamp.Before(y.Timestamp)
+		}
+		if x.Currency != y.Currency {
+			return x.Currency < y.Currency
+		}
+		return x.Exchange < y.Exchange
+	})
+
+	spreadAggregates := make([]bookkeeper.SpreadAggregate, 0, len(a.spreads))
+	for key, value := range a.spreads {
+		spreadAggregates = append(spreadAggregates, bookkeeper.SpreadAggregate{Currency: key.currency, ExchangeA: key.exchangeA, ExchangeB: key.exchangeB, IntervalMinutes: key.intervalMinutes, Timestamp: key.timestamp, Open: value.open, High: value.high, Low: value.low, Close: value.close, Average: value.sum / float64(value.count), Count: value.count})
+	}
+	sort.Slice(spreadAggregates, func(i, j int) bool {
+		x, y := spreadAggregates[i], spreadAggregates[j]
+		if x.IntervalMinutes != y.IntervalMinutes {
+			return x.IntervalMinutes < y.IntervalMinutes
+		}
+		if !x.Timestamp.Equal(y.Timestamp) {
+			return x.Timestamp.Before(y.Timestamp)
+		}
+		if x.Currency != y.Currency {
+			return x.Currency < y.Currency
+		}
+		if x.ExchangeA != y.ExchangeA {
+			return x.ExchangeA < y.ExchangeA
+		}
+		return x.ExchangeB < y.ExchangeB
+	})
+
+	quotes := a.quotes
+	a.quotes = 0
+	a.prices = make(map[priceKey]*ohlc)
+	a.spreads = make(map[spreadKey]*ohlc)
+	return quotes, priceAggregates, spreadAggregates
+}
+
+/*
+Stores the price history and its aggregates in the database.
+*/
+type DatabaseStore struct{}
+
+func (DatabaseStore) StreamPriceRecords(from time.Time, to time.Time, handle func(bookkeeper.PriceRecord) error) error {
+	return bookkeeper.StreamPriceRecords(from, to, handle)
+}
+
+func (DatabaseStore) RecordAggregates(priceAggregates []bookkeeper.PriceAggregate, spreadAggregates []bookkeeper.SpreadAggregate) error {
+	return bookkeeper.RecordAggregates(priceAggregates, spreadAggregates)
+}
+
+func (DatabaseStore) LatestAggregateTime(intervalMinutes int) (time.Time, error) {
+	return bookkeeper.GetLatestPriceAggregateTime(intervalMinutes)
+}
+
+func (DatabaseStore) OldestPriceRecordTime(withOpportunities bool) (time.Time, error) {
+	return bookkeeper.GetOldestPriceRecordTime(withOpportunities)
+}
+
+func (DatabaseStore) DeletePriceRecords(from time.Time, to time.Time) (int64, error) {
+	return bookkeeper.DeletePriceRecords(from, to)
+}
@@ -0,0 +1,235 @@
+package retention
+
+import (
+	"cryptoArbitrageBot/bookkeeper"
+	"cryptoArbitrageBot/internal/utils"
+	"fmt"
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+	"log"
+	"sort"
+	"testing"
+	"time"
+)
+
+func setupTest(tb testing.TB) func(tb testing.TB) {
+	log.Println("Setup tests.")
+
+	viper.SetConfigName("config-DEV")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath("../")
+	err := viper.ReadInConfig() // Find and read the config file
+	if err != nil {
+		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+	}
+
+	utils.InitializeLogger()
+	utils.SetLoggerLevel("DEBUG")
+
+	return func(tb testing.TB) {
+		log.Println("Teardown tests.")
+	}
+}
+
+/*
+Keeps the price records in timestamp order and the aggregates by interval and start.
+*/
+type fakeStore struct {
+	priceRecords     []bookkeeper.PriceRecord
+	priceAggregates  map[string]bookkeeper.PriceAggregate
+	spreadAggregates map[string]bookkeeper.SpreadAggregate
+	streamed         int
+}
+
+func newFakeStore(priceRecords ...bookkeeper.PriceRecord) *fakeStore {
+	sort.SliceStable(priceRecords, func(i, j int) bool {
+		return priceRecords[i].Timestamp.Before(priceRecords[j].Timestamp)
+	})
+	return &fakeStore{
+		priceRecords:     priceRecords,
+		priceAggregates:  make(map[string]bookkeeper.PriceAggregate),
+		spreadAggregates: make(map[string]bookkeeper.SpreadAggregate),
+	}
+}
+
+func (f *fakeStore) StreamPriceRecords(from time.Time, to time.Time, handle func(bookkeeper.PriceRecord) error) error {
+	for _, priceRecord := range f.priceRecords {
+		if priceRecord.Timestamp.Before(from) || !priceRecord.Timestamp.Before(to) {
+			continue
+		}
+		f.streamed++
+		if err := handle(priceRecord); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) RecordAggregates(priceAggregates []bookkeeper.PriceAggregate, spreadAggregates []bookkeeper.SpreadAggregate) error {
+	for _, aggregate := range priceAggregates {
+		f.priceAggregates[fmt.Sprintf("%s %s %d %s", aggregate.Exchange, aggregate.Currency, aggregate.IntervalMinutes, aggregate.Timestamp.Format("15:04"))] = aggregate
+	}
+	for _, aggregate := range spreadAggregates {
+		f.spreadAggregates[fmt.Sprintf("%s %s-%s %d %s", aggregate.Currency, aggregate.ExchangeA, aggregate.ExchangeB, aggregate.IntervalMinutes, aggregate.Timestamp.Format("15:04"))] = aggregate
+	}
+	return nil
+}
+
+func (f *fakeStore) LatestAggregateTime(intervalMinutes int) (time.Time, error) {
+	var latest time.Time
+	for _, aggregate := range f.priceAggregates {
+		if aggregate.IntervalMinutes == intervalMinutes && aggregate.Timestamp.After(latest) {
+			latest = aggregate.Timestamp
+		}
+	}
+	return latest, nil
+}
+
+func (f *fakeStore) OldestPriceRecordTime(withOpportunities bool) (time.Time, error) {
+	for _, priceRecord := range f.priceRecords {
+		if withOpportunities || !priceRecord.IsArbitrageOpportunity {
+			return priceRecord.Timestamp, nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+func (f *fakeStore) DeletePriceRecords(from time.Time, to time.Time) (int64, error) {
+	var deleted int64
+	kept := f.priceRecords[:0]
+	for _, priceRecord := range f.priceRecords {
+		if !priceRecord.IsArbitrageOpportunity && !priceRecord.Timestamp.Before(from) && priceRecord.Timestamp.Before(to) {
+			deleted++
+			continue
+		}
+		kept = append(kept, priceRecord)
+	}
+	f.priceRecords = kept
+	return deleted, nil
+}
+
+func quoteForTest(exchange string, timestamp string, price float64) bookkeeper.PriceRecord {
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		panic(err)
+	}
+	return bookkeeper.PriceRecord{Exchange: exchange, Currency: "BTCUSD", Timestamp: parsed, Price: price}
+}
+
+func Test_RollUp(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	store := newFakeStore(
+		quoteForTest("Coinbase", "2023-07-26T10:00:05Z", 100),
+		quoteForTest("Kraken", "2023-07-26T10:00:07Z", 101),
+		quoteForTest("Coinbase", "2023-07-26T10:00:40Z", 104),
+		quoteForTest("Coinbase", "2023-07-26T10:00:55Z", 102),
+		quoteForTest("Kraken", "2023-07-26T10:01:30Z", 99),
+		quoteForTest("Coinbase", "2023-07-26T11:59:58Z", 103),
+		// After the last complete hour, rolled up by the next run
+		quoteForTest("Coinbase", "2023-07-26T12:00:01Z", 110),
+	)
+	retention := NewRetention(store, Config{SpreadMaxQuoteAge: 10 * time.Second})
+	assert.Equal(t, retention.Apply(time.Date(2023, 7, 26, 12, 30, 0, 0, time.UTC)), nil)
+
+	tests := []struct {
+		name string
+		key  string
+		want bookkeeper.PriceAggregate
+	}{
+		{
+			name: "A minute is rolled up into its open, high, low, close and average.",
+			key:  "Coinbase BTCUSD 1 10:00",
+			want: bookkeeper.PriceAggregate{Exchange: "Coinbase", Currency: "BTCUSD", IntervalMinutes: 1, Timestamp: time.Date(2023, 7, 26, 10, 0, 0, 0, time.UTC), Open: 100, High: 104, Low: 100, Close: 102, Average: 102, Count: 3},
+		},
+		{
+			name: "An hour is rolled up across its minutes.",
+			key:  "Kraken BTCUSD 60 10:00",
+			want: bookkeeper.PriceAggregate{Exchange: "Kraken", Currency: "BTCUSD", IntervalMinutes: 60, Timestamp: time.Date(2023, 7, 26, 10, 0, 0, 0, time.UTC), Open: 101, High: 101, Low: 99, Close: 99, Average: 100, Count: 2},
+		},
+		{
+			name: "Later hours are rolled up in the same run.",
+			key:  "Coinbase BTCUSD 60 11:00",
+			want: bookkeeper.PriceAggregate{Exchange: "Coinbase", Currency: "BTCUSD", IntervalMinutes: 60, Timestamp: time.Date(2023, 7, 26, 11, 0, 0, 0, time.UTC), Open: 103, High: 103, Low: 103, Close: 103, Average: 103, Count: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, store.priceAggregates[tt.key], tt.want)
+		})
+	}
+
+	t.Run("Spreads compare the quotes of two exchanges in name order.", func(t *testing.T) {
+		// 10:00:07 Kraken 101 against Coinbase 100, 10:00:55 Coinbase 102 has no recent Kraken quote
+		spread := store.spreadAggregates["BTCUSD Coinbase-Kraken 1 10:00"]
+		assert.Equal(t, spread.Count, 1)
+		assert.Equal(t, spread.Open, (100.0-101.0)/101.0*100)
+		_, ok := store.spreadAggregates["BTCUSD Coinbase-Kraken 1 10:01"]
+		assert.Equal(t, ok, false)
+	})
+
+	t.Run("The hour that has not ended is not rolled up.", func(t *testing.T) {
+		_, ok := store.priceAggregates["Coinbase BTCUSD 60 12:00"]
+		assert.Equal(t, ok, false)
+	})
+
+	t.Run("The next run continues after the latest hour.", func(t *testing.T) {
+		store.streamed = 0
+		assert.Equal(t, retention.Apply(time.Date(2023, 7, 26, 13, 5, 0, 0, time.UTC)), nil)
+		assert.Equal(t, store.streamed, 1)
+		assert.Equal(t, store.priceAggregates["Coinbase BTCUSD 60 12:00"].Close, 110.0)
+	})
+}
+
+func Test_Prune(t *testing.T) {
+	teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	opportunity := quoteForTest("Kraken", "2023-07-26T08:30:00Z", 101)
+	opportunity.IsArbitrageOpportunity = true
+
+	tests := []struct {
+		name      string
+		rawMaxAge time.Duration
+		now       time.Time
+		want      []string
+	}{
+		{
+			name:      "Raw quotes older than the maximum age are deleted, opportunities are kept.",
+			rawMaxAge: 90 * time.Minute,
+			now:       time.Date(2023, 7, 26, 11, 30, 0, 0, time.UTC),
+			want:      []string{"08:30", "10:15", "11:10"},
+		},
+		{
+			name:      "Raw quotes that are not rolled up yet are kept.",
+			rawMaxAge: time.Minute,
+			now:       time.Date(2023, 7, 26, 11, 30, 0, 0, time.UTC),
+			want:      []string{"08:30", "11:10"},
+		},
+		{
+			name:      "A maximum age of 0 keeps every raw quote.",
+			rawMaxAge: 0,
+			now:       time.Date(2023, 7, 26, 11, 30, 0, 0, time.UTC),
+			want:      []string{"07:15", "08:30", "09:45", "10:15", "11:10"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(
+				quoteForTest("Coinbase", "2023-07-26T07:15:00Z", 100),
+				opportunity,
+				quoteForTest("Coinbase", "2023-07-26T09:45:00Z", 100),
+				quoteForTest("Coinbase", "2023-07-26T10:15:00Z", 100),
+				quoteForTest("Coinbase", "2023-07-26T11:10:00Z", 100),
+			)
+			assert.Equal(t, NewRetention(store, Config{RawMaxAge: tt.rawMaxAge}).Apply(tt.now), nil)
+
+			var kept []string
+			for _, priceRecord := range store.priceRecords {
+				kept = append(kept, priceRecord.Timestamp.Format("15:04"))
+			}
+			assert.Equal(t, kept, tt.want)
+		})
+	}
+}